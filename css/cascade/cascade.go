@@ -0,0 +1,425 @@
+/*
+Package cascade computes the styles of html elements from css stylesheets,
+the <style> elements of a document and inline style attributes.
+
+	tree, _ := h5.New(rdr)
+	styles, err := cascade.Compute(tree, sheet)
+	if styles[n].Get("display") == "none" {
+	    // n is hidden
+	}
+
+The cascade follows http://www.w3.org/TR/css-cascade-3/ using the
+selector.Chain matching and specificity, source order, !important and
+inheritance for the inherited properties. Custom properties are inherited
+and var() functions are substituted; a value that is invalid at
+computed-value time behaves like unset. Shorthands like margin and font
+are replaced by their longhands unless their values use var(). Otherwise
+values are the cascaded specified values; relative lengths and keywords
+are not resolved.
+Rules inside conditional AtRules like @media are not applied.
+*/
+package cascade
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+// Style maps css property names to their values for one element.
+type Style map[string]string
+
+// Get returns the value of the property prop falling back to its initial
+// value if it isn't set.
+func (s Style) Get(prop string) string {
+	if v, ok := s[prop]; ok {
+		return v
+	}
+	return Initial(prop)
+}
+
+// Styles maps element nodes to their Style.
+type Styles map[*html.Node]Style
+
+// The precedence levels of declarations from lowest to highest.
+// http://www.w3.org/TR/css-cascade-3/#cascade-origin
+const (
+	userAgentNormal = iota
+	authorNormal
+	inlineNormal
+	authorImportant
+	inlineImportant
+)
+
+// weight orders declarations in the cascade.
+type weight struct {
+	level       int
+	specificity int64
+	order       int
+}
+
+func (w weight) less(o weight) bool {
+	if w.level != o.level {
+		return w.level < o.level
+	}
+	if w.specificity != o.specificity {
+		return w.specificity < o.specificity
+	}
+	return w.order < o.order
+}
+
+// declaration is a css.Declaration with its place in the cascade.
+type declaration struct {
+	css.Declaration
+	weight
+}
+
+type rule struct {
+	chn    *selector.Chain
+	decls  css.DeclarationList
+	author bool
+	order  int
+}
+
+// rules is a list of rules in source order.
+type rules []rule
+
+func (rs *rules) add(ss *css.Stylesheet, author bool) {
+	for _, st := range css.Flatten(ss).Statements {
+		if st.Ruleset == nil {
+			continue
+		}
+		for _, chn := range st.Ruleset.Selector {
+			if !chn.Matchable() {
+				continue
+			}
+			*rs = append(*rs, rule{chn, st.Ruleset.DeclarationList, author, len(*rs)})
+		}
+	}
+}
+
+// match returns the declarations of every rule matching n.
+func (rs rules) match(n *html.Node) []declaration {
+	var ds []declaration
+	for _, r := range rs {
+		if !r.chn.Match(n) {
+			continue
+		}
+		for _, d := range r.decls {
+			level := userAgentNormal
+			switch {
+			case r.author && d.Important:
+				level = authorImportant
+			case r.author:
+				level = authorNormal
+			}
+			ds = append(ds, declaration{d, weight{level, r.chn.Specificity(), r.order}})
+		}
+	}
+	return ds
+}
+
+// inline returns the declarations in the style attribute of n.
+func inline(n *html.Node) ([]declaration, error) {
+	var ds []declaration
+	for _, a := range n.Attr {
+		if a.Key != "style" {
+			continue
+		}
+		dl, err := css.ParseDeclarations(a.Val)
+		if err != nil {
+			return nil, err
+		}
+		for i, d := range dl {
+			level := inlineNormal
+			if d.Important {
+				level = inlineImportant
+			}
+			ds = append(ds, declaration{d, weight{level: level, order: i}})
+		}
+	}
+	return ds, nil
+}
+
+// sortDeclarations sorts declarations from lowest to highest precedence.
+func sortDeclarations(ds []declaration) {
+	sort.SliceStable(ds, func(i, j int) bool {
+		return ds[i].weight.less(ds[j].weight)
+	})
+}
+
+// StyleSheets parses the <style> elements in the tree rooted at n in
+// document order.
+func StyleSheets(n *html.Node) ([]*css.Stylesheet, error) {
+	var sheets []*css.Stylesheet
+	var err error
+	h5.WalkNodes(n, func(n *html.Node) {
+		if err != nil || !isStyleElement(n) {
+			return
+		}
+		var ss *css.Stylesheet
+		ss, err = css.ParseString(textContent(n))
+		sheets = append(sheets, ss)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sheets, nil
+}
+
+func isStyleElement(n *html.Node) bool {
+	if n.Type != html.ElementNode || h5.Data(n) != "style" {
+		return false
+	}
+	for _, a := range n.Attr {
+		if a.Key == "type" && a.Val != "" && !strings.EqualFold(a.Val, "text/css") {
+			return false
+		}
+	}
+	return true
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+	}
+	return b.String()
+}
+
+// Cascade matches elements against a list of author stylesheets.
+type Cascade struct {
+	rs rules
+}
+
+// New constructs a Cascade from author stylesheets in source order.
+func New(sheets ...*css.Stylesheet) *Cascade {
+	c := &Cascade{}
+	for _, ss := range sheets {
+		c.rs.add(ss, true)
+	}
+	return c
+}
+
+// Declarations returns the declarations from the stylesheets and the style
+// attribute of n that win the cascade. They are ordered from lowest to
+// highest precedence and there is one per property.
+func (c *Cascade) Declarations(n *html.Node) (css.DeclarationList, error) {
+	ds, err := c.cascade(n)
+	if err != nil {
+		return nil, err
+	}
+	var dl css.DeclarationList
+	for i, d := range ds {
+		if !overridden(ds[i+1:], d.Property) {
+			dl = append(dl, d.Declaration)
+		}
+	}
+	return dl, nil
+}
+
+// Vars returns the custom properties of n with their var() functions
+// resolved. parent are the Vars of the parent of n which n inherits.
+func (c *Cascade) Vars(n *html.Node, parent map[string]string) (map[string]string, error) {
+	dl, err := c.Declarations(n)
+	if err != nil {
+		return nil, err
+	}
+	vars := map[string]string{}
+	for prop, v := range parent {
+		vars[prop] = v
+	}
+	for _, d := range dl {
+		if !css.IsCustomProperty(d.Property) {
+			continue
+		}
+		switch strings.ToLower(d.Value) {
+		case "inherit", "unset":
+		case "initial":
+			delete(vars, d.Property)
+		default:
+			vars[d.Property] = d.Value
+		}
+	}
+	return css.ResolveVars(vars), nil
+}
+
+func overridden(ds []declaration, prop string) bool {
+	for _, d := range ds {
+		if d.Property == prop {
+			return true
+		}
+	}
+	return false
+}
+
+// cascade returns every declaration applying to n sorted from lowest to
+// highest precedence.
+func (c *Cascade) cascade(n *html.Node) ([]declaration, error) {
+	ds := c.rs.match(n)
+	inl, err := inline(n)
+	if err != nil {
+		return nil, err
+	}
+	ds = append(ds, inl...)
+	sortDeclarations(ds)
+	return ds, nil
+}
+
+// Compute computes the Style of every element in the tree. The cascade is
+// built from a user agent stylesheet, the sheets passed in, the <style>
+// elements in the document and the style attributes of the elements.
+func Compute(tree h5.Tree, sheets ...*css.Stylesheet) (Styles, error) {
+	docSheets, err := StyleSheets(tree.Top())
+	if err != nil {
+		return nil, err
+	}
+	c := &Cascade{}
+	c.rs.add(userAgent, false)
+	for _, ss := range append(sheets, docSheets...) {
+		c.rs.add(ss, true)
+	}
+	styles := Styles{}
+	tree.Walk(func(n *html.Node) {
+		if err != nil || n.Type != html.ElementNode {
+			return
+		}
+		styles[n], err = c.computeStyle(n, styles[n.Parent])
+	})
+	if err != nil {
+		return nil, err
+	}
+	return styles, nil
+}
+
+func (c *Cascade) computeStyle(n *html.Node, parent Style) (Style, error) {
+	ds, err := c.cascade(n)
+	if err != nil {
+		return nil, err
+	}
+	s := Style{}
+	// Later declarations have a higher precedence so they overwrite
+	// earlier ones. Shorthands are expanded so they cascade with their
+	// longhands. A shorthand using var() can't be expanded until its
+	// var() are substituted so it replaces the longhands before it for
+	// now.
+	order := map[string]int{}
+	for i, d := range ds {
+		delete(s, d.Property)
+		for _, l := range css.ExpandShorthands(css.DeclarationList{d.Declaration}) {
+			for _, lh := range css.Longhands(l.Property) {
+				delete(s, lh)
+			}
+			s[l.Property] = l.Value
+			order[l.Property] = i
+		}
+	}
+	for prop, v := range s {
+		switch strings.ToLower(v) {
+		case "inherit":
+			inherit(s, parent, prop)
+		case "initial":
+			setInitial(s, prop)
+		case "unset":
+			if Inherited(prop) {
+				inherit(s, parent, prop)
+			} else {
+				setInitial(s, prop)
+			}
+		}
+	}
+	for prop, v := range parent {
+		if _, ok := s[prop]; !ok && Inherited(prop) {
+			s[prop] = v
+		}
+	}
+	resolveVars(s, parent)
+	expandVarShorthands(s, parent, order)
+	return s, nil
+}
+
+// expandVarShorthands sets the longhands of the shorthands in s that used
+// var() unless a later declaration set them. order is the position of the
+// declaration that set each property.
+func expandVarShorthands(s, parent Style, order map[string]int) {
+	for prop, v := range s {
+		i, ok := order[prop]
+		if !ok || css.Longhands(prop) == nil {
+			continue
+		}
+		dl, ok := css.Declaration{Property: prop, Value: v}.Expand()
+		if !ok {
+			continue
+		}
+		for _, l := range dl {
+			if j, ok := order[l.Property]; ok && j > i || l.Property == prop {
+				continue
+			}
+			switch strings.ToLower(l.Value) {
+			case "inherit":
+				inherit(s, parent, l.Property)
+			case "initial":
+				setInitial(s, l.Property)
+			default:
+				s[l.Property] = l.Value
+			}
+		}
+	}
+}
+
+// resolveVars substitutes the var() functions in the values of s. The
+// custom properties of parent have already been resolved.
+// http://www.w3.org/TR/css-variables-1/#invalid-at-computed-value-time
+func resolveVars(s, parent Style) {
+	vars := map[string]string{}
+	for prop, v := range s {
+		if css.IsCustomProperty(prop) {
+			vars[prop] = v
+			delete(s, prop)
+		}
+	}
+	for prop, v := range css.ResolveVars(vars) {
+		s[prop] = v
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := s[name]
+		return v, ok
+	}
+	for prop, v := range s {
+		if css.IsCustomProperty(prop) {
+			continue
+		}
+		if v, ok := css.SubstituteVars(v, lookup); ok {
+			s[prop] = v
+		} else if Inherited(prop) {
+			// An invalid value behaves like unset.
+			inherit(s, parent, prop)
+		} else {
+			setInitial(s, prop)
+		}
+	}
+}
+
+func inherit(s, parent Style, prop string) {
+	if v, ok := parent[prop]; ok {
+		s[prop] = v
+	} else {
+		setInitial(s, prop)
+	}
+}
+
+func setInitial(s Style, prop string) {
+	if v := Initial(prop); v != "" {
+		s[prop] = v
+	} else {
+		// Keep the keyword so the parent value isn't inherited.
+		s[prop] = "initial"
+	}
+}
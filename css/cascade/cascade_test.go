@@ -0,0 +1,206 @@
+package cascade
+
+import (
+	"testing"
+
+	"golang.org/x/net/html"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+func byId(tree *h5.Tree, id string) *html.Node {
+	var found *html.Node
+	tree.Walk(func(n *html.Node) {
+		for _, a := range n.Attr {
+			if a.Key == "id" && a.Val == id {
+				found = n
+			}
+		}
+	})
+	return found
+}
+
+func compute(t *testing.T, doc string, sheets ...string) (*h5.Tree, Styles) {
+	tree, err := h5.NewFromString(doc)
+	if err != nil {
+		t.Fatalf("Error parsing html: %s", err)
+	}
+	var ss []*css.Stylesheet
+	for _, s := range sheets {
+		sheet, err := css.ParseString(s)
+		if err != nil {
+			t.Fatalf("Error parsing css: %s", err)
+		}
+		ss = append(ss, sheet)
+	}
+	styles, err := Compute(*tree, ss...)
+	if err != nil {
+		t.Fatalf("Error computing styles: %s", err)
+	}
+	return tree, styles
+}
+
+func TestCompute(t *testing.T) {
+	tree, styles := compute(t, `<html><head><style>
+		p { color: red; }
+		.note { color: blue; }
+		#x { color: green; }
+		p { color: purple; }
+		.imp { color: orange !important; }
+	</style></head><body>
+		<p id=a>a</p>
+		<p id=b class=note>b</p>
+		<p id=x class=note>x</p>
+		<p id=c style="color: black">c</p>
+		<p id=d class=imp style="color: black">d</p>
+		<p id=e class=imp style="color: black !important">e</p>
+	</body></html>`)
+	cases := map[string]string{
+		"a": "purple", // source order
+		"b": "blue",   // class beats tag
+		"x": "green",  // id beats class
+		"c": "black",  // inline beats id
+		"d": "orange", // !important beats inline
+		"e": "black",  // inline !important beats !important
+	}
+	for id, color := range cases {
+		if got := styles[byId(tree, id)].Get("color"); got != color {
+			t.Errorf("#%s expected color %s got %s", id, color, got)
+		}
+	}
+}
+
+func TestComputeInheritance(t *testing.T) {
+	tree, styles := compute(t, `<div id=a style="color: red; border: 1px solid; font-weight: bold">
+		<p id=b>b <span id=c style="border: inherit; color: initial">c</span></p>
+		<b id=d style="font-weight: unset">d</b>
+	</div>`)
+	cases := []struct {
+		id, prop, value string
+	}{
+		{"b", "color", "red"},
+		{"a", "border-top-style", "solid"},
+		{"b", "border-top-style", ""},
+		{"b", "display", "block"},
+		{"c", "display", "inline"},
+		{"c", "color", "canvastext"},
+		{"c", "border-top-width", "initial"},
+		{"c", "font-weight", "bold"},
+		{"d", "font-weight", "bold"},
+	}
+	for _, c := range cases {
+		if got := styles[byId(tree, c.id)].Get(c.prop); got != c.value {
+			t.Errorf("#%s expected %s: %q got %q", c.id, c.prop, c.value, got)
+		}
+	}
+}
+
+func TestComputeSheets(t *testing.T) {
+	tree, styles := compute(t,
+		`<style>div.x { display: flex; }</style><div id=a class=x><a id=b href=#>b</a></div><div id=c hidden></div>`,
+		`div { display: none; } a:hover { display: block; } div > a { font-weight: 700; }`)
+	cases := []struct {
+		id, prop, value string
+	}{
+		// The document's <style> elements come after the sheets passed in.
+		{"a", "display", "flex"},
+		{"b", "display", "inline"},
+		{"b", "font-weight", "700"},
+		{"c", "display", "none"},
+	}
+	for _, c := range cases {
+		if got := styles[byId(tree, c.id)].Get(c.prop); got != c.value {
+			t.Errorf("#%s expected %s: %q got %q", c.id, c.prop, c.value, got)
+		}
+	}
+}
+
+func TestComputeShorthands(t *testing.T) {
+	tree, styles := compute(t, `<style>
+		p { margin: 1px; font: bold 12px serif; }
+		.top { margin-top: 2px; }
+		.all { margin: 3px; }
+	</style>
+	<p id=a>a</p>
+	<p id=b class=top>b</p>
+	<p id=c class="top all" style="margin-left: 4px">c</p>`)
+	cases := []struct {
+		id, prop, value string
+	}{
+		{"a", "font-weight", "bold"},
+		{"a", "font-size", "12px"},
+		{"a", "font-family", "serif"},
+		{"a", "font-style", "normal"},
+		{"a", "margin-top", "1px"},
+		{"b", "margin-top", "2px"},
+		{"b", "margin-left", "1px"},
+		// .all comes after .top in the source.
+		{"c", "margin-top", "3px"},
+		{"c", "margin-left", "4px"},
+	}
+	for _, c := range cases {
+		if got := styles[byId(tree, c.id)].Get(c.prop); got != c.value {
+			t.Errorf("#%s expected %s: %q got %q", c.id, c.prop, c.value, got)
+		}
+	}
+}
+
+func TestCascadeDeclarations(t *testing.T) {
+	tree, _ := h5.NewFromString(`<p id=a class=x style="color: black; margin: 0">a</p>`)
+	ss, _ := css.ParseString(`p { color: red; padding: 1px; } .x { padding: 2px; margin: 1px !important; }`)
+	dl, err := New(ss).Declarations(byId(tree, "a"))
+	if err != nil {
+		t.Fatalf("Error cascading: %s", err)
+	}
+	expected := "padding: 2px; color: black; margin: 1px !important"
+	if dl.String() != expected {
+		t.Errorf("Expected %q got %q", expected, dl.String())
+	}
+}
+
+func TestComputeNesting(t *testing.T) {
+	tree, styles := compute(t,
+		`<style>.card { color: red; & > p { color: blue; } &.big p { font-size: 20px; } }</style><div id=a class=card><p id=b>b</p></div><div id=c class="card big"><p id=d>d</p></div>`)
+	cases := []struct {
+		id, prop, value string
+	}{
+		{"a", "color", "red"},
+		{"b", "color", "blue"},
+		{"b", "font-size", "medium"},
+		{"d", "font-size", "20px"},
+	}
+	for _, c := range cases {
+		if got := styles[byId(tree, c.id)].Get(c.prop); got != c.value {
+			t.Errorf("#%s expected %s: %q got %q", c.id, c.prop, c.value, got)
+		}
+	}
+}
+
+func TestComputeVars(t *testing.T) {
+	tree, styles := compute(t,
+		`<style>:root { --gap: 2px; --fg: red } .dark { --fg: white } p { color: var(--fg); margin: var(--gap) calc(var(--gap) * 2); font-size: var(--none) }</style><p id=a>a</p><div class=dark><p id=b>b</p></div><p id=c style="--gap: 1px">c</p>`+
+			`<div style="--m: 5px"><p id=d style="margin-top: 9px; margin: var(--m)">d</p>`+
+			`<p id=e style="margin-top: 9px; margin: var(--m); margin-top: 1px">e</p>`+
+			`<p id=f style="margin: var(--m); margin: 0">f</p></div>`)
+	cases := []struct {
+		id, prop, value string
+	}{
+		{"a", "color", "red"},
+		{"a", "margin", "2px calc(2px * 2)"},
+		{"a", "font-size", "medium"},
+		{"b", "color", "white"},
+		{"c", "margin", "1px calc(1px * 2)"},
+		{"d", "margin-top", "5px"},
+		{"d", "margin-left", "5px"},
+		{"e", "margin-top", "1px"},
+		{"e", "margin-left", "5px"},
+		{"f", "margin-top", "0"},
+		{"f", "margin", ""},
+	}
+	for _, c := range cases {
+		if got := styles[byId(tree, c.id)].Get(c.prop); got != c.value {
+			t.Errorf("#%s expected %s: %q got %q", c.id, c.prop, c.value, got)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package cascade
+
+import "go.marzhillstudios.com/pkg/go-html-transform/css"
+
+// userAgentCSS is a minimal user agent stylesheet covering the display and
+// font defaults of the html elements.
+// http://www.w3.org/TR/html5/rendering.html
+const userAgentCSS = `
+html, address, blockquote, body, center, dialog, div, figure, figcaption,
+footer, form, header, hr, legend, listing, main, p, plaintext, pre, xmp,
+article, aside, h1, h2, h3, h4, h5, h6, hgroup, nav, section, dir, dd, dl,
+dt, menu, ol, ul, details, summary, fieldset, optgroup { display: block; }
+li { display: list-item; }
+head, link, meta, script, style, template, title, base, datalist, noscript,
+param, rp, area, [hidden] { display: none; }
+table { display: table; }
+caption { display: table-caption; }
+colgroup { display: table-column-group; }
+col { display: table-column; }
+thead { display: table-header-group; }
+tbody { display: table-row-group; }
+tfoot { display: table-footer-group; }
+tr { display: table-row; }
+td, th { display: table-cell; }
+ruby { display: ruby; }
+rt { display: ruby-text; }
+b, strong, th, h1, h2, h3, h4, h5, h6, optgroup { font-weight: bold; }
+i, cite, em, var, address, dfn { font-style: italic; }
+h1 { font-size: 2em; }
+h2 { font-size: 1.5em; }
+h3 { font-size: 1.17em; }
+h5 { font-size: 0.83em; }
+h6 { font-size: 0.67em; }
+small { font-size: smaller; }
+big { font-size: larger; }
+pre, code, kbd, samp, tt, listing, plaintext, xmp { font-family: monospace; }
+pre, listing, plaintext, xmp { white-space: pre; }
+textarea { white-space: pre-wrap; }
+nobr { white-space: nowrap; }
+u, ins { text-decoration: underline; }
+s, strike, del { text-decoration: line-through; }
+center, th { text-align: center; }
+sub { vertical-align: sub; }
+sup { vertical-align: super; }
+`
+
+var userAgent *css.Stylesheet
+
+func init() {
+	var err error
+	userAgent, err = css.ParseString(userAgentCSS)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// inherited are the properties whose values are inherited by default.
+// http://www.w3.org/TR/CSS21/propidx.html
+var inherited = map[string]bool{
+	"azimuth":             true,
+	"border-collapse":     true,
+	"border-spacing":      true,
+	"caption-side":        true,
+	"color":               true,
+	"cursor":              true,
+	"direction":           true,
+	"empty-cells":         true,
+	"font":                true,
+	"font-family":         true,
+	"font-size":           true,
+	"font-stretch":        true,
+	"font-style":          true,
+	"font-variant":        true,
+	"font-weight":         true,
+	"hyphens":             true,
+	"letter-spacing":      true,
+	"line-height":         true,
+	"list-style":          true,
+	"list-style-image":    true,
+	"list-style-position": true,
+	"list-style-type":     true,
+	"orphans":             true,
+	"overflow-wrap":       true,
+	"pointer-events":      true,
+	"quotes":              true,
+	"tab-size":            true,
+	"text-align":          true,
+	"text-align-last":     true,
+	"text-indent":         true,
+	"text-shadow":         true,
+	"text-transform":      true,
+	"visibility":          true,
+	"white-space":         true,
+	"widows":              true,
+	"word-break":          true,
+	"word-spacing":        true,
+	"word-wrap":           true,
+	"writing-mode":        true,
+
+	// The longhands of font-variant and the ones font resets.
+	"font-feature-settings":   true,
+	"font-kerning":            true,
+	"font-language-override":  true,
+	"font-optical-sizing":     true,
+	"font-size-adjust":        true,
+	"font-variant-alternates": true,
+	"font-variant-caps":       true,
+	"font-variant-east-asian": true,
+	"font-variant-emoji":      true,
+	"font-variant-ligatures":  true,
+	"font-variant-numeric":    true,
+	"font-variant-position":   true,
+	"font-variation-settings": true,
+}
+
+// Inherited returns true if the property prop is inherited by default.
+// Custom properties are always inherited.
+func Inherited(prop string) bool {
+	return inherited[prop] || len(prop) > 2 && prop[:2] == "--"
+}
+
+// initial are the initial values of common properties.
+var initial = map[string]string{
+	"background-color": "transparent",
+	"background-image": "none",
+	"border-collapse":  "separate",
+	"color":            "canvastext",
+	"direction":        "ltr",
+	"display":          "inline",
+	"float":            "none",
+	"font-size":        "medium",
+	"font-style":       "normal",
+	"font-variant":     "normal",
+	"font-weight":      "normal",
+	"height":           "auto",
+	"letter-spacing":   "normal",
+	"line-height":      "normal",
+	"list-style-type":  "disc",
+	"opacity":          "1",
+	"overflow":         "visible",
+	"position":         "static",
+	"text-align":       "start",
+	"text-decoration":  "none",
+	"text-indent":      "0",
+	"text-transform":   "none",
+	"vertical-align":   "baseline",
+	"visibility":       "visible",
+	"white-space":      "normal",
+	"width":            "auto",
+	"word-spacing":     "normal",
+}
+
+// Initial returns the initial value of the property prop or an empty
+// string if it isn't known.
+func Initial(prop string) string {
+	return initial[prop]
+}
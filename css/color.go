@@ -0,0 +1,519 @@
+package css
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Color is an sRGB color with an alpha channel. Colors in other color
+// spaces like lab() are converted to sRGB when they are parsed and
+// clipped to its gamut.
+type Color struct {
+	R, G, B uint8
+	// Alpha from 0 (transparent) to 1 (opaque).
+	A float64
+	// Current is true for currentColor whose value is the color property
+	// of the element it applies to. The other fields are zero.
+	Current bool
+}
+
+// ParseColor parses a css color value like #fff, red, rgb(0, 0, 0) or
+// oklch(70% 0.1 120).
+func ParseColor(s string) (Color, error) {
+	vl, err := ParseValue(s)
+	if err != nil {
+		return Color{}, err
+	}
+	if len(vl) != 1 {
+		return Color{}, fmt.Errorf("Invalid color %q", s)
+	}
+	c, ok := vl[0].Color()
+	if !ok {
+		return Color{}, fmt.Errorf("Invalid color %q", s)
+	}
+	return c, nil
+}
+
+func colorFromComponent(cv ComponentValue) (Color, bool) {
+	switch cv.Type {
+	case HashValue:
+		return colorFromHex(cv.Value)
+	case IdentValue:
+		if cv.IsIdent("currentcolor") {
+			return Color{Current: true}, true
+		}
+		c, ok := namedColors[strings.ToLower(cv.Value)]
+		return c, ok
+	case FunctionValue:
+		switch strings.ToLower(cv.Value) {
+		case "rgb", "rgba":
+			return colorFromRGB(cv.Args)
+		case "hsl", "hsla":
+			return colorFromHSL(cv.Args)
+		case "hwb":
+			return colorFromHWB(cv.Args)
+		case "lab":
+			return colorFromLab(cv.Args, false)
+		case "lch":
+			return colorFromLab(cv.Args, true)
+		case "oklab":
+			return colorFromOklab(cv.Args, false)
+		case "oklch":
+			return colorFromOklab(cv.Args, true)
+		}
+	}
+	return Color{}, false
+}
+
+func colorFromHex(h string) (Color, bool) {
+	switch len(h) {
+	case 3, 4:
+		// Expand the short form so #abc becomes #aabbcc.
+		long := make([]byte, 0, 8)
+		for i := 0; i < len(h); i++ {
+			long = append(long, h[i], h[i])
+		}
+		h = string(long)
+	case 6, 8:
+	default:
+		return Color{}, false
+	}
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return Color{}, false
+	}
+	if len(h) == 6 {
+		v = v<<8 | 0xff
+	}
+	return Color{
+		R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8),
+		A: float64(uint8(v)) / 255,
+	}, true
+}
+
+// colorArgs splits the arguments of a color function into its channels
+// and alpha. The space syntax with an optional / alpha is always
+// supported and the legacy comma syntax of rgb() and hsl() if legacy is
+// true. The two can't be mixed and only the space syntax takes none.
+func colorArgs(args ValueList, legacy bool) (channels ValueList, alpha *ComponentValue, ok bool) {
+	if commaSyntax(args) {
+		if !legacy {
+			return nil, nil, false
+		}
+		// Values and commas have to alternate.
+		for i, a := range args {
+			if (a.Type == CommaValue) != (i%2 == 1) || a.Type == SlashValue || a.IsIdent("none") {
+				return nil, nil, false
+			}
+			if i%2 == 0 {
+				channels = append(channels, a)
+			}
+		}
+		if len(args)%2 == 0 {
+			return nil, nil, false
+		}
+		if len(channels) == 4 {
+			alpha = &channels[3]
+			channels = channels[:3]
+		}
+		return channels, alpha, len(channels) == 3
+	}
+	for i, a := range args {
+		if a.Type != SlashValue {
+			channels = append(channels, a)
+			continue
+		}
+		if len(channels) != 3 || i != len(args)-2 {
+			return nil, nil, false
+		}
+		alpha = &args[i+1]
+		break
+	}
+	return channels, alpha, len(channels) == 3
+}
+
+// commaSyntax returns true if the arguments of a color function use the
+// legacy comma syntax.
+func commaSyntax(args ValueList) bool {
+	for _, a := range args {
+		if a.Type == CommaValue {
+			return true
+		}
+	}
+	return false
+}
+
+func alphaValue(a *ComponentValue) (float64, bool) {
+	if a == nil {
+		return 1, true
+	}
+	v, ok := channel(*a, 1)
+	return clamp(v, 0, 1), ok
+}
+
+func clamp(f, min, max float64) float64 {
+	return math.Max(min, math.Min(max, f))
+}
+
+func to8bit(f float64) uint8 {
+	return uint8(math.Round(clamp(f, 0, 255)))
+}
+
+func colorFromRGB(args ValueList) (Color, bool) {
+	channels, alpha, ok := colorArgs(args, true)
+	if !ok {
+		return Color{}, false
+	}
+	var rgb [3]uint8
+	for i, ch := range channels {
+		v, ok := channel(ch, 255)
+		if !ok {
+			return Color{}, false
+		}
+		rgb[i] = to8bit(v)
+	}
+	a, ok := alphaValue(alpha)
+	return Color{R: rgb[0], G: rgb[1], B: rgb[2], A: a}, ok
+}
+
+func hue(cv ComponentValue) (float64, bool) {
+	switch {
+	case cv.Type == NumberValue:
+		return cv.Number, true
+	case cv.IsIdent("none"):
+		return 0, true
+	case cv.Type == DimensionValue:
+		switch cv.Unit {
+		case "deg":
+			return cv.Number, true
+		case "rad":
+			return cv.Number * 180 / math.Pi, true
+		case "grad":
+			return cv.Number * 360 / 400, true
+		case "turn":
+			return cv.Number * 360, true
+		}
+	}
+	return 0, false
+}
+
+func colorFromHSL(args ValueList) (Color, bool) {
+	channels, alpha, ok := colorArgs(args, true)
+	if !ok {
+		return Color{}, false
+	}
+	h, ok := hue(channels[0])
+	if !ok {
+		return Color{}, false
+	}
+	sat, ok := percentChannel(channels[1], commaSyntax(args))
+	if !ok {
+		return Color{}, false
+	}
+	l, ok := percentChannel(channels[2], commaSyntax(args))
+	if !ok {
+		return Color{}, false
+	}
+	a, ok := alphaValue(alpha)
+	r, g, b := hslToRGB(h, sat, l)
+	return rgbColor(r, g, b, a), ok
+}
+
+// hslToRGB converts a hue in degrees and a saturation and lightness from
+// 0 to 1 into rgb channels from 0 to 1.
+// http://www.w3.org/TR/css3-color/#hsl-color
+func hslToRGB(h, s, l float64) (r, g, b float64) {
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	s, l = clamp(s, 0, 1), clamp(l, 0, 1)
+	f := func(n float64) float64 {
+		k := math.Mod(n+h/30, 12)
+		a := s * math.Min(l, 1-l)
+		return l - a*math.Max(-1, math.Min(k-3, math.Min(9-k, 1)))
+	}
+	return f(0), f(8), f(4)
+}
+
+// String formats the Color as #rrggbb or as rgba() if it is not opaque.
+func (c Color) String() string {
+	if c.Current {
+		return "currentcolor"
+	}
+	if c.A >= 1 {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+	return fmt.Sprintf("rgba(%d, %d, %d, %s)", c.R, c.G, c.B, formatNumber(math.Round(c.A*1000)/1000))
+}
+
+// rgbColor makes a Color from sRGB channels from 0 to 1 clipping them to
+// the sRGB gamut.
+func rgbColor(r, g, b, a float64) Color {
+	return Color{R: to8bit(r * 255), G: to8bit(g * 255), B: to8bit(b * 255), A: a}
+}
+
+// channel returns the value of a color function channel scaling
+// percentages so 100% is full. none is 0.
+func channel(cv ComponentValue, full float64) (float64, bool) {
+	switch {
+	case cv.Type == NumberValue:
+		return cv.Number, true
+	case cv.Type == PercentageValue:
+		return cv.Number * full / 100, true
+	case cv.IsIdent("none"):
+		return 0, true
+	}
+	return 0, false
+}
+
+// percentChannel returns a saturation, lightness, whiteness or blackness
+// from 0 to 1. The legacy comma syntax only takes percentages and the
+// space syntax numbers from 0 to 100 and none as well.
+func percentChannel(cv ComponentValue, legacy bool) (float64, bool) {
+	if legacy && cv.Type != PercentageValue {
+		return 0, false
+	}
+	v, ok := channel(cv, 100)
+	return v / 100, ok
+}
+
+func colorFromHWB(args ValueList) (Color, bool) {
+	channels, alpha, ok := colorArgs(args, false)
+	if !ok {
+		return Color{}, false
+	}
+	h, ok := hue(channels[0])
+	if !ok {
+		return Color{}, false
+	}
+	w, ok := percentChannel(channels[1], false)
+	if !ok {
+		return Color{}, false
+	}
+	bl, ok := percentChannel(channels[2], false)
+	if !ok {
+		return Color{}, false
+	}
+	a, ok := alphaValue(alpha)
+	w, bl = clamp(w, 0, 1), clamp(bl, 0, 1)
+	if w+bl >= 1 {
+		gray := w / (w + bl)
+		return rgbColor(gray, gray, gray, a), ok
+	}
+	r, g, b := hslToRGB(h, 1, 0.5)
+	f := func(c float64) float64 { return c*(1-w-bl) + w }
+	return rgbColor(f(r), f(g), f(b), a), ok
+}
+
+// labArgs returns the lightness and a and b axes of a lab(), lch(),
+// oklab() or oklch() color. Polar colors have their chroma and hue
+// converted to the axes. lightness, axis and chroma are the values of
+// 100%.
+func labArgs(args ValueList, polar bool, lightness, axis, chroma float64) (l, a, b, alpha float64, ok bool) {
+	channels, al, ok := colorArgs(args, false)
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+	if l, ok = channel(channels[0], lightness); !ok {
+		return 0, 0, 0, 0, false
+	}
+	if alpha, ok = alphaValue(al); !ok {
+		return 0, 0, 0, 0, false
+	}
+	if polar {
+		c, ok := channel(channels[1], chroma)
+		if !ok {
+			return 0, 0, 0, 0, false
+		}
+		h := 0.0
+		if !channels[2].IsIdent("none") {
+			if h, ok = hue(channels[2]); !ok {
+				return 0, 0, 0, 0, false
+			}
+		}
+		c = math.Max(c, 0)
+		return l, c * math.Cos(h*math.Pi/180), c * math.Sin(h*math.Pi/180), alpha, true
+	}
+	if a, ok = channel(channels[1], axis); !ok {
+		return 0, 0, 0, 0, false
+	}
+	if b, ok = channel(channels[2], axis); !ok {
+		return 0, 0, 0, 0, false
+	}
+	return l, a, b, alpha, true
+}
+
+// colorFromLab converts a CIE lab() or lch() color to sRGB.
+// http://www.w3.org/TR/css-color-4/#color-conversion-code
+func colorFromLab(args ValueList, polar bool) (Color, bool) {
+	l, a, b, alpha, ok := labArgs(args, polar, 100, 125, 150)
+	if !ok {
+		return Color{}, false
+	}
+	const (
+		kappa   = 24389.0 / 27
+		epsilon = 216.0 / 24389
+	)
+	l = clamp(l, 0, 100)
+	fy := (l + 16) / 116
+	fx, fz := fy+a/500, fy-b/200
+	f := func(t float64) float64 {
+		if t*t*t > epsilon {
+			return t * t * t
+		}
+		return (116*t - 16) / kappa
+	}
+	y := l / kappa
+	if l > kappa*epsilon {
+		y = fy * fy * fy
+	}
+	// XYZ relative to the D50 white point.
+	x, z := f(fx)*0.3457/0.3585, f(fz)*(1-0.3457-0.3585)/0.3585
+	// Bradford adaptation to D65.
+	x, y, z = 0.9554734527042182*x-0.023098536874261423*y+0.0632593086610217*z,
+		-0.028369706963208136*x+1.0099954580058226*y+0.021041398966943008*z,
+		0.012314001688319899*x-0.020507696433477912*y+1.3303659366080753*z
+	r := 3.2409699419045226*x - 1.537383177570094*y - 0.4986107602930034*z
+	g := -0.9692436362808796*x + 1.8759675015077202*y + 0.04155505740717559*z
+	bl := 0.05563007969699366*x - 0.20397695888897652*y + 1.0569715142428786*z
+	return rgbColor(gamma(r), gamma(g), gamma(bl), alpha), true
+}
+
+// colorFromOklab converts an oklab() or oklch() color to sRGB.
+// http://bottosson.github.io/posts/oklab/
+func colorFromOklab(args ValueList, polar bool) (Color, bool) {
+	l, a, b, alpha, ok := labArgs(args, polar, 1, 0.4, 0.4)
+	if !ok {
+		return Color{}, false
+	}
+	l = clamp(l, 0, 1)
+	l_ := l + 0.3963377774*a + 0.2158037573*b
+	m_ := l - 0.1055613458*a - 0.0638541728*b
+	s_ := l - 0.0894841775*a - 1.2914855480*b
+	lc, mc, sc := l_*l_*l_, m_*m_*m_, s_*s_*s_
+	r := 4.0767416621*lc - 3.3077115913*mc + 0.2309699292*sc
+	g := -1.2684380046*lc + 2.6097574011*mc - 0.3413193965*sc
+	bl := -0.0041960863*lc - 0.7034186147*mc + 1.7076147010*sc
+	return rgbColor(gamma(r), gamma(g), gamma(bl), alpha), true
+}
+
+// gamma applies the sRGB transfer function to a linear channel.
+func gamma(c float64) float64 {
+	if math.Abs(c) <= 0.0031308 {
+		return 12.92 * c
+	}
+	return math.Copysign(1.055*math.Pow(math.Abs(c), 1/2.4)-0.055, c)
+}
+
+// linear removes the sRGB transfer function from a channel from 0 to 255.
+func linear(c uint8) float64 {
+	f := float64(c) / 255
+	if f <= 0.04045 {
+		return f / 12.92
+	}
+	return math.Pow((f+0.055)/1.055, 2.4)
+}
+
+// RGBA implements the image/color.Color interface.
+func (c Color) RGBA() (r, g, b, a uint32) {
+	premultiply := func(ch uint8) uint32 {
+		return uint32(math.Round(float64(ch) * 0x101 * c.A))
+	}
+	return premultiply(c.R), premultiply(c.G), premultiply(c.B), uint32(math.Round(c.A * 0xffff))
+}
+
+// Resolve returns current if c is currentColor and c otherwise.
+func (c Color) Resolve(current Color) Color {
+	if c.Current {
+		return current
+	}
+	return c
+}
+
+// Over composites c over the Color bg.
+func (c Color) Over(bg Color) Color {
+	a := c.A + bg.A*(1-c.A)
+	if a == 0 {
+		return Color{}
+	}
+	mix := func(fg, b uint8) uint8 {
+		return to8bit((float64(fg)*c.A + float64(b)*bg.A*(1-c.A)) / a)
+	}
+	return Color{R: mix(c.R, bg.R), G: mix(c.G, bg.G), B: mix(c.B, bg.B), A: a}
+}
+
+// Luminance returns the relative luminance of c from 0 for black to 1
+// for white ignoring its alpha.
+// http://www.w3.org/TR/WCAG21/#dfn-relative-luminance
+func (c Color) Luminance() float64 {
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+var white = Color{R: 255, G: 255, B: 255, A: 1}
+
+// Contrast returns the WCAG contrast ratio from 1 to 21 of the text color
+// fg on the background bg. A translucent bg is composited over white and
+// a translucent fg over bg first.
+// http://www.w3.org/TR/WCAG21/#dfn-contrast-ratio
+func Contrast(fg, bg Color) float64 {
+	bg = bg.Over(white)
+	l1, l2 := fg.Over(bg).Luminance(), bg.Luminance()
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// shortNames maps colors to their shortest keyword where it is shorter
+// than their hex form.
+var shortNames = func() map[Color]string {
+	names := map[Color]string{}
+	for name, c := range namedColors {
+		if c.A < 1 {
+			continue
+		}
+		if old, ok := names[c]; !ok || len(name) < len(old) || len(name) == len(old) && name < old {
+			names[c] = name
+		}
+	}
+	return names
+}()
+
+// Shortest formats the Color using its shortest css representation. It
+// is a keyword, a 3, 4, 6 or 8 digit hex color or rgba() when the alpha
+// can't be expressed in hex exactly.
+func (c Color) Shortest() string {
+	if c.Current {
+		return "currentcolor"
+	}
+	alpha := math.Round(c.A * 255)
+	if math.Abs(alpha/255-c.A) > 1e-9 {
+		a := strings.TrimPrefix(formatNumber(math.Round(c.A*1000)/1000), "0")
+		return fmt.Sprintf("rgba(%d,%d,%d,%s)", c.R, c.G, c.B, a)
+	}
+	ch := []uint8{c.R, c.G, c.B}
+	if alpha < 255 {
+		ch = append(ch, uint8(alpha))
+	}
+	short := true
+	for _, v := range ch {
+		short = short && v>>4 == v&0xf
+	}
+	var b strings.Builder
+	b.WriteByte('#')
+	for _, v := range ch {
+		if short {
+			fmt.Fprintf(&b, "%x", v&0xf)
+		} else {
+			fmt.Fprintf(&b, "%02x", v)
+		}
+	}
+	hex := b.String()
+	if name, ok := shortNames[c]; ok && len(name) < len(hex) {
+		return name
+	}
+	return hex
+}
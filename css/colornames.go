@@ -0,0 +1,155 @@
+package css
+
+// namedColors are the css color keywords.
+// http://www.w3.org/TR/css-color-4/#named-colors
+var namedColors = map[string]Color{
+	"aliceblue":            Color{R: 240, G: 248, B: 255, A: 1},
+	"antiquewhite":         Color{R: 250, G: 235, B: 215, A: 1},
+	"aqua":                 Color{R: 0, G: 255, B: 255, A: 1},
+	"aquamarine":           Color{R: 127, G: 255, B: 212, A: 1},
+	"azure":                Color{R: 240, G: 255, B: 255, A: 1},
+	"beige":                Color{R: 245, G: 245, B: 220, A: 1},
+	"bisque":               Color{R: 255, G: 228, B: 196, A: 1},
+	"black":                Color{R: 0, G: 0, B: 0, A: 1},
+	"blanchedalmond":       Color{R: 255, G: 235, B: 205, A: 1},
+	"blue":                 Color{R: 0, G: 0, B: 255, A: 1},
+	"blueviolet":           Color{R: 138, G: 43, B: 226, A: 1},
+	"brown":                Color{R: 165, G: 42, B: 42, A: 1},
+	"burlywood":            Color{R: 222, G: 184, B: 135, A: 1},
+	"cadetblue":            Color{R: 95, G: 158, B: 160, A: 1},
+	"chartreuse":           Color{R: 127, G: 255, B: 0, A: 1},
+	"chocolate":            Color{R: 210, G: 105, B: 30, A: 1},
+	"coral":                Color{R: 255, G: 127, B: 80, A: 1},
+	"cornflowerblue":       Color{R: 100, G: 149, B: 237, A: 1},
+	"cornsilk":             Color{R: 255, G: 248, B: 220, A: 1},
+	"crimson":              Color{R: 220, G: 20, B: 60, A: 1},
+	"cyan":                 Color{R: 0, G: 255, B: 255, A: 1},
+	"darkblue":             Color{R: 0, G: 0, B: 139, A: 1},
+	"darkcyan":             Color{R: 0, G: 139, B: 139, A: 1},
+	"darkgoldenrod":        Color{R: 184, G: 134, B: 11, A: 1},
+	"darkgray":             Color{R: 169, G: 169, B: 169, A: 1},
+	"darkgreen":            Color{R: 0, G: 100, B: 0, A: 1},
+	"darkgrey":             Color{R: 169, G: 169, B: 169, A: 1},
+	"darkkhaki":            Color{R: 189, G: 183, B: 107, A: 1},
+	"darkmagenta":          Color{R: 139, G: 0, B: 139, A: 1},
+	"darkolivegreen":       Color{R: 85, G: 107, B: 47, A: 1},
+	"darkorange":           Color{R: 255, G: 140, B: 0, A: 1},
+	"darkorchid":           Color{R: 153, G: 50, B: 204, A: 1},
+	"darkred":              Color{R: 139, G: 0, B: 0, A: 1},
+	"darksalmon":           Color{R: 233, G: 150, B: 122, A: 1},
+	"darkseagreen":         Color{R: 143, G: 188, B: 143, A: 1},
+	"darkslateblue":        Color{R: 72, G: 61, B: 139, A: 1},
+	"darkslategray":        Color{R: 47, G: 79, B: 79, A: 1},
+	"darkslategrey":        Color{R: 47, G: 79, B: 79, A: 1},
+	"darkturquoise":        Color{R: 0, G: 206, B: 209, A: 1},
+	"darkviolet":           Color{R: 148, G: 0, B: 211, A: 1},
+	"deeppink":             Color{R: 255, G: 20, B: 147, A: 1},
+	"deepskyblue":          Color{R: 0, G: 191, B: 255, A: 1},
+	"dimgray":              Color{R: 105, G: 105, B: 105, A: 1},
+	"dimgrey":              Color{R: 105, G: 105, B: 105, A: 1},
+	"dodgerblue":           Color{R: 30, G: 144, B: 255, A: 1},
+	"firebrick":            Color{R: 178, G: 34, B: 34, A: 1},
+	"floralwhite":          Color{R: 255, G: 250, B: 240, A: 1},
+	"forestgreen":          Color{R: 34, G: 139, B: 34, A: 1},
+	"fuchsia":              Color{R: 255, G: 0, B: 255, A: 1},
+	"gainsboro":            Color{R: 220, G: 220, B: 220, A: 1},
+	"ghostwhite":           Color{R: 248, G: 248, B: 255, A: 1},
+	"gold":                 Color{R: 255, G: 215, B: 0, A: 1},
+	"goldenrod":            Color{R: 218, G: 165, B: 32, A: 1},
+	"gray":                 Color{R: 128, G: 128, B: 128, A: 1},
+	"green":                Color{R: 0, G: 128, B: 0, A: 1},
+	"greenyellow":          Color{R: 173, G: 255, B: 47, A: 1},
+	"grey":                 Color{R: 128, G: 128, B: 128, A: 1},
+	"honeydew":             Color{R: 240, G: 255, B: 240, A: 1},
+	"hotpink":              Color{R: 255, G: 105, B: 180, A: 1},
+	"indianred":            Color{R: 205, G: 92, B: 92, A: 1},
+	"indigo":               Color{R: 75, G: 0, B: 130, A: 1},
+	"ivory":                Color{R: 255, G: 255, B: 240, A: 1},
+	"khaki":                Color{R: 240, G: 230, B: 140, A: 1},
+	"lavender":             Color{R: 230, G: 230, B: 250, A: 1},
+	"lavenderblush":        Color{R: 255, G: 240, B: 245, A: 1},
+	"lawngreen":            Color{R: 124, G: 252, B: 0, A: 1},
+	"lemonchiffon":         Color{R: 255, G: 250, B: 205, A: 1},
+	"lightblue":            Color{R: 173, G: 216, B: 230, A: 1},
+	"lightcoral":           Color{R: 240, G: 128, B: 128, A: 1},
+	"lightcyan":            Color{R: 224, G: 255, B: 255, A: 1},
+	"lightgoldenrodyellow": Color{R: 250, G: 250, B: 210, A: 1},
+	"lightgray":            Color{R: 211, G: 211, B: 211, A: 1},
+	"lightgreen":           Color{R: 144, G: 238, B: 144, A: 1},
+	"lightgrey":            Color{R: 211, G: 211, B: 211, A: 1},
+	"lightpink":            Color{R: 255, G: 182, B: 193, A: 1},
+	"lightsalmon":          Color{R: 255, G: 160, B: 122, A: 1},
+	"lightseagreen":        Color{R: 32, G: 178, B: 170, A: 1},
+	"lightskyblue":         Color{R: 135, G: 206, B: 250, A: 1},
+	"lightslategray":       Color{R: 119, G: 136, B: 153, A: 1},
+	"lightslategrey":       Color{R: 119, G: 136, B: 153, A: 1},
+	"lightsteelblue":       Color{R: 176, G: 196, B: 222, A: 1},
+	"lightyellow":          Color{R: 255, G: 255, B: 224, A: 1},
+	"lime":                 Color{R: 0, G: 255, B: 0, A: 1},
+	"limegreen":            Color{R: 50, G: 205, B: 50, A: 1},
+	"linen":                Color{R: 250, G: 240, B: 230, A: 1},
+	"magenta":              Color{R: 255, G: 0, B: 255, A: 1},
+	"maroon":               Color{R: 128, G: 0, B: 0, A: 1},
+	"mediumaquamarine":     Color{R: 102, G: 205, B: 170, A: 1},
+	"mediumblue":           Color{R: 0, G: 0, B: 205, A: 1},
+	"mediumorchid":         Color{R: 186, G: 85, B: 211, A: 1},
+	"mediumpurple":         Color{R: 147, G: 112, B: 219, A: 1},
+	"mediumseagreen":       Color{R: 60, G: 179, B: 113, A: 1},
+	"mediumslateblue":      Color{R: 123, G: 104, B: 238, A: 1},
+	"mediumspringgreen":    Color{R: 0, G: 250, B: 154, A: 1},
+	"mediumturquoise":      Color{R: 72, G: 209, B: 204, A: 1},
+	"mediumvioletred":      Color{R: 199, G: 21, B: 133, A: 1},
+	"midnightblue":         Color{R: 25, G: 25, B: 112, A: 1},
+	"mintcream":            Color{R: 245, G: 255, B: 250, A: 1},
+	"mistyrose":            Color{R: 255, G: 228, B: 225, A: 1},
+	"moccasin":             Color{R: 255, G: 228, B: 181, A: 1},
+	"navajowhite":          Color{R: 255, G: 222, B: 173, A: 1},
+	"navy":                 Color{R: 0, G: 0, B: 128, A: 1},
+	"oldlace":              Color{R: 253, G: 245, B: 230, A: 1},
+	"olive":                Color{R: 128, G: 128, B: 0, A: 1},
+	"olivedrab":            Color{R: 107, G: 142, B: 35, A: 1},
+	"orange":               Color{R: 255, G: 165, B: 0, A: 1},
+	"orangered":            Color{R: 255, G: 69, B: 0, A: 1},
+	"orchid":               Color{R: 218, G: 112, B: 214, A: 1},
+	"palegoldenrod":        Color{R: 238, G: 232, B: 170, A: 1},
+	"palegreen":            Color{R: 152, G: 251, B: 152, A: 1},
+	"paleturquoise":        Color{R: 175, G: 238, B: 238, A: 1},
+	"palevioletred":        Color{R: 219, G: 112, B: 147, A: 1},
+	"papayawhip":           Color{R: 255, G: 239, B: 213, A: 1},
+	"peachpuff":            Color{R: 255, G: 218, B: 185, A: 1},
+	"peru":                 Color{R: 205, G: 133, B: 63, A: 1},
+	"pink":                 Color{R: 255, G: 192, B: 203, A: 1},
+	"plum":                 Color{R: 221, G: 160, B: 221, A: 1},
+	"powderblue":           Color{R: 176, G: 224, B: 230, A: 1},
+	"purple":               Color{R: 128, G: 0, B: 128, A: 1},
+	"rebeccapurple":        Color{R: 102, G: 51, B: 153, A: 1},
+	"red":                  Color{R: 255, G: 0, B: 0, A: 1},
+	"rosybrown":            Color{R: 188, G: 143, B: 143, A: 1},
+	"royalblue":            Color{R: 65, G: 105, B: 225, A: 1},
+	"saddlebrown":          Color{R: 139, G: 69, B: 19, A: 1},
+	"salmon":               Color{R: 250, G: 128, B: 114, A: 1},
+	"sandybrown":           Color{R: 244, G: 164, B: 96, A: 1},
+	"seagreen":             Color{R: 46, G: 139, B: 87, A: 1},
+	"seashell":             Color{R: 255, G: 245, B: 238, A: 1},
+	"sienna":               Color{R: 160, G: 82, B: 45, A: 1},
+	"silver":               Color{R: 192, G: 192, B: 192, A: 1},
+	"skyblue":              Color{R: 135, G: 206, B: 235, A: 1},
+	"slateblue":            Color{R: 106, G: 90, B: 205, A: 1},
+	"slategray":            Color{R: 112, G: 128, B: 144, A: 1},
+	"slategrey":            Color{R: 112, G: 128, B: 144, A: 1},
+	"snow":                 Color{R: 255, G: 250, B: 250, A: 1},
+	"springgreen":          Color{R: 0, G: 255, B: 127, A: 1},
+	"steelblue":            Color{R: 70, G: 130, B: 180, A: 1},
+	"tan":                  Color{R: 210, G: 180, B: 140, A: 1},
+	"teal":                 Color{R: 0, G: 128, B: 128, A: 1},
+	"thistle":              Color{R: 216, G: 191, B: 216, A: 1},
+	"tomato":               Color{R: 255, G: 99, B: 71, A: 1},
+	"turquoise":            Color{R: 64, G: 224, B: 208, A: 1},
+	"violet":               Color{R: 238, G: 130, B: 238, A: 1},
+	"wheat":                Color{R: 245, G: 222, B: 179, A: 1},
+	"white":                Color{R: 255, G: 255, B: 255, A: 1},
+	"whitesmoke":           Color{R: 245, G: 245, B: 245, A: 1},
+	"yellow":               Color{R: 255, G: 255, B: 0, A: 1},
+	"yellowgreen":          Color{R: 154, G: 205, B: 50, A: 1},
+	"transparent":          Color{},
+}
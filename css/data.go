@@ -2,13 +2,26 @@
 // http://www.w3.org/TR/css-syntax-3/
 package css
 
-import "go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+import (
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+)
 
 // Stylesheet is a list of Statements
 type Stylesheet struct {
 	Statements []Statement
 }
 
+func (ss *Stylesheet) String() string {
+	parts := make([]string, 0, len(ss.Statements))
+	for _, st := range ss.Statements {
+		parts = append(parts, st.String())
+	}
+	return strings.Join(parts, "\n")
+}
+
 // Statement is either a Ruleset or an AtRule or a comment.
 type Statement struct {
 	*Ruleset
@@ -17,18 +30,67 @@ type Statement struct {
 	*HtmlComment
 }
 
+func (st Statement) String() string {
+	switch {
+	case st.Ruleset != nil:
+		return st.Ruleset.String()
+	case st.AtRule != nil:
+		return st.AtRule.String()
+	case st.Comment != nil:
+		return "/*" + string(*st.Comment) + "*/"
+	case st.HtmlComment != nil:
+		return string(*st.HtmlComment)
+	}
+	return ""
+}
+
 // AtRule is an AtKeyword an optional param and an SimpleBlock.
 // http://www.w3.org/TR/css-syntax-3/#consume-an-at-rule0
 type AtRule struct {
-	AtKeyword   string
+	// The name of the AtRule without the @. eg: media
+	AtKeyword string
+	// The prelude of the AtRule split on whitespace.
 	Param       []string
 	SimpleBlock *SimpleBlock
 }
 
+func (ar *AtRule) String() string {
+	s := "@" + ar.AtKeyword
+	if len(ar.Param) > 0 {
+		s += " " + strings.Join(ar.Param, " ")
+	}
+	if ar.SimpleBlock == nil {
+		return s + ";"
+	}
+	return s + " {\n" + ar.SimpleBlock.String() + "\n}"
+}
+
 // Ruleset is a selector followed by a Declaration Block
 type Ruleset struct {
-	Selector *selector.Chain
+	// Selector is the comma separated selector list of the rule. It was a
+	// single *selector.Chain before the parser supported selector lists;
+	// that Chain is Selector[0].
+	Selector selector.Group
 	DeclarationList
+	// Nested are the rules, conditional AtRules and any declarations
+	// following them nested inside this Ruleset in source order. The
+	// Selectors of nested Rulesets contain a & for their parent.
+	// http://www.w3.org/TR/css-nesting-1/
+	Nested []BlockItem
+}
+
+func (rs *Ruleset) String() string {
+	if len(rs.Nested) > 0 {
+		s := rs.Selector.String() + " {"
+		if len(rs.DeclarationList) > 0 {
+			s += " " + rs.DeclarationList.String() + ";"
+		}
+		return s + "\n" + (&SimpleBlock{Content: rs.Nested}).String() + "\n}"
+	}
+	if len(rs.DeclarationList) == 0 {
+		return rs.Selector.String() + " { }"
+	}
+	return rs.Selector.String() + " { " + rs.DeclarationList.String() + "; }"
 }
 
 // BlockItem contains one and only one of DeclarationList, *Ruleset, or *AtRule
@@ -38,18 +100,58 @@ type BlockItem struct {
 	*AtRule
 }
 
+func (bi BlockItem) String() string {
+	switch {
+	case bi.Ruleset != nil:
+		return bi.Ruleset.String()
+	case bi.AtRule != nil:
+		return bi.AtRule.String()
+	}
+	return bi.DeclarationList.String() + ";"
+}
+
 // SimpleBlock contains a list of BlockItems
 type SimpleBlock struct {
 	Content []BlockItem
 }
 
+func (sb *SimpleBlock) String() string {
+	parts := make([]string, 0, len(sb.Content))
+	for _, bi := range sb.Content {
+		parts = append(parts, bi.String())
+	}
+	return strings.Join(parts, "\n")
+}
+
 // DeclarationList is a list of Declarations forming a block.
 type DeclarationList []Declaration
 
+// String serializes the DeclarationList in the form used by style
+// attributes. eg: color: red; margin: 0
+func (dl DeclarationList) String() string {
+	parts := make([]string, 0, len(dl))
+	for _, d := range dl {
+		parts = append(parts, d.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
 // Declaration is a Property and Value pair.
 type Declaration struct {
 	Property string
 	Value    string
+	// Important is true if the Declaration was marked !important.
+	Important bool
+	// Pos is the source position of the property if the Declaration was
+	// parsed by ParseWithPositions.
+	Pos tokenizer.Position
+}
+
+func (d Declaration) String() string {
+	if d.Important {
+		return d.Property + ": " + d.Value + " !important"
+	}
+	return d.Property + ": " + d.Value
 }
 
 type Comment string
@@ -0,0 +1,84 @@
+package css
+
+import (
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+)
+
+// Flatten returns a copy of the Stylesheet ss without nested rules. Nested
+// Rulesets become top level Rulesets whose Selectors have their & resolved
+// against the parent rule with selector.Group.Nest. Declarations in
+// AtRules nested in a style rule become Rulesets with the parent's
+// Selector inside the AtRule which is moved to the top level. Rules follow
+// their parent rule in source order. ss isn't modified.
+//
+// Like the & of css nesting a parent rule with a selector list like
+// ".a, #b { & .c {} }" resolves to ":is(.a, #b) .c" which has the
+// specificity of the most specific parent.
+func Flatten(ss *Stylesheet) *Stylesheet {
+	out := &Stylesheet{}
+	for _, st := range ss.Statements {
+		switch {
+		case st.Ruleset != nil:
+			for _, bi := range flattenRuleset(st.Ruleset, nil) {
+				out.Statements = append(out.Statements, Statement{Ruleset: bi.Ruleset, AtRule: bi.AtRule})
+			}
+		case st.AtRule != nil:
+			out.Statements = append(out.Statements, Statement{AtRule: flattenAtRule(st.AtRule, nil)})
+		default:
+			out.Statements = append(out.Statements, st)
+		}
+	}
+	return out
+}
+
+// flattenRuleset flattens rs nested in a rule with the selector parent.
+// parent is nil for a top level rule.
+func flattenRuleset(rs *Ruleset, parent selector.Group) []BlockItem {
+	sel := rs.Selector.Nest(parent)
+	if len(rs.Nested) == 0 {
+		return []BlockItem{{Ruleset: &Ruleset{Selector: sel, DeclarationList: rs.DeclarationList}}}
+	}
+	var items []BlockItem
+	if len(rs.DeclarationList) > 0 {
+		items = append(items, BlockItem{Ruleset: &Ruleset{Selector: sel, DeclarationList: rs.DeclarationList}})
+	}
+	return append(items, flattenItems(rs.Nested, sel)...)
+}
+
+// flattenItems flattens the contents of a block nested in a rule with the
+// selector parent.
+func flattenItems(nested []BlockItem, parent selector.Group) []BlockItem {
+	var items []BlockItem
+	for _, bi := range nested {
+		switch {
+		case bi.Ruleset != nil:
+			items = append(items, flattenRuleset(bi.Ruleset, parent)...)
+		case bi.AtRule != nil:
+			items = append(items, BlockItem{AtRule: flattenAtRule(bi.AtRule, parent)})
+		case len(bi.DeclarationList) > 0 && parent != nil:
+			items = append(items, BlockItem{Ruleset: &Ruleset{Selector: parent, DeclarationList: bi.DeclarationList}})
+		default:
+			items = append(items, bi)
+		}
+	}
+	return items
+}
+
+// flattenAtRule flattens the rules in the block of ar which is nested in a
+// rule with the selector parent or at the top level if parent is nil.
+func flattenAtRule(ar *AtRule, parent selector.Group) *AtRule {
+	if ar.SimpleBlock == nil || !hasRules(ar.SimpleBlock.Content) && parent == nil {
+		return ar
+	}
+	return &AtRule{AtKeyword: ar.AtKeyword, Param: ar.Param,
+		SimpleBlock: &SimpleBlock{Content: flattenItems(ar.SimpleBlock.Content, parent)}}
+}
+
+func hasRules(items []BlockItem) bool {
+	for _, bi := range items {
+		if bi.Ruleset != nil || bi.AtRule != nil {
+			return true
+		}
+	}
+	return false
+}
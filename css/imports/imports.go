@@ -0,0 +1,252 @@
+/*
+Package imports resolves the @import rules of a css Stylesheet producing a
+single self-contained Stylesheet.
+
+	ss, _ := css.ParseString(src)
+	flat, err := imports.Resolve(ss, "css/main.css", imports.FS(os.DirFS("static")))
+
+Imported stylesheets are loaded through a Loader. The media queries,
+supports() and layer() conditions of an @import wrap the imported rules in
+the equivalent @media, @supports and @layer rules. Import urls are
+resolved relative to the stylesheet importing them.
+*/
+package imports
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+// Loader loads the source of imported stylesheets.
+type Loader interface {
+	// Load returns the source of the stylesheet at url. url has already
+	// been resolved against the url of the importing stylesheet.
+	Load(url string) ([]byte, error)
+}
+
+// LoaderFunc adapts a func to the Loader interface.
+type LoaderFunc func(url string) ([]byte, error)
+
+// Load calls f(url).
+func (f LoaderFunc) Load(url string) ([]byte, error) {
+	return f(url)
+}
+
+type fsLoader struct {
+	fsys fs.FS
+}
+
+// FS returns a Loader that loads relative and root relative urls from
+// fsys. Urls with a scheme or a host can't be loaded.
+func FS(fsys fs.FS) Loader {
+	return fsLoader{fsys}
+}
+
+func (l fsLoader) Load(u string) ([]byte, error) {
+	pu, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	if pu.Scheme != "" || pu.Host != "" {
+		return nil, fmt.Errorf("Can't load %s from a file system", u)
+	}
+	return fs.ReadFile(l.fsys, strings.TrimPrefix(path.Clean("/"+pu.Path), "/"))
+}
+
+// Resolve returns a copy of the Stylesheet ss with its @import rules
+// replaced by the rules of the stylesheets they import. base is the url of
+// ss which relative imports are resolved against. @import rules following
+// any other rule are invalid and dropped. It returns an error naming the
+// chain of imports if a stylesheet can't be loaded or parsed or the
+// imports form a cycle.
+func Resolve(ss *css.Stylesheet, base string, l Loader) (*css.Stylesheet, error) {
+	r := &resolver{l: l, cache: map[string]*css.Stylesheet{}}
+	return r.resolve(ss, []string{base})
+}
+
+type resolver struct {
+	l     Loader
+	cache map[string]*css.Stylesheet
+}
+
+// importRule is a parsed @import prelude.
+type importRule struct {
+	url      string
+	layer    *string
+	supports string
+	media    []string
+}
+
+func parseImport(ar *css.AtRule) (*importRule, error) {
+	if len(ar.Param) == 0 {
+		return nil, fmt.Errorf("Missing url in @import")
+	}
+	vl, err := css.ParseValue(ar.Param[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(vl) != 1 || (vl[0].Type != css.URLValue && vl[0].Type != css.StringValue) {
+		return nil, fmt.Errorf("Invalid url %s in @import", ar.Param[0])
+	}
+	imp := &importRule{url: vl[0].Value}
+	params := ar.Param[1:]
+	if len(params) > 0 {
+		lp := strings.ToLower(params[0])
+		switch {
+		case lp == "layer":
+			imp.layer = new(string)
+			params = params[1:]
+		case strings.HasPrefix(lp, "layer(") && strings.HasSuffix(lp, ")"):
+			name := strings.TrimSpace(params[0][len("layer(") : len(params[0])-1])
+			imp.layer = &name
+			params = params[1:]
+		}
+	}
+	if len(params) > 0 {
+		lp := strings.ToLower(params[0])
+		if strings.HasPrefix(lp, "supports(") && strings.HasSuffix(lp, ")") {
+			cond := strings.TrimSpace(params[0][len("supports(") : len(params[0])-1])
+			if !strings.HasPrefix(cond, "(") && !strings.HasPrefix(strings.ToLower(cond), "not ") {
+				// A bare declaration is shorthand for a parenthesized one.
+				cond = "(" + cond + ")"
+			}
+			imp.supports = cond
+			params = params[1:]
+		}
+	}
+	imp.media = params
+	return imp, nil
+}
+
+// resolveURL resolves ref against base. Unlike url.ResolveReference a
+// relative base gives a relative url so it can be loaded from an fs.FS.
+func resolveURL(base, ref string) (string, error) {
+	r, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	if r.IsAbs() || r.Host != "" || b.IsAbs() || b.Host != "" || strings.HasPrefix(b.Path, "/") {
+		return b.ResolveReference(r).String(), nil
+	}
+	if !strings.HasPrefix(r.Path, "/") {
+		r.Path = path.Join(path.Dir(b.Path), r.Path)
+	}
+	return r.String(), nil
+}
+
+func (r *resolver) resolve(ss *css.Stylesheet, chain []string) (*css.Stylesheet, error) {
+	out := &css.Stylesheet{}
+	importsAllowed := true
+	for _, st := range ss.Statements {
+		if st.AtRule == nil {
+			if st.Ruleset != nil {
+				importsAllowed = false
+			}
+			out.Statements = append(out.Statements, st)
+			continue
+		}
+		switch strings.ToLower(st.AtRule.AtKeyword) {
+		case "charset":
+			if len(chain) == 1 {
+				out.Statements = append(out.Statements, st)
+			}
+			continue
+		case "import":
+			if !importsAllowed {
+				continue
+			}
+			sts, err := r.importRule(st.AtRule, chain)
+			if err != nil {
+				return nil, err
+			}
+			out.Statements = append(out.Statements, sts...)
+			continue
+		case "layer":
+			// @layer statements may come before @import.
+			if st.AtRule.SimpleBlock != nil {
+				importsAllowed = false
+			}
+		default:
+			importsAllowed = false
+		}
+		out.Statements = append(out.Statements, st)
+	}
+	return out, nil
+}
+
+// importRule loads, resolves and wraps the stylesheet imported by ar.
+func (r *resolver) importRule(ar *css.AtRule, chain []string) ([]css.Statement, error) {
+	importer := chain[len(chain)-1]
+	imp, err := parseImport(ar)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid import in %s: %s", strings.Join(chain, " -> "), err)
+	}
+	u, err := resolveURL(importer, imp.url)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid import in %s: %s", strings.Join(chain, " -> "), err)
+	}
+	chain = append(chain[:len(chain):len(chain)], u)
+	for _, c := range chain[:len(chain)-1] {
+		if c == u {
+			return nil, fmt.Errorf("Import cycle %s", strings.Join(chain, " -> "))
+		}
+	}
+	ss, ok := r.cache[u]
+	if !ok {
+		src, err := r.l.Load(u)
+		if err != nil {
+			return nil, fmt.Errorf("Error loading %s: %s", strings.Join(chain, " -> "), err)
+		}
+		ss, err = css.ParseString(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing %s: %s", strings.Join(chain, " -> "), err)
+		}
+		r.cache[u] = ss
+	}
+	ss, err = r.resolve(ss, chain)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(ss.Statements, imp), nil
+}
+
+// wrap wraps imported statements in the AtRules equivalent to the
+// conditions of the @import.
+func wrap(sts []css.Statement, imp *importRule) []css.Statement {
+	if imp.layer != nil {
+		var params []string
+		if *imp.layer != "" {
+			params = []string{*imp.layer}
+		}
+		sts = wrapIn(sts, "layer", params)
+	}
+	if imp.supports != "" {
+		sts = wrapIn(sts, "supports", []string{imp.supports})
+	}
+	if len(imp.media) > 0 {
+		sts = wrapIn(sts, "media", imp.media)
+	}
+	return sts
+}
+
+func wrapIn(sts []css.Statement, keyword string, params []string) []css.Statement {
+	sb := &css.SimpleBlock{}
+	for _, st := range sts {
+		switch {
+		case st.Ruleset != nil:
+			sb.Content = append(sb.Content, css.BlockItem{Ruleset: st.Ruleset})
+		case st.AtRule != nil && !strings.EqualFold(st.AtRule.AtKeyword, "charset"):
+			sb.Content = append(sb.Content, css.BlockItem{AtRule: st.AtRule})
+		}
+	}
+	return []css.Statement{{AtRule: &css.AtRule{AtKeyword: keyword, Param: params, SimpleBlock: sb}}}
+}
@@ -0,0 +1,125 @@
+package imports
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+func resolveString(t *testing.T, src, base string, l Loader) (string, error) {
+	ss, err := css.ParseString(src)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	out, err := Resolve(ss, base, l)
+	if err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+func TestResolve(t *testing.T) {
+	fsys := fstest.MapFS{
+		"css/base.css":       {Data: []byte(`@charset "utf-8"; @import "reset.css"; body { margin: 0; }`)},
+		"css/reset.css":      {Data: []byte(`* { padding: 0; }`)},
+		"css/print.css":      {Data: []byte(`p { color: black; }`)},
+		"vendor/grid.css":    {Data: []byte(`.grid { display: grid; }`)},
+		"css/components.css": {Data: []byte(`.btn { color: red; }`)},
+	}
+	out, err := resolveString(t, `@charset "utf-8";
+@layer base, components;
+@import url(base.css) layer(base);
+@import "print.css" print;
+@import "/vendor/grid.css" supports(display: grid) screen and (min-width: 600px);
+@import url("components.css") layer;
+a { color: blue; }
+@import "late.css";`, "css/main.css", FS(fsys))
+	if err != nil {
+		t.Fatalf("Error resolving imports: %s", err)
+	}
+	expected := `@charset "utf-8";
+@layer base, components;
+@layer base {
+* { padding: 0; }
+body { margin: 0; }
+}
+@media print {
+p { color: black; }
+}
+@media screen and (min-width: 600px) {
+@supports (display: grid) {
+.grid { display: grid; }
+}
+}
+@layer {
+.btn { color: red; }
+}
+a { color: blue; }`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+}
+
+func TestResolveLoaderFunc(t *testing.T) {
+	var loaded []string
+	l := LoaderFunc(func(url string) ([]byte, error) {
+		loaded = append(loaded, url)
+		return []byte(`p { color: red; }`), nil
+	})
+	out, err := resolveString(t, `@import "http://example.com/a/b.css"; @import "c.css";`,
+		"http://example.com/a/main.css", l)
+	if err != nil {
+		t.Fatalf("Error resolving imports: %s", err)
+	}
+	if out != "p { color: red; }\np { color: red; }" {
+		t.Errorf("Unexpected output %q", out)
+	}
+	if strings.Join(loaded, " ") != "http://example.com/a/b.css http://example.com/a/c.css" {
+		t.Errorf("Unexpected urls loaded %q", loaded)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.css": {Data: []byte(`@import "b.css";`)},
+		"b.css": {Data: []byte(`@import "a.css";`)},
+		"c.css": {Data: []byte(`@import "missing.css";`)},
+	}
+	tests := []struct{ src, err string }{
+		{`@import "a.css";`, "Import cycle main.css -> a.css -> b.css -> a.css"},
+		{`@import "c.css";`, "Error loading main.css -> c.css -> missing.css"},
+		{`@import "http://example.com/x.css";`, "Error loading main.css -> http://example.com/x.css: Can't load"},
+		{`@import 12px;`, "Invalid import in main.css"},
+	}
+	for _, test := range tests {
+		_, err := resolveString(t, test.src, "main.css", FS(fsys))
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("Expected an error starting with %q got %v", test.err, err)
+		}
+	}
+}
+
+func TestResolveInvalidRule(t *testing.T) {
+	fsys := fstest.MapFS{"bad.css": {Data: []byte(`a > > b { color: red; } p { color: blue; }`)}}
+	out, err := resolveString(t, `@import "bad.css";`, "main.css", FS(fsys))
+	if err != nil {
+		t.Fatalf("Error resolving imports: %s", err)
+	}
+	if out != "p { color: blue; }" {
+		t.Errorf("Expected the invalid rule to be dropped got %q", out)
+	}
+}
+
+func ExampleResolve() {
+	fsys := fstest.MapFS{"theme.css": {Data: []byte(`p { color: red; }`)}}
+	ss, _ := css.ParseString(`@import "theme.css" (prefers-color-scheme: dark);`)
+	flat, _ := Resolve(ss, "main.css", FS(fsys))
+	fmt.Println(flat)
+	// Output:
+	// @media (prefers-color-scheme: dark) {
+	// p { color: red; }
+	// }
+}
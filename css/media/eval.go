@@ -0,0 +1,369 @@
+package media
+
+import (
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+// Environment describes the device and user preferences media queries are
+// evaluated against. Zero values fall back to the defaults documented on
+// each field.
+type Environment struct {
+	// The media type. Defaults to screen.
+	Type string
+	// The viewport size in css pixels.
+	Width, Height float64
+	// The device pixel ratio in dppx. Defaults to 1.
+	Resolution float64
+	// The font size in pixels used to resolve em and rem. Defaults to 16.
+	FontSize float64
+	// The bits per color component. 0 for a monochrome device.
+	Color int
+	// The bits per pixel of a monochrome device.
+	Monochrome int
+	// The preferred color scheme, light or dark. Defaults to light.
+	ColorScheme string
+	// ReducedMotion is true if the user prefers reduced motion.
+	ReducedMotion bool
+	// Hover is true if the primary pointer can hover.
+	Hover bool
+	// The accuracy of the primary pointer: none, coarse or fine. Defaults
+	// to none.
+	Pointer string
+}
+
+// Screen is a typical desktop browser Environment.
+var Screen = Environment{
+	Type:        "screen",
+	Width:       1280,
+	Height:      800,
+	Resolution:  1,
+	Color:       8,
+	ColorScheme: "light",
+	Hover:       true,
+	Pointer:     "fine",
+}
+
+// Print is a typical printer Environment with a Letter sized page.
+var Print = Environment{
+	Type:        "print",
+	Width:       816,
+	Height:      1056,
+	Resolution:  3,
+	Color:       8,
+	ColorScheme: "light",
+}
+
+func (e *Environment) mediaType() string {
+	if e.Type == "" {
+		return "screen"
+	}
+	return strings.ToLower(e.Type)
+}
+
+func (e *Environment) fontSize() float64 {
+	if e.FontSize == 0 {
+		return 16
+	}
+	return e.FontSize
+}
+
+func (e *Environment) resolution() float64 {
+	if e.Resolution == 0 {
+		return 1
+	}
+	return e.Resolution
+}
+
+// Match returns true if any Query in the list matches env. An empty
+// QueryList matches everything.
+func (ql QueryList) Match(env *Environment) bool {
+	if len(ql) == 0 {
+		return true
+	}
+	for _, q := range ql {
+		if q.Match(env) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match returns true if the Query matches env.
+func (q *Query) Match(env *Environment) bool {
+	m := q.Type == "" || q.Type == "all" || q.Type == env.mediaType()
+	if m && q.Condition != nil {
+		m = q.Condition.Match(env)
+	}
+	return m != q.Not
+}
+
+// Match returns true if the Condition matches env.
+func (c *Condition) Match(env *Environment) bool {
+	switch c.Type {
+	case FeatureCondition:
+		return c.Feature.Match(env)
+	case NotCondition:
+		return !c.Children[0].Match(env)
+	case AndCondition:
+		for _, child := range c.Children {
+			if !child.Match(env) {
+				return false
+			}
+		}
+		return true
+	case OrCondition:
+		for _, child := range c.Children {
+			if child.Match(env) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rangeFeature is a feature compared numerically.
+type rangeFeature struct {
+	// value returns the value of the feature for an Environment.
+	value func(e *Environment) float64
+	// convert converts a query value to the units of value.
+	convert func(e *Environment, vl css.ValueList) (float64, bool)
+}
+
+var rangeFeatures = map[string]rangeFeature{
+	"width":               {func(e *Environment) float64 { return e.Width }, length},
+	"height":              {func(e *Environment) float64 { return e.Height }, length},
+	"device-width":        {func(e *Environment) float64 { return e.Width }, length},
+	"device-height":       {func(e *Environment) float64 { return e.Height }, length},
+	"aspect-ratio":        {(*Environment).aspectRatio, ratio},
+	"device-aspect-ratio": {(*Environment).aspectRatio, ratio},
+	"resolution":          {(*Environment).resolution, resolution},
+	"color":               {func(e *Environment) float64 { return float64(e.Color) }, integer},
+	"monochrome":          {func(e *Environment) float64 { return float64(e.Monochrome) }, integer},
+	"color-index":         {func(e *Environment) float64 { return 0 }, integer},
+}
+
+// discreteFeatures are the features compared to keywords.
+var discreteFeatures = map[string]func(e *Environment) string{
+	"orientation": func(e *Environment) string {
+		if e.Height >= e.Width {
+			return "portrait"
+		}
+		return "landscape"
+	},
+	"prefers-color-scheme": func(e *Environment) string {
+		if e.ColorScheme == "" {
+			return "light"
+		}
+		return strings.ToLower(e.ColorScheme)
+	},
+	"prefers-reduced-motion": func(e *Environment) string {
+		if e.ReducedMotion {
+			return "reduce"
+		}
+		return "no-preference"
+	},
+	"hover":       hover,
+	"any-hover":   hover,
+	"pointer":     pointer,
+	"any-pointer": pointer,
+	"grid":        func(e *Environment) string { return "0" },
+	"scan": func(e *Environment) string {
+		return "progressive"
+	},
+}
+
+func hover(e *Environment) string {
+	if e.Hover {
+		return "hover"
+	}
+	return "none"
+}
+
+func pointer(e *Environment) string {
+	if e.Pointer == "" {
+		return "none"
+	}
+	return strings.ToLower(e.Pointer)
+}
+
+func (e *Environment) aspectRatio() float64 {
+	if e.Height == 0 {
+		return 0
+	}
+	return e.Width / e.Height
+}
+
+// Match returns true if the Feature matches env.
+func (f *Feature) Match(env *Environment) bool {
+	name, op := f.Name, "="
+	switch {
+	case strings.HasPrefix(name, "min-"):
+		name, op = name[4:], ">="
+	case strings.HasPrefix(name, "max-"):
+		name, op = name[4:], "<="
+	}
+	if rf, ok := rangeFeatures[name]; ok {
+		v, conv := rf.value(env), rf.convert
+		switch {
+		case f.Range != nil:
+			for _, c := range f.Range {
+				if !compare(env, v, c.Op, c.Value, conv) {
+					return false
+				}
+			}
+			return true
+		case f.Value != nil:
+			return compare(env, v, op, f.Value, conv)
+		case op == "=":
+			return v != 0
+		}
+		return false
+	}
+	if df, ok := discreteFeatures[name]; ok && op == "=" && f.Range == nil {
+		v := df(env)
+		if f.Value == nil {
+			return v != "none" && v != "no-preference" && v != "0"
+		}
+		return len(f.Value) == 1 && strings.EqualFold(f.Value[0].String(), v)
+	}
+	return false
+}
+
+func compare(env *Environment, v float64, op string, vl css.ValueList, conv func(*Environment, css.ValueList) (float64, bool)) bool {
+	x, ok := conv(env, vl)
+	if !ok {
+		return false
+	}
+	switch op {
+	case "<":
+		return v < x
+	case "<=":
+		return v <= x
+	case ">":
+		return v > x
+	case ">=":
+		return v >= x
+	case "=":
+		return v == x
+	}
+	return false
+}
+
+// length converts a length to pixels.
+func length(env *Environment, vl css.ValueList) (float64, bool) {
+	if len(vl) != 1 {
+		return 0, false
+	}
+	l, ok := vl[0].Length()
+	if !ok {
+		return 0, false
+	}
+	switch l.Unit {
+	case "em", "rem":
+		return l.Value * env.fontSize(), true
+	case "vw":
+		return l.Value * env.Width / 100, true
+	case "vh":
+		return l.Value * env.Height / 100, true
+	}
+	return l.Px()
+}
+
+// ratio converts a ratio like 16/9 or a single number to a number.
+func ratio(env *Environment, vl css.ValueList) (float64, bool) {
+	switch {
+	case len(vl) == 1 && vl[0].Type == css.NumberValue:
+		return vl[0].Number, true
+	case len(vl) == 3 && vl[0].Type == css.NumberValue &&
+		vl[1].Type == css.SlashValue && vl[2].Type == css.NumberValue && vl[2].Number != 0:
+		return vl[0].Number / vl[2].Number, true
+	}
+	return 0, false
+}
+
+// resolution converts a resolution to dppx.
+func resolution(env *Environment, vl css.ValueList) (float64, bool) {
+	if len(vl) != 1 || vl[0].Type != css.DimensionValue {
+		return 0, false
+	}
+	switch vl[0].Unit {
+	case "dppx", "x":
+		return vl[0].Number, true
+	case "dpi":
+		return vl[0].Number / 96, true
+	case "dpcm":
+		return vl[0].Number * 2.54 / 96, true
+	}
+	return 0, false
+}
+
+func integer(env *Environment, vl css.ValueList) (float64, bool) {
+	if len(vl) != 1 || vl[0].Type != css.NumberValue {
+		return 0, false
+	}
+	return vl[0].Number, true
+}
+
+// Apply returns a copy of the Stylesheet ss with the @media rules
+// resolved for env. The rules of matching @media rules replace them and
+// the rest are dropped. @import rules whose media queries don't match are
+// dropped. ss isn't modified.
+func Apply(ss *css.Stylesheet, env *Environment) *css.Stylesheet {
+	out := &css.Stylesheet{}
+	for _, st := range ss.Statements {
+		if st.AtRule == nil {
+			out.Statements = append(out.Statements, st)
+			continue
+		}
+		switch strings.ToLower(st.AtRule.AtKeyword) {
+		case "media":
+			if !atRuleMatches(st.AtRule, env) || st.AtRule.SimpleBlock == nil {
+				continue
+			}
+			for _, bi := range applyItems(st.AtRule.SimpleBlock.Content, env) {
+				out.Statements = append(out.Statements, css.Statement{Ruleset: bi.Ruleset, AtRule: bi.AtRule})
+			}
+		case "import":
+			if atRuleMatches(st.AtRule, env) {
+				out.Statements = append(out.Statements, st)
+			}
+		default:
+			out.Statements = append(out.Statements, css.Statement{AtRule: applyAtRule(st.AtRule, env)})
+		}
+	}
+	return out
+}
+
+func atRuleMatches(ar *css.AtRule, env *Environment) bool {
+	ql, err := FromAtRule(ar)
+	return err == nil && ql.Match(env)
+}
+
+// applyAtRule resolves the @media rules nested in a conditional AtRule
+// like @supports.
+func applyAtRule(ar *css.AtRule, env *Environment) *css.AtRule {
+	if ar.SimpleBlock == nil {
+		return ar
+	}
+	return &css.AtRule{AtKeyword: ar.AtKeyword, Param: ar.Param,
+		SimpleBlock: &css.SimpleBlock{Content: applyItems(ar.SimpleBlock.Content, env)}}
+}
+
+func applyItems(items []css.BlockItem, env *Environment) []css.BlockItem {
+	var out []css.BlockItem
+	for _, bi := range items {
+		switch {
+		case bi.AtRule != nil && strings.EqualFold(bi.AtRule.AtKeyword, "media"):
+			if atRuleMatches(bi.AtRule, env) && bi.AtRule.SimpleBlock != nil {
+				out = append(out, applyItems(bi.AtRule.SimpleBlock.Content, env)...)
+			}
+		case bi.AtRule != nil:
+			out = append(out, css.BlockItem{AtRule: applyAtRule(bi.AtRule, env)})
+		default:
+			out = append(out, bi)
+		}
+	}
+	return out
+}
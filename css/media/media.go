@@ -0,0 +1,415 @@
+/*
+Package media parses css media queries and evaluates them against an
+Environment describing a device.
+
+	ql, _ := media.Parse("screen and (400px <= width <= 700px)")
+	if ql.Match(&media.Environment{Type: "screen", Width: 500}) {
+	    // the rules apply
+	}
+
+The package follows http://www.w3.org/TR/mediaqueries-4/ including the
+range syntax, and/or/not conditions and the min- and max- prefixes.
+Features the Environment doesn't describe evaluate to false.
+*/
+package media
+
+import (
+	"fmt"
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+// QueryList is a comma separated list of media Queries. It matches if any
+// of its Queries match.
+type QueryList []*Query
+
+// Query is a single media query like "not print and (color)".
+type Query struct {
+	// Not negates the whole query.
+	Not bool
+	// Only is the only keyword which has no effect on matching.
+	Only bool
+	// The lowercased media type or empty if the query has none.
+	Type string
+	// The Condition of the query or nil if it has none.
+	Condition *Condition
+}
+
+type conditionType int
+
+const (
+	// A single media feature like (width >= 400px).
+	FeatureCondition conditionType = iota
+	// A negated condition like not (color).
+	NotCondition
+	// Conditions joined with and.
+	AndCondition
+	// Conditions joined with or.
+	OrCondition
+	// A parenthesized expression the spec reserves for the future. It
+	// never matches.
+	UnknownCondition
+)
+
+// Condition is a tree of media features joined with and, or and not.
+type Condition struct {
+	Type conditionType
+	// The Feature if Type is FeatureCondition.
+	Feature *Feature
+	// The operands if Type is NotCondition, AndCondition or OrCondition.
+	Children []*Condition
+	// The source text if Type is UnknownCondition.
+	Text string
+}
+
+// Feature tests one media feature.
+type Feature struct {
+	// The lowercased feature name including any min- or max- prefix.
+	Name string
+	// The value of a plain feature like (width: 400px). Nil for a boolean
+	// feature like (color) or a range.
+	Value css.ValueList
+	// The comparisons of a range feature like (400px <= width <= 700px)
+	// normalized so the feature name is on the left. ie:
+	// width >= 400px, width <= 700px.
+	Range []Comparison
+}
+
+// Comparison compares a feature to a value.
+type Comparison struct {
+	// One of <, <=, >, >= or =.
+	Op    string
+	Value css.ValueList
+}
+
+// Parse parses a comma separated list of media queries. An empty string
+// is an empty QueryList which matches everything. As the spec requires a
+// malformed query becomes "not all" without affecting the other queries
+// in the list. It only returns an error if s can't be tokenized.
+func Parse(s string) (QueryList, error) {
+	vl, err := css.ParseValue(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(vl) == 0 {
+		return QueryList{}, nil
+	}
+	var ql QueryList
+	for _, part := range vl.Split() {
+		q, err := parseQuery(part)
+		if err != nil {
+			q = &Query{Not: true, Type: "all"}
+		}
+		ql = append(ql, q)
+	}
+	return ql, nil
+}
+
+// MustParse parses a list of media queries. Panics if s can't be
+// tokenized.
+func MustParse(s string) QueryList {
+	ql, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return ql
+}
+
+// FromAtRule parses the media queries in the prelude of an @media or
+// @import AtRule. For @import the url and any layer() or supports() are
+// skipped.
+func FromAtRule(ar *css.AtRule) (QueryList, error) {
+	params := ar.Param
+	if strings.EqualFold(ar.AtKeyword, "import") {
+		for len(params) > 0 && isImportPrelude(params[0]) {
+			params = params[1:]
+		}
+	}
+	return Parse(strings.Join(params, " "))
+}
+
+func isImportPrelude(p string) bool {
+	lp := strings.ToLower(p)
+	return strings.HasPrefix(lp, "url(") || strings.HasPrefix(lp, `"`) ||
+		strings.HasPrefix(lp, "'") || lp == "layer" ||
+		strings.HasPrefix(lp, "layer(") || strings.HasPrefix(lp, "supports(")
+}
+
+func parseQuery(vl css.ValueList) (*Query, error) {
+	if len(vl) == 0 {
+		return nil, fmt.Errorf("Empty media query")
+	}
+	q := &Query{}
+	i := 0
+	if vl[0].Type == css.IdentValue && len(vl) > 1 && vl[1].Type == css.IdentValue {
+		switch {
+		case vl[0].IsIdent("not"):
+			q.Not = true
+			i++
+		case vl[0].IsIdent("only"):
+			q.Only = true
+			i++
+		}
+	}
+	if vl[i].Type != css.IdentValue || vl[i].IsIdent("not") {
+		if i > 0 {
+			return nil, fmt.Errorf("Expected a media type after %s", vl[0].Value)
+		}
+		c, err := parseCondition(vl, true)
+		if err != nil {
+			return nil, err
+		}
+		q.Condition = c
+		return q, nil
+	}
+	q.Type = strings.ToLower(vl[i].Value)
+	switch q.Type {
+	case "only", "not", "and", "or", "layer":
+		return nil, fmt.Errorf("Invalid media type %s", q.Type)
+	}
+	i++
+	if i == len(vl) {
+		return q, nil
+	}
+	if !vl[i].IsIdent("and") || i+1 == len(vl) {
+		return nil, fmt.Errorf("Expected and after the media type %s", q.Type)
+	}
+	c, err := parseCondition(vl[i+1:], false)
+	if err != nil {
+		return nil, err
+	}
+	q.Condition = c
+	return q, nil
+}
+
+// parseCondition parses a media condition. allowOr is false for the
+// condition following a media type.
+func parseCondition(vl css.ValueList, allowOr bool) (*Condition, error) {
+	if len(vl) == 0 {
+		return nil, fmt.Errorf("Empty media condition")
+	}
+	if vl[0].IsIdent("not") {
+		if len(vl) != 2 {
+			return nil, fmt.Errorf("Expected one condition after not")
+		}
+		c, err := parseInParens(vl[1])
+		if err != nil {
+			return nil, err
+		}
+		return &Condition{Type: NotCondition, Children: []*Condition{c}}, nil
+	}
+	first, err := parseInParens(vl[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(vl) == 1 {
+		return first, nil
+	}
+	c := &Condition{Children: []*Condition{first}}
+	word := strings.ToLower(vl[1].Value)
+	switch {
+	case vl[1].IsIdent("and"):
+		c.Type = AndCondition
+	case vl[1].IsIdent("or") && allowOr:
+		c.Type = OrCondition
+	default:
+		return nil, fmt.Errorf("Unexpected %s in media condition", vl[1])
+	}
+	for i := 1; i < len(vl); i += 2 {
+		op := vl[i]
+		if !op.IsIdent(word) {
+			return nil, fmt.Errorf("Can't mix and and or without parentheses")
+		}
+		if i+1 == len(vl) {
+			return nil, fmt.Errorf("Expected a condition after %s", op.Value)
+		}
+		child, err := parseInParens(vl[i+1])
+		if err != nil {
+			return nil, err
+		}
+		c.Children = append(c.Children, child)
+	}
+	return c, nil
+}
+
+// parseInParens parses a parenthesized condition or media feature.
+func parseInParens(cv css.ComponentValue) (*Condition, error) {
+	if cv.Type == css.FunctionValue {
+		return &Condition{Type: UnknownCondition, Text: cv.String()}, nil
+	}
+	if cv.Type != css.BlockValue || cv.Value != "(" {
+		return nil, fmt.Errorf("Expected a parenthesized condition got %s", cv)
+	}
+	args := cv.Args
+	if len(args) == 0 {
+		return nil, fmt.Errorf("Empty parentheses in media condition")
+	}
+	if args[0].IsIdent("not") || args[0].Type == css.BlockValue || args[0].Type == css.FunctionValue {
+		c, err := parseCondition(args, true)
+		if err != nil {
+			return &Condition{Type: UnknownCondition, Text: cv.String()}, nil
+		}
+		return c, nil
+	}
+	f, err := parseFeature(args)
+	if err != nil {
+		// Anything else in parentheses is general enclosed which is
+		// valid but never matches.
+		return &Condition{Type: UnknownCondition, Text: cv.String()}, nil
+	}
+	return &Condition{Type: FeatureCondition, Feature: f}, nil
+}
+
+func parseFeature(vl css.ValueList) (*Feature, error) {
+	if len(vl) == 1 && vl[0].Type == css.IdentValue {
+		return &Feature{Name: strings.ToLower(vl[0].Value)}, nil
+	}
+	if len(vl) > 2 && vl[0].Type == css.IdentValue && isDelim(vl[1], ":") {
+		return &Feature{Name: strings.ToLower(vl[0].Value), Value: vl[2:]}, nil
+	}
+	return parseRange(vl)
+}
+
+func isDelim(cv css.ComponentValue, d string) bool {
+	return cv.Type == css.DelimValue && cv.Value == d
+}
+
+// parseRange parses the range syntax like width >= 400px or
+// 400px < width <= 700px.
+func parseRange(vl css.ValueList) (*Feature, error) {
+	var operands []css.ValueList
+	var ops []string
+	start := 0
+	for i := 0; i < len(vl); i++ {
+		if !isDelim(vl[i], "<") && !isDelim(vl[i], ">") && !isDelim(vl[i], "=") {
+			continue
+		}
+		op := vl[i].Value
+		operands = append(operands, vl[start:i])
+		if op != "=" && i+1 < len(vl) && isDelim(vl[i+1], "=") {
+			op += "="
+			i++
+		}
+		ops = append(ops, op)
+		start = i + 1
+	}
+	operands = append(operands, vl[start:])
+	for _, o := range operands {
+		if len(o) == 0 {
+			return nil, fmt.Errorf("Missing operand in media feature %s", vl)
+		}
+	}
+	name := func(o css.ValueList) (string, bool) {
+		if len(o) == 1 && o[0].Type == css.IdentValue {
+			return strings.ToLower(o[0].Value), true
+		}
+		return "", false
+	}
+	switch len(operands) {
+	case 2:
+		if n, ok := name(operands[0]); ok {
+			return &Feature{Name: n, Range: []Comparison{{ops[0], operands[1]}}}, nil
+		}
+		if n, ok := name(operands[1]); ok {
+			return &Feature{Name: n, Range: []Comparison{{flip(ops[0]), operands[0]}}}, nil
+		}
+	case 3:
+		n, ok := name(operands[1])
+		lt := ops[0][0] == '<' && ops[1][0] == '<'
+		gt := ops[0][0] == '>' && ops[1][0] == '>'
+		if ok && (lt || gt) {
+			return &Feature{Name: n, Range: []Comparison{
+				{flip(ops[0]), operands[0]},
+				{ops[1], operands[2]},
+			}}, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid media feature %s", vl)
+}
+
+// flip reverses a comparison operator so its operands can be swapped.
+func flip(op string) string {
+	switch op[0] {
+	case '<':
+		return ">" + op[1:]
+	case '>':
+		return "<" + op[1:]
+	}
+	return op
+}
+
+func (ql QueryList) String() string {
+	ss := make([]string, len(ql))
+	for i, q := range ql {
+		ss[i] = q.String()
+	}
+	return strings.Join(ss, ", ")
+}
+
+func (q *Query) String() string {
+	var parts []string
+	switch {
+	case q.Not:
+		parts = append(parts, "not")
+	case q.Only:
+		parts = append(parts, "only")
+	}
+	if q.Type != "" {
+		parts = append(parts, q.Type)
+		if q.Condition != nil {
+			parts = append(parts, "and")
+		}
+	}
+	if q.Condition != nil {
+		parts = append(parts, q.Condition.String())
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, " ")
+}
+
+func (c *Condition) String() string {
+	switch c.Type {
+	case FeatureCondition:
+		return "(" + c.Feature.String() + ")"
+	case NotCondition:
+		return "not " + c.Children[0].inParens()
+	case AndCondition, OrCondition:
+		op := " and "
+		if c.Type == OrCondition {
+			op = " or "
+		}
+		ss := make([]string, len(c.Children))
+		for i, child := range c.Children {
+			ss[i] = child.inParens()
+		}
+		return strings.Join(ss, op)
+	case UnknownCondition:
+		return c.Text
+	}
+	panic("Unreachable")
+}
+
+// inParens serializes a nested Condition wrapping it in parentheses if
+// it isn't already.
+func (c *Condition) inParens() string {
+	if c.Type == FeatureCondition || c.Type == UnknownCondition {
+		return c.String()
+	}
+	return "(" + c.String() + ")"
+}
+
+func (f *Feature) String() string {
+	switch {
+	case len(f.Range) == 2:
+		return f.Range[0].Value.String() + " " + flip(f.Range[0].Op) + " " + f.Name +
+			" " + f.Range[1].Op + " " + f.Range[1].Value.String()
+	case len(f.Range) == 1:
+		return f.Name + " " + f.Range[0].Op + " " + f.Range[0].Value.String()
+	case f.Value != nil:
+		return f.Name + ": " + f.Value.String()
+	}
+	return f.Name
+}
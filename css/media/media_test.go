@@ -0,0 +1,128 @@
+package media
+
+import (
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+func TestParseString(t *testing.T) {
+	tests := []struct{ in, out string }{
+		{"", ""},
+		{"screen", "screen"},
+		{"ONLY Screen and (Max-Width: 600px)", "only screen and (max-width: 600px)"},
+		{"not print and (color)", "not print and (color)"},
+		{"(400px <= width <= 700px)", "(400px <= width <= 700px)"},
+		{"(width>=400px)", "(width >= 400px)"},
+		{"(600px > width)", "(width < 600px)"},
+		{"(color) or (not (hover)) , print", "(color) or (not (hover)), print"},
+		{"(aspect-ratio: 16/9)", "(aspect-ratio: 16/9)"},
+		{"screen and (color) or (hover)", "not all"},
+		{"(color) and (hover) or (grid)", "not all"},
+		{"screen and", "not all"},
+		{"(foo bar)", "(foo bar)"},
+		{"screen, , print", "screen, not all, print"},
+	}
+	for _, test := range tests {
+		ql, err := Parse(test.in)
+		if err != nil {
+			t.Errorf("Error parsing %q: %s", test.in, err)
+			continue
+		}
+		if ql.String() != test.out {
+			t.Errorf("Parsing %q expected %q got %q", test.in, test.out, ql.String())
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	phone := &Environment{Type: "screen", Width: 375, Height: 812, Resolution: 3,
+		Color: 8, ColorScheme: "dark", Pointer: "coarse", ReducedMotion: true}
+	tests := []struct {
+		query string
+		env   *Environment
+		match bool
+	}{
+		{"", phone, true},
+		{"all", &Print, true},
+		{"screen", phone, true},
+		{"print", phone, false},
+		{"not print", phone, true},
+		{"print", &Print, true},
+		{"tv", &Screen, false},
+		{"(max-width: 600px)", phone, true},
+		{"(max-width: 600px)", &Screen, false},
+		{"(min-width: 40em)", &Screen, true},
+		{"(400px <= width <= 700px)", phone, false},
+		{"(300px < width <= 700px)", phone, true},
+		{"(width > 375px)", phone, false},
+		{"(width = 375px)", phone, true},
+		{"(orientation: portrait)", phone, true},
+		{"(orientation: landscape)", &Screen, true},
+		{"(prefers-color-scheme: dark)", phone, true},
+		{"(prefers-color-scheme: dark)", &Screen, false},
+		{"(prefers-reduced-motion)", phone, true},
+		{"(prefers-reduced-motion)", &Screen, false},
+		{"(hover: hover)", &Screen, true},
+		{"(hover)", phone, false},
+		{"(pointer: coarse)", phone, true},
+		{"(min-resolution: 2dppx)", phone, true},
+		{"(min-resolution: 192dpi)", &Screen, false},
+		{"(min-aspect-ratio: 16/10)", &Screen, true},
+		{"(color)", &Screen, true},
+		{"(monochrome)", &Screen, false},
+		{"not (color)", &Screen, false},
+		{"(hover) or (pointer: coarse)", phone, true},
+		{"(hover) and (pointer: coarse)", phone, false},
+		{"screen and (not (hover))", phone, true},
+		{"(unknown-feature)", &Screen, false},
+		{"(min-orientation: portrait)", phone, false},
+		{"print, (max-width: 400px)", phone, true},
+		{"screen and (color) or (hover)", &Screen, false},
+	}
+	for _, test := range tests {
+		ql := MustParse(test.query)
+		if ql.Match(test.env) != test.match {
+			t.Errorf("Expected %q matching %+v to be %v", test.query, *test.env, test.match)
+		}
+	}
+}
+
+func TestFromAtRule(t *testing.T) {
+	ss, err := css.ParseString(`@import url(a.css) layer(base) screen and (color); @media print { p { color: red; } }`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	for i, expected := range []string{"screen and (color)", "print"} {
+		ql, err := FromAtRule(ss.Statements[i].AtRule)
+		if err != nil {
+			t.Fatalf("Error parsing media queries: %s", err)
+		}
+		if ql.String() != expected {
+			t.Errorf("Expected %q got %q", expected, ql.String())
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	ss, err := css.ParseString(`p { color: red; }
+@media (max-width: 600px) { p { color: blue; } @media print { p { color: black; } } }
+@media print { p { color: green; } }
+@supports (display: grid) { @media screen { div { display: grid; } } }`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	before := ss.String()
+	out := Apply(ss, &Environment{Type: "screen", Width: 400}).String()
+	expected := `p { color: red; }
+p { color: blue; }
+@supports (display: grid) {
+div { display: grid; }
+}`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+	if ss.String() != before {
+		t.Errorf("Apply modified its input")
+	}
+}
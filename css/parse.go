@@ -0,0 +1,451 @@
+package css
+
+import (
+	"io"
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+)
+
+// ruleListAtRules are the AtRules whose blocks contain rules instead of
+// declarations.
+var ruleListAtRules = map[string]bool{
+	"media":             true,
+	"supports":          true,
+	"document":          true,
+	"-moz-document":     true,
+	"container":         true,
+	"layer":             true,
+	"scope":             true,
+	"starting-style":    true,
+	"keyframes":         true,
+	"-webkit-keyframes": true,
+	"-moz-keyframes":    true,
+	"-o-keyframes":      true,
+}
+
+// conditionalAtRules are the AtRules that may be nested in a style rule
+// where their blocks contain declarations and nested rules.
+var conditionalAtRules = map[string]bool{
+	"media":          true,
+	"supports":       true,
+	"document":       true,
+	"-moz-document":  true,
+	"container":      true,
+	"layer":          true,
+	"scope":          true,
+	"starting-style": true,
+}
+
+// Parse parses a Stylesheet from an io.Reader.
+func Parse(r io.Reader) (*Stylesheet, error) {
+	p, err := newParser(r)
+	if err != nil {
+		return nil, err
+	}
+	return p.parseStylesheet()
+}
+
+// ParseWithPositions parses a Stylesheet from an io.Reader recording the
+// source position of each Declaration in its Pos.
+func ParseWithPositions(r io.Reader) (*Stylesheet, error) {
+	p, err := newParser(r)
+	if err != nil {
+		return nil, err
+	}
+	p.positions = true
+	return p.parseStylesheet()
+}
+
+// ParseString parses a Stylesheet from a string.
+func ParseString(s string) (*Stylesheet, error) {
+	return Parse(strings.NewReader(s))
+}
+
+// ParseDeclarations parses a list of declarations like the contents of a
+// style attribute.
+func ParseDeclarations(s string) (DeclarationList, error) {
+	p, err := newParser(strings.NewReader(s))
+	if err != nil {
+		return nil, err
+	}
+	items, err := p.parseDeclarationBlock(false)
+	if err != nil {
+		return nil, err
+	}
+	return declarations(items), nil
+}
+
+type parser struct {
+	toks []*tokenizer.Token
+	i    int
+	// positions records the Pos of Declarations.
+	positions bool
+}
+
+func newParser(r io.Reader) (*parser, error) {
+	p := &parser{}
+	tk := tokenizer.New(r)
+	for {
+		t, err := tk.Next()
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return p, nil
+		}
+		p.toks = append(p.toks, t)
+	}
+}
+
+// peek returns the next token without consuming it or nil at EOF.
+func (p *parser) peek() *tokenizer.Token {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return nil
+}
+
+// next consumes the next token and returns it or nil at EOF.
+func (p *parser) next() *tokenizer.Token {
+	t := p.peek()
+	if t != nil {
+		p.i++
+	}
+	return t
+}
+
+func (p *parser) skipWS() {
+	for t := p.peek(); t != nil && (t.Type == tokenizer.WS || t.Type == tokenizer.Comment); t = p.peek() {
+		p.next()
+	}
+}
+
+func (p *parser) parseStylesheet() (*Stylesheet, error) {
+	ss := &Stylesheet{}
+	for t := p.peek(); t != nil; t = p.peek() {
+		switch t.Type {
+		case tokenizer.WS:
+			p.next()
+		case tokenizer.Comment:
+			p.next()
+			c := Comment(strings.TrimSuffix(strings.TrimPrefix(t.String, "/*"), "*/"))
+			ss.Statements = append(ss.Statements, Statement{Comment: &c})
+		case tokenizer.CDO, tokenizer.CDC:
+			p.next()
+			c := HtmlComment(t.String)
+			ss.Statements = append(ss.Statements, Statement{HtmlComment: &c})
+		case tokenizer.AtKeyword:
+			ar, err := p.parseAtRule(false)
+			if err != nil {
+				return nil, err
+			}
+			ss.Statements = append(ss.Statements, Statement{AtRule: ar})
+		default:
+			rs, err := p.parseRuleset(false)
+			if err != nil {
+				return nil, err
+			}
+			if rs != nil {
+				ss.Statements = append(ss.Statements, Statement{Ruleset: rs})
+			}
+		}
+	}
+	return ss, nil
+}
+
+// consumeUntil consumes tokens until one of the stop types is found
+// outside of any nested block. The stop token is not consumed.
+func (p *parser) consumeUntil(stop ...string) []*tokenizer.Token {
+	var toks []*tokenizer.Token
+	depth := 0
+	for t := p.peek(); t != nil; t = p.peek() {
+		if depth == 0 {
+			for _, s := range stop {
+				if t.Text() == s {
+					return toks
+				}
+			}
+		}
+		switch t.Type {
+		case tokenizer.LParen, tokenizer.LBracket, tokenizer.LBrace, tokenizer.Function:
+			depth++
+		case tokenizer.RParen, tokenizer.RBracket, tokenizer.RBrace:
+			if depth == 0 {
+				// An unbalanced closer ends whatever we were consuming.
+				return toks
+			}
+			depth--
+		}
+		toks = append(toks, p.next())
+	}
+	return toks
+}
+
+// text joins tokens back into css source text collapsing whitespace and
+// comments into single spaces.
+func text(toks []*tokenizer.Token) string {
+	var b strings.Builder
+	for _, t := range toks {
+		switch t.Type {
+		case tokenizer.Comment, tokenizer.WS:
+			// A comment separates tokens like whitespace does.
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), " ") {
+				b.WriteByte(' ')
+			}
+		default:
+			b.WriteString(t.Text())
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// params splits an AtRule prelude on its top level whitespace.
+func params(toks []*tokenizer.Token) []string {
+	var ps []string
+	var cur []*tokenizer.Token
+	depth := 0
+	for _, t := range toks {
+		switch t.Type {
+		case tokenizer.LParen, tokenizer.LBracket, tokenizer.Function:
+			depth++
+		case tokenizer.RParen, tokenizer.RBracket:
+			depth--
+		case tokenizer.WS:
+			if depth == 0 {
+				if s := text(cur); s != "" {
+					ps = append(ps, s)
+				}
+				cur = nil
+				continue
+			}
+		}
+		cur = append(cur, t)
+	}
+	if s := text(cur); s != "" {
+		ps = append(ps, s)
+	}
+	return ps
+}
+
+// parseAtRule parses an AtRule. nested is true for an AtRule inside a
+// style rule.
+func (p *parser) parseAtRule(nested bool) (*AtRule, error) {
+	t := p.next()
+	ar := &AtRule{AtKeyword: tokenizer.Unescape(strings.TrimPrefix(t.String, "@"))}
+	ar.Param = params(p.consumeUntil(";", "{"))
+	end := p.next()
+	if end == nil || end.Type != tokenizer.LBrace {
+		// A statement AtRule like @import ends with a ; or at EOF.
+		return ar, nil
+	}
+	var items []BlockItem
+	var err error
+	kw := strings.ToLower(ar.AtKeyword)
+	switch {
+	case nested && conditionalAtRules[kw]:
+		items, err = p.parseDeclarationBlock(true)
+	case ruleListAtRules[kw]:
+		items, err = p.parseRuleList()
+	default:
+		items, err = p.parseDeclarationBlock(false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.next() // the closing }
+	ar.SimpleBlock = &SimpleBlock{Content: items}
+	return ar, nil
+}
+
+// parseRuleList parses the contents of a block of rules up to but not
+// including the closing }.
+func (p *parser) parseRuleList() ([]BlockItem, error) {
+	var items []BlockItem
+	for t := p.peek(); t != nil && t.Type != tokenizer.RBrace; t = p.peek() {
+		switch t.Type {
+		case tokenizer.WS, tokenizer.Comment, tokenizer.CDO, tokenizer.CDC:
+			p.next()
+		case tokenizer.AtKeyword:
+			ar, err := p.parseAtRule(false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, BlockItem{AtRule: ar})
+		default:
+			rs, err := p.parseRuleset(false)
+			if err != nil {
+				return nil, err
+			}
+			if rs != nil {
+				items = append(items, BlockItem{Ruleset: rs})
+			}
+		}
+	}
+	return items, nil
+}
+
+// parseRuleset parses a style rule. nested is true for a rule nested in
+// another style rule.
+func (p *parser) parseRuleset(nested bool) (*Ruleset, error) {
+	prelude := p.consumeUntil("{")
+	t := p.next()
+	if t == nil || t.Type != tokenizer.LBrace {
+		// A Ruleset without a block is dropped.
+		return nil, nil
+	}
+	sel := text(prelude)
+	parseGroup := selector.SelectorGroup
+	if nested {
+		parseGroup = selector.NestedSelectorGroup
+	}
+	g, selErr := parseGroup(sel)
+	items, err := p.parseDeclarationBlock(true)
+	if err != nil {
+		return nil, err
+	}
+	p.next() // the closing }
+	if selErr != nil {
+		// A Ruleset with an invalid selector is dropped along with its
+		// block.
+		// http://www.w3.org/TR/css-syntax-3/#style-rules
+		return nil, nil
+	}
+	rs := &Ruleset{Selector: g}
+	if len(items) > 0 && items[0].Ruleset == nil && items[0].AtRule == nil {
+		rs.DeclarationList = items[0].DeclarationList
+		items = items[1:]
+	}
+	if len(items) > 0 {
+		rs.Nested = items
+	}
+	return rs, nil
+}
+
+// startsRule returns true if the tokens up to the next ; or { start a
+// nested style rule rather than a declaration.
+func (p *parser) startsRule() bool {
+	if t := p.peek(); t.Type == tokenizer.Ident && strings.HasPrefix(t.String, "--") {
+		// Custom properties may contain {} blocks.
+		return false
+	}
+	i := p.i
+	p.consumeUntil(";", "{")
+	t := p.peek()
+	p.i = i
+	return t != nil && t.Type == tokenizer.LBrace
+}
+
+// declarations collects the declarations from a list of BlockItems.
+func declarations(items []BlockItem) DeclarationList {
+	var dl DeclarationList
+	for _, bi := range items {
+		dl = append(dl, bi.DeclarationList...)
+	}
+	return dl
+}
+
+// parseDeclarationBlock parses declarations and AtRules up to but not
+// including the closing }. nested is true for the block of a style rule
+// which may contain nested style rules.
+func (p *parser) parseDeclarationBlock(nested bool) ([]BlockItem, error) {
+	var items []BlockItem
+	var dl DeclarationList
+	for t := p.peek(); t != nil && t.Type != tokenizer.RBrace; t = p.peek() {
+		switch {
+		case t.Type == tokenizer.WS, t.Type == tokenizer.Comment, t.Type == tokenizer.Semicolon:
+			p.next()
+		case t.Type == tokenizer.AtKeyword:
+			ar, err := p.parseAtRule(nested)
+			if err != nil {
+				return nil, err
+			}
+			if len(dl) > 0 {
+				items = append(items, BlockItem{DeclarationList: dl})
+				dl = nil
+			}
+			items = append(items, BlockItem{AtRule: ar})
+		case nested && p.startsRule():
+			rs, err := p.parseRuleset(true)
+			if err != nil {
+				return nil, err
+			}
+			if rs == nil {
+				continue
+			}
+			if len(dl) > 0 {
+				items = append(items, BlockItem{DeclarationList: dl})
+				dl = nil
+			}
+			items = append(items, BlockItem{Ruleset: rs})
+		case t.Type == tokenizer.Ident:
+			if d, ok := p.parseDeclaration(); ok {
+				dl = append(dl, d)
+			}
+		default:
+			// Anything else is an invalid declaration which we skip.
+			if p.consumeUntil(";") == nil {
+				// Skip a stray closing paren or bracket.
+				p.next()
+			}
+		}
+	}
+	if len(dl) > 0 {
+		items = append(items, BlockItem{DeclarationList: dl})
+	}
+	return items, nil
+}
+
+// parseDeclaration parses a single declaration. It returns false if the
+// declaration was invalid.
+// http://www.w3.org/TR/css-syntax-3/#consume-a-declaration
+func (p *parser) parseDeclaration() (Declaration, bool) {
+	start := p.next()
+	name := tokenizer.Unescape(start.String)
+	p.skipWS()
+	if t := p.peek(); t == nil || t.Type != tokenizer.Colon {
+		p.consumeUntil(";")
+		return Declaration{}, false
+	}
+	p.next()
+	value := p.consumeUntil(";")
+	d := Declaration{Property: name}
+	if p.positions {
+		d.Pos = start.Position
+	}
+	if !strings.HasPrefix(name, "--") {
+		// Custom properties are case sensitive everything else isn't.
+		d.Property = strings.ToLower(name)
+	}
+	value, d.Important = stripImportant(value)
+	d.Value = text(value)
+	if d.Value == "" && !strings.HasPrefix(name, "--") {
+		return Declaration{}, false
+	}
+	return d, true
+}
+
+// stripImportant removes a trailing !important from a declaration value.
+func stripImportant(toks []*tokenizer.Token) ([]*tokenizer.Token, bool) {
+	i := lastSignificant(toks, len(toks))
+	if i < 0 || toks[i].Type != tokenizer.Ident || !strings.EqualFold(toks[i].String, "important") {
+		return toks, false
+	}
+	j := lastSignificant(toks, i)
+	if j < 0 || toks[j].Type != tokenizer.Delim || toks[j].String != "!" {
+		return toks, false
+	}
+	return toks[:j], true
+}
+
+// lastSignificant returns the index of the last token before end that
+// isn't whitespace or a comment.
+func lastSignificant(toks []*tokenizer.Token, end int) int {
+	for i := end - 1; i >= 0; i-- {
+		if toks[i].Type != tokenizer.WS && toks[i].Type != tokenizer.Comment {
+			return i
+		}
+	}
+	return -1
+}
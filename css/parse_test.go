@@ -0,0 +1,215 @@
+package css
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseStylesheet(t *testing.T) {
+	ss, err := ParseString(`
+/* header */
+@import url(foo.css) screen;
+a, b.c { color: red; margin : 0 auto !important }
+@media screen and (max-width: 600px) {
+	div > p { font: 12px/1.5 "Helvetica Neue", serif; }
+}
+@font-face { font-family: Foo; src: url(foo.woff) }
+`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	if len(ss.Statements) != 5 {
+		t.Fatalf("Expected 5 statements got %d: %v", len(ss.Statements), ss.Statements)
+	}
+	if c := ss.Statements[0].Comment; c == nil || *c != " header " {
+		t.Errorf("Expected a comment got %v", ss.Statements[0])
+	}
+	imp := ss.Statements[1].AtRule
+	if imp == nil || imp.AtKeyword != "import" || imp.SimpleBlock != nil {
+		t.Fatalf("Expected an @import got %v", ss.Statements[1])
+	}
+	if !reflect.DeepEqual(imp.Param, []string{"url(foo.css)", "screen"}) {
+		t.Errorf("Unexpected @import params %q", imp.Param)
+	}
+	rs := ss.Statements[2].Ruleset
+	if rs == nil || len(rs.Selector) != 2 || rs.Selector.String() != "a, b.c" {
+		t.Fatalf("Expected a Ruleset got %v", ss.Statements[2])
+	}
+	expected := DeclarationList{
+		{Property: "color", Value: "red"},
+		{Property: "margin", Value: "0 auto", Important: true},
+	}
+	if !reflect.DeepEqual(rs.DeclarationList, expected) {
+		t.Errorf("Expected %v got %v", expected, rs.DeclarationList)
+	}
+	media := ss.Statements[3].AtRule
+	if media == nil || media.AtKeyword != "media" {
+		t.Fatalf("Expected an @media got %v", ss.Statements[3])
+	}
+	if !reflect.DeepEqual(media.Param, []string{"screen", "and", "(max-width: 600px)"}) {
+		t.Errorf("Unexpected @media params %q", media.Param)
+	}
+	if len(media.SimpleBlock.Content) != 1 || media.SimpleBlock.Content[0].Ruleset == nil {
+		t.Fatalf("Expected one Ruleset in @media got %v", media.SimpleBlock)
+	}
+	inner := media.SimpleBlock.Content[0].Ruleset
+	if inner.Selector.String() != "div>p" || inner.DeclarationList[0].Value != `12px/1.5 "Helvetica Neue", serif` {
+		t.Errorf("Unexpected nested Ruleset %v", inner)
+	}
+	ff := ss.Statements[4].AtRule
+	if ff == nil || len(ff.SimpleBlock.Content) != 1 || len(ff.SimpleBlock.Content[0].DeclarationList) != 2 {
+		t.Errorf("Expected two declarations in @font-face got %v", ff)
+	}
+}
+
+func TestParseRecovery(t *testing.T) {
+	ss, err := ParseString(`a { color red; ) background: blue; ; width: }`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	expected := DeclarationList{{Property: "background", Value: "blue"}}
+	if !reflect.DeepEqual(ss.Statements[0].Ruleset.DeclarationList, expected) {
+		t.Errorf("Expected %v got %v", expected, ss.Statements[0].Ruleset.DeclarationList)
+	}
+}
+
+func TestParseInvalidSelector(t *testing.T) {
+	ss, err := ParseString(`a > > b { color: red; } p { color: blue; } div { a[ { color: green; } }`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	if ss.String() != "p { color: blue; }\ndiv { }" {
+		t.Errorf("Expected the invalid rules to be dropped got %q", ss)
+	}
+}
+
+func TestParseDeclarations(t *testing.T) {
+	dl, err := ParseDeclarations(`COLOR: Red; --Brand: #fff ;background:url("a;b.png")`)
+	if err != nil {
+		t.Fatalf("Error parsing declarations: %s", err)
+	}
+	expected := DeclarationList{
+		{Property: "color", Value: "Red"},
+		{Property: "--Brand", Value: "#fff"},
+		{Property: "background", Value: `url("a;b.png")`},
+	}
+	if !reflect.DeepEqual(dl, expected) {
+		t.Errorf("Expected %v got %v", expected, dl)
+	}
+	if dl.String() != `color: Red; --Brand: #fff; background: url("a;b.png")` {
+		t.Errorf("Unexpected String() %q", dl.String())
+	}
+}
+
+func TestStylesheetString(t *testing.T) {
+	in := `@charset "utf-8";
+a, b { color: red; margin: 0 !important; }
+@media print {
+p { }
+}`
+	ss, err := ParseString(in)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	if ss.String() != in {
+		t.Errorf("Expected %q got %q", in, ss.String())
+	}
+}
+
+func TestStringEscapesRoundTrip(t *testing.T) {
+	for _, in := range []string{
+		`p { content: "a\"b"; }`,
+		`p { content: 'x\'y'; }`,
+		`p { content: "a\\"; }`,
+	} {
+		ss, err := ParseString(in)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %s", in, err)
+		}
+		if ss.String() != in {
+			t.Errorf("Expected %q got %q", in, ss.String())
+		}
+	}
+}
+
+func TestParseNesting(t *testing.T) {
+	ss, err := ParseString(`.card {
+	color: red;
+	& .title { font-weight: bold; }
+	&:hover { color: blue; }
+	p a:hover { color: green; }
+	> li { margin: 0; }
+	@media (max-width: 600px) {
+		padding: 0;
+		.title { font-size: 12px; }
+	}
+	--brand: { x };
+	background: white;
+}`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	rs := ss.Statements[0].Ruleset
+	expected := DeclarationList{{Property: "color", Value: "red"}}
+	if !reflect.DeepEqual(rs.DeclarationList, expected) {
+		t.Errorf("Expected %v got %v", expected, rs.DeclarationList)
+	}
+	if len(rs.Nested) != 6 {
+		t.Fatalf("Expected 6 nested items got %d: %v", len(rs.Nested), rs.Nested)
+	}
+	for i, sel := range []string{"& .title", "&:hover", "& p a:hover", "&>li"} {
+		if r := rs.Nested[i].Ruleset; r == nil || r.Selector.String() != sel {
+			t.Errorf("Expected a nested Ruleset %q got %v", sel, rs.Nested[i])
+		}
+	}
+	media := rs.Nested[4].AtRule
+	if media == nil || len(media.SimpleBlock.Content) != 2 ||
+		media.SimpleBlock.Content[0].DeclarationList[0].Property != "padding" ||
+		media.SimpleBlock.Content[1].Ruleset.Selector.String() != "& .title" {
+		t.Errorf("Unexpected nested @media %v", rs.Nested[4])
+	}
+	expected = DeclarationList{{Property: "--brand", Value: "{ x }"}, {Property: "background", Value: "white"}}
+	if !reflect.DeepEqual(rs.Nested[5].DeclarationList, expected) {
+		t.Errorf("Expected %v got %v", expected, rs.Nested[5].DeclarationList)
+	}
+	reparsed, err := ParseString(ss.String())
+	if err != nil || reparsed.String() != ss.String() {
+		t.Errorf("Expected %q to round trip got %v %v", ss.String(), reparsed, err)
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	ss, err := ParseString(`.card, #main {
+	color: red;
+	&:hover { color: blue; }
+	.title { b: 1; span { c: 2; } }
+	@media print { padding: 0; > p { d: 3; } }
+	background: white;
+}
+@media screen { ul { > li { e: 4; } } }
+& > p { f: 5; }`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	before := ss.String()
+	out := Flatten(ss).String()
+	expected := `.card, #main { color: red; }
+:is(.card, #main):hover { color: blue; }
+:is(.card, #main) .title { b: 1; }
+:is(.card, #main) .title span { c: 2; }
+@media print {
+.card, #main { padding: 0; }
+:is(.card, #main)>p { d: 3; }
+}
+.card, #main { background: white; }
+@media screen {
+ul>li { e: 4; }
+}
+:root>p { f: 5; }`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+	if ss.String() != before {
+		t.Errorf("Flatten modified its input %q", ss.String())
+	}
+}
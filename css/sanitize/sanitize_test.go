@@ -0,0 +1,131 @@
+package sanitize
+
+import (
+	"testing"
+)
+
+func TestStyle(t *testing.T) {
+	cases := []struct {
+		style, expected string
+	}{
+		{"color: red; margin: 0 auto", "color: red; margin: 0 auto"},
+		{"COLOR : Red !important", "color: Red !important"},
+		{"color: red; behavior: url(x.htc)", "color: red"},
+		{"-moz-binding: url(x.xml#xss); color: red", "color: red"},
+		{"width: expression(alert(1))", ""},
+		{"width: exp/**/ression(alert(1))", ""},
+		{`width: \65 xpression(alert(1))`, ""},
+		{`\62 ehavior: url(x.htc)`, ""},
+		{"background: url(javascript:alert(1))", ""},
+		{"background: url('javascript:alert(1)')", ""},
+		{`background: url("java\9 script:alert(1)")`, ""},
+		{`background: url("  JavaScript:alert(1)")`, ""},
+		{`background: u\72 l(javascript:alert(1))`, ""},
+		{"background: url(/a.png) no-repeat", `background: url("/a.png") no-repeat`},
+		{"background-image: url(https://example.com/a.png)",
+			`background-image: url("https://example.com/a.png")`},
+		{"background-image: url(data:image/png;base64,AAAA)", ""},
+		{"background-image: url(//evil.com/x)", `background-image: url("//evil.com/x")`},
+		{`content: "\3c/style>"`, ""},
+		{"color: rgb(1 2 3 / 50%); width: calc(100% - 2px)",
+			"color: rgb(1 2 3 / 50%); width: calc(100% - 2px)"},
+		{"font-family: 'a</style><script>'", `font-family: "a\3c /style\3e \3c script\3e "`},
+		{"color: red; } margin: 0; body { color: blue", "color: red; margin: 0"},
+		{"color: red; @import 'x.css'; margin: 0", "color: red; margin: 0"},
+		{"color: red; p { color: blue } margin: 0", "color: red; margin: 0"},
+		{"position: fixed; color: red", "color: red"},
+		{"--x: url(javascript:alert(1))", ""},
+		{"width: 1\\70 x", "width: 1px"},
+		{"color: red;;; margin: 0 ! important", "color: red; margin: 0 !important"},
+		{"margin: 0 ! foo", ""},
+		{"margin: (0", ""},
+	}
+	s := New()
+	for _, c := range cases {
+		if got := s.Style(c.style); got != c.expected {
+			t.Errorf("Style(%q) expected %q got %q", c.style, c.expected, got)
+		}
+	}
+}
+
+func TestStylesheet(t *testing.T) {
+	cases := []struct {
+		src, expected string
+	}{
+		{"p { color: red }", "p { color: red; }"},
+		{"a:hover, .x > b[type^='t'] { color: red; behavior: url(x) }",
+			`a:hover, .x > b[type^="t"] { color: red; }`},
+		{`a[href^="http"] { background: url(https://evil/leak) }`, ""},
+		{`input[VALUE$='a'] { color: red }`, ""},
+		{`p { & [data-token*=x] { color: red } }`, ""},
+		{"p { } a { behavior: url(x) } b { color: red }", "b { color: red; }"},
+		{"@media print { p { } }", ""},
+		{`a[href] { color: red }`, "a[href] { color: red; }"},
+		{"@import 'evil.css'; p { color: red }", "p { color: red; }"},
+		{"@import url(evil.css) screen; p { color: red }", "p { color: red; }"},
+		{"@-moz-document url-prefix() { p { color: red } } a { color: blue }", "a { color: blue; }"},
+		{"@media screen and (max-width: 600px) { p { color: red } }",
+			"@media screen and (max-width: 600px) {\np { color: red; }\n}"},
+		{"@font-face { font-family: x; src: url(x.woff) } p { color: red }", "p { color: red; }"},
+		{"@keyframes spin { from { opacity: 0 } 50% { opacity: 1 } }",
+			"@keyframes spin {\nfrom { opacity: 0; }\n50% { opacity: 1; }\n}"},
+		{"p { color: red; & span { color: blue } }", "p { color: red;\n& span { color: blue; }\n}"},
+		{"p</style><script>alert(1)</script> { color: red }", ""},
+		{"<!-- p { color: red } -->", "p { color: red; }"},
+		{"} p { color: red }", "p { color: red; }"},
+		{"p { color: red", "p { color: red; }"},
+	}
+	s := New()
+	for _, c := range cases {
+		if got := s.Stylesheet(c.src); got != c.expected {
+			t.Errorf("Stylesheet(%q) expected\n%q got\n%q", c.src, c.expected, got)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	s := New()
+	s.AllowProperties("position")
+	s.AllowSchemes("data")
+	s.AllowCustomProperties()
+	s.AllowAtRules("font-face", "@import")
+	s.AllowProperties("content")
+	cases := []struct {
+		style, expected string
+	}{
+		{`content: "\3c/style>"`, `content: "\3c /style\3e "`},
+		{"position: fixed", "position: fixed"},
+		{"background: url(data:image/png;base64,AAAA)", `background: url("data:image/png;base64,AAAA")`},
+		{"--x: 1px; --y: url(javascript:x)", "--x: 1px"},
+	}
+	for _, c := range cases {
+		if got := s.Style(c.style); got != c.expected {
+			t.Errorf("Style(%q) expected %q got %q", c.style, c.expected, got)
+		}
+	}
+	src := "@import 'x.css'; @font-face { font-family: x; src: url(x.woff) format('woff'); color: red }"
+	expected := `@font-face { font-family: x; src: url("x.woff") format("woff"); }`
+	if got := s.Stylesheet(src); got != expected {
+		t.Errorf("Stylesheet(%q) expected\n%q got\n%q", src, expected, got)
+	}
+}
+
+func TestRemoteURLs(t *testing.T) {
+	s := &Sanitizer{
+		properties: map[string]bool{"background": true, "color": true},
+		functions:  map[string]bool{},
+		schemes:    map[string]bool{"https": true},
+		atRules:    map[string]bool{},
+	}
+	for _, style := range []string{"background: url(//evil.com/x)", "background: url(///evil.com/x)"} {
+		if got := s.Style(style); got != "" {
+			t.Errorf("Style(%q) expected the protocol relative url to be dropped got %q", style, got)
+		}
+	}
+	s.schemes = map[string]bool{"data": true}
+	src := `a[href^="http"] { background: url(data:image/png,x) }`
+	expected := `a[href^="http"] { background: url("data:image/png,x"); }`
+	if got := s.Stylesheet(src); got != expected {
+		t.Errorf("Stylesheet(%q) expected\n%q got\n%q", src, expected, got)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+Package scope rewrites the rules of a css Stylesheet so they only apply to
+the elements of one component.
+
+	s := scope.FromSource(componentCSS)
+	scoped, err := s.Stylesheet(ss)
+	t.Apply(transform.ScopeAttrib(s.Attr()), ".card")
+
+By default every selector is made to require the scope attribute, eg:
+data-s-1a2b3c4d, on the element it matches like Vue's scoped styles so
+the component's elements need stamping with it. With a Selector the rules
+are scoped to the descendants of the elements it matches instead. The
+@keyframes defined in the Stylesheet are renamed with the scope ID along
+with the animations referring to them.
+*/
+package scope
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+)
+
+// Scope is the scope of a component's css.
+type Scope struct {
+	// ID identifies the component. It names the scope attribute and
+	// suffixes the component's @keyframes names.
+	ID string
+	// Selector, if not empty, scopes rules to the descendants of the
+	// elements it matches instead of requiring the scope attribute.
+	Selector string
+}
+
+// New constructs a Scope with the given ID.
+func New(id string) Scope {
+	return Scope{ID: id}
+}
+
+// FromSource constructs a Scope with an ID derived from the component's
+// source so it is stable across builds.
+func FromSource(src string) Scope {
+	h := fnv.New32a()
+	io.WriteString(h, src)
+	return New(fmt.Sprintf("%08x", h.Sum32()))
+}
+
+// Attr returns the name of the scope attribute.
+func (s Scope) Attr() string {
+	return "data-s-" + s.ID
+}
+
+// Keyframes returns the scoped name of the @keyframes named name.
+func (s Scope) Keyframes(name string) string {
+	return name + "-" + s.ID
+}
+
+// Stylesheet returns a copy of ss with every selector scoped and its
+// @keyframes renamed. Nested rules are flattened first. It returns an
+// error if Selector isn't a valid selector group.
+func (s Scope) Stylesheet(ss *css.Stylesheet) (*css.Stylesheet, error) {
+	sc := &scoper{s: s, keyframes: map[string]bool{}}
+	if s.Selector != "" {
+		var err error
+		if sc.under, err = selector.SelectorGroup(s.Selector); err != nil {
+			return nil, fmt.Errorf("Invalid scope selector %q: %s", s.Selector, err)
+		}
+	}
+	ss = css.Flatten(ss)
+	for _, st := range ss.Statements {
+		if st.AtRule != nil {
+			sc.collectKeyframes(st.AtRule)
+		}
+	}
+	out := &css.Stylesheet{}
+	for _, st := range ss.Statements {
+		switch {
+		case st.Ruleset != nil:
+			st.Ruleset = sc.ruleset(st.Ruleset)
+		case st.AtRule != nil:
+			st.AtRule = sc.atRule(st.AtRule)
+		}
+		out.Statements = append(out.Statements, st)
+	}
+	return out, nil
+}
+
+type scoper struct {
+	s         Scope
+	under     selector.Group
+	keyframes map[string]bool
+}
+
+func isKeyframes(ar *css.AtRule) bool {
+	return strings.HasSuffix(strings.ToLower(ar.AtKeyword), "keyframes")
+}
+
+func (sc *scoper) collectKeyframes(ar *css.AtRule) {
+	if isKeyframes(ar) {
+		if len(ar.Param) > 0 {
+			sc.keyframes[ar.Param[0]] = true
+		}
+		return
+	}
+	if ar.SimpleBlock != nil {
+		for _, bi := range ar.SimpleBlock.Content {
+			if bi.AtRule != nil {
+				sc.collectKeyframes(bi.AtRule)
+			}
+		}
+	}
+}
+
+func (sc *scoper) ruleset(rs *css.Ruleset) *css.Ruleset {
+	var g selector.Group
+	for _, chn := range rs.Selector {
+		if sc.under != nil {
+			for _, u := range sc.under {
+				g = append(g, descendant(u, chn))
+			}
+		} else {
+			g = append(g, sc.withAttr(chn))
+		}
+	}
+	return &css.Ruleset{Selector: g, DeclarationList: sc.declarations(rs.DeclarationList)}
+}
+
+// withAttr returns a copy of chn whose last Sequence requires the scope
+// attribute. The attribute goes before any PseudoElements since they must
+// end the Sequence.
+func (sc *scoper) withAttr(chn *selector.Chain) *selector.Chain {
+	attr := selector.SimpleSelector{Type: selector.Attr, AttrName: sc.s.Attr()}
+	out := &selector.Chain{Head: chn.Head, Tail: append([]selector.Link(nil), chn.Tail...)}
+	seq := &out.Head
+	if len(out.Tail) > 0 {
+		seq = &out.Tail[len(out.Tail)-1].Sequence
+	}
+	i := len(*seq)
+	for i > 0 && (*seq)[i-1].Type == selector.PseudoElement {
+		i--
+	}
+	scoped := make(selector.Sequence, 0, len(*seq)+1)
+	scoped = append(scoped, (*seq)[:i]...)
+	scoped = append(scoped, attr)
+	*seq = append(scoped, (*seq)[i:]...)
+	return out
+}
+
+// descendant returns the Chain matching the elements chn matches that are
+// descendants of the elements u matches.
+func descendant(u, chn *selector.Chain) *selector.Chain {
+	out := &selector.Chain{Head: u.Head}
+	out.Tail = append(out.Tail, u.Tail...)
+	out.Tail = append(out.Tail, selector.Link{Combinator: selector.Descendant, Sequence: chn.Head})
+	out.Tail = append(out.Tail, chn.Tail...)
+	return out
+}
+
+func (sc *scoper) atRule(ar *css.AtRule) *css.AtRule {
+	if isKeyframes(ar) {
+		if len(ar.Param) == 0 {
+			return ar
+		}
+		params := append([]string{sc.s.Keyframes(ar.Param[0])}, ar.Param[1:]...)
+		return &css.AtRule{AtKeyword: ar.AtKeyword, Param: params, SimpleBlock: ar.SimpleBlock}
+	}
+	if ar.SimpleBlock == nil {
+		return ar
+	}
+	out := &css.AtRule{AtKeyword: ar.AtKeyword, Param: ar.Param, SimpleBlock: &css.SimpleBlock{}}
+	for _, bi := range ar.SimpleBlock.Content {
+		switch {
+		case bi.Ruleset != nil:
+			bi.Ruleset = sc.ruleset(bi.Ruleset)
+		case bi.AtRule != nil:
+			bi.AtRule = sc.atRule(bi.AtRule)
+		}
+		out.SimpleBlock.Content = append(out.SimpleBlock.Content, bi)
+	}
+	return out
+}
+
+// declarations renames the animations in dl that refer to the
+// Stylesheet's @keyframes.
+func (sc *scoper) declarations(dl css.DeclarationList) css.DeclarationList {
+	out := make(css.DeclarationList, 0, len(dl))
+	for _, d := range dl {
+		switch d.Property {
+		case "animation", "animation-name", "-webkit-animation", "-webkit-animation-name":
+			d.Value = sc.animations(d.Value)
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+func (sc *scoper) animations(value string) string {
+	var b strings.Builder
+	tk := tokenizer.New(strings.NewReader(value))
+	for {
+		t, err := tk.Next()
+		if err != nil {
+			return value
+		}
+		if t == nil {
+			break
+		}
+		switch {
+		case t.Type == tokenizer.Comment:
+		case t.Type == tokenizer.WS:
+			b.WriteByte(' ')
+		case t.Type == tokenizer.Ident && sc.keyframes[t.String]:
+			b.WriteString(sc.s.Keyframes(t.String))
+		default:
+			b.WriteString(t.Text())
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,81 @@
+package scope
+
+import (
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+const src = `
+.card, .card > p::before { color: red; animation: 1s fade infinite, spin 2s }
+.card { & .title:hover { font-weight: bold } }
+@media print { a { color: black } }
+@keyframes fade { from { opacity: 0 } to { opacity: 1 } }
+`
+
+func scoped(t *testing.T, s Scope) string {
+	ss, err := css.ParseString(src)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	before := ss.String()
+	out, err := s.Stylesheet(ss)
+	if err != nil {
+		t.Fatalf("Error scoping stylesheet: %s", err)
+	}
+	if ss.String() != before {
+		t.Errorf("Stylesheet modified its input")
+	}
+	return out.String()
+}
+
+func TestStylesheetAttr(t *testing.T) {
+	expected := `.card[data-s-x], .card>p[data-s-x]::before { color: red; animation: 1s fade-x infinite, spin 2s; }
+.card .title:hover[data-s-x] { font-weight: bold; }
+@media print {
+a[data-s-x] { color: black; }
+}
+@keyframes fade-x {
+from { opacity: 0; }
+to { opacity: 1; }
+}`
+	if got := scoped(t, New("x")); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+}
+
+func TestStylesheetSelector(t *testing.T) {
+	expected := `#app .card, #app .card>p::before { color: red; animation: 1s fade-x infinite, spin 2s; }
+#app .card .title:hover { font-weight: bold; }
+@media print {
+#app a { color: black; }
+}
+@keyframes fade-x {
+from { opacity: 0; }
+to { opacity: 1; }
+}`
+	if got := scoped(t, Scope{ID: "x", Selector: "#app"}); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+	if _, err := (Scope{ID: "x", Selector: "a > > b"}).Stylesheet(&css.Stylesheet{}); err == nil {
+		t.Errorf("Expected an error for an invalid scope selector")
+	}
+}
+
+func TestStylesheetMatches(t *testing.T) {
+	s := FromSource(src)
+	if s.ID != FromSource(src).ID || s.ID == FromSource(src+" ").ID {
+		t.Errorf("Expected a stable ID derived from the source got %q", s.ID)
+	}
+	ss, _ := css.ParseString(`p { color: red }`)
+	out, err := s.Stylesheet(ss)
+	if err != nil {
+		t.Fatalf("Error scoping stylesheet: %s", err)
+	}
+	tree, _ := h5.NewFromString(`<p id=a ` + s.Attr() + `>a</p><p id=b>b</p>`)
+	found := out.Statements[0].Ruleset.Selector.Find(tree.Top())
+	if len(found) != 1 || found[0].Attr[0].Val != "a" {
+		t.Errorf("Expected the scoped selector to match only #a got %v", found)
+	}
+}
@@ -4,10 +4,12 @@
 package selector
 
 import (
-	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5/walk"
 
 	"fmt"
+	"strconv"
 	"strings"
+	"sync"
 
 	"golang.org/x/net/html"
 )
@@ -29,6 +31,8 @@ const (
 	PseudoClass
 	// Pseudoelement Selector
 	PseudoElement
+	// Nesting selector & referring to the parent rule of a nested rule
+	Nesting
 )
 
 // combinator combines two selector sequences together
@@ -71,6 +75,12 @@ const (
 	Contains
 	// Test that an attribute starts with a value or a value with a dash.
 	DashPrefix
+	// Test that an attribute starts with a value.
+	Prefix
+	// Test that an attribute ends with a value.
+	Suffix
+	// Test that an attribute contains a value as a substring.
+	Substring
 )
 
 func (t attrMatchType) String() string {
@@ -83,6 +93,12 @@ func (t attrMatchType) String() string {
 		return "~="
 	case DashPrefix:
 		return "|="
+	case Prefix:
+		return "^="
+	case Suffix:
+		return "$="
+	case Substring:
+		return "*="
 	}
 	panic("Unreachable")
 }
@@ -99,6 +115,9 @@ type SimpleSelector struct {
 	Value string
 	// The attribute name if Type is Attr
 	AttrName string
+	// IgnoreCase is true if Type is Attr and the Value is matched ignoring
+	// ASCII case like [type="a" i].
+	IgnoreCase bool
 }
 
 const (
@@ -123,26 +142,75 @@ func attrExactly(val string, a *html.Attribute) bool {
 	return val == a.Val
 }
 
+// tagName returns the tag name of n like h5.Data which selector can't
+// import since h5 uses the css packages.
+func tagName(n *html.Node) string {
+	if n.Data == "" {
+		return n.DataAtom.String()
+	}
+	return n.Data
+}
+
+// walkNodes calls f for n and each of its descendants like h5.WalkNodes.
+func walkNodes(n *html.Node, f func(*html.Node)) {
+	if n != nil {
+		walk.Walker(func(n *html.Node) walk.Action {
+			f(n)
+			return walk.Continue
+		}).Walk(n)
+	}
+}
+
 // Match returns true if this SimpleSelector matches this node false otherwise.
 func (ss SimpleSelector) Match(n *html.Node) bool {
 	if n == nil {
 		return false
 	}
 	if ss.Type == Tag {
-		return strings.ToLower(ss.Tag) == strings.ToLower(h5.Data(n))
+		return strings.ToLower(ss.Tag) == strings.ToLower(tagName(n))
+	}
+	if ss.Type == Universal {
+		return n.Type == html.ElementNode
 	}
 	if ss.Type == PseudoClass {
-		switch ss.Value {
+		if n.Type != html.ElementNode {
+			return false
+		}
+		name, args := pseudoArgs(ss.Value)
+		switch name {
 		case "root":
-			return n.Parent == nil
+			return n.Parent == nil || n.Parent.Type == html.DocumentNode
 		case "first-child":
-			return n.Parent != nil && n.Parent.FirstChild == n
+			return prevElement(n, false) == nil
 		case "last-child":
-			return n.Parent != nil && n.Parent.LastChild == n
+			return nextElement(n, false) == nil
 		case "only-child":
-			return n.PrevSibling == nil && n.NextSibling == nil
+			return prevElement(n, false) == nil && nextElement(n, false) == nil
+		case "first-of-type":
+			return prevElement(n, true) == nil
+		case "last-of-type":
+			return nextElement(n, true) == nil
+		case "only-of-type":
+			return prevElement(n, true) == nil && nextElement(n, true) == nil
+		case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+			a, b, ok := parseNth(args)
+			if !ok {
+				panic(fmt.Errorf("Can't match with PseudoClass %s", ss.Value))
+			}
+			return nthMatch(a, b, elementIndex(n, strings.HasPrefix(name, "nth-last"), strings.HasSuffix(name, "of-type")))
+		case "not", "is", "where", "matches":
+			g, err := argGroup(args)
+			if err != nil {
+				panic(fmt.Errorf("Can't match with PseudoClass %s", ss.Value))
+			}
+			return g.Match(n) != (name == "not")
 		case "empty":
-			return n.FirstChild == nil
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type != html.CommentNode {
+					return false
+				}
+			}
+			return true
 		default:
 			// TODO(jwall):
 			panic(fmt.Errorf("Can't match with PseudoClass %s", ss.Value))
@@ -162,13 +230,23 @@ func (ss SimpleSelector) Match(n *html.Node) bool {
 			}
 		case Attr:
 			if strings.ToLower(a.Key) == strings.ToLower(ss.AttrName) {
+				val := ss.Value
+				if ss.IgnoreCase {
+					a.Val, val = strings.ToLower(a.Val), strings.ToLower(val)
+				}
 				switch ss.AttrMatch {
 				case Exactly:
-					return attrExactly(ss.Value, &a)
+					return attrExactly(val, &a)
 				case Contains:
-					return attrContains(ss.Value, &a)
+					return attrContains(val, &a)
 				case DashPrefix:
-					return attrDashPrefix(ss.Value, &a)
+					return attrDashPrefix(val, &a)
+				case Prefix:
+					return val != "" && strings.HasPrefix(a.Val, val)
+				case Suffix:
+					return val != "" && strings.HasSuffix(a.Val, val)
+				case Substring:
+					return val != "" && strings.Contains(a.Val, val)
 				}
 				return true
 			}
@@ -177,12 +255,165 @@ func (ss SimpleSelector) Match(n *html.Node) bool {
 	return false
 }
 
-// Specificity returns the CSS3 specificity for a SimpleSelector.
+// pseudoArgs splits the Value of a PseudoClass like "nth-child(2n+1)" into
+// its lower case name and its arguments.
+func pseudoArgs(v string) (name, args string) {
+	i := strings.IndexByte(v, '(')
+	if i < 0 || !strings.HasSuffix(v, ")") {
+		return strings.ToLower(v), ""
+	}
+	return strings.ToLower(v[:i]), strings.TrimSpace(v[i+1 : len(v)-1])
+}
+
+// argGroups caches the parsed arguments of PseudoClasses like :not(.a).
+var argGroups sync.Map
+
+// argGroup parses the selector list argument of a PseudoClass.
+func argGroup(args string) (Group, error) {
+	if g, ok := argGroups.Load(args); ok {
+		return g.(Group), nil
+	}
+	g, err := SelectorGroup(args)
+	if err != nil {
+		return nil, err
+	}
+	argGroups.Store(args, g)
+	return g, nil
+}
+
+// parseNth parses the An+B argument of the :nth-child PseudoClasses. The
+// "of S" form isn't supported.
+// http://www.w3.org/TR/css-syntax-3/#anb-microsyntax
+func parseNth(s string) (a, b int, ok bool) {
+	s = strings.ToLower(strings.Join(strings.Fields(s), ""))
+	switch s {
+	case "odd":
+		return 2, 1, true
+	case "even":
+		return 2, 0, true
+	case "":
+		return 0, 0, false
+	}
+	i := strings.IndexByte(s, 'n')
+	if i < 0 {
+		b, err := strconv.Atoi(s)
+		return 0, b, err == nil
+	}
+	switch s[:i] {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(s[:i]); err != nil {
+			return 0, 0, false
+		}
+	}
+	if rest := s[i+1:]; rest != "" {
+		if rest[0] != '+' && rest[0] != '-' {
+			return 0, 0, false
+		}
+		var err error
+		if b, err = strconv.Atoi(rest); err != nil {
+			return 0, 0, false
+		}
+	}
+	return a, b, true
+}
+
+// nthMatch returns true if the 1 based index i is An+B for some n >= 0.
+func nthMatch(a, b, i int) bool {
+	if a == 0 {
+		return i == b
+	}
+	return (i-b)%a == 0 && (i-b)/a >= 0
+}
+
+// elementIndex returns the 1 based index of n among its element siblings
+// counting from the last one if last is set. Only the elements of the
+// same type as n are counted if ofType is set.
+func elementIndex(n *html.Node, last, ofType bool) int {
+	i := 1
+	for s := sibling(n, last, ofType); s != nil; s = sibling(s, last, ofType) {
+		i++
+	}
+	return i
+}
+
+func sibling(n *html.Node, next, ofType bool) *html.Node {
+	if next {
+		return nextElement(n, ofType)
+	}
+	return prevElement(n, ofType)
+}
+
+// matchablePseudoClasses are the PseudoClasses Match knows how to match.
+var matchablePseudoClasses = map[string]bool{
+	"root":             true,
+	"first-child":      true,
+	"last-child":       true,
+	"only-child":       true,
+	"first-of-type":    true,
+	"last-of-type":     true,
+	"only-of-type":     true,
+	"nth-child":        true,
+	"nth-last-child":   true,
+	"nth-of-type":      true,
+	"nth-last-of-type": true,
+	"not":              true,
+	"is":               true,
+	"where":            true,
+	"matches":          true,
+	"empty":            true,
+}
+
+// Matchable returns true if Match can match this SimpleSelector against a
+// node. PseudoElements and dynamic PseudoClasses like :hover can't be
+// matched against a static document. Nesting selectors have to be
+// resolved with Group.Nest first.
+func (ss SimpleSelector) Matchable() bool {
+	switch ss.Type {
+	case PseudoElement, Nesting:
+		return false
+	case PseudoClass:
+		name, args := pseudoArgs(ss.Value)
+		if !matchablePseudoClasses[name] {
+			return false
+		}
+		switch name {
+		case "nth-child", "nth-last-child", "nth-of-type", "nth-last-of-type":
+			_, _, ok := parseNth(args)
+			return ok
+		case "not", "is", "where", "matches":
+			g, err := argGroup(args)
+			return err == nil && g.Matchable()
+		}
+		return !strings.Contains(ss.Value, "(")
+	}
+	return true
+}
+
+// Specificity returns the CSS3 specificity for a SimpleSelector. The
+// specificity of :not, :is and :matches is the largest of their arguments
+// and :where has none.
+// http://www.w3.org/TR/selectors-4/#specificity-rules
 func (ss SimpleSelector) Specificity() int64 {
 	switch ss.Type {
 	case Id:
 		return aMul
-	case Class, Attr, PseudoClass:
+	case PseudoClass:
+		name, args := pseudoArgs(ss.Value)
+		switch name {
+		case "where":
+			return 0
+		case "not", "is", "matches":
+			if g, err := argGroup(args); err == nil {
+				return g.Specificity()
+			}
+		}
+		return bMul
+	case Class, Attr:
 		return bMul
 	case Tag, PseudoElement:
 		return 1
@@ -197,19 +428,50 @@ func (ss SimpleSelector) String() string {
 	case Class:
 		return "." + ss.Value
 	case Attr:
-		return "[" + ss.AttrName + ss.AttrMatch.String() + ss.Value + "]"
+		if ss.AttrMatch == Presence {
+			return "[" + ss.AttrName + "]"
+		}
+		if ss.IgnoreCase {
+			return "[" + ss.AttrName + ss.AttrMatch.String() + quoteAttrValue(ss.Value) + " i]"
+		}
+		return "[" + ss.AttrName + ss.AttrMatch.String() + quoteAttrValue(ss.Value) + "]"
 	case PseudoClass:
 		return ":" + ss.Value
 	case PseudoElement:
 		return "::" + ss.Value
 	case Universal:
 		return "*"
+	case Nesting:
+		return "&"
 	case Tag:
 		return ss.Tag
 	}
 	panic("Unreachable")
 }
 
+var attrValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\a `)
+
+// quoteAttrValue quotes an attribute value if it isn't a plain identifier.
+func quoteAttrValue(v string) string {
+	if isIdent(v) {
+		return v
+	}
+	return `"` + attrValueEscaper.Replace(v) + `"`
+}
+
+// isIdent returns true if v can be written as a css identifier without
+// escapes.
+func isIdent(v string) bool {
+	rest := strings.TrimPrefix(v, "-")
+	if rest == "" || rest[0] == '-' || ('0' <= rest[0] && rest[0] <= '9') {
+		return false
+	}
+	return strings.IndexFunc(v, func(r rune) bool {
+		return !(r == '-' || r == '_' || r >= 0x80 ||
+			('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9'))
+	}) < 0
+}
+
 // Sequence is a list of SimpleSelectors describing multiple things about an
 // element.
 type Sequence []SimpleSelector
@@ -218,7 +480,7 @@ type Sequence []SimpleSelector
 // n.
 func (s Sequence) Find(n *html.Node) []*html.Node {
 	var found []*html.Node
-	h5.WalkNodes(n, func(n *html.Node) {
+	walkNodes(n, func(n *html.Node) {
 		if s.Match(n) {
 			found = append(found, n)
 		}
@@ -238,6 +500,17 @@ func (s Sequence) Match(n *html.Node) bool {
 	return match
 }
 
+// Matchable returns true if every SimpleSelector in this Sequence is
+// Matchable.
+func (s Sequence) Matchable() bool {
+	for _, ss := range s {
+		if !ss.Matchable() {
+			return false
+		}
+	}
+	return true
+}
+
 func (s Sequence) String() string {
 	ss := ""
 	for _, sel := range s {
@@ -249,18 +522,11 @@ func (s Sequence) String() string {
 // Specificity returns the CSS3 specificity for a given sequence of
 // SimpleSelectors.
 func (s Sequence) Specificity() int64 {
-	var a, b, c int64 = 0, 0, 0
+	var sp int64
 	for _, sel := range s {
-		switch sel.Type {
-		case Id:
-			a++
-		case Class, Attr, PseudoClass:
-			b++
-		case Tag, PseudoElement:
-			c++
-		}
+		sp += sel.Specificity()
 	}
-	return a*aMul + b*bMul + c
+	return sp
 }
 
 // Link joins a sequence to another sequence with a combinator.
@@ -277,7 +543,7 @@ func (l Link) Find(n *html.Node) []*html.Node {
 	switch l.Combinator {
 	case Descendant:
 		// walk the node tree returning any nodes the sequence matches
-		h5.WalkNodes(n, func(n *html.Node) {
+		walkNodes(n, func(n *html.Node) {
 			if l.Sequence.Match(n) {
 				found = append(found, n)
 			}
@@ -366,3 +632,256 @@ func (chn *Chain) Specificity() int64 {
 	}
 	return sp
 }
+
+// Match returns true if this Chain matches the node n. The Chain is
+// matched right to left so n must match the last Sequence in the Chain.
+func (chn *Chain) Match(n *html.Node) bool {
+	if chn == nil || n == nil || n.Type != html.ElementNode {
+		return false
+	}
+	return chn.matchAt(len(chn.Tail), n)
+}
+
+// sequence returns the i'th Sequence of the Chain where 0 is the Head.
+func (chn *Chain) sequence(i int) Sequence {
+	if i == 0 {
+		return chn.Head
+	}
+	return chn.Tail[i-1].Sequence
+}
+
+func (chn *Chain) matchAt(i int, n *html.Node) bool {
+	if n == nil || n.Type != html.ElementNode || !chn.sequence(i).Match(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch chn.Tail[i-1].Combinator {
+	case Descendant:
+		for p := n.Parent; p != nil; p = p.Parent {
+			if chn.matchAt(i-1, p) {
+				return true
+			}
+		}
+	case Child:
+		return chn.matchAt(i-1, n.Parent)
+	case AdjacentSibling:
+		return chn.matchAt(i-1, prevElement(n, false))
+	case Sibling:
+		for s := prevElement(n, false); s != nil; s = prevElement(s, false) {
+			if chn.matchAt(i-1, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// prevElement returns the previous element sibling of n. If ofType is set
+// it returns the previous one with the same name as n.
+func prevElement(n *html.Node, ofType bool) *html.Node {
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode && (!ofType || sameType(s, n)) {
+			return s
+		}
+	}
+	return nil
+}
+
+// nextElement returns the next element sibling of n like prevElement.
+func nextElement(n *html.Node, ofType bool) *html.Node {
+	for s := n.NextSibling; s != nil; s = s.NextSibling {
+		if s.Type == html.ElementNode && (!ofType || sameType(s, n)) {
+			return s
+		}
+	}
+	return nil
+}
+
+func sameType(a, b *html.Node) bool {
+	return a.Namespace == b.Namespace && strings.EqualFold(a.Data, b.Data)
+}
+
+// Specificity returns the largest CSS3 specificity of the Chains in the
+// Group.
+func (g Group) Specificity() int64 {
+	var sp int64
+	for _, chn := range g {
+		if s := chn.Specificity(); s > sp {
+			sp = s
+		}
+	}
+	return sp
+}
+
+// Matchable returns true if every Chain in the Group is Matchable.
+func (g Group) Matchable() bool {
+	for _, chn := range g {
+		if !chn.Matchable() {
+			return false
+		}
+	}
+	return len(g) > 0
+}
+
+// Matchable returns true if every Sequence in this Chain is Matchable.
+func (chn *Chain) Matchable() bool {
+	if chn == nil {
+		return false
+	}
+	for i := 0; i <= len(chn.Tail); i++ {
+		if !chn.sequence(i).Matchable() {
+			return false
+		}
+	}
+	return true
+}
+
+// Group is a comma separated list of Chains.
+type Group []*Chain
+
+// Find all the nodes in a html.Node tree that match any Chain in the Group.
+// Nodes are returned once in the order of the first Chain that found them.
+func (g Group) Find(n *html.Node) []*html.Node {
+	set := make(map[*html.Node]struct{})
+	var found []*html.Node
+	for _, chn := range g {
+		for _, n1 := range chn.Find(n) {
+			if _, ok := set[n1]; !ok {
+				found = append(found, n1)
+				set[n1] = struct{}{}
+			}
+		}
+	}
+	return found
+}
+
+// Match returns true if any Chain in the Group matches the node n.
+func (g Group) Match(n *html.Node) bool {
+	for _, chn := range g {
+		if chn.Match(n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g Group) String() string {
+	ss := make([]string, len(g))
+	for i, chn := range g {
+		ss[i] = chn.String()
+	}
+	return strings.Join(ss, ", ")
+}
+
+// hasNesting returns true if the Chain contains a Nesting selector.
+func (chn *Chain) hasNesting() bool {
+	for i := 0; i <= len(chn.Tail); i++ {
+		for _, ss := range chn.sequence(i) {
+			if ss.Type == Nesting {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Nest resolves the Nesting selectors in a Group nested inside a rule
+// with the selector parent. Like the & of css nesting the parent acts like
+// :is(parent) so ".a, #b { & .c {} }" resolves to ":is(.a, #b) .c" with
+// the specificity of #b .c and ".x .y { .z & {} }" matches a .y inside a
+// .z inside a .x. A parent that is a single Chain is written out in place
+// of the & when that means the same thing like ".card { &:hover {} }"
+// resolving to ".card:hover". A nil parent resolves & to :root.
+func (g Group) Nest(parent Group) Group {
+	if parent == nil {
+		parent = Group{{Head: Sequence{{Type: PseudoClass, Value: "root"}}}}
+	}
+	is := &Chain{Head: Sequence{{Type: PseudoClass, Value: "is(" + parent.String() + ")"}}}
+	var out Group
+	for _, chn := range g {
+		switch {
+		case !chn.hasNesting():
+			out = append(out, chn)
+		case len(parent) == 1 && (len(parent[0].Tail) == 0 || chn.nestsHead()):
+			out = append(out, chn.nest(parent[0]))
+		default:
+			out = append(out, chn.nest(is))
+		}
+	}
+	return out
+}
+
+// nestsHead returns true if the only Nesting selector in chn is in its
+// Head. Replacing it with a Chain then means the same as :is() of it.
+func (chn *Chain) nestsHead() bool {
+	n := 0
+	for i := 0; i <= len(chn.Tail); i++ {
+		for _, ss := range chn.sequence(i) {
+			if ss.Type == Nesting {
+				if i > 0 {
+					return false
+				}
+				n++
+			}
+		}
+	}
+	return n == 1
+}
+
+// nest replaces the Nesting selectors in chn with the Chain p.
+func (chn *Chain) nest(p *Chain) *Chain {
+	out := &Chain{}
+	add := func(c combinator, seq Sequence) {
+		if out.Head == nil {
+			out.Head = seq
+		} else {
+			out.Tail = append(out.Tail, Link{Combinator: c, Sequence: seq})
+		}
+	}
+	for i := 0; i <= len(chn.Tail); i++ {
+		c := Descendant
+		if i > 0 {
+			c = chn.Tail[i-1].Combinator
+		}
+		seq := chn.sequence(i)
+		var tags, rest Sequence
+		nested := false
+		for _, ss := range seq {
+			switch ss.Type {
+			case Nesting:
+				nested = true
+			case Tag, Universal:
+				tags = append(tags, ss)
+			default:
+				rest = append(rest, ss)
+			}
+		}
+		if !nested {
+			add(c, append(Sequence{}, seq...))
+			continue
+		}
+		// The parent Chain takes the place of the &. Its last Sequence
+		// merges with the rest of the Sequence containing the &.
+		for j := 0; j < len(p.Tail); j++ {
+			pc := c
+			if j > 0 {
+				pc = p.Tail[j-1].Combinator
+			}
+			add(pc, append(Sequence{}, p.sequence(j)...))
+		}
+		if len(p.Tail) > 0 {
+			c = p.Tail[len(p.Tail)-1].Combinator
+		}
+		last := append(Sequence{}, tags...)
+		for _, ss := range p.sequence(len(p.Tail)) {
+			if len(tags) > 0 && ss.Type == Universal {
+				continue
+			}
+			last = append(last, ss)
+		}
+		add(c, append(last, rest...))
+	}
+	return out
+}
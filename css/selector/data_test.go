@@ -47,3 +47,102 @@ func TestSelectorString(t *testing.T) {
 		t.Errorf("Next byte was not %c", b)
 	}
 }
+
+func TestSelectorGroup(t *testing.T) {
+	cases := []struct {
+		in, out string
+		n       int
+	}{
+		{"a, b", "a, b", 2},
+		{" ul li ,ol>li ", "ul li, ol>li", 2},
+		{"a:not(.b, .c), d", "a:not(.b, .c), d", 2},
+		{`a[title="x, y"]`, `a[title="x, y"]`, 1},
+	}
+	for _, c := range cases {
+		g, err := SelectorGroup(c.in)
+		if err != nil {
+			t.Errorf("Error parsing %q %q", c.in, err)
+			continue
+		}
+		if len(g) != c.n {
+			t.Errorf("Expected %d selectors in %q got %d", c.n, c.in, len(g))
+		}
+		if g.String() != c.out {
+			t.Errorf("%q != %q", g.String(), c.out)
+		}
+	}
+	if _, err := SelectorGroup("a,,b"); err == nil {
+		t.Errorf("Expected an error for an empty selector")
+	}
+}
+
+func TestNestedSelectorGroup(t *testing.T) {
+	cases := []struct{ in, out string }{
+		{"&:hover", "&:hover"},
+		{".title", "& .title"},
+		{"> li, + p", "&>li, &+p"},
+		{".parent &", ".parent &"},
+		{"div&.x", "div&.x"},
+	}
+	for _, c := range cases {
+		g, err := NestedSelectorGroup(c.in)
+		if err != nil {
+			t.Errorf("Error parsing %q %q", c.in, err)
+			continue
+		}
+		if g.String() != c.out {
+			t.Errorf("%q != %q", g.String(), c.out)
+		}
+		if g[0].Matchable() {
+			t.Errorf("Expected %q not to be Matchable", c.in)
+		}
+	}
+}
+
+func TestGroupNest(t *testing.T) {
+	cases := []struct{ parent, nested, out string }{
+		{".card", "&:hover", ".card:hover"},
+		{".card", ".title", ".card .title"},
+		{"ul.list, ol", "> li", ":is(ul.list, ol)>li"},
+		{".a .b", "&.c + &", ":is(.a .b).c+:is(.a .b)"},
+		{".a .b", "& .c", ".a .b .c"},
+		{".a .b", "p&", ".a p.b"},
+		{".a>.b", ".x &", ".x :is(.a>.b)"},
+		{".a, .b", ".c, &.d", ":is(.a, .b) .c, :is(.a, .b).d"},
+		{".x", "div&", "div.x"},
+		{"*", "p&", "p"},
+		{"", "& p", ":root p"},
+	}
+	for _, c := range cases {
+		var parent Group
+		if c.parent != "" {
+			parent = mustGroup(t, c.parent, SelectorGroup)
+		}
+		g := mustGroup(t, c.nested, NestedSelectorGroup).Nest(parent)
+		if g.String() != c.out {
+			t.Errorf("Nesting %q in %q: %q != %q", c.nested, c.parent, g.String(), c.out)
+		}
+	}
+	g := mustGroup(t, "#a, .b", SelectorGroup)
+	nested := mustGroup(t, "&:hover p", NestedSelectorGroup).Nest(g)
+	if nested[0].Specificity() != g[0].Specificity()+bMul+1 {
+		t.Errorf("Unexpected specificity %d for %s", nested[0].Specificity(), nested[0])
+	}
+	nested = mustGroup(t, "& .c", NestedSelectorGroup).Nest(mustGroup(t, ".a, #b", SelectorGroup))
+	if len(nested) != 1 || nested[0].Specificity() != aMul+bMul {
+		t.Errorf("Expected %s to have the specificity of #b .c got %d", nested, nested[0].Specificity())
+	}
+	nested = mustGroup(t, ".z &", NestedSelectorGroup).Nest(mustGroup(t, ".x .y", SelectorGroup))
+	y := partial(`<div class="x"><div class="z"><p class="y"></p></div></div>`).FirstChild.FirstChild
+	if !nested.Match(y) {
+		t.Errorf("Expected %s to match .x .z .y", nested)
+	}
+}
+
+func mustGroup(t *testing.T, sel string, parse func(string) (Group, error)) Group {
+	g, err := parse(sel)
+	if err != nil {
+		t.Fatalf("Error parsing %q %q", sel, err)
+	}
+	return g
+}
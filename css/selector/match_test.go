@@ -1,11 +1,11 @@
 package selector
 
 import (
-	"go.marzhillstudios.com/pkg/go-html-transform/h5"
-
+	"strings"
 	"testing"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
 )
 
 type testSpec struct {
@@ -15,14 +15,24 @@ type testSpec struct {
 	ns []*html.Node
 }
 
+// partials parses an html fragment in a <body> like h5.PartialFromString
+// which the tests can't use since h5 imports the css packages.
+func partials(s string) []*html.Node {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	ns, _ := html.ParseFragment(strings.NewReader(s), body)
+	return ns
+}
+
 func partial(s string) *html.Node {
-	ns, _ := h5.PartialFromString(s)
-	return ns[0]
+	return partials(s)[0]
 }
 
-func partials(s string) []*html.Node {
-	ns, _ := h5.PartialFromString(s)
-	return ns
+func render(ns []*html.Node) string {
+	var b strings.Builder
+	for _, n := range ns {
+		html.Render(&b, n)
+	}
+	return b.String()
 }
 
 var matchers = []testSpec{
@@ -74,6 +84,24 @@ var matchers = []testSpec{
 		partial("<a class=\"baz foo0 bar\"></a>"),
 		nil,
 	},
+	testSpec{
+		"a[href^=http]",
+		partial("<a href=\"http://example.com\"></a>"),
+		partial("<a href=\"/http\"></a>"),
+		nil,
+	},
+	testSpec{
+		"a[href$='.pdf']",
+		partial("<a href=\"/doc.pdf\"></a>"),
+		partial("<a href=\"/doc.pdf.html\"></a>"),
+		nil,
+	},
+	testSpec{
+		"a[href*=\"example\"]",
+		partial("<a href=\"http://example.com\"></a>"),
+		partial("<a href=\"http://exmpl.com\"></a>"),
+		nil,
+	},
 }
 
 var finders = []testSpec{
@@ -178,11 +206,11 @@ func TestSelectorFind(t *testing.T) {
 		ns := chn.Find(spec.n)
 		if len(ns) < 1 {
 			t.Errorf("%q didn't find any nodes in %q",
-				chn, h5.RenderNodesToString([]*html.Node{spec.n}))
+				chn, render([]*html.Node{spec.n}))
 		}
-		if h5.RenderNodesToString(ns) != h5.RenderNodesToString(spec.ns) {
+		if render(ns) != render(spec.ns) {
 			t.Errorf("Got: %q Expected: %q",
-				h5.RenderNodesToString(ns), h5.RenderNodesToString(spec.ns))
+				render(ns), render(spec.ns))
 		}
 	}
 }
@@ -195,11 +223,141 @@ func TestSelectorMatch(t *testing.T) {
 		}
 		if !chn.Head.Match(spec.n) {
 			t.Errorf("spec %q didn't match %q when it should have",
-				chn, h5.RenderNodesToString([]*html.Node{spec.n}))
+				chn, render([]*html.Node{spec.n}))
 		}
 		if chn.Head.Match(spec.n2) {
 			t.Errorf("spec %q matched %q when it shouldn't have",
-				chn, h5.RenderNodesToString([]*html.Node{spec.n2}))
+				chn, render([]*html.Node{spec.n2}))
+		}
+	}
+}
+
+var chainMatchers = []struct {
+	s     string
+	doc   string
+	match []string
+}{
+	{"div span", "<div><p><span id=a></span></p></div><span id=b></span>", []string{"a"}},
+	{"div>span", "<div><span id=a></span><p><span id=b></span></p></div>", []string{"a"}},
+	{"p+span", "<p></p> <span id=a></span><span id=b></span>", []string{"a"}},
+	{"p~span", "<span id=a></span><p></p><span id=b></span><span id=c></span>", []string{"b", "c"}},
+	{"div p>span.x", "<div><p><span id=a class=x></span><span id=b></span></p></div>", []string{"a"}},
+	{"ul a, ol a", "<ul><li><a id=a></a></li></ul><ol><li><a id=b></a></li></ol><a id=c></a>", []string{"a", "b"}},
+	{"*", "<p id=a>x<span id=b></span></p>", []string{"a", "b"}},
+	{"ul > *", "<ul>\n <li id=a></li>\n <li id=b><i id=c></i></li>\n</ul>", []string{"a", "b"}},
+	{"li:first-child", "<ul>\n <li id=a></li>\n <li id=b></li>\n</ul>", []string{"a"}},
+	{"li:last-child", "<ul>\n <li id=a></li>\n <li id=b></li>\n</ul>", []string{"b"}},
+	{"li:only-child", "<ul>\n <li id=a></li>\n</ul><ul><li id=b></li><li id=c></li></ul>", []string{"a"}},
+	{"li:nth-child(2n+1)", "<ul> <li id=a></li> <li id=b></li> <li id=c></li> </ul>", []string{"a", "c"}},
+	{"li:nth-child(even)", "<ul> <li id=a></li> <li id=b></li> <li id=c></li> </ul>", []string{"b"}},
+	{"li:nth-last-child(-n+2)", "<ul> <li id=a></li> <li id=b></li> <li id=c></li> </ul>", []string{"b", "c"}},
+	{"span:nth-of-type(2)", "<p><span id=a></span><i id=b></i><span id=c></span></p>", []string{"c"}},
+	{"span:first-of-type", "<p><i id=a></i><span id=b></span><span id=c></span></p>", []string{"b"}},
+	{"p :not(i)", "<p><span id=a></span><i id=b></i></p>", []string{"a"}},
+	{":is(i, b).x", "<p><i id=a class=x></i><b id=b class=x></b><i id=c></i></p>", []string{"a", "b"}},
+	{"p :where(div i)", "<div><p><i id=a></i></p></div><p><i id=b></i></p>", []string{"a"}},
+	{"p:empty", "<p id=a><!-- x --></p><p id=b> </p>", []string{"a"}},
+	{`a[type="PDF" i]`, "<a id=a type=pdf></a><a id=b type=doc></a>", []string{"a"}},
+	{`a[title="a]b"]`, "<a id=a title=a]b></a><a id=b title=a></a>", []string{"a"}},
+	{`a[href$=".pdf" s]`, "<a id=a href=x.pdf></a><a id=b href=x.PDF></a>", []string{"a"}},
+	{`a[title=a\]b]`, "<a id=a title=a]b></a>", []string{"a"}},
+}
+
+func TestChainMatch(t *testing.T) {
+	for _, spec := range chainMatchers {
+		g, err := SelectorGroup(spec.s)
+		if err != nil {
+			t.Errorf("Error parsing selector %q: %s", spec.s, err)
+			continue
+		}
+		root := partial("<section>" + spec.doc + "</section>")
+		var matched []string
+		walkNodes(root, func(n *html.Node) {
+			if g.Match(n) {
+				for _, a := range n.Attr {
+					if a.Key == "id" {
+						matched = append(matched, a.Val)
+					}
+				}
+			}
+		})
+		if strings.Join(matched, ",") != strings.Join(spec.match, ",") {
+			t.Errorf("%q matched %v expected %v", spec.s, matched, spec.match)
+		}
+	}
+}
+
+func TestMatchable(t *testing.T) {
+	cases := []struct {
+		s  string
+		ok bool
+	}{
+		{"a:first-child", true},
+		{"a:hover", false},
+		{"p::first-line", false},
+		{"div a:not(.foo)", true},
+		{"a:not(:hover)", false},
+		{"*", true},
+		{"li:nth-child(2n+1)", true},
+		{"li:nth-child(2n+1 of .x)", false},
+		{"a:has(b)", false},
+	}
+	for _, c := range cases {
+		chn, err := Selector(c.s)
+		if err != nil {
+			t.Errorf("Error parsing selector %q: %s", c.s, err)
+			continue
+		}
+		if chn.Matchable() != c.ok {
+			t.Errorf("%q Matchable() expected %v", c.s, c.ok)
+		}
+	}
+}
+
+func TestAttrSelectorParse(t *testing.T) {
+	cases := []struct{ in, out string }{
+		{`[href$=".pdf" i]`, `[href$=".pdf" i]`},
+		{`[ title = "a]b" ]`, `[title="a]b"]`},
+		{`[title='say "hi"']`, `[title="say \"hi\""]`},
+		{`[lang|=en S]`, `[lang|=en]`},
+		{`[data-x="\31 0"]`, `[data-x="10"]`},
+	}
+	for _, c := range cases {
+		chn, err := Selector(c.in)
+		if err != nil {
+			t.Errorf("Error parsing %q: %s", c.in, err)
+			continue
+		}
+		if chn.String() != c.out {
+			t.Errorf("%q parsed to %q expected %q", c.in, chn, c.out)
+		}
+	}
+	for _, in := range []string{`[href="x]`, `[href=]`, `[href="x" q]`, `[=x]`, `[href^x]`} {
+		if _, err := Selector(in); err == nil {
+			t.Errorf("Expected an error parsing %q", in)
+		}
+	}
+}
+
+func TestPseudoClassSpecificity(t *testing.T) {
+	cases := []struct {
+		s  string
+		sp int64
+	}{
+		{"a:not(p)", 2},
+		{"a:not(#x, p)", aMul + 1},
+		{":is(.a, p)", bMul},
+		{"a:where(#x)", 1},
+		{"li:nth-child(2)", bMul + 1},
+	}
+	for _, c := range cases {
+		chn, err := Selector(c.s)
+		if err != nil {
+			t.Errorf("Error parsing %q: %s", c.s, err)
+			continue
+		}
+		if chn.Specificity() != c.sp {
+			t.Errorf("%q has specificity %d expected %d", c.s, chn.Specificity(), c.sp)
 		}
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
 )
 
 var (
@@ -28,6 +30,81 @@ func Selector(sel string) (*Chain, error) {
 	return SelectorFromScanner(strings.NewReader(sel))
 }
 
+// SelectorGroup parses a comma separated list of selectors into a Group.
+func SelectorGroup(sel string) (Group, error) {
+	var g Group
+	for _, part := range splitGroup(sel) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("Empty selector in group %q", sel)
+		}
+		chn, err := Selector(part)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		g = append(g, chn)
+	}
+	return g, nil
+}
+
+// NestedSelectorGroup parses the selector group of a rule nested in
+// another style rule. Members without a & are relative to the parent rule
+// so they get an implicit & prefix. eg: "> .title" becomes "& > .title"
+// and ".title" becomes "& .title".
+func NestedSelectorGroup(sel string) (Group, error) {
+	var g Group
+	for _, part := range splitGroup(sel) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return nil, fmt.Errorf("Empty selector in group %q", sel)
+		}
+		if strings.ContainsAny(part[:1], ">+~") {
+			part = "& " + part
+		}
+		chn, err := Selector(part)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if !chn.hasNesting() {
+			if chn, err = Selector("& " + part); err != nil && err != io.EOF {
+				return nil, err
+			}
+		}
+		g = append(g, chn)
+	}
+	return g, nil
+}
+
+// splitGroup splits a selector group on the commas that aren't inside
+// brackets, parens or quotes.
+func splitGroup(sel string) []string {
+	var parts []string
+	depth := 0
+	var quote byte
+	start := 0
+	for i := 0; i < len(sel); i++ {
+		c := sel[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, sel[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, sel[start:])
+}
+
 func consumeValue(rdr io.ByteScanner) ([]byte, error) {
 	bs := []byte{}
 	for c, err := rdr.ReadByte(); err != io.EOF; c, err = rdr.ReadByte() {
@@ -38,7 +115,7 @@ func consumeValue(rdr io.ByteScanner) ([]byte, error) {
 		case '{':
 			rdr.UnreadByte()
 			return bs, EOS
-		case '>', '+', '~', ' ', '\t', '\n', '\f', ',', '.', '#', '[', ':':
+		case '>', '+', '~', ' ', '\t', '\n', '\f', ',', '.', '#', '[', ':', '(', '&':
 			rdr.UnreadByte()
 			return bs, nil
 		default:
@@ -71,47 +148,179 @@ func parseSimpleSelector(rdr io.ByteScanner, sel *SimpleSelector) error {
 		sel.Type = PseudoElement
 		bs = bs[1:]
 	}
+	if err == nil && (sel.Type == PseudoClass || sel.Type == PseudoElement) {
+		// Functional pseudo classes like :not(.foo) keep their arguments
+		// in the Value.
+		args, err := consumeArgs(rdr)
+		if err != nil {
+			return err
+		}
+		bs = append(bs, args...)
+	}
 	sel.Value = string(bs)
 	return err
 }
 
+// consumeArgs consumes a parenthesized argument list if there is one.
+func consumeArgs(rdr io.ByteScanner) ([]byte, error) {
+	c, err := rdr.ReadByte()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c != '(' {
+		rdr.UnreadByte()
+		return nil, nil
+	}
+	bs := []byte{c}
+	depth := 1
+	for c, err := rdr.ReadByte(); err != io.EOF; c, err = rdr.ReadByte() {
+		if err != nil {
+			return nil, err
+		}
+		bs = append(bs, c)
+		switch c {
+		case '"', '\'':
+			str, err := consumeQuoted(rdr, c)
+			if err != nil {
+				return nil, err
+			}
+			bs = append(append(bs, str...), c)
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return bs, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("Didn't close arguments %q", bs)
+}
+
+var attrMatchers = map[byte]attrMatchType{
+	'~': Contains,
+	'|': DashPrefix,
+	'^': Prefix,
+	'$': Suffix,
+	'*': Substring,
+}
+
+// parseSimpleAttr parses an attribute selector following its [ like
+// `href$=".pdf" i]`.
+// http://www.w3.org/TR/selectors-4/#attribute-selectors
 func parseSimpleAttr(rdr io.ByteScanner, sel *SimpleSelector) error {
+	c, err := readNonSpace(rdr)
 	var name []byte
+	for err == nil && !isSpace(c) && strings.IndexByte("=~|^$*]{", c) < 0 {
+		name = append(name, c)
+		c, err = rdr.ReadByte()
+	}
+	if err == nil && isSpace(c) {
+		c, err = readNonSpace(rdr)
+	}
+	if err != nil {
+		return fmt.Errorf("Didn't close Attribute Matcher")
+	}
+	if len(name) == 0 {
+		return fmt.Errorf("Missing attribute name in Attribute Matcher")
+	}
+	sel.AttrName = string(name)
+	switch c {
+	case ']':
+		return nil
+	case '=':
+		sel.AttrMatch = Exactly
+	case '~', '|', '^', '$', '*':
+		if c1, err := rdr.ReadByte(); err != nil || c1 != '=' {
+			return fmt.Errorf("Invalid Attribute Matcher %c", c)
+		}
+		sel.AttrMatch = attrMatchers[c]
+	default:
+		return fmt.Errorf("Unexpected %q in Attribute Matcher", c)
+	}
+	if c, err = readNonSpace(rdr); err != nil {
+		return fmt.Errorf("Didn't close Attribute Matcher")
+	}
 	var value []byte
-	var c1 byte = 0
-	for c2, err := rdr.ReadByte(); err != io.EOF; c2, err = rdr.ReadByte() {
-		if err != nil {
+	if c == '"' || c == '\'' {
+		if value, err = consumeQuoted(rdr, c); err != nil {
 			return err
 		}
-		switch c2 {
-		case ']':
-			sel.AttrName = string(name)
-			sel.Value = string(value)
-			return nil
-		case '=':
-			if c1 == '~' {
-				sel.AttrMatch = Contains
-			} else if c1 == '|' {
-				sel.AttrMatch = DashPrefix
-			} else {
-				sel.AttrMatch = Exactly
+		c, err = readNonSpace(rdr)
+	} else {
+		for err == nil && c != ']' && !isSpace(c) {
+			value = append(value, c)
+			if c == '\\' {
+				if c, err = rdr.ReadByte(); err == nil {
+					value = append(value, c)
+				}
 			}
-		case '{':
-			rdr.UnreadByte()
-			return EOS
-		case '~':
-		case '|':
-		// TODO(jwall): Substring matchers
-		default:
-			if sel.AttrMatch == Presence {
-				name = append(name, c2)
-			} else {
-				value = append(value, c2)
+			c, err = rdr.ReadByte()
+		}
+		if len(value) == 0 {
+			return fmt.Errorf("Missing value in Attribute Matcher")
+		}
+		if err == nil && isSpace(c) {
+			c, err = readNonSpace(rdr)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Didn't close Attribute Matcher")
+	}
+	sel.Value = tokenizer.Unescape(string(value))
+	switch c {
+	case 'i', 'I', 's', 'S':
+		// The s flag is the default case sensitive matching.
+		sel.IgnoreCase = c == 'i' || c == 'I'
+		if c, err = readNonSpace(rdr); err != nil {
+			return fmt.Errorf("Didn't close Attribute Matcher")
+		}
+	}
+	if c != ']' {
+		return fmt.Errorf("Unexpected %q in Attribute Matcher", c)
+	}
+	return nil
+}
+
+// consumeQuoted consumes a string up to the closing quote q returning it
+// with its escapes still in place.
+func consumeQuoted(rdr io.ByteScanner, q byte) ([]byte, error) {
+	var bs []byte
+	for c, err := rdr.ReadByte(); err != io.EOF; c, err = rdr.ReadByte() {
+		if err != nil {
+			return nil, err
+		}
+		switch c {
+		case q:
+			return bs, nil
+		case '\\':
+			bs = append(bs, c)
+			if c, err = rdr.ReadByte(); err != nil {
+				return nil, fmt.Errorf("Didn't close string %q", bs)
 			}
+		case '\n':
+			return nil, fmt.Errorf("Newline in string %q", bs)
+		}
+		bs = append(bs, c)
+	}
+	return nil, fmt.Errorf("Didn't close string %q", bs)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// readNonSpace reads the next byte that isn't whitespace.
+func readNonSpace(rdr io.ByteScanner) (byte, error) {
+	for {
+		c, err := rdr.ReadByte()
+		if err != nil || !isSpace(c) {
+			return c, err
 		}
-		c1 = c2
 	}
-	return fmt.Errorf("Didn't close Attribute Matcher")
 }
 
 func parseSequence(rdr io.ByteScanner) (Sequence, error) {
@@ -124,6 +333,8 @@ func parseSequence(rdr io.ByteScanner) (Sequence, error) {
 		switch c {
 		case '*':
 			seq = append(seq, SimpleSelector{Type: Universal})
+		case '&':
+			seq = append(seq, SimpleSelector{Type: Nesting})
 		case '#':
 			sel := SimpleSelector{Type: Id, AttrName: "id"}
 			if err := parseSimpleSelector(rdr, &sel); err != nil {
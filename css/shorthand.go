@@ -0,0 +1,935 @@
+package css
+
+import (
+	"strings"
+)
+
+// shorthand expands the value of a shorthand property into the values of
+// its longhands and collapses them back.
+type shorthand struct {
+	longhands []string
+	// expand returns the values of the longhands in order or false if vl
+	// isn't valid for the shorthand.
+	expand func(vl ValueList) ([]string, bool)
+	// collapse returns the shortest shorthand value setting the longhands
+	// to values or false if the shorthand can't express them.
+	collapse func(values []string) (string, bool)
+}
+
+func sides(prefix, suffix string) []string {
+	return []string{prefix + "top" + suffix, prefix + "right" + suffix,
+		prefix + "bottom" + suffix, prefix + "left" + suffix}
+}
+
+func borderSide(side string) shorthand {
+	return shorthand{
+		longhands: []string{"border-" + side + "-width", "border-" + side + "-style", "border-" + side + "-color"},
+		expand:    expandLine(isLineStyle),
+		collapse:  collapseLine,
+	}
+}
+
+// shorthands are the supported shorthand properties.
+var shorthands = map[string]shorthand{
+	"margin":        {sides("margin-", ""), expandBox(isMargin), collapseBox},
+	"padding":       {sides("padding-", ""), expandBox(isPadding), collapseBox},
+	"inset":         {sides("", ""), expandBox(isMargin), collapseBox},
+	"border-width":  {sides("border-", "-width"), expandBox(isLineWidth), collapseBox},
+	"border-style":  {sides("border-", "-style"), expandBox(isLineStyle), collapseBox},
+	"border-color":  {sides("border-", "-color"), expandBox(isColorLike), collapseBox},
+	"border-top":    borderSide("top"),
+	"border-right":  borderSide("right"),
+	"border-bottom": borderSide("bottom"),
+	"border-left":   borderSide("left"),
+	"border": {
+		longhands: append(append(sides("border-", "-width"), sides("border-", "-style")...), sides("border-", "-color")...),
+		expand:    expandBorder,
+		collapse:  collapseBorder,
+	},
+	"outline": {
+		longhands: []string{"outline-width", "outline-style", "outline-color"},
+		expand: expandLine(func(cv ComponentValue) bool {
+			return isLineStyle(cv) || cv.IsIdent("auto")
+		}),
+		collapse: collapseLine,
+	},
+	"border-radius": {
+		longhands: []string{"border-top-left-radius", "border-top-right-radius",
+			"border-bottom-right-radius", "border-bottom-left-radius"},
+		expand:   expandRadius,
+		collapse: collapseRadius,
+	},
+	"gap":      {[]string{"row-gap", "column-gap"}, expandPair(isGap), collapsePair},
+	"overflow": {[]string{"overflow-x", "overflow-y"}, expandPair(isOverflow), collapsePair},
+	"font": {
+		longhands: []string{"font-style", "font-variant-caps", "font-weight", "font-stretch",
+			"font-size", "line-height", "font-family"},
+		expand:   expandFont,
+		collapse: collapseFont,
+	},
+	"background": {
+		longhands: []string{"background-image", "background-position", "background-size",
+			"background-repeat", "background-attachment", "background-origin",
+			"background-clip", "background-color"},
+		expand:   expandBackground,
+		collapse: collapseBackground,
+	},
+	"flex": {
+		longhands: []string{"flex-grow", "flex-shrink", "flex-basis"},
+		expand:    expandFlex,
+		collapse:  collapseFlex,
+	},
+	"list-style": {
+		longhands: []string{"list-style-type", "list-style-position", "list-style-image"},
+		expand:    expandListStyle,
+		collapse:  collapseListStyle,
+	},
+}
+
+// resets are the longhands a shorthand can't set but resets to their
+// initial values.
+var resets = map[string]DeclarationList{
+	"border": {
+		{Property: "border-image-source", Value: "none"},
+		{Property: "border-image-slice", Value: "100%"},
+		{Property: "border-image-width", Value: "1"},
+		{Property: "border-image-outset", Value: "0"},
+		{Property: "border-image-repeat", Value: "stretch"},
+	},
+	"font": {
+		{Property: "font-size-adjust", Value: "none"},
+		{Property: "font-kerning", Value: "auto"},
+		{Property: "font-variant-ligatures", Value: "normal"},
+		{Property: "font-variant-position", Value: "normal"},
+		{Property: "font-variant-numeric", Value: "normal"},
+		{Property: "font-variant-alternates", Value: "normal"},
+		{Property: "font-variant-east-asian", Value: "normal"},
+		{Property: "font-variant-emoji", Value: "normal"},
+		{Property: "font-feature-settings", Value: "normal"},
+		{Property: "font-variation-settings", Value: "normal"},
+		{Property: "font-language-override", Value: "normal"},
+		{Property: "font-optical-sizing", Value: "auto"},
+	},
+}
+
+// allLonghands returns the longhands of the shorthand prop followed by the
+// ones it resets.
+func allLonghands(prop string) []string {
+	props := append([]string(nil), shorthands[prop].longhands...)
+	for _, d := range resets[prop] {
+		props = append(props, d.Property)
+	}
+	return props
+}
+
+// collapseOrder is the order shorthands are tried in when collapsing so
+// the ones covering the most longhands win.
+var collapseOrder = []string{
+	"border", "border-width", "border-style", "border-color",
+	"border-top", "border-right", "border-bottom", "border-left",
+	"border-radius", "margin", "padding", "inset", "outline", "gap",
+	"overflow", "font", "background", "flex", "list-style",
+}
+
+// cssWideKeywords are the keywords every property accepts.
+var cssWideKeywords = map[string]bool{
+	"inherit":      true,
+	"initial":      true,
+	"unset":        true,
+	"revert":       true,
+	"revert-layer": true,
+}
+
+// Longhands returns the longhand properties set or reset by the shorthand
+// property prop or nil if prop isn't a supported shorthand.
+func Longhands(prop string) []string {
+	if _, ok := shorthands[prop]; !ok {
+		return nil
+	}
+	return allLonghands(prop)
+}
+
+// Expand returns the longhand Declarations equivalent to d if it is a
+// supported shorthand. Longhands the shorthand doesn't mention get their
+// initial values. A Declaration that isn't a shorthand or whose value uses
+// var() is returned as it is. It returns false if the value isn't valid
+// for the shorthand.
+func (d Declaration) Expand() (DeclarationList, bool) {
+	sh, ok := shorthands[d.Property]
+	if !ok || hasVar(d.Value) {
+		return DeclarationList{d}, true
+	}
+	props := allLonghands(d.Property)
+	var values []string
+	if v := strings.ToLower(strings.TrimSpace(d.Value)); cssWideKeywords[v] {
+		for range props {
+			values = append(values, v)
+		}
+	} else {
+		vl, err := ParseValue(d.Value)
+		if err != nil || len(vl) == 0 {
+			return nil, false
+		}
+		if values, ok = sh.expand(vl); !ok {
+			return nil, false
+		}
+		for _, r := range resets[d.Property] {
+			values = append(values, r.Value)
+		}
+	}
+	dl := make(DeclarationList, len(values))
+	for i, v := range values {
+		dl[i] = Declaration{Property: props[i], Value: v, Important: d.Important}
+	}
+	return dl, true
+}
+
+// ExpandShorthands returns a copy of dl with the supported shorthands
+// replaced by their longhands. Invalid shorthands are left as they are.
+func ExpandShorthands(dl DeclarationList) DeclarationList {
+	out := make(DeclarationList, 0, len(dl))
+	for _, d := range dl {
+		if ex, ok := d.Expand(); ok {
+			out = append(out, ex...)
+		} else {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// CollapseShorthands returns a copy of dl with each complete set of
+// longhands replaced by the shortest equivalent shorthand in place of the
+// first of them. A set is only collapsed if each longhand is declared once
+// with the same importance, none of them use var() and no other
+// Declaration in dl sets any of them through a shorthand.
+func CollapseShorthands(dl DeclarationList) DeclarationList {
+	out := append(DeclarationList(nil), dl...)
+	for _, prop := range collapseOrder {
+		out = collapseShorthand(out, prop)
+	}
+	return out
+}
+
+func collapseShorthand(dl DeclarationList, prop string) DeclarationList {
+	sh := shorthands[prop]
+	props := allLonghands(prop)
+	index := map[string]int{}
+	for i, d := range dl {
+		index[d.Property] = i
+	}
+	for i, d := range dl {
+		if d.Property == prop {
+			return dl
+		}
+		if _, ok := shorthands[d.Property]; ok && overlaps(allLonghands(d.Property), props) {
+			return dl
+		}
+		for _, l := range props {
+			if d.Property == l && index[l] != i {
+				// Declared more than once.
+				return dl
+			}
+		}
+	}
+	first := len(dl)
+	values := make([]string, len(props))
+	for j, l := range props {
+		i, ok := index[l]
+		if !ok || hasVar(dl[i].Value) || dl[i].Important != dl[index[sh.longhands[0]]].Important {
+			return dl
+		}
+		if i < first {
+			first = i
+		}
+		values[j] = dl[i].Value
+	}
+	v, ok := collapseKeywords(values)
+	if !ok {
+		// The shorthand would reset the longhands it can't set.
+		for j, r := range resets[prop] {
+			if !strings.EqualFold(values[len(sh.longhands)+j], r.Value) {
+				return dl
+			}
+		}
+		if v, ok = sh.collapse(values[:len(sh.longhands)]); !ok {
+			return dl
+		}
+	}
+	out := make(DeclarationList, 0, len(dl)-len(values)+1)
+	for i, d := range dl {
+		switch {
+		case i == first:
+			out = append(out, Declaration{Property: prop, Value: v, Important: d.Important})
+		case !contains(props, d.Property):
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// collapseKeywords collapses longhands all set to the same css-wide
+// keyword.
+func collapseKeywords(values []string) (string, bool) {
+	v := strings.ToLower(values[0])
+	if !cssWideKeywords[v] {
+		return "", false
+	}
+	for _, other := range values[1:] {
+		if strings.ToLower(other) != v {
+			return "", false
+		}
+	}
+	return v, true
+}
+
+func overlaps(a, b []string) bool {
+	for _, s := range a {
+		if contains(b, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(ss []string, s string) bool {
+	for _, o := range ss {
+		if o == s {
+			return true
+		}
+	}
+	return false
+}
+
+// hasKeyword returns true if values contains a css-wide keyword. They
+// can't be combined with other values in a shorthand.
+func hasKeyword(values []string) bool {
+	for _, v := range values {
+		if cssWideKeywords[strings.ToLower(v)] {
+			return true
+		}
+	}
+	return false
+}
+
+func isMath(cv ComponentValue) bool {
+	if cv.Type != FunctionValue {
+		return false
+	}
+	switch strings.ToLower(cv.Value) {
+	case "calc", "min", "max", "clamp":
+		return true
+	}
+	return false
+}
+
+func isLength(cv ComponentValue) bool {
+	_, ok := cv.Length()
+	return ok || isMath(cv)
+}
+
+func isLengthPercentage(cv ComponentValue) bool {
+	return isLength(cv) || cv.Type == PercentageValue
+}
+
+// isPadding is isLengthPercentage without the negative values padding,
+// border widths and radii don't allow.
+func isPadding(cv ComponentValue) bool {
+	return isLengthPercentage(cv) && cv.Number >= 0
+}
+
+func isMargin(cv ComponentValue) bool {
+	return isLengthPercentage(cv) || cv.IsIdent("auto")
+}
+
+func isLineWidth(cv ComponentValue) bool {
+	return isLength(cv) && cv.Number >= 0 || cv.IsIdent("thin") || cv.IsIdent("medium") || cv.IsIdent("thick")
+}
+
+var lineStyles = map[string]bool{
+	"none": true, "hidden": true, "dotted": true, "dashed": true, "solid": true,
+	"double": true, "groove": true, "ridge": true, "inset": true, "outset": true,
+}
+
+func isLineStyle(cv ComponentValue) bool {
+	return cv.Type == IdentValue && lineStyles[strings.ToLower(cv.Value)]
+}
+
+// isColorLike returns true if cv could be a color. Identifiers, hashes
+// and functions other than math functions are assumed to be colors when
+// they aren't any other component of a shorthand.
+func isColorLike(cv ComponentValue) bool {
+	switch cv.Type {
+	case IdentValue, HashValue:
+		return true
+	case FunctionValue:
+		return !isMath(cv)
+	}
+	return false
+}
+
+func isGap(cv ComponentValue) bool {
+	return isLengthPercentage(cv) || cv.IsIdent("normal")
+}
+
+var overflows = map[string]bool{
+	"visible": true, "hidden": true, "clip": true, "scroll": true, "auto": true,
+}
+
+func isOverflow(cv ComponentValue) bool {
+	return cv.Type == IdentValue && overflows[strings.ToLower(cv.Value)]
+}
+
+// expandBox expands the 1 to 4 values of a top, right, bottom, left
+// shorthand like margin.
+func expandBox(valid func(ComponentValue) bool) func(ValueList) ([]string, bool) {
+	return func(vl ValueList) ([]string, bool) {
+		if len(vl) > 4 {
+			return nil, false
+		}
+		var v []string
+		for _, cv := range vl {
+			if !valid(cv) {
+				return nil, false
+			}
+			v = append(v, cv.String())
+		}
+		return boxValues(v), true
+	}
+}
+
+// boxValues fills in the omitted sides of a 1 to 4 value box.
+func boxValues(v []string) []string {
+	switch len(v) {
+	case 1:
+		return []string{v[0], v[0], v[0], v[0]}
+	case 2:
+		return []string{v[0], v[1], v[0], v[1]}
+	case 3:
+		return []string{v[0], v[1], v[2], v[1]}
+	}
+	return v
+}
+
+func collapseBox(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	t, r, b, l := v[0], v[1], v[2], v[3]
+	switch {
+	case r != l:
+		return t + " " + r + " " + b + " " + l, true
+	case t != b:
+		return t + " " + r + " " + b, true
+	case t != r:
+		return t + " " + r, true
+	}
+	return t, true
+}
+
+// expandPair expands the 1 or 2 values of a shorthand like gap.
+func expandPair(valid func(ComponentValue) bool) func(ValueList) ([]string, bool) {
+	return func(vl ValueList) ([]string, bool) {
+		if len(vl) > 2 {
+			return nil, false
+		}
+		for _, cv := range vl {
+			if !valid(cv) {
+				return nil, false
+			}
+		}
+		if len(vl) == 1 {
+			return []string{vl[0].String(), vl[0].String()}, true
+		}
+		return []string{vl[0].String(), vl[1].String()}, true
+	}
+}
+
+func collapsePair(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	if v[0] == v[1] {
+		return v[0], true
+	}
+	return v[0] + " " + v[1], true
+}
+
+// expandLine expands the width, style and color of a line like
+// border-top or outline given in any order.
+func expandLine(isStyle func(ComponentValue) bool) func(ValueList) ([]string, bool) {
+	return func(vl ValueList) ([]string, bool) {
+		var width, style, color string
+		for _, cv := range vl {
+			switch {
+			case width == "" && isLineWidth(cv):
+				width = cv.String()
+			case style == "" && isStyle(cv):
+				style = cv.String()
+			case color == "" && isColorLike(cv):
+				color = cv.String()
+			default:
+				return nil, false
+			}
+		}
+		return []string{
+			orDefault(width, "medium"), orDefault(style, "none"), orDefault(color, "currentcolor"),
+		}, true
+	}
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func collapseLine(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	var parts []string
+	if v[0] != "medium" {
+		parts = append(parts, v[0])
+	}
+	if v[1] != "none" {
+		parts = append(parts, v[1])
+	}
+	if !strings.EqualFold(v[2], "currentcolor") {
+		parts = append(parts, v[2])
+	}
+	if len(parts) == 0 {
+		return "none", true
+	}
+	return strings.Join(parts, " "), true
+}
+
+func expandBorder(vl ValueList) ([]string, bool) {
+	line, ok := expandLine(isLineStyle)(vl)
+	if !ok {
+		return nil, false
+	}
+	var v []string
+	for _, l := range line {
+		v = append(v, l, l, l, l)
+	}
+	return v, true
+}
+
+// collapseBorder collapses the widths, styles and colors of the four
+// sides which must be the same on every side.
+func collapseBorder(v []string) (string, bool) {
+	for i := 0; i < 12; i += 4 {
+		if v[i] != v[i+1] || v[i] != v[i+2] || v[i] != v[i+3] {
+			return "", false
+		}
+	}
+	return collapseLine([]string{v[0], v[4], v[8]})
+}
+
+func expandRadius(vl ValueList) ([]string, bool) {
+	h, v := vl, ValueList(nil)
+	for i, cv := range vl {
+		if cv.Type == SlashValue {
+			h, v = vl[:i], vl[i+1:]
+			if len(v) == 0 {
+				return nil, false
+			}
+		}
+	}
+	horizontal, ok := expandBox(isPadding)(h)
+	if !ok || len(h) == 0 {
+		return nil, false
+	}
+	vertical := horizontal
+	if v != nil {
+		if vertical, ok = expandBox(isPadding)(v); !ok {
+			return nil, false
+		}
+	}
+	corners := make([]string, 4)
+	for i := range corners {
+		corners[i] = horizontal[i]
+		if vertical[i] != horizontal[i] {
+			corners[i] += " " + vertical[i]
+		}
+	}
+	return corners, true
+}
+
+func collapseRadius(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	h, vert := make([]string, 4), make([]string, 4)
+	for i, corner := range v {
+		radii := strings.Fields(corner)
+		switch len(radii) {
+		case 1:
+			h[i], vert[i] = radii[0], radii[0]
+		case 2:
+			h[i], vert[i] = radii[0], radii[1]
+		default:
+			return "", false
+		}
+	}
+	hs, _ := collapseBox(h)
+	vs, _ := collapseBox(vert)
+	if hs == vs {
+		return hs, true
+	}
+	return hs + " / " + vs, true
+}
+
+var fontStretches = map[string]bool{
+	"ultra-condensed": true, "extra-condensed": true, "condensed": true,
+	"semi-condensed": true, "semi-expanded": true, "expanded": true,
+	"extra-expanded": true, "ultra-expanded": true,
+}
+
+var fontSizes = map[string]bool{
+	"xx-small": true, "x-small": true, "small": true, "medium": true, "large": true,
+	"x-large": true, "xx-large": true, "xxx-large": true, "larger": true, "smaller": true,
+}
+
+func isFontWeight(cv ComponentValue) bool {
+	switch {
+	case cv.Type == NumberValue:
+		return cv.Number >= 1 && cv.Number <= 1000
+	case cv.Type == IdentValue:
+		switch strings.ToLower(cv.Value) {
+		case "bold", "bolder", "lighter":
+			return true
+		}
+	}
+	return false
+}
+
+// expandFont expands the font shorthand. The system font keywords like
+// caption aren't supported.
+func expandFont(vl ValueList) ([]string, bool) {
+	var style, variant, weight, stretch string
+	i := 0
+prefix:
+	for ; i < len(vl) && i < 4; i++ {
+		cv := vl[i]
+		switch {
+		case cv.IsIdent("normal"):
+			// normal resets whichever property it doesn't name.
+		case style == "" && (cv.IsIdent("italic") || cv.IsIdent("oblique")):
+			style = cv.String()
+			if cv.IsIdent("oblique") && i+1 < len(vl) && vl[i+1].Type == DimensionValue &&
+				vl[i+1].Unit == "deg" {
+				i++
+				style += " " + vl[i].String()
+			}
+		case variant == "" && cv.IsIdent("small-caps"):
+			variant = cv.String()
+		case weight == "" && isFontWeight(cv):
+			weight = cv.String()
+		case stretch == "" && cv.Type == IdentValue && fontStretches[strings.ToLower(cv.Value)]:
+			stretch = cv.String()
+		default:
+			break prefix
+		}
+	}
+	if i == len(vl) {
+		return nil, false
+	}
+	cv := vl[i]
+	if !isLengthPercentage(cv) && !(cv.Type == IdentValue && fontSizes[strings.ToLower(cv.Value)]) {
+		return nil, false
+	}
+	size, lineHeight := cv.String(), "normal"
+	i++
+	if i+1 < len(vl) && vl[i].Type == SlashValue {
+		lh := vl[i+1]
+		if !isLengthPercentage(lh) && lh.Type != NumberValue && !lh.IsIdent("normal") {
+			return nil, false
+		}
+		lineHeight = lh.String()
+		i += 2
+	}
+	family := vl[i:]
+	if len(family) == 0 {
+		return nil, false
+	}
+	for _, cv := range family {
+		if cv.Type != IdentValue && cv.Type != StringValue && cv.Type != CommaValue {
+			return nil, false
+		}
+	}
+	return []string{
+		orDefault(style, "normal"), orDefault(variant, "normal"), orDefault(weight, "normal"),
+		orDefault(stretch, "normal"), size, lineHeight, family.String(),
+	}, true
+}
+
+func collapseFont(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	style, variant, weight, stretch, size, lineHeight, family := v[0], v[1], v[2], v[3], v[4], v[5], v[6]
+	if variant != "normal" && !strings.EqualFold(variant, "small-caps") ||
+		stretch != "normal" && !fontStretches[strings.ToLower(stretch)] {
+		// The shorthand can only set these to some of their values.
+		return "", false
+	}
+	var parts []string
+	for _, p := range []string{style, variant, weight, stretch} {
+		if p != "normal" {
+			parts = append(parts, p)
+		}
+	}
+	if lineHeight != "normal" {
+		size += "/" + lineHeight
+	}
+	return strings.Join(append(parts, size, family), " "), true
+}
+
+var (
+	backgroundRepeats     = map[string]bool{"repeat": true, "space": true, "round": true, "no-repeat": true}
+	backgroundAttachments = map[string]bool{"scroll": true, "fixed": true, "local": true}
+	backgroundBoxes       = map[string]bool{"border-box": true, "padding-box": true, "content-box": true}
+	backgroundPositions   = map[string]bool{"left": true, "right": true, "top": true, "bottom": true, "center": true}
+)
+
+func isIdentIn(cv ComponentValue, set map[string]bool) bool {
+	return cv.Type == IdentValue && set[strings.ToLower(cv.Value)]
+}
+
+func isPosition(cv ComponentValue) bool {
+	return isLengthPercentage(cv) || isIdentIn(cv, backgroundPositions)
+}
+
+func isImage(cv ComponentValue) bool {
+	switch cv.Type {
+	case URLValue:
+		return true
+	case FunctionValue:
+		name := strings.ToLower(cv.Value)
+		return strings.HasSuffix(name, "gradient") || name == "image-set" || name == "image" ||
+			name == "cross-fade" || name == "element"
+	}
+	return false
+}
+
+// expandBackground expands a single layer background. Backgrounds with
+// several comma separated layers aren't supported.
+func expandBackground(vl ValueList) ([]string, bool) {
+	var image, position, size, repeat, attachment, color string
+	var boxes []string
+	for i := 0; i < len(vl); i++ {
+		cv := vl[i]
+		switch {
+		case image == "" && (isImage(cv) || cv.IsIdent("none")):
+			image = cv.String()
+		case position == "" && isPosition(cv):
+			var pos []string
+			for ; i < len(vl) && isPosition(vl[i]) && len(pos) < 4; i++ {
+				pos = append(pos, vl[i].String())
+			}
+			position = strings.Join(pos, " ")
+			if i < len(vl) && vl[i].Type == SlashValue {
+				var sz []string
+				for i++; i < len(vl) && len(sz) < 2 && (isLengthPercentage(vl[i]) || vl[i].IsIdent("auto")); i++ {
+					sz = append(sz, vl[i].String())
+				}
+				if len(sz) == 0 && i < len(vl) && (vl[i].IsIdent("cover") || vl[i].IsIdent("contain")) {
+					sz = append(sz, vl[i].String())
+					i++
+				}
+				if len(sz) == 0 {
+					return nil, false
+				}
+				size = strings.Join(sz, " ")
+			}
+			i--
+		case repeat == "" && (cv.IsIdent("repeat-x") || cv.IsIdent("repeat-y")):
+			repeat = cv.String()
+		case repeat == "" && isIdentIn(cv, backgroundRepeats):
+			repeat = cv.String()
+			if i+1 < len(vl) && isIdentIn(vl[i+1], backgroundRepeats) {
+				i++
+				repeat += " " + vl[i].String()
+			}
+		case attachment == "" && isIdentIn(cv, backgroundAttachments):
+			attachment = cv.String()
+		case len(boxes) < 2 && isIdentIn(cv, backgroundBoxes):
+			boxes = append(boxes, cv.String())
+		case color == "" && isColorLike(cv):
+			color = cv.String()
+		default:
+			return nil, false
+		}
+	}
+	origin, clip := "padding-box", "border-box"
+	switch len(boxes) {
+	case 1:
+		origin, clip = boxes[0], boxes[0]
+	case 2:
+		origin, clip = boxes[0], boxes[1]
+	}
+	return []string{
+		orDefault(image, "none"), orDefault(position, "0% 0%"), orDefault(size, "auto"),
+		orDefault(repeat, "repeat"), orDefault(attachment, "scroll"), origin, clip,
+		orDefault(color, "transparent"),
+	}, true
+}
+
+func collapseBackground(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	for _, p := range v {
+		if strings.Contains(p, ",") {
+			// Several layers.
+			return "", false
+		}
+	}
+	image, position, size, repeat, attachment, origin, clip, color := v[0], v[1], v[2], v[3], v[4], v[5], v[6], v[7]
+	var parts []string
+	if image != "none" {
+		parts = append(parts, image)
+	}
+	switch {
+	case size != "auto":
+		parts = append(parts, position+" / "+size)
+	case position != "0% 0%":
+		parts = append(parts, position)
+	}
+	if repeat != "repeat" {
+		parts = append(parts, repeat)
+	}
+	if attachment != "scroll" {
+		parts = append(parts, attachment)
+	}
+	switch {
+	case origin == "padding-box" && clip == "border-box":
+	case origin == clip:
+		parts = append(parts, origin)
+	default:
+		parts = append(parts, origin, clip)
+	}
+	if color != "transparent" {
+		parts = append(parts, color)
+	}
+	if len(parts) == 0 {
+		return "none", true
+	}
+	return strings.Join(parts, " "), true
+}
+
+func isFlexBasis(cv ComponentValue) bool {
+	return isLengthPercentage(cv) || cv.IsIdent("auto") || cv.IsIdent("content")
+}
+
+func expandFlex(vl ValueList) ([]string, bool) {
+	if len(vl) == 1 {
+		switch {
+		case vl[0].IsIdent("none"):
+			return []string{"0", "0", "auto"}, true
+		case vl[0].IsIdent("auto"):
+			return []string{"1", "1", "auto"}, true
+		}
+	}
+	var grow, shrink, basis string
+	for i, cv := range vl {
+		switch {
+		case cv.Type == NumberValue && grow == "":
+			grow = cv.String()
+		case cv.Type == NumberValue && shrink == "" && basis == "" && i > 0 && vl[i-1].Type == NumberValue:
+			shrink = cv.String()
+		case basis == "" && isFlexBasis(cv):
+			basis = cv.String()
+		default:
+			return nil, false
+		}
+	}
+	if grow == "" {
+		grow = "1"
+	} else if basis == "" {
+		basis = "0%"
+	}
+	return []string{grow, orDefault(shrink, "1"), orDefault(basis, "auto")}, true
+}
+
+// isNumber returns true if s is a single unitless number.
+func isNumber(s string) bool {
+	vl, err := ParseValue(s)
+	return err == nil && len(vl) == 1 && vl[0].Type == NumberValue
+}
+
+func collapseFlex(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	grow, shrink, basis := v[0], v[1], v[2]
+	switch {
+	case grow == "0" && shrink == "0" && basis == "auto":
+		return "none", true
+	case grow == "1" && shrink == "1" && basis == "auto":
+		return "auto", true
+	case shrink == "1" && basis == "0%":
+		return grow, true
+	case shrink == "1" && !isNumber(basis):
+		// A unitless basis like 0 would be read back as the shrink.
+		return grow + " " + basis, true
+	}
+	return grow + " " + shrink + " " + basis, true
+}
+
+func expandListStyle(vl ValueList) ([]string, bool) {
+	var typ, position, image string
+	nones := 0
+	for _, cv := range vl {
+		switch {
+		case cv.IsIdent("none"):
+			nones++
+		case position == "" && (cv.IsIdent("inside") || cv.IsIdent("outside")):
+			position = cv.String()
+		case image == "" && isImage(cv):
+			image = cv.String()
+		case typ == "" && (cv.Type == IdentValue || cv.Type == StringValue || cv.IsFunction("symbols")):
+			typ = cv.String()
+		default:
+			return nil, false
+		}
+	}
+	// none sets whichever of the type and image isn't otherwise set or
+	// both if neither is.
+	switch {
+	case nones == 0:
+	case nones == 1 && typ == "" && image == "", nones == 2 && typ == "" && image == "":
+		typ, image = "none", "none"
+	case nones == 1 && image == "":
+		image = "none"
+	case nones == 1 && typ == "":
+		typ = "none"
+	default:
+		return nil, false
+	}
+	return []string{orDefault(typ, "disc"), orDefault(position, "outside"), orDefault(image, "none")}, true
+}
+
+func collapseListStyle(v []string) (string, bool) {
+	if hasKeyword(v) {
+		return "", false
+	}
+	typ, position, image := v[0], v[1], v[2]
+	var parts []string
+	if typ != "disc" {
+		parts = append(parts, typ)
+	}
+	if position != "outside" {
+		parts = append(parts, position)
+	}
+	if image != "none" {
+		parts = append(parts, image)
+	}
+	if len(parts) == 0 {
+		return "disc", true
+	}
+	return strings.Join(parts, " "), true
+}
@@ -0,0 +1,214 @@
+package css
+
+import (
+	"strings"
+	"testing"
+)
+
+// longhands formats the expansion of a shorthand for comparison.
+func longhands(prop, value string) string {
+	dl, ok := Declaration{Property: prop, Value: value}.Expand()
+	if !ok {
+		return "invalid"
+	}
+	return dl.String()
+}
+
+// borderResets and fontResets are the longhands border and font reset.
+const (
+	borderResets = "; border-image-source: none; border-image-slice: 100%; border-image-width: 1; " +
+		"border-image-outset: 0; border-image-repeat: stretch"
+	fontResets = "; font-size-adjust: none; font-kerning: auto; font-variant-ligatures: normal; " +
+		"font-variant-position: normal; font-variant-numeric: normal; font-variant-alternates: normal; " +
+		"font-variant-east-asian: normal; font-variant-emoji: normal; font-feature-settings: normal; " +
+		"font-variation-settings: normal; font-language-override: normal; font-optical-sizing: auto"
+)
+
+func TestExpand(t *testing.T) {
+	cases := []struct {
+		prop, value, expected string
+	}{
+		{"color", "red", "color: red"},
+		{"margin", "1px", "margin-top: 1px; margin-right: 1px; margin-bottom: 1px; margin-left: 1px"},
+		{"margin", "1px auto", "margin-top: 1px; margin-right: auto; margin-bottom: 1px; margin-left: auto"},
+		{"margin", "1px 2px 3px", "margin-top: 1px; margin-right: 2px; margin-bottom: 3px; margin-left: 2px"},
+		{"margin", "1px 2px 3px 4px 5px", "invalid"},
+		{"margin", "inherit", "margin-top: inherit; margin-right: inherit; margin-bottom: inherit; margin-left: inherit"},
+		{"margin", "var(--m)", "margin: var(--m)"},
+		{"padding", "0 calc(1em + 2px) 5% 4px", "padding-top: 0; padding-right: calc(1em + 2px); padding-bottom: 5%; padding-left: 4px"},
+		{"padding", "auto", "invalid"},
+		{"padding", "1px -2px", "invalid"},
+		{"padding", "-5%", "invalid"},
+		{"margin", "5deg 2s", "invalid"},
+		{"padding", "3s", "invalid"},
+		{"border", "1s solid red", "invalid"},
+		{"gap", "2s", "invalid"},
+		{"font", "12px/2deg serif", "invalid"},
+		{"inset", "0 auto", "top: 0; right: auto; bottom: 0; left: auto"},
+		{"border-width", "thin 2px", "border-top-width: thin; border-right-width: 2px; border-bottom-width: thin; border-left-width: 2px"},
+		{"border-style", "solid dashed", "border-top-style: solid; border-right-style: dashed; border-bottom-style: solid; border-left-style: dashed"},
+		{"border-width", "-1px", "invalid"},
+		{"border-style", "wavy", "invalid"},
+		{"border-color", "red #00f", "border-top-color: red; border-right-color: #00f; border-bottom-color: red; border-left-color: #00f"},
+		{"border-top", "red 1px", "border-top-width: 1px; border-top-style: none; border-top-color: red"},
+		{"border-right", "dashed", "border-right-width: medium; border-right-style: dashed; border-right-color: currentcolor"},
+		{"border-bottom", "thick double rgb(0, 0, 0)", "border-bottom-width: thick; border-bottom-style: double; border-bottom-color: rgb(0, 0, 0)"},
+		{"border-left", "1px 2px", "invalid"},
+		{"border", "1px solid red", "border-top-width: 1px; border-right-width: 1px; border-bottom-width: 1px; border-left-width: 1px; " +
+			"border-top-style: solid; border-right-style: solid; border-bottom-style: solid; border-left-style: solid; " +
+			"border-top-color: red; border-right-color: red; border-bottom-color: red; border-left-color: red" + borderResets},
+		{"outline", "auto 2px", "outline-width: 2px; outline-style: auto; outline-color: currentcolor"},
+		{"border-radius", "4px", "border-top-left-radius: 4px; border-top-right-radius: 4px; border-bottom-right-radius: 4px; border-bottom-left-radius: 4px"},
+		{"border-radius", "1px 2px / 3px", "border-top-left-radius: 1px 3px; border-top-right-radius: 2px 3px; border-bottom-right-radius: 1px 3px; border-bottom-left-radius: 2px 3px"},
+		{"border-radius", "1px /", "invalid"},
+		{"border-radius", "-1px", "invalid"},
+		{"gap", "1px", "row-gap: 1px; column-gap: 1px"},
+		{"gap", "normal 2%", "row-gap: normal; column-gap: 2%"},
+		{"overflow", "hidden auto", "overflow-x: hidden; overflow-y: auto"},
+		{"overflow", "sideways", "invalid"},
+		{"font", "12px serif", "font-style: normal; font-variant-caps: normal; font-weight: normal; font-stretch: normal; " +
+			"font-size: 12px; line-height: normal; font-family: serif" + fontResets},
+		{"font", `italic bold condensed 1.2em/1.5 "Helvetica Neue", Arial, sans-serif`,
+			"font-style: italic; font-variant-caps: normal; font-weight: bold; font-stretch: condensed; " +
+				`font-size: 1.2em; line-height: 1.5; font-family: "Helvetica Neue", Arial, sans-serif` + fontResets},
+		{"font", "normal small-caps 700 large/20px Georgia", "font-style: normal; font-variant-caps: small-caps; font-weight: 700; font-stretch: normal; " +
+			"font-size: large; line-height: 20px; font-family: Georgia" + fontResets},
+		{"font", "bold serif", "invalid"},
+		{"font", "12px", "invalid"},
+		{"background", "red", "background-image: none; background-position: 0% 0%; background-size: auto; background-repeat: repeat; " +
+			"background-attachment: scroll; background-origin: padding-box; background-clip: border-box; background-color: red"},
+		{"background", "url(a.png) center / cover no-repeat fixed content-box #fff",
+			"background-image: url(a.png); background-position: center; background-size: cover; background-repeat: no-repeat; " +
+				"background-attachment: fixed; background-origin: content-box; background-clip: content-box; background-color: #fff"},
+		{"background", "linear-gradient(red, blue) left 10px top / 50% auto repeat-x border-box padding-box",
+			"background-image: linear-gradient(red, blue); background-position: left 10px top; background-size: 50% auto; background-repeat: repeat-x; " +
+				"background-attachment: scroll; background-origin: border-box; background-clip: padding-box; background-color: transparent"},
+		{"background", "url(a.png), red", "invalid"},
+		{"background", "url(a.png) no-repeat, url(b.png) repeat-x", "invalid"},
+		{"flex", "none", "flex-grow: 0; flex-shrink: 0; flex-basis: auto"},
+		{"flex", "auto", "flex-grow: 1; flex-shrink: 1; flex-basis: auto"},
+		{"flex", "2", "flex-grow: 2; flex-shrink: 1; flex-basis: 0%"},
+		{"flex", "10em", "flex-grow: 1; flex-shrink: 1; flex-basis: 10em"},
+		{"flex", "2 3", "flex-grow: 2; flex-shrink: 3; flex-basis: 0%"},
+		{"flex", "2 3 0", "flex-grow: 2; flex-shrink: 3; flex-basis: 0"},
+		{"flex", "1 2 3", "invalid"},
+		{"list-style", "none", "list-style-type: none; list-style-position: outside; list-style-image: none"},
+		{"list-style", "square inside", "list-style-type: square; list-style-position: inside; list-style-image: none"},
+		{"list-style", "none url(dot.png)", "list-style-type: none; list-style-position: outside; list-style-image: url(dot.png)"},
+		{"list-style", "circle none", "list-style-type: circle; list-style-position: outside; list-style-image: none"},
+	}
+	for _, c := range cases {
+		if got := longhands(c.prop, c.value); got != c.expected {
+			t.Errorf("Expanding %s: %s expected\n%s\ngot\n%s", c.prop, c.value, c.expected, got)
+		}
+	}
+}
+
+func TestCollapseShorthands(t *testing.T) {
+	cases := []struct {
+		decls, expected string
+	}{
+		{"margin-top: 1px; margin-right: 1px; margin-bottom: 1px; margin-left: 1px", "margin: 1px"},
+		{"color: red; margin-top: 1px; margin-right: 2px; margin-bottom: 1px; margin-left: 2px; display: block",
+			"color: red; margin: 1px 2px; display: block"},
+		{"margin-top: 1px; margin-right: 2px; margin-bottom: 3px; margin-left: 2px", "margin: 1px 2px 3px"},
+		{"margin-top: 1px; margin-right: 2px; margin-bottom: 3px; margin-left: 4px", "margin: 1px 2px 3px 4px"},
+		{"margin-top: 1px; margin-right: 1px; margin-bottom: 1px", "margin-top: 1px; margin-right: 1px; margin-bottom: 1px"},
+		{"margin-top: 1px; margin-right: 1px; margin-bottom: 1px; margin-left: 1px !important",
+			"margin-top: 1px; margin-right: 1px; margin-bottom: 1px; margin-left: 1px !important"},
+		{"margin-top: 1px; margin-right: 1px; margin-bottom: 1px; margin-left: var(--m)",
+			"margin-top: 1px; margin-right: 1px; margin-bottom: 1px; margin-left: var(--m)"},
+		{"margin-top: 1px; margin: 0; margin-right: 1px; margin-bottom: 1px; margin-left: 1px",
+			"margin-top: 1px; margin: 0; margin-right: 1px; margin-bottom: 1px; margin-left: 1px"},
+		{"margin-top: inherit; margin-right: inherit; margin-bottom: inherit; margin-left: inherit", "margin: inherit"},
+		{"padding-top: 0; padding-right: 0; padding-bottom: 0; padding-left: 0", "padding: 0"},
+		{"top: 0; right: 0; bottom: auto; left: 0", "inset: 0 0 auto"},
+		{"border-top-width: 1px; border-right-width: 1px; border-bottom-width: 1px; border-left-width: 1px; " +
+			"border-top-style: solid; border-right-style: solid; border-bottom-style: solid; border-left-style: solid; " +
+			"border-top-color: red; border-right-color: red; border-bottom-color: red; border-left-color: red" + borderResets,
+			"border: 1px solid red"},
+		{"border-top-width: 1px; border-right-width: 1px; border-bottom-width: 1px; border-left-width: 1px; " +
+			"border-top-style: solid; border-right-style: solid; border-bottom-style: solid; border-left-style: solid; " +
+			"border-top-color: red; border-right-color: red; border-bottom-color: red; border-left-color: red",
+			"border-width: 1px; border-style: solid; border-color: red"},
+		{"border-top-width: 1px; border-right-width: 2px; border-bottom-width: 1px; border-left-width: 2px; " +
+			"border-top-style: solid; border-right-style: solid; border-bottom-style: solid; border-left-style: solid; " +
+			"border-top-color: red; border-right-color: red; border-bottom-color: red; border-left-color: blue",
+			"border-width: 1px 2px; border-style: solid; border-color: red red red blue"},
+		{"border-top-width: medium; border-top-style: dashed; border-top-color: currentcolor", "border-top: dashed"},
+		{"border-right-width: medium; border-right-style: none; border-right-color: currentcolor", "border-right: none"},
+		{"border-bottom-width: 1px; border-bottom-style: solid; border-bottom-color: red", "border-bottom: 1px solid red"},
+		{"border-left-width: 0; border-left-style: none; border-left-color: red", "border-left: 0 red"},
+		{"outline-width: thin; outline-style: auto; outline-color: currentcolor", "outline: thin auto"},
+		{"border-top-left-radius: 4px; border-top-right-radius: 4px; border-bottom-right-radius: 4px; border-bottom-left-radius: 4px",
+			"border-radius: 4px"},
+		{"border-top-left-radius: 1px 3px; border-top-right-radius: 2px 3px; border-bottom-right-radius: 1px 3px; border-bottom-left-radius: 2px 3px",
+			"border-radius: 1px 2px / 3px"},
+		{"row-gap: 1px; column-gap: 2px", "gap: 1px 2px"},
+		{"overflow-x: hidden; overflow-y: hidden", "overflow: hidden"},
+		{"font-style: italic; font-variant-caps: normal; font-weight: bold; font-stretch: normal; " +
+			"font-size: 12px; line-height: 1.5; font-family: Arial, sans-serif" + fontResets,
+			"font: italic bold 12px/1.5 Arial, sans-serif"},
+		{"font-style: italic; font-variant-caps: normal; font-weight: bold; font-stretch: normal; " +
+			"font-size: 12px; line-height: 1.5; font-family: Arial, sans-serif" + strings.Replace(fontResets, "font-kerning: auto", "font-kerning: none", 1),
+			"font-style: italic; font-variant-caps: normal; font-weight: bold; font-stretch: normal; " +
+				"font-size: 12px; line-height: 1.5; font-family: Arial, sans-serif" + strings.Replace(fontResets, "font-kerning: auto", "font-kerning: none", 1)},
+		{"font-style: normal; font-variant-caps: all-small-caps; font-weight: normal; font-stretch: normal; " +
+			"font-size: 12px; line-height: normal; font-family: serif",
+			"font-style: normal; font-variant-caps: all-small-caps; font-weight: normal; font-stretch: normal; " +
+				"font-size: 12px; line-height: normal; font-family: serif"},
+		{"background-image: none; background-position: 0% 0%; background-size: auto; background-repeat: repeat; " +
+			"background-attachment: scroll; background-origin: padding-box; background-clip: border-box; background-color: red",
+			"background: red"},
+		{"background-image: url(a.png); background-position: center; background-size: cover; background-repeat: no-repeat; " +
+			"background-attachment: scroll; background-origin: content-box; background-clip: content-box; background-color: transparent",
+			"background: url(a.png) center / cover no-repeat content-box"},
+		{"background-image: none; background-position: 0% 0%; background-size: auto; background-repeat: repeat; " +
+			"background-attachment: scroll; background-origin: padding-box; background-clip: border-box; background-color: transparent",
+			"background: none"},
+		{"flex-grow: 0; flex-shrink: 0; flex-basis: auto", "flex: none"},
+		{"flex-grow: 1; flex-shrink: 1; flex-basis: auto", "flex: auto"},
+		{"flex-grow: 2; flex-shrink: 1; flex-basis: 0%", "flex: 2"},
+		{"flex-grow: 2; flex-shrink: 1; flex-basis: 10em", "flex: 2 10em"},
+		{"flex-grow: 2; flex-shrink: 3; flex-basis: 10em", "flex: 2 3 10em"},
+		{"list-style-type: square; list-style-position: outside; list-style-image: none", "list-style: square"},
+		{"list-style-type: disc; list-style-position: outside; list-style-image: none", "list-style: disc"},
+	}
+	for _, c := range cases {
+		dl, err := ParseDeclarations(c.decls)
+		if err != nil {
+			t.Fatalf("Error parsing %q: %s", c.decls, err)
+		}
+		if got := CollapseShorthands(dl).String(); got != c.expected {
+			t.Errorf("Collapsing %s expected\n%s\ngot\n%s", c.decls, c.expected, got)
+		}
+	}
+}
+
+func TestShorthandRoundTrip(t *testing.T) {
+	for prop := range shorthands {
+		if len(Longhands(prop)) == 0 {
+			t.Errorf("%s has no longhands", prop)
+		}
+	}
+	dl, err := ParseDeclarations(`margin: 0 auto; border: 2px dashed #ccc; font: bold 14px/1.2 Arial; background: url(a.png) no-repeat; flex: 1 1 200px; list-style: none`)
+	if err != nil {
+		t.Fatalf("Error parsing declarations: %s", err)
+	}
+	expected := `margin: 0 auto; border: 2px dashed #ccc; font: bold 14px/1.2 Arial; background: url(a.png) no-repeat; flex: 1 200px; list-style: none`
+	if got := CollapseShorthands(ExpandShorthands(dl)).String(); got != expected {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+	for _, flex := range []string{"none", "auto", "2", "10em", "2 3", "2 3 0", "1 1 0", "1 0", "0 0 0", "1 1 0%", "2 1 5px"} {
+		want := longhands("flex", flex)
+		dl := ExpandShorthands(DeclarationList{{Property: "flex", Value: flex}})
+		collapsed := CollapseShorthands(dl)
+		if len(collapsed) != 1 || collapsed[0].Property != "flex" {
+			t.Errorf("flex: %s didn't collapse: %s", flex, collapsed)
+			continue
+		}
+		if got := longhands("flex", collapsed[0].Value); got != want {
+			t.Errorf("flex: %s collapsed to %s which expands to\n%s\nnot\n%s", flex, collapsed[0].Value, got, want)
+		}
+	}
+}
@@ -6,11 +6,13 @@ package tokenizer
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
-	"log"
-
+	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 var EUnexpextedEOF = fmt.Errorf("Unexpected EOF.")
@@ -40,7 +42,8 @@ var unknownRune = []byte("\uFFFD")
 
 func preprocess(buf []byte, r *bufio.Reader) (int, error) {
 	i := 0
-	for c, n, err := r.ReadRune(); err == nil; c, n, err = r.ReadRune() {
+	c, n, err := r.ReadRune()
+	for ; err == nil; c, n, err = r.ReadRune() {
 		if i+n > len(buf) {
 			// We don't have room so unread the rune and return.
 			r.UnreadRune()
@@ -48,14 +51,14 @@ func preprocess(buf []byte, r *bufio.Reader) (int, error) {
 		}
 		switch c {
 		case '\x00':
-			if len(buf)-1 < i+len(unknownRune) {
-				copy(buf[i:len(unknownRune)], unknownRune)
-			} else {
+			if i+len(unknownRune) > len(buf) {
 				// We don't have room so unread the rune and
 				// return.
 				r.UnreadRune()
-				return i + n, err
+				return i, nil
 			}
+			copy(buf[i:i+len(unknownRune)], unknownRune)
+			n = len(unknownRune)
 		case '\r':
 			buf[i] = '\n'
 			nxt, err := r.Peek(1)
@@ -69,6 +72,9 @@ func preprocess(buf []byte, r *bufio.Reader) (int, error) {
 		}
 		i += n
 	}
+	if err == io.EOF && i == 0 {
+		return 0, io.EOF
+	}
 	return i, nil
 }
 
@@ -96,11 +102,12 @@ func NewTrackingReader(r io.Reader, splitFunc func(data []byte, atEOF bool) (adv
 			rdr.lastCol = rdr.col
 			rdr.lastL = rdr.l
 			for _, b := range data[:advance] {
-				if b == '\n' || atEOF { // Treat eof as a newline
+				if b == '\n' {
 					rdr.l++
 					rdr.col = 1
+				} else {
+					rdr.col++
 				}
-				rdr.col++
 			}
 		}
 		return
@@ -109,8 +116,10 @@ func NewTrackingReader(r io.Reader, splitFunc func(data []byte, atEOF bool) (adv
 	return rdr
 }
 
+// Position returns the Position of the start of the last token scanned.
+// Lines and columns count from 1 and columns count bytes.
 func (l *PositionTrackingScanner) Position() Position {
-	return Position{Line: l.l, Column: l.col}
+	return Position{Line: l.lastL, Column: l.lastCol}
 }
 
 type Tokenizer struct {
@@ -159,6 +168,32 @@ type Token struct {
 	String string
 }
 
+// tokenText is the source text for the tokens that don't carry a String.
+var tokenText = map[tokenType]string{
+	Colon:          ":",
+	Semicolon:      ";",
+	LBrace:         "{",
+	RBrace:         "}",
+	LParen:         "(",
+	RParen:         ")",
+	LBracket:       "[",
+	RBracket:       "]",
+	Includes:       "~=",
+	Prefixmatch:    "^=",
+	Suffixmatch:    "$=",
+	SubstringMatch: "*=",
+	Dashmatch:      "|=",
+	Column:         "||",
+}
+
+// Text returns the css source text for a Token.
+func (t Token) Text() string {
+	if s, ok := tokenText[t.Type]; ok {
+		return s
+	}
+	return t.String
+}
+
 func New(r io.Reader) *Tokenizer {
 	return &Tokenizer{p: NewTrackingReader(r, splitFunc)}
 }
@@ -234,56 +269,164 @@ func consumeEscaped(data []byte, atEOF bool) (advance int, token []byte, err err
 	}
 }
 
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('A' <= c && c <= 'F') || ('a' <= c && c <= 'f')
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isNameStart reports whether c can start an identifier.
+func isNameStart(c byte) bool {
+	return c == '_' || ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || c >= 0x80
+}
+
+// isNameChar reports whether c can appear in an identifier.
+func isNameChar(c byte) bool {
+	return isNameStart(c) || isDigit(c) || c == '-'
+}
+
+// startsIdent reports whether data starts with an identifier.
+// http://www.w3.org/TR/css-syntax-3/#would-start-an-identifier
+func startsIdent(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	switch c := data[0]; {
+	case c == '-':
+		return len(data) > 1 && (isNameStart(data[1]) || data[1] == '-' ||
+			(data[1] == '\\' && len(data) > 2 && data[2] != '\n'))
+	case c == '\\':
+		return len(data) > 1 && data[1] != '\n'
+	default:
+		return isNameStart(c)
+	}
+}
+
+// startsNumber reports whether data starts with a number.
+// http://www.w3.org/TR/css-syntax-3/#starts-with-a-number
+func startsNumber(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	if data[0] == '+' || data[0] == '-' {
+		data = data[1:]
+	}
+	if len(data) == 0 {
+		return false
+	}
+	return isDigit(data[0]) || (data[0] == '.' && len(data) > 1 && isDigit(data[1]))
+}
+
 func consumeUnicodeRange(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	if !(data[0] == 'u' || data[0] == 'U') {
+	if len(data) < 2 || !(data[0] == 'u' || data[0] == 'U') {
 		return 0, nil, nil
 	}
-	log.Printf("datalen %d", len(data))
-	n, tok, err := consumeHexDigits(data[1:], atEOF)
-	log.Printf("xxx: Consumed %d hex digits %q", n, tok)
-	if tok != nil && n <= 6 {
-		if len(data) > n+1 && data[n+1] == '-' {
-			log.Printf("data[n+1 %q", data[n+1])
-			n2, tok2, err := consumeHexDigits(data[n+2:], atEOF)
-			log.Printf("Consumed %d hex digits %q", n2, tok2)
-			if tok2 != nil && n2 <= 6 {
-				advance = n + n2 + 2
-				return advance, data[:advance], nil
-			} else {
-				return 0, nil, err
-			}
-		} else {
-			return n + 1, data[:n+1], err
+	i := 1
+	if data[i] == '+' {
+		i++
+	}
+	start := i
+	for i < len(data) && i-start < 6 && (isHexDigit(data[i]) || data[i] == '?') {
+		i++
+	}
+	if i == start {
+		return 0, nil, nil
+	}
+	if i+1 < len(data) && data[i] == '-' && isHexDigit(data[i+1]) {
+		i++
+		start = i
+		for i < len(data) && i-start < 6 && isHexDigit(data[i]) {
+			i++
 		}
 	}
-	return 0, nil, err
+	if i < len(data) && isNameChar(data[i]) {
+		// Not a unicode range after all, just an identifier that happens to
+		// start with hex digits.
+		return 0, nil, nil
+	}
+	if i == len(data) && !atEOF {
+		return 0, nil, nil
+	}
+	return i, data[:i], nil
 }
 
 func consumeIdent(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	for i, c := range data {
-		// TODO(jwall): consumeIdentStart
+	i := 0
+	for i < len(data) {
+		c := data[i]
 		switch {
 		case c == '\\':
-			advance, token, err, _ = consumeEscaped(data, atEOF)
-			if advance > 0 {
-				advance += i
-				token = append(data[:i], token...)
-				continue
+			if i+1 >= len(data) {
+				if atEOF {
+					return i, data[:i], nil
+				}
+				return 0, nil, nil
 			}
+			n, _, err, _ := consumeEscaped(data[i:], atEOF)
 			if err != nil {
-				return 0, nil, err
+				if i == 0 {
+					return 0, nil, err
+				}
+				return i, data[:i], nil
 			}
-			return 0, nil, nil
-		case c == '\n' || c == '\r' || c == '\t' || c == ' ':
+			i += n
+		case isNameChar(c):
+			i++
+		default:
 			return i, data[:i], nil
-		case c == '-' || c == '_':
-		case '0' <= c && c <= '9':
-		case 'A' <= c && c <= 'Z':
-		case 'a' <= c && c <= 'z':
-		case '\x00' <= c && c <= '\xed':
-			// TODO(jwall) nonascii case
+		}
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// consumeIdentLike consumes an identifier, a function name including the
+// opening paren or an unquoted url.
+// http://www.w3.org/TR/css-syntax-3/#consume-an-ident-like-token
+func consumeIdentLike(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	advance, token, err = consumeIdent(data, atEOF)
+	if advance == 0 || err != nil {
+		return
+	}
+	if advance == len(data) {
+		if !atEOF {
+			return 0, nil, nil
+		}
+		return
+	}
+	if data[advance] != '(' {
+		return
+	}
+	if !strings.EqualFold(string(token), "url") {
+		return advance + 1, data[:advance+1], nil
+	}
+	i := advance + 1
+	for i < len(data) && isWhitespace(data[i]) {
+		i++
+	}
+	if i == len(data) && !atEOF {
+		return 0, nil, nil
+	}
+	if i < len(data) && (data[i] == '"' || data[i] == '\'') {
+		return advance + 1, data[:advance+1], nil
+	}
+	return consumeURL(data, advance+1, atEOF)
+}
+
+// consumeURL consumes the remainder of an unquoted url starting at i.
+func consumeURL(data []byte, i int, atEOF bool) (advance int, token []byte, err error) {
+	for i < len(data) {
+		switch data[i] {
+		case ')':
+			return i + 1, data[:i+1], nil
+		case '\\':
+			i += 2
 		default:
-			return i, data[:i+1], nil
+			i++
 		}
 	}
 	if atEOF {
@@ -296,41 +439,94 @@ func consumeCdcOrIdent(data []byte, atEOF bool) (advance int, token []byte, err
 	if len(data) >= 3 && string(data[:3]) == "-->" {
 		return 3, data[:3], nil
 	}
-	return consumeIdent(data, atEOF)
+	if !atEOF && len(data) < 4 {
+		return 0, nil, nil
+	}
+	if startsNumber(data) {
+		return consumeNumericOrUnit(data, atEOF)
+	}
+	if startsIdent(data) {
+		return consumeIdentLike(data, atEOF)
+	}
+	return 1, data[:1], nil
 }
 
 // TODO(jwall): handle partial matches when !atEOF
 func consumeOnePrefix(data []byte, expected []string, atEOF bool) (advance int, token []byte, err error) {
-	if atEOF {
-		return 0, nil, fmt.Errorf("Invalid Token expecting one of %v got %q", expected, data[:2])
-	}
 	for _, expect := range expected {
 		el := len(expect)
 		if len(data) >= el && string(data[:el]) == expect {
 			return el, data[:el], nil
 		}
 	}
-	return 0, nil, nil
+	if !atEOF && len(data) < 2 {
+		return 0, nil, nil
+	}
+	// Not a match so this is just a delimiter.
+	return 1, data[:1], nil
+}
+
+func consumeDigits(data []byte, i int) int {
+	for i < len(data) && isDigit(data[i]) {
+		i++
+	}
+	return i
+}
+
+// numericPrefix returns the length of the number at the start of data.
+// http://www.w3.org/TR/css-syntax-3/#consume-a-number
+func numericPrefix(data []byte) int {
+	i := 0
+	if i < len(data) && (data[i] == '+' || data[i] == '-') {
+		i++
+	}
+	i = consumeDigits(data, i)
+	if i+1 < len(data) && data[i] == '.' && isDigit(data[i+1]) {
+		i = consumeDigits(data, i+1)
+	}
+	if i+1 < len(data) && (data[i] == 'e' || data[i] == 'E') {
+		if isDigit(data[i+1]) {
+			i = consumeDigits(data, i+1)
+		} else if i+2 < len(data) && (data[i+1] == '+' || data[i+1] == '-') && isDigit(data[i+2]) {
+			i = consumeDigits(data, i+2)
+		}
+	}
+	return i
 }
 
 func consumeNumericOrUnit(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	for i, c := range data {
-		switch c {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		case '%':
-			return i + 1, data[:i+1], nil
-		case ';':
-			return i, data[:i], nil
-		//case ' ', '\n', '\r', '\t', '\f':
-		//	return i, data[:i], nil
-		default:
-			advance, token, err = consumeIdent(data[i:], atEOF)
-			if advance > 0 {
-				advance += i
-				token = data[:len(token)+i]
-				return
-			}
+	// We need a little lookahead to see past exponents and units.
+	if !atEOF && numericPrefix(data)+3 >= len(data) {
+		return 0, nil, nil
+	}
+	i := numericPrefix(data)
+	if i < len(data) && data[i] == '%' {
+		return i + 1, data[:i+1], nil
+	}
+	if startsIdent(data[i:]) {
+		n, _, err := consumeIdent(data[i:], atEOF)
+		if err != nil {
+			return 0, nil, err
 		}
+		if n == 0 {
+			return 0, nil, nil
+		}
+		i += n
+	}
+	return i, data[:i], nil
+}
+
+// SplitNumeric splits the String of a Number, Percentage or Dimension
+// token into its numeric part and its unit. The unit of a Percentage is
+// "%".
+func SplitNumeric(s string) (number, unit string) {
+	i := numericPrefix([]byte(s))
+	return s[:i], s[i:]
+}
+
+func consumeComment(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := strings.Index(string(data[2:]), "*/"); i >= 0 {
+		return i + 4, data[:i+4], nil
 	}
 	if atEOF {
 		return len(data), data, nil
@@ -338,6 +534,10 @@ func consumeNumericOrUnit(data []byte, atEOF bool) (advance int, token []byte, e
 	return 0, nil, nil
 }
 
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\t' || c == '\r' || c == '\f'
+}
+
 func consumeQuoted(data []byte, atEOF bool) (advance int, token []byte, err error) {
 	next, tok, err := consumeQuotedBy(data[0])(data[1:], atEOF)
 	if err != nil {
@@ -346,48 +546,31 @@ func consumeQuoted(data []byte, atEOF bool) (advance int, token []byte, err erro
 	return next + 1, append(data[:1], tok...), nil
 }
 
+// consumeQuotedBy consumes the rest of a string ended by q. Escapes are
+// kept as they are in the source so Token.Text round trips; Unescape
+// resolves them.
 func consumeQuotedBy(q byte) bufio.SplitFunc {
 	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		for i, c := range data {
-			if c == q {
+		for i := 0; i < len(data); i++ {
+			switch data[i] {
+			case q:
 				return i + 1, data[:i+1], nil
-			}
-			if c == '\\' {
-				if len(data) > i+1 {
-					//log.Printf("consuming escaped %q", data[i:])
-					next, _, err, isHex := consumeEscaped(data[i:], atEOF)
-					//log.Printf("next: %d, tok: %q, err: %v", next, tok, err)
-					if err != nil {
-						if len(data) > next+1 && data[i+1] == '\n' {
-							next, tok, err := consumeQuotedBy(q)(data[i+2:], atEOF)
-							if err != nil {
-								return 0, nil, err
-							}
-							escaped := append(append(data[:i], data[i+1]), tok...)
-							next = next + i + 2
-							return next, escaped, nil
-						}
-						return 0, nil, err
+			case '\\':
+				if i+1 == len(data) {
+					if atEOF {
+						return len(data), data, nil
 					}
-					if next > 0 {
-						next, tok, err := consumeQuotedBy(q)(data[i+2:], atEOF)
-						if err != nil {
-							return 0, nil, err
-						}
-						escaped := data[:i]
-						if isHex {
-							escaped = append(escaped, c)
-						}
-						escaped = append(append(escaped, data[i+1]), tok...)
-						next = next + i + 2
-						return next, escaped, nil
-						//return next + i, append(data[:i], tok...), nil
-					}
-				} else {
 					return 0, nil, nil
 				}
+				// Skip the escaped character so an escaped quote doesn't
+				// end the string.
+				i++
 			}
 		}
+		if atEOF {
+			// An unterminated string ends at EOF.
+			return len(data), data, nil
+		}
 		return 0, nil, nil
 	}
 }
@@ -398,7 +581,7 @@ func splitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
 		return 0, nil, nil
 	}
 	switch data[0] {
-	case ':', ';', '{', '}', '(', ')', '[', ']':
+	case ':', ';', '{', '}', '(', ')', '[', ']', ',':
 		return 1, data[:1], nil
 	case '"', '\'':
 		return consumeQuoted(data, atEOF)
@@ -410,11 +593,39 @@ func splitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
 		return consumeCdcOrIdent(data, atEOF)
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		return consumeNumericOrUnit(data, atEOF)
+	case '+', '.':
+		if !atEOF && len(data) < 3 {
+			return 0, nil, nil
+		}
+		if startsNumber(data) {
+			return consumeNumericOrUnit(data, atEOF)
+		}
+		return 1, data[:1], nil
+	case '/':
+		if len(data) < 2 && !atEOF {
+			return 0, nil, nil
+		}
+		if len(data) > 1 && data[1] == '*' {
+			return consumeComment(data, atEOF)
+		}
+		return 1, data[:1], nil
 	case '|':
 		return consumeOnePrefix(data, []string{"|=", "||"}, atEOF)
 	case '~':
 		return consumeOnePrefix(data, []string{"~="}, atEOF)
+	case '^':
+		return consumeOnePrefix(data, []string{"^="}, atEOF)
+	case '$':
+		return consumeOnePrefix(data, []string{"$="}, atEOF)
+	case '*':
+		return consumeOnePrefix(data, []string{"*="}, atEOF)
 	case '#', '@':
+		if len(data) < 3 && !atEOF {
+			return 0, nil, nil
+		}
+		if !startsIdent(data[1:]) && !(data[0] == '#' && len(data) > 1 && isNameChar(data[1])) {
+			return 1, data[:1], nil
+		}
 		advance, token, err = consumeIdent(data[1:], atEOF)
 		if advance > 0 {
 			advance++
@@ -422,19 +633,28 @@ func splitFunc(data []byte, atEOF bool) (advance int, token []byte, err error) {
 			return
 		}
 	case 'u', 'U':
-		log.Printf("Consuming Unicode %q", data)
+		// The longest unicode range is U+XXXXXX-XXXXXX.
+		if !atEOF && len(data) < 16 {
+			return 0, nil, nil
+		}
 		if n, tok, err := consumeUnicodeRange(data, atEOF); tok != nil {
-			log.Printf("Consumed Unicode Range %q", tok)
 			return n, tok, err
-		} else {
-			// Ident?
 		}
+		return consumeIdentLike(data, atEOF)
 	case '\\':
-		fallthrough
+		if len(data) < 2 && !atEOF {
+			return 0, nil, nil
+		}
+		if !startsIdent(data) {
+			return 1, data[:1], nil
+		}
+		return consumeIdentLike(data, atEOF)
 	default:
-		return consumeIdent(data, atEOF)
-		// TODO(jwall): return consumeURI
-
+		if isNameStart(data[0]) {
+			return consumeIdentLike(data, atEOF)
+		}
+		_, n := utf8.DecodeRune(data)
+		return n, data[:n], nil
 	}
 	if !atEOF {
 		return 0, nil, nil
@@ -462,6 +682,8 @@ func (t *Tokenizer) Next() (*Token, error) {
 			return &Token{Position: t.p.Position(), Type: LBracket}, nil
 		case "]":
 			return &Token{Position: t.p.Position(), Type: RBracket}, nil
+		case ",":
+			return &Token{Position: t.p.Position(), Type: Comma, String: tok}, nil
 		case "~=":
 			return &Token{Position: t.p.Position(), Type: Includes}, nil
 		case "^=":
@@ -474,82 +696,108 @@ func (t *Tokenizer) Next() (*Token, error) {
 			return &Token{Position: t.p.Position(), Type: Dashmatch}, nil
 		case "||":
 			return &Token{Position: t.p.Position(), Type: Column}, nil
-		case "<":
-			return &Token{Position: t.p.Position(), Type: Delim, String: tok}, nil
 		case "<!--":
 			return &Token{Position: t.p.Position(), Type: CDO, String: tok}, nil
 		case "-->":
 			return &Token{Position: t.p.Position(), Type: CDC, String: tok}, nil
 		default:
-			switch tok[0] {
-			case '@':
+			switch {
+			case strings.HasPrefix(tok, "/*"):
+				return &Token{Position: t.p.Position(), Type: Comment, String: tok}, nil
+			case tok[0] == '@' && len(tok) > 1:
 				return &Token{Position: t.p.Position(), Type: AtKeyword, String: tok}, nil
-			case '#':
+			case tok[0] == '#' && len(tok) > 1:
 				return &Token{Position: t.p.Position(), Type: Hash, String: tok}, nil
-			case '\n', '\t', '\r', '\f', ' ':
+			case isWhitespace(tok[0]):
 				return &Token{Position: t.p.Position(), Type: WS, String: tok}, nil
-			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			case startsNumber([]byte(tok)):
 				return handleNumberPrefixToken(t.p.Position(), tok)
-			case 'u':
-				log.Printf("Encountered possible unicode token %q", tok)
-				// TODO(jwall): return handleIdentUnicodeOrUrlPrefixToken(tok)
-				return handleUnicodeToken(t.p.Position(), tok)
-			case '"', '\'':
+			case tok[0] == '"' || tok[0] == '\'':
 				return &Token{Position: t.p.Position(), Type: String, String: tok}, nil
-			default:
+			case len(tok) > 1 && (tok[0] == 'u' || tok[0] == 'U') && isUnicodeRange(tok):
+				return &Token{Position: t.p.Position(), Type: UnicodeRange, String: tok}, nil
+			case len(tok) > 4 && strings.EqualFold(tok[:4], "url(") && tok[len(tok)-1] != '(':
+				if tok[len(tok)-1] != ')' {
+					return &Token{Position: t.p.Position(), Type: BadUri, String: tok}, nil
+				}
+				return &Token{Position: t.p.Position(), Type: Uri, String: tok}, nil
+			case tok[len(tok)-1] == '(':
+				return &Token{Position: t.p.Position(), Type: Function, String: tok}, nil
+			case startsIdent([]byte(tok)):
 				return &Token{Position: t.p.Position(), Type: Ident, String: tok}, nil
+			default:
+				return &Token{Position: t.p.Position(), Type: Delim, String: tok}, nil
 			}
 		}
 	}
 	return nil, t.p.Err()
 }
 
-func handleUnicodeToken(p Position, tok string) (*Token, error) {
+func isUnicodeRange(tok string) bool {
 	dashCount := 0
-	nonHexChar := 0
-	for _, r := range tok {
+	for _, r := range tok[1:] {
 		switch {
 		case r == '-':
 			dashCount += 1
-		case r == 'u' || r == 'U':
+		case r == '+' || r == '?':
 		case '0' <= r && r <= '9':
 		case 'A' <= r && r <= 'F':
 		case 'a' <= r && r <= 'f':
 		default:
-			nonHexChar += 1
+			return false
 		}
 	}
-	log.Printf("dashCount = %d", dashCount)
-	log.Printf("nonHexChar = %d", nonHexChar)
-	if dashCount <= 1 && nonHexChar == 0 {
-		log.Printf("Emiting unicode token: %q", tok)
-		return &Token{Position: p, Type: UnicodeRange, String: tok}, nil
-	}
-	return nil, nil
+	return dashCount <= 1
 }
 
 func handleNumberPrefixToken(p Position, tok string) (*Token, error) {
-	if strings.HasSuffix(tok, "%") {
+	_, unit := SplitNumeric(tok)
+	switch unit {
+	case "":
+		return &Token{Position: p, Type: Number, String: tok}, nil
+	case "%":
 		return &Token{Position: p, Type: Percentage, String: tok}, nil
 	}
-	eCount := 0
-	for _, r := range tok {
-		switch r {
-		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-		case 'e', 'E':
-			// This might be a number using scientific notation.
-			eCount += 1
-		default:
-			switch tok[len(tok)-1] {
-			case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
-				// It's an Ident token if there is non numerical in the middle.
-				return &Token{Position: p, Type: Ident, String: tok}, nil
+	return &Token{Position: p, Type: Dimension, String: tok}, nil
+}
+
+// Unescape resolves the css escapes in s.
+// http://www.w3.org/TR/css-syntax-3/#consume-an-escaped-code-point
+func Unescape(s string) string {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s
+	}
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 == len(s) {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		switch {
+		case s[i] == '\n':
+			// An escaped newline is removed entirely.
+		case isHexDigit(s[i]):
+			j := i
+			for j < len(s) && j-i < 6 && isHexDigit(s[j]) {
+				j++
+			}
+			r, _ := strconv.ParseUint(s[i:j], 16, 32)
+			if r == 0 || r > unicode.MaxRune || (0xD800 <= r && r <= 0xDFFF) {
+				r = utf8.RuneError
 			}
-			return &Token{Position: p, Type: Dimension, String: tok}, nil
+			buf.WriteRune(rune(r))
+			// A single whitespace after a hex escape belongs to the escape.
+			if j < len(s) && isWhitespace(s[j]) {
+				j++
+			}
+			i = j - 1
+		default:
+			_, n := utf8.DecodeRuneInString(s[i:])
+			buf.WriteString(s[i : i+n])
+			i += n - 1
 		}
 	}
-	if eCount <= 1 {
-		return &Token{Position: p, Type: Number, String: tok}, nil
-	}
-	return &Token{Position: p, Type: Ident, String: tok}, nil
+	return buf.String()
 }
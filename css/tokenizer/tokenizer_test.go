@@ -64,9 +64,9 @@ var simpleTokenTests = []struct {
 	{`\"fo`, []Token{Token{Type: Ident, String: `\"fo`}}},
 	// Strings
 	{`"foo"`, []Token{Token{Type: String, String: `"foo"`}}},
-	{"\"fo\\\n\"", []Token{Token{Type: String, String: "\"fo\n\""}}},
-	{"'fo\\\n'", []Token{Token{Type: String, String: "'fo\n'"}}},
-	{"\"\\\"\"", []Token{Token{Type: String, String: "\"\"\""}}},
+	{"\"fo\\\n\"", []Token{Token{Type: String, String: "\"fo\\\n\""}}},
+	{"'fo\\\n'", []Token{Token{Type: String, String: "'fo\\\n'"}}},
+	{"\"\\\"\"", []Token{Token{Type: String, String: "\"\\\"\""}}},
 	// HexDigit cases
 	{`"fo\91f6o"`, []Token{Token{Type: String, String: `"fo\91f6o"`}}},
 	{`"t\91f6t"`, []Token{Token{Type: String, String: `"t\91f6t"`}}},
@@ -80,9 +80,39 @@ var simpleTokenTests = []struct {
 	//{`\\foo`, []Token{Token{Type: Ident, String: `\\foo`}}},
 	//{`\91f6td`, []Token{Token{Type: Ident, String: `\91f6td`}}},
 	//{`td\91f6dt`, []Token{Token{Type: Ident, String: `td\91f6dt`}}},
-	// TODO(jwall): Comment
-	// TODO(jwall): Function
-	// TODO(jwall): URL
+	// Numbers
+	{`1.5em`, []Token{Token{Type: Dimension, String: `1.5em`}}},
+	{`-.5`, []Token{Token{Type: Number, String: `-.5`}}},
+	{`+2e-3px`, []Token{Token{Type: Dimension, String: `+2e-3px`}}},
+	{`12.5%`, []Token{Token{Type: Percentage, String: `12.5%`}}},
+	// Delimiters
+	{`,`, []Token{Token{Type: Comma, String: `,`}}},
+	{`/`, []Token{Token{Type: Delim, String: `/`}}},
+	{`!important`, []Token{Token{Type: Delim, String: `!`},
+		Token{Type: Ident, String: `important`}}},
+	{`1px - 2px`, []Token{Token{Type: Dimension, String: `1px`},
+		Token{Type: WS, String: ` `}, Token{Type: Delim, String: `-`},
+		Token{Type: WS, String: ` `}, Token{Type: Dimension, String: `2px`}}},
+	// Idents followed by other tokens
+	{`a,b`, []Token{Token{Type: Ident, String: `a`}, Token{Type: Comma, String: `,`},
+		Token{Type: Ident, String: `b`}}},
+	{`underline;`, []Token{Token{Type: Ident, String: `underline`},
+		Token{Type: Semicolon}}},
+	{`#fff)`, []Token{Token{Type: Hash, String: `#fff`}, Token{Type: RParen}}},
+	// Comment
+	{`/* foo */a`, []Token{Token{Type: Comment, String: `/* foo */`},
+		Token{Type: Ident, String: `a`}}},
+	// Function
+	{`rgb(1,2)`, []Token{Token{Type: Function, String: `rgb(`},
+		Token{Type: Number, String: `1`}, Token{Type: Comma, String: `,`},
+		Token{Type: Number, String: `2`}, Token{Type: RParen}}},
+	// URL
+	{`url(foo.png)`, []Token{Token{Type: Uri, String: `url(foo.png)`}}},
+	{`url( "foo.png")`, []Token{Token{Type: Function, String: `url(`},
+		Token{Type: WS, String: ` `}, Token{Type: String, String: `"foo.png"`},
+		Token{Type: RParen}}},
+	{`url(foo`, []Token{Token{Type: BadUri, String: `url(foo`}}},
+	{`U+0025-00FF`, []Token{Token{Type: UnicodeRange, String: `U+0025-00FF`}}},
 }
 
 func testStream(t *testing.T, input string, out []Token) {
@@ -122,6 +152,27 @@ func TestTokenizer(t *testing.T) {
 	}
 }
 
+func TestPosition(t *testing.T) {
+	tk := New(strings.NewReader("a{\n  color: red;\n\tmargin:0}"))
+	var got []string
+	for {
+		tok, err := tk.Next()
+		if err != nil {
+			t.Fatalf("Error tokenizing: %s", err)
+		}
+		if tok == nil {
+			break
+		}
+		if tok.Type == Ident {
+			got = append(got, fmt.Sprintf("%s %d:%d", tok.String, tok.Line, tok.Column))
+		}
+	}
+	expected := []string{"a 1:1", "color 2:3", "red 2:10", "margin 3:2"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected positions %q got %q", expected, got)
+	}
+}
+
 //func TestConsumeUnicode(t *testing.T) {
 //	input := []byte(`\91f6too`)
 //	n, tok, _ := consumeUnicodeRange(input, false)
@@ -222,3 +273,39 @@ func TestPreprocess(t *testing.T) {
 		}
 	}
 }
+
+func TestUnescape(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+	}{
+		{`foo`, `foo`},
+		{`\66oo`, `foo`},
+		{`\000066 oo`, `foo`},
+		{`\"fo`, `"fo`},
+		{`j\61vascript`, `javascript`},
+		{`\0`, "\uFFFD"},
+	}
+	for _, c := range cases {
+		if got := Unescape(c.input); got != c.expected {
+			t.Errorf("Expected %q got %q", c.expected, got)
+		}
+	}
+}
+
+func TestSplitNumeric(t *testing.T) {
+	cases := []struct {
+		input, number, unit string
+	}{
+		{`12px`, `12`, `px`},
+		{`-1.5e2em`, `-1.5e2`, `em`},
+		{`50%`, `50`, `%`},
+		{`3`, `3`, ``},
+	}
+	for _, c := range cases {
+		number, unit := SplitNumeric(c.input)
+		if number != c.number || unit != c.unit {
+			t.Errorf("Expected (%q, %q) got (%q, %q)", c.number, c.unit, number, unit)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+type nodeType int
+
+const (
+	keywordNode  nodeType = iota // an identifier like auto
+	typeNode                     // a <type> like <length>
+	literalNode                  // a literal , or /
+	sequenceNode                 // children in order
+	allNode                      // a && b: every child in any order
+	anyNode                      // a || b: one or more children in any order
+	oneOfNode                    // a | b: exactly one child
+	repeatNode                   // a child repeated min to max times
+)
+
+// node is a compiled value definition.
+// http://www.w3.org/TR/css-values-3/#value-defs
+type node struct {
+	typ      nodeType
+	value    string
+	children []*node
+	min, max int
+	// comma is true if repetitions are comma separated like <color>#.
+	comma bool
+}
+
+// grammar matches ValueLists against value definitions. Named types are
+// either predicates on a single ComponentValue or other definitions.
+type grammar struct {
+	predicates map[string]func(css.ComponentValue) bool
+	types      map[string]string
+	compiled   map[string]*node
+}
+
+// compile parses a value definition like "<length> | auto".
+func compile(def string) (*node, error) {
+	p := &defParser{toks: lexDef(def)}
+	n, err := p.parseOneOf()
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(p.toks) {
+		return nil, fmt.Errorf("Unexpected %q in value definition %q", p.toks[p.i], def)
+	}
+	return n, nil
+}
+
+// lexDef splits a value definition into its tokens.
+func lexDef(def string) []string {
+	var toks []string
+	for i := 0; i < len(def); {
+		c := def[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '<':
+			j := strings.IndexByte(def[i:], '>')
+			if j < 0 {
+				j = len(def) - i - 1
+			}
+			toks = append(toks, def[i:i+j+1])
+			i += j + 1
+		case c == '{':
+			j := strings.IndexByte(def[i:], '}')
+			if j < 0 {
+				j = len(def) - i - 1
+			}
+			toks = append(toks, def[i:i+j+1])
+			i += j + 1
+		case strings.HasPrefix(def[i:], "||"), strings.HasPrefix(def[i:], "&&"):
+			toks = append(toks, def[i:i+2])
+			i += 2
+		case strings.IndexByte("[]|?*+#,/", c) >= 0:
+			toks = append(toks, def[i:i+1])
+			i++
+		default:
+			j := i
+			for j < len(def) && strings.IndexByte(" \t\n<{[]|?*+#,/&", def[j]) < 0 {
+				j++
+			}
+			toks = append(toks, def[i:j])
+			i = j
+		}
+	}
+	return toks
+}
+
+type defParser struct {
+	toks []string
+	i    int
+}
+
+func (p *defParser) peek() string {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return ""
+}
+
+// parseOneOf, parseAny, parseAll and parseSequence parse the combinators
+// from the loosest to the tightest binding.
+func (p *defParser) parseOneOf() (*node, error) {
+	return p.parseJoined("|", oneOfNode, p.parseAny)
+}
+
+func (p *defParser) parseAny() (*node, error) {
+	return p.parseJoined("||", anyNode, p.parseAll)
+}
+
+func (p *defParser) parseAll() (*node, error) {
+	return p.parseJoined("&&", allNode, p.parseSequence)
+}
+
+func (p *defParser) parseJoined(sep string, typ nodeType, next func() (*node, error)) (*node, error) {
+	n, err := next()
+	if err != nil {
+		return nil, err
+	}
+	children := []*node{n}
+	for p.peek() == sep {
+		p.i++
+		if n, err = next(); err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &node{typ: typ, children: children}, nil
+}
+
+func (p *defParser) parseSequence() (*node, error) {
+	var children []*node
+	for {
+		switch p.peek() {
+		case "", "]", "|", "||", "&&":
+			if len(children) == 0 {
+				return nil, fmt.Errorf("Empty value definition at %q", p.peek())
+			}
+			if len(children) == 1 {
+				return children[0], nil
+			}
+			return &node{typ: sequenceNode, children: children}, nil
+		}
+		n, err := p.parseMultiplied()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+}
+
+func (p *defParser) parseMultiplied() (*node, error) {
+	n, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case tok == "?":
+			n = &node{typ: repeatNode, children: []*node{n}, min: 0, max: 1}
+		case tok == "*":
+			n = &node{typ: repeatNode, children: []*node{n}, min: 0, max: -1}
+		case tok == "+":
+			n = &node{typ: repeatNode, children: []*node{n}, min: 1, max: -1}
+		case tok == "#":
+			n = &node{typ: repeatNode, children: []*node{n}, min: 1, max: -1, comma: true}
+		case strings.HasPrefix(tok, "{"):
+			min, max, err := parseRange(tok)
+			if err != nil {
+				return nil, err
+			}
+			if n.typ == repeatNode && n.comma && n.min == 1 && n.max == -1 {
+				// #{A,B} is a comma separated list of A to B items.
+				n.min, n.max = min, max
+			} else {
+				n = &node{typ: repeatNode, children: []*node{n}, min: min, max: max}
+			}
+		default:
+			return n, nil
+		}
+		p.i++
+	}
+}
+
+// parseRange parses a {A}, {A,} or {A,B} multiplier.
+func parseRange(tok string) (min, max int, err error) {
+	parts := strings.Split(strings.Trim(tok, "{}"), ",")
+	if min, err = strconv.Atoi(strings.TrimSpace(parts[0])); err != nil {
+		return 0, 0, fmt.Errorf("Invalid multiplier %s", tok)
+	}
+	switch {
+	case len(parts) == 1:
+		return min, min, nil
+	case strings.TrimSpace(parts[1]) == "":
+		return min, -1, nil
+	}
+	if max, err = strconv.Atoi(strings.TrimSpace(parts[1])); err != nil {
+		return 0, 0, fmt.Errorf("Invalid multiplier %s", tok)
+	}
+	return min, max, nil
+}
+
+func (p *defParser) parseTerm() (*node, error) {
+	tok := p.peek()
+	p.i++
+	switch {
+	case tok == "[":
+		n, err := p.parseOneOf()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != "]" {
+			return nil, fmt.Errorf("Unclosed [ in value definition")
+		}
+		p.i++
+		return n, nil
+	case strings.HasPrefix(tok, "<") && strings.HasSuffix(tok, ">"):
+		return &node{typ: typeNode, value: tok[1 : len(tok)-1]}, nil
+	case tok == "," || tok == "/":
+		return &node{typ: literalNode, value: tok}, nil
+	case tok == "" || strings.ContainsAny(tok[:1], "]|?*+#{&"):
+		return nil, fmt.Errorf("Unexpected %q in value definition", tok)
+	}
+	return &node{typ: keywordNode, value: tok}, nil
+}
+
+// resolve returns the compiled definition of a named type.
+func (g *grammar) resolve(name string) (*node, error) {
+	if n, ok := g.compiled[name]; ok {
+		return n, nil
+	}
+	def, ok := g.types[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown type <%s>", name)
+	}
+	n, err := compile(def)
+	if err != nil {
+		return nil, err
+	}
+	g.compiled[name] = n
+	return n, nil
+}
+
+// matches returns true if n matches all of vl.
+func (g *grammar) matches(n *node, vl css.ValueList) bool {
+	for _, end := range g.match(n, vl, 0) {
+		if end == len(vl) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns every index n can finish matching vl at when it starts
+// matching at i.
+func (g *grammar) match(n *node, vl css.ValueList, i int) []int {
+	switch n.typ {
+	case keywordNode:
+		if i < len(vl) && vl[i].IsIdent(n.value) {
+			return []int{i + 1}
+		}
+	case literalNode:
+		if i < len(vl) && vl[i].Value == n.value &&
+			(vl[i].Type == css.CommaValue || vl[i].Type == css.SlashValue || vl[i].Type == css.DelimValue) {
+			return []int{i + 1}
+		}
+	case typeNode:
+		if pred, ok := g.predicates[n.value]; ok {
+			if i < len(vl) && pred(vl[i]) {
+				return []int{i + 1}
+			}
+			return nil
+		}
+		def, err := g.resolve(n.value)
+		if err != nil {
+			return nil
+		}
+		return g.match(def, vl, i)
+	case sequenceNode:
+		ends := []int{i}
+		for _, c := range n.children {
+			var next []int
+			for _, e := range ends {
+				next = append(next, g.match(c, vl, e)...)
+			}
+			if ends = dedupe(next); len(ends) == 0 {
+				return nil
+			}
+		}
+		return ends
+	case oneOfNode:
+		var ends []int
+		for _, c := range n.children {
+			ends = append(ends, g.match(c, vl, i)...)
+		}
+		return dedupe(ends)
+	case allNode, anyNode:
+		return dedupe(g.matchUnordered(n, vl, i, make([]bool, len(n.children)), 0))
+	case repeatNode:
+		return dedupe(g.matchRepeat(n, vl, i, 0))
+	}
+	return nil
+}
+
+// matchUnordered matches the children of an && or || node that haven't
+// been used yet in any order.
+func (g *grammar) matchUnordered(n *node, vl css.ValueList, i int, used []bool, count int) []int {
+	var ends []int
+	if n.typ == anyNode && count > 0 || count == len(n.children) {
+		ends = append(ends, i)
+	}
+	for j, c := range n.children {
+		if used[j] {
+			continue
+		}
+		used[j] = true
+		for _, e := range g.match(c, vl, i) {
+			if e == i && n.typ == anyNode {
+				// Empty matches can't make progress in a || node.
+				continue
+			}
+			ends = append(ends, g.matchUnordered(n, vl, e, used, count+1)...)
+		}
+		used[j] = false
+	}
+	return ends
+}
+
+func (g *grammar) matchRepeat(n *node, vl css.ValueList, i, count int) []int {
+	var ends []int
+	if count >= n.min {
+		ends = append(ends, i)
+	}
+	if n.max >= 0 && count >= n.max {
+		return ends
+	}
+	start := i
+	if n.comma && count > 0 {
+		if i >= len(vl) || vl[i].Type != css.CommaValue {
+			return ends
+		}
+		start++
+	}
+	for _, e := range g.match(n.children[0], vl, start) {
+		if e > i {
+			ends = append(ends, g.matchRepeat(n, vl, e, count+1)...)
+		}
+	}
+	return ends
+}
+
+func dedupe(ends []int) []int {
+	if len(ends) < 2 {
+		return ends
+	}
+	sort.Ints(ends)
+	out := ends[:1]
+	for _, e := range ends[1:] {
+		if e != out[len(out)-1] {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -0,0 +1,446 @@
+package validate
+
+import (
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+var lengthUnits = map[string]bool{
+	"px": true, "em": true, "rem": true, "ex": true, "rex": true, "ch": true, "rch": true,
+	"cap": true, "rcap": true, "ic": true, "ric": true, "lh": true, "rlh": true,
+	"vw": true, "vh": true, "vi": true, "vb": true, "vmin": true, "vmax": true,
+	"svw": true, "svh": true, "lvw": true, "lvh": true, "dvw": true, "dvh": true,
+	"cqw": true, "cqh": true, "cqi": true, "cqb": true, "cqmin": true, "cqmax": true,
+	"cm": true, "mm": true, "q": true, "in": true, "pt": true, "pc": true,
+}
+
+func isMath(cv css.ComponentValue) bool {
+	if cv.Type != css.FunctionValue {
+		return false
+	}
+	switch strings.ToLower(cv.Value) {
+	case "calc", "min", "max", "clamp", "round", "mod", "rem", "abs", "sign":
+		return true
+	}
+	return false
+}
+
+func isFunction(names ...string) func(css.ComponentValue) bool {
+	return func(cv css.ComponentValue) bool {
+		if cv.Type != css.FunctionValue {
+			return false
+		}
+		for _, name := range names {
+			if strings.EqualFold(cv.Value, name) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func dimension(units ...string) func(css.ComponentValue) bool {
+	return func(cv css.ComponentValue) bool {
+		if isMath(cv) {
+			return true
+		}
+		if cv.Type != css.DimensionValue {
+			return false
+		}
+		for _, u := range units {
+			if cv.Unit == u {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func isLength(cv css.ComponentValue) bool {
+	return cv.Type == css.DimensionValue && lengthUnits[cv.Unit] ||
+		cv.Type == css.NumberValue && cv.Number == 0 || isMath(cv)
+}
+
+func isPercentage(cv css.ComponentValue) bool {
+	return cv.Type == css.PercentageValue || isMath(cv)
+}
+
+func isNumber(cv css.ComponentValue) bool {
+	return cv.Type == css.NumberValue || isMath(cv)
+}
+
+func isInteger(cv css.ComponentValue) bool {
+	return cv.Type == css.NumberValue && cv.Number == float64(int64(cv.Number)) || isMath(cv)
+}
+
+func isColor(cv css.ComponentValue) bool {
+	_, ok := cv.Color()
+	return ok || cv.Type == css.FunctionValue && strings.EqualFold(cv.Value, "color-mix") ||
+		cv.Type == css.FunctionValue && strings.EqualFold(cv.Value, "color")
+}
+
+var isGradient = isFunction("linear-gradient", "radial-gradient", "conic-gradient",
+	"repeating-linear-gradient", "repeating-radial-gradient", "repeating-conic-gradient",
+	"-webkit-linear-gradient", "-webkit-radial-gradient", "image-set", "-webkit-image-set",
+	"cross-fade", "element", "image")
+
+func isCustomIdent(cv css.ComponentValue) bool {
+	if cv.Type != css.IdentValue {
+		return false
+	}
+	switch strings.ToLower(cv.Value) {
+	case "inherit", "initial", "unset", "revert", "revert-layer", "default":
+		return false
+	}
+	return true
+}
+
+// predicates are the types matching a single ComponentValue.
+var predicates = map[string]func(css.ComponentValue) bool{
+	"length":     isLength,
+	"percentage": isPercentage,
+	"length-percentage": func(cv css.ComponentValue) bool {
+		return isLength(cv) || isPercentage(cv)
+	},
+	"number":       isNumber,
+	"integer":      isInteger,
+	"color":        isColor,
+	"url":          func(cv css.ComponentValue) bool { return cv.Type == css.URLValue },
+	"string":       func(cv css.ComponentValue) bool { return cv.Type == css.StringValue },
+	"image":        func(cv css.ComponentValue) bool { return cv.Type == css.URLValue || isGradient(cv) },
+	"time":         dimension("s", "ms"),
+	"angle":        dimension("deg", "rad", "grad", "turn"),
+	"resolution":   dimension("dpi", "dpcm", "dppx", "x"),
+	"flex":         dimension("fr"),
+	"custom-ident": isCustomIdent,
+	"easing-function": func(cv css.ComponentValue) bool {
+		return isFunction("cubic-bezier", "steps", "linear")(cv)
+	},
+	"transform-function": isFunction("matrix", "matrix3d", "translate", "translatex",
+		"translatey", "translatez", "translate3d", "scale", "scalex", "scaley", "scalez",
+		"scale3d", "rotate", "rotatex", "rotatey", "rotatez", "rotate3d", "skew", "skewx",
+		"skewy", "perspective"),
+	"filter-function": isFunction("blur", "brightness", "contrast", "drop-shadow",
+		"grayscale", "hue-rotate", "invert", "opacity", "saturate", "sepia"),
+	"basic-shape":      isFunction("inset", "circle", "ellipse", "polygon", "path", "rect", "xywh"),
+	"grid-function":    isFunction("repeat", "minmax", "fit-content"),
+	"size-function":    isFunction("fit-content", "anchor-size"),
+	"content-function": isFunction("counter", "counters", "attr"),
+	"line-names": func(cv css.ComponentValue) bool {
+		return cv.Type == css.BlockValue && cv.Value == "["
+	},
+}
+
+// types are the named value definitions used by the property grammars.
+var types = map[string]string{
+	"line-style":        "none | hidden | dotted | dashed | solid | double | groove | ridge | inset | outset",
+	"line-width":        "<length> | thin | medium | thick",
+	"position":          "[ left | center | right | top | bottom | <length-percentage> ]{1,4}",
+	"bg-size":           "[ <length-percentage> | auto ]{1,2} | cover | contain",
+	"repeat-style":      "repeat-x | repeat-y | [ repeat | space | round | no-repeat ]{1,2}",
+	"box":               "border-box | padding-box | content-box",
+	"shadow":            "inset || <length>{2,4} || <color>",
+	"generic-family":    "serif | sans-serif | cursive | fantasy | monospace | system-ui | ui-serif | ui-sans-serif | ui-monospace | ui-rounded | math | emoji | fangsong",
+	"family-name":       "<string> | <custom-ident>+",
+	"font-size-keyword": "xx-small | x-small | small | medium | large | x-large | xx-large | xxx-large | larger | smaller",
+	"font-stretch-keyword": "ultra-condensed | extra-condensed | condensed | semi-condensed | normal | " +
+		"semi-expanded | expanded | extra-expanded | ultra-expanded",
+	"size":   "<length-percentage> | auto | min-content | max-content | fit-content | stretch | <size-function>",
+	"easing": "linear | ease | ease-in | ease-out | ease-in-out | step-start | step-end | <easing-function>",
+	"single-transition": "[ none | all | <custom-ident> ] || <time> || <easing> || <time> || " +
+		"normal | allow-discrete",
+	"single-animation": "<time> || <easing> || <time> || [ infinite | <number> ] || " +
+		"[ normal | reverse | alternate | alternate-reverse ] || [ none | forwards | backwards | both ] || " +
+		"[ running | paused ] || [ none | <custom-ident> | <string> ]",
+	"flex-direction":       "row | row-reverse | column | column-reverse",
+	"flex-wrap":            "nowrap | wrap | wrap-reverse",
+	"self-position":        "center | start | end | self-start | self-end | flex-start | flex-end",
+	"content-distribution": "space-between | space-around | space-evenly | stretch",
+	"track-size":           "<length-percentage> | <flex> | auto | min-content | max-content | <grid-function>",
+	"grid-line": "auto | span && [ <integer> || <custom-ident> ] | <integer> && <custom-ident>? | " +
+		"<custom-ident>",
+	"break": "auto | avoid | always | all | avoid-page | page | left | right | recto | verso | " +
+		"avoid-column | column | avoid-region | region",
+	"cursor-keyword": "auto | default | none | context-menu | help | pointer | progress | wait | cell | " +
+		"crosshair | text | vertical-text | alias | copy | move | no-drop | not-allowed | grab | grabbing | " +
+		"e-resize | n-resize | ne-resize | nw-resize | s-resize | se-resize | sw-resize | w-resize | " +
+		"ew-resize | ns-resize | nesw-resize | nwse-resize | col-resize | row-resize | all-scroll | " +
+		"zoom-in | zoom-out",
+	"paint": "none | <color> | <url> [ none | <color> ]? | context-fill | context-stroke",
+	"blend-mode": "normal | multiply | screen | overlay | darken | lighten | color-dodge | color-burn | " +
+		"hard-light | soft-light | difference | exclusion | hue | saturation | color | luminosity",
+}
+
+// properties are the value definitions of the known properties. The
+// shorthands css.Declaration.Expand supports are validated by expanding
+// them instead.
+var properties = map[string]string{
+	"color":                 "<color>",
+	"background-color":      "<color>",
+	"background-image":      "[ <image> | none ]#",
+	"background-position":   "<position>#",
+	"background-position-x": "[ left | center | right | <length-percentage> ]#",
+	"background-position-y": "[ top | center | bottom | <length-percentage> ]#",
+	"background-size":       "<bg-size>#",
+	"background-repeat":     "<repeat-style>#",
+	"background-attachment": "[ scroll | fixed | local ]#",
+	"background-origin":     "<box>#",
+	"background-clip":       "[ <box> | text ]#",
+	"background-blend-mode": "<blend-mode>#",
+
+	"margin-top": "<length-percentage> | auto", "margin-right": "<length-percentage> | auto",
+	"margin-bottom": "<length-percentage> | auto", "margin-left": "<length-percentage> | auto",
+	"margin-block": "[ <length-percentage> | auto ]{1,2}", "margin-inline": "[ <length-percentage> | auto ]{1,2}",
+	"margin-block-start": "<length-percentage> | auto", "margin-block-end": "<length-percentage> | auto",
+	"margin-inline-start": "<length-percentage> | auto", "margin-inline-end": "<length-percentage> | auto",
+	"padding-top": "<length-percentage>", "padding-right": "<length-percentage>",
+	"padding-bottom": "<length-percentage>", "padding-left": "<length-percentage>",
+	"padding-block": "<length-percentage>{1,2}", "padding-inline": "<length-percentage>{1,2}",
+	"padding-block-start": "<length-percentage>", "padding-block-end": "<length-percentage>",
+	"padding-inline-start": "<length-percentage>", "padding-inline-end": "<length-percentage>",
+	"inset-block": "[ <length-percentage> | auto ]{1,2}", "inset-inline": "[ <length-percentage> | auto ]{1,2}",
+	"inset-block-start": "<length-percentage> | auto", "inset-block-end": "<length-percentage> | auto",
+	"inset-inline-start": "<length-percentage> | auto", "inset-inline-end": "<length-percentage> | auto",
+
+	"border-top-width": "<line-width>", "border-right-width": "<line-width>",
+	"border-bottom-width": "<line-width>", "border-left-width": "<line-width>",
+	"border-top-style": "<line-style>", "border-right-style": "<line-style>",
+	"border-bottom-style": "<line-style>", "border-left-style": "<line-style>",
+	"border-top-color": "<color>", "border-right-color": "<color>",
+	"border-bottom-color": "<color>", "border-left-color": "<color>",
+	"border-top-left-radius": "<length-percentage>{1,2}", "border-top-right-radius": "<length-percentage>{1,2}",
+	"border-bottom-right-radius": "<length-percentage>{1,2}", "border-bottom-left-radius": "<length-percentage>{1,2}",
+	"border-block": "<line-width> || <line-style> || <color>", "border-inline": "<line-width> || <line-style> || <color>",
+	"border-block-start": "<line-width> || <line-style> || <color>", "border-block-end": "<line-width> || <line-style> || <color>",
+	"border-inline-start": "<line-width> || <line-style> || <color>", "border-inline-end": "<line-width> || <line-style> || <color>",
+	"border-block-width": "<line-width>{1,2}", "border-inline-width": "<line-width>{1,2}",
+	"border-block-start-width": "<line-width>", "border-block-end-width": "<line-width>",
+	"border-inline-start-width": "<line-width>", "border-inline-end-width": "<line-width>",
+	"border-block-style": "<line-style>{1,2}", "border-inline-style": "<line-style>{1,2}",
+	"border-block-start-style": "<line-style>", "border-block-end-style": "<line-style>",
+	"border-inline-start-style": "<line-style>", "border-inline-end-style": "<line-style>",
+	"border-block-color": "<color>{1,2}", "border-inline-color": "<color>{1,2}",
+	"border-block-start-color": "<color>", "border-block-end-color": "<color>",
+	"border-inline-start-color": "<color>", "border-inline-end-color": "<color>",
+	"border-start-start-radius": "<length-percentage>{1,2}", "border-start-end-radius": "<length-percentage>{1,2}",
+	"border-end-start-radius": "<length-percentage>{1,2}", "border-end-end-radius": "<length-percentage>{1,2}",
+	"border-collapse": "collapse | separate",
+	"border-spacing":  "<length>{1,2}",
+	"border-image":    "none | <image> || <number>{1,4} || [ stretch | repeat | round | space ]{1,2}",
+	"outline-width":   "<line-width>",
+	"outline-style":   "auto | <line-style>",
+	"outline-color":   "<color> | invert",
+	"outline-offset":  "<length>",
+
+	"display": "[ block | inline | run-in ] || [ flow | flow-root | table | flex | grid | ruby ] || list-item | " +
+		"inline-block | inline-table | inline-flex | inline-grid | contents | none | table-row-group | " +
+		"table-header-group | table-footer-group | table-row | table-cell | table-column-group | " +
+		"table-column | table-caption | ruby-base | ruby-text | ruby-base-container | ruby-text-container",
+	"position":    "static | relative | absolute | fixed | sticky",
+	"top":         "<length-percentage> | auto",
+	"right":       "<length-percentage> | auto",
+	"bottom":      "<length-percentage> | auto",
+	"left":        "<length-percentage> | auto",
+	"z-index":     "auto | <integer>",
+	"float":       "left | right | none | inline-start | inline-end",
+	"clear":       "none | left | right | both | inline-start | inline-end",
+	"box-sizing":  "content-box | border-box",
+	"width":       "<size>",
+	"height":      "<size>",
+	"min-width":   "<size>",
+	"min-height":  "<size>",
+	"max-width":   "none | <size>",
+	"max-height":  "none | <size>",
+	"inline-size": "<size>", "block-size": "<size>",
+	"min-inline-size": "<size>", "min-block-size": "<size>",
+	"max-inline-size": "none | <size>", "max-block-size": "none | <size>",
+	"aspect-ratio":    "auto || [ <number> [ / <number> ]? ]",
+	"overflow-x":      "visible | hidden | clip | scroll | auto",
+	"overflow-y":      "visible | hidden | clip | scroll | auto",
+	"visibility":      "visible | hidden | collapse",
+	"opacity":         "<number> | <percentage>",
+	"object-fit":      "fill | contain | cover | none | scale-down",
+	"object-position": "<position>",
+	"vertical-align":  "baseline | sub | super | text-top | text-bottom | middle | top | bottom | <length-percentage>",
+	"content": "normal | none | [ <string> | <image> | <content-function> | open-quote | close-quote | " +
+		"no-open-quote | no-close-quote ]+ [ / [ <string> | <content-function> ]+ ]?",
+	"quotes":              "none | auto | [ <string> <string> ]+",
+	"counter-reset":       "none | [ <custom-ident> <integer>? ]+",
+	"counter-increment":   "none | [ <custom-ident> <integer>? ]+",
+	"list-style-type":     "<custom-ident> | <string> | none",
+	"list-style-position": "inside | outside",
+	"list-style-image":    "<image> | none",
+	"table-layout":        "auto | fixed",
+	"caption-side":        "top | bottom",
+	"empty-cells":         "show | hide",
+	"cursor":              "[ <url> [ <number> <number> ]? , ]* <cursor-keyword>",
+	"pointer-events": "auto | none | visiblePainted | visibleFill | visibleStroke | visible | painted | " +
+		"fill | stroke | all",
+	"user-select":         "auto | text | none | contain | all",
+	"resize":              "none | both | horizontal | vertical | block | inline",
+	"appearance":          "none | auto | menulist-button | textfield",
+	"accent-color":        "auto | <color>",
+	"caret-color":         "auto | <color>",
+	"color-scheme":        "normal | [ light | dark | <custom-ident> ]+ && only?",
+	"scroll-behavior":     "auto | smooth",
+	"isolation":           "auto | isolate",
+	"mix-blend-mode":      "<blend-mode> | plus-darker | plus-lighter",
+	"will-change":         "auto | <custom-ident>#",
+	"filter":              "none | [ <filter-function> | <url> ]+",
+	"backdrop-filter":     "none | [ <filter-function> | <url> ]+",
+	"clip-path":           "none | <url> | <basic-shape> || <box> | <box>",
+	"box-shadow":          "none | <shadow>#",
+	"text-shadow":         "none | <shadow>#",
+	"transform":           "none | <transform-function>+",
+	"transform-origin":    "[ left | center | right | top | bottom | <length-percentage> ]{1,3}",
+	"transform-style":     "flat | preserve-3d",
+	"perspective":         "none | <length>",
+	"backface-visibility": "visible | hidden",
+	"touch-action": "auto | none | [ [ pan-x | pan-left | pan-right ] || [ pan-y | pan-up | pan-down ] || " +
+		"pinch-zoom ] | manipulation",
+	"contain":               "none | strict | content | [ [ size | inline-size ] || layout || style || paint ]",
+	"container-type":        "normal | [ [ size | inline-size ] || scroll-state ]",
+	"container-name":        "none | <custom-ident>+",
+	"container":             "[ none | <custom-ident>+ ] [ / [ normal | [ [ size | inline-size ] || scroll-state ] ] ]?",
+	"content-visibility":    "visible | auto | hidden",
+	"overscroll-behavior":   "[ contain | none | auto ]{1,2}",
+	"overscroll-behavior-x": "contain | none | auto",
+	"overscroll-behavior-y": "contain | none | auto",
+
+	"fill":              "<paint>",
+	"fill-opacity":      "<number> | <percentage>",
+	"fill-rule":         "nonzero | evenodd",
+	"stroke":            "<paint>",
+	"stroke-width":      "<length-percentage> | <number>",
+	"stroke-opacity":    "<number> | <percentage>",
+	"stroke-linecap":    "butt | round | square",
+	"stroke-linejoin":   "miter | miter-clip | round | bevel | arcs",
+	"stroke-miterlimit": "<number>",
+	"stroke-dasharray":  "none | [ <length-percentage> | <number> ]+#",
+	"stroke-dashoffset": "<length-percentage> | <number>",
+
+	"font-style":            "normal | italic | oblique <angle>?",
+	"font-variant":          "normal | none | small-caps | all-small-caps | petite-caps | all-petite-caps | unicase | titling-caps",
+	"font-variant-caps":     "normal | small-caps | all-small-caps | petite-caps | all-petite-caps | unicase | titling-caps",
+	"font-weight":           "normal | bold | bolder | lighter | <number>",
+	"font-stretch":          "<font-stretch-keyword> | <percentage>",
+	"font-size":             "<length-percentage> | <font-size-keyword>",
+	"line-height":           "normal | <number> | <length-percentage>",
+	"font-family":           "[ <family-name> | <generic-family> ]#",
+	"font-display":          "auto | block | swap | fallback | optional",
+	"font-feature-settings": "normal | [ <string> [ <integer> | on | off ]? ]#",
+	"letter-spacing":        "normal | <length>",
+	"word-spacing":          "normal | <length>",
+	"text-align":            "start | end | left | right | center | justify | match-parent",
+	"text-align-last":       "auto | start | end | left | right | center | justify | match-parent",
+	"text-decoration": "[ none | [ underline || overline || line-through || blink ] ] || " +
+		"[ solid | double | dotted | dashed | wavy ] || <color> || [ auto | from-font | <length-percentage> ]",
+	"text-decoration-line":      "none | [ underline || overline || line-through || blink ]",
+	"text-decoration-style":     "solid | double | dotted | dashed | wavy",
+	"text-decoration-color":     "<color>",
+	"text-decoration-thickness": "auto | from-font | <length-percentage>",
+	"text-underline-offset":     "auto | <length-percentage>",
+	"text-transform":            "none | capitalize | uppercase | lowercase | full-width",
+	"text-indent":               "<length-percentage> && hanging? && each-line?",
+	"text-overflow":             "clip | ellipsis | <string>",
+	"text-rendering":            "auto | optimizeSpeed | optimizeLegibility | geometricPrecision",
+	"white-space":               "normal | pre | nowrap | pre-wrap | pre-line | break-spaces",
+	"word-break":                "normal | break-all | keep-all | break-word",
+	"overflow-wrap":             "normal | break-word | anywhere",
+	"word-wrap":                 "normal | break-word | anywhere",
+	"hyphens":                   "none | manual | auto",
+	"tab-size":                  "<number> | <length>",
+	"direction":                 "ltr | rtl",
+	"unicode-bidi":              "normal | embed | isolate | bidi-override | isolate-override | plaintext",
+	"writing-mode":              "horizontal-tb | vertical-rl | vertical-lr | sideways-rl | sideways-lr",
+
+	"transition":                 "<single-transition>#",
+	"transition-property":        "none | [ all | <custom-ident> ]#",
+	"transition-duration":        "<time>#",
+	"transition-delay":           "<time>#",
+	"transition-timing-function": "<easing>#",
+	"animation":                  "<single-animation>#",
+	"animation-name":             "[ none | <custom-ident> | <string> ]#",
+	"animation-duration":         "[ auto | <time> ]#",
+	"animation-delay":            "<time>#",
+	"animation-timing-function":  "<easing>#",
+	"animation-iteration-count":  "[ infinite | <number> ]#",
+	"animation-direction":        "[ normal | reverse | alternate | alternate-reverse ]#",
+	"animation-fill-mode":        "[ none | forwards | backwards | both ]#",
+	"animation-play-state":       "[ running | paused ]#",
+
+	"flex-direction":  "<flex-direction>",
+	"flex-wrap":       "<flex-wrap>",
+	"flex-flow":       "<flex-direction> || <flex-wrap>",
+	"flex-grow":       "<number>",
+	"flex-shrink":     "<number>",
+	"flex-basis":      "content | <size>",
+	"order":           "<integer>",
+	"justify-content": "normal | <content-distribution> | [ safe | unsafe ]? [ <self-position> | left | right ]",
+	"justify-items": "normal | stretch | baseline | first baseline | last baseline | legacy | " +
+		"[ safe | unsafe ]? [ <self-position> | left | right ]",
+	"justify-self": "auto | normal | stretch | baseline | first baseline | last baseline | " +
+		"[ safe | unsafe ]? [ <self-position> | left | right ]",
+	"align-items": "normal | stretch | baseline | first baseline | last baseline | " +
+		"[ safe | unsafe ]? <self-position>",
+	"align-self": "auto | normal | stretch | baseline | first baseline | last baseline | " +
+		"[ safe | unsafe ]? <self-position>",
+	"align-content": "normal | baseline | first baseline | last baseline | <content-distribution> | " +
+		"[ safe | unsafe ]? <self-position>",
+	"place-items":   "[ normal | stretch | baseline | <self-position> ]{1,2}",
+	"place-content": "[ normal | baseline | <content-distribution> | <self-position> ]{1,2}",
+	"place-self":    "[ auto | normal | stretch | baseline | <self-position> ]{1,2}",
+	"row-gap":       "normal | <length-percentage>",
+	"column-gap":    "normal | <length-percentage>",
+
+	"grid-template-columns": "none | subgrid | masonry | [ <track-size> | <line-names> ]+",
+	"grid-template-rows":    "none | subgrid | masonry | [ <track-size> | <line-names> ]+",
+	"grid-template-areas":   "none | <string>+",
+	"grid-auto-columns":     "<track-size>+",
+	"grid-auto-rows":        "<track-size>+",
+	"grid-auto-flow":        "[ row | column ] || dense",
+	"grid-row-start":        "<grid-line>",
+	"grid-row-end":          "<grid-line>",
+	"grid-column-start":     "<grid-line>",
+	"grid-column-end":       "<grid-line>",
+	"grid-row":              "<grid-line> [ / <grid-line> ]?",
+	"grid-column":           "<grid-line> [ / <grid-line> ]?",
+	"grid-area":             "<grid-line> [ / <grid-line> ]{0,3}",
+	"columns":               "[ <length> | auto ] || [ <integer> | auto ]",
+	"column-count":          "auto | <integer>",
+	"column-width":          "auto | <length>",
+
+	"break-before": "<break>",
+	"break-after":  "<break>",
+	"break-inside": "auto | avoid | avoid-page | avoid-column | avoid-region",
+
+	// Deprecated properties.
+	"clip":              "auto | <basic-shape>",
+	"grid-gap":          "[ normal | <length-percentage> ]{1,2}",
+	"grid-row-gap":      "normal | <length-percentage>",
+	"grid-column-gap":   "normal | <length-percentage>",
+	"page-break-before": "auto | always | avoid | left | right",
+	"page-break-after":  "auto | always | avoid | left | right",
+	"page-break-inside": "auto | avoid",
+}
+
+// shorthandNames are the shorthands css.Declaration.Expand supports. They
+// are only used to suggest corrections for unknown properties.
+var shorthandNames = []string{
+	"margin", "padding", "inset", "border", "border-width", "border-style",
+	"border-color", "border-top", "border-right", "border-bottom", "border-left",
+	"border-radius", "outline", "gap", "overflow", "font", "background", "flex",
+	"list-style",
+}
+
+// deprecated maps deprecated properties to the properties replacing them.
+var deprecated = map[string]string{
+	"clip":              "clip-path",
+	"grid-gap":          "gap",
+	"grid-row-gap":      "row-gap",
+	"grid-column-gap":   "column-gap",
+	"page-break-before": "break-before",
+	"page-break-after":  "break-after",
+	"page-break-inside": "break-inside",
+}
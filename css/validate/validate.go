@@ -0,0 +1,334 @@
+/*
+Package validate checks css Declarations against the value definitions of
+the properties they set.
+
+	ss, err := css.ParseWithPositions(r)
+	v := validate.New()
+	v.AllowVendorPrefix("webkit")
+	for _, p := range v.Stylesheet(ss) {
+		log.Println(p)
+	}
+
+The built-in table covers the common properties. Properties can be added
+or overridden with Define using the value definition syntax of the css
+specs, eg: "<length> | auto". Custom properties are always valid unless
+they are Defined and values using var() aren't checked since they can't
+be known until computed.
+*/
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+)
+
+type problemKind int
+
+const (
+	// UnknownProperty is a property not in the Validator's table.
+	UnknownProperty problemKind = iota
+	// InvalidValue is a value not matching the property's definition.
+	InvalidValue
+	// DeprecatedProperty is a valid but deprecated property.
+	DeprecatedProperty
+)
+
+func (k problemKind) String() string {
+	switch k {
+	case UnknownProperty:
+		return "unknown property"
+	case InvalidValue:
+		return "invalid value"
+	case DeprecatedProperty:
+		return "deprecated property"
+	}
+	panic("Unreachable")
+}
+
+// Problem is a Declaration that failed validation.
+type Problem struct {
+	Kind problemKind
+	// Pos is the position of the Declaration if it was parsed by
+	// css.ParseWithPositions.
+	Pos         tokenizer.Position
+	Declaration css.Declaration
+	Message     string
+}
+
+func (p Problem) Error() string {
+	if p.Pos.Line > 0 {
+		return fmt.Sprintf("%d:%d: %s", p.Pos.Line, p.Pos.Column, p.Message)
+	}
+	return p.Message
+}
+
+// Validator validates Declarations against a table of properties.
+type Validator struct {
+	g          *grammar
+	properties map[string]*node
+	deprecated map[string]string
+	allowed    map[string]bool
+	prefixes   []string
+}
+
+// New constructs a Validator with the built-in property table.
+func New() *Validator {
+	v := &Validator{
+		g: &grammar{
+			predicates: predicates,
+			types:      types,
+			compiled:   map[string]*node{},
+		},
+		properties: map[string]*node{},
+		deprecated: map[string]string{},
+		allowed:    map[string]bool{},
+	}
+	for prop, def := range properties {
+		v.properties[prop] = mustCompile(def)
+	}
+	for prop, replacement := range deprecated {
+		v.deprecated[prop] = replacement
+	}
+	return v
+}
+
+func mustCompile(def string) *node {
+	n, err := compile(def)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+// Define sets the value definition of the property prop replacing any
+// built-in one. It returns an error if def isn't a valid value definition
+// or refers to an unknown type.
+func (v *Validator) Define(prop, def string) error {
+	n, err := compile(def)
+	if err != nil {
+		return err
+	}
+	if err := v.checkTypes(n); err != nil {
+		return err
+	}
+	v.properties[strings.ToLower(prop)] = n
+	return nil
+}
+
+func (v *Validator) checkTypes(n *node) error {
+	if n.typ == typeNode {
+		if _, ok := v.g.predicates[n.value]; ok {
+			return nil
+		}
+		_, err := v.g.resolve(n.value)
+		return err
+	}
+	for _, c := range n.children {
+		if err := v.checkTypes(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Allow accepts the properties props with any value.
+func (v *Validator) Allow(props ...string) {
+	for _, prop := range props {
+		v.allowed[strings.ToLower(prop)] = true
+	}
+}
+
+// AllowVendorPrefix accepts any property with the vendor prefix, eg:
+// webkit for -webkit-appearance, with any value.
+func (v *Validator) AllowVendorPrefix(prefix string) {
+	v.prefixes = append(v.prefixes, "-"+strings.Trim(strings.ToLower(prefix), "-")+"-")
+}
+
+// Deprecate marks the property prop as deprecated in favour of
+// replacement which may be empty.
+func (v *Validator) Deprecate(prop, replacement string) {
+	v.deprecated[strings.ToLower(prop)] = replacement
+}
+
+// Declaration validates d returning nil if it is valid.
+func (v *Validator) Declaration(d css.Declaration) *Problem {
+	prop := strings.ToLower(d.Property)
+	n, known := v.properties[prop]
+	switch {
+	case css.IsCustomProperty(d.Property) && !known:
+		return nil
+	case v.allowed[prop]:
+		return nil
+	}
+	for _, prefix := range v.prefixes {
+		if strings.HasPrefix(prop, prefix) {
+			return nil
+		}
+	}
+	if !known && css.Longhands(prop) == nil {
+		msg := "Unknown property " + d.Property
+		if s := v.suggest(prop); s != "" {
+			msg += ", did you mean " + s + "?"
+		}
+		return v.problem(UnknownProperty, d, msg)
+	}
+	if !v.validValue(prop, n, d) {
+		return v.problem(InvalidValue, d, fmt.Sprintf("Invalid value %q for %s", d.Value, d.Property))
+	}
+	if replacement, ok := v.deprecated[prop]; ok {
+		msg := d.Property + " is deprecated"
+		if replacement != "" {
+			msg += ", use " + replacement
+		}
+		return v.problem(DeprecatedProperty, d, msg)
+	}
+	return nil
+}
+
+func (v *Validator) problem(kind problemKind, d css.Declaration, msg string) *Problem {
+	return &Problem{Kind: kind, Pos: d.Pos, Declaration: d, Message: msg}
+}
+
+func isWideKeyword(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "inherit", "initial", "unset", "revert", "revert-layer":
+		return true
+	}
+	return false
+}
+
+func (v *Validator) validValue(prop string, n *node, d css.Declaration) bool {
+	if isWideKeyword(d.Value) || len(css.VarNames(d.Value)) > 0 {
+		return true
+	}
+	vl, err := css.ParseValue(d.Value)
+	if err != nil || len(vl) == 0 {
+		return false
+	}
+	if n != nil {
+		return v.g.matches(n, vl)
+	}
+	// Shorthands are valid if they expand to valid longhands. Each
+	// background layer is expanded separately.
+	layers := []string{d.Value}
+	if prop == "background" {
+		layers = nil
+		for _, l := range vl.Split() {
+			layers = append(layers, l.String())
+		}
+	}
+	for _, layer := range layers {
+		dl, ok := css.Declaration{Property: prop, Value: layer}.Expand()
+		if !ok {
+			return false
+		}
+		for _, lh := range dl {
+			if lh.Value == "" || lh.Property == prop {
+				continue
+			}
+			if !v.validValue(lh.Property, v.properties[lh.Property], lh) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// suggest returns the known property closest to the misspelt prop.
+func (v *Validator) suggest(prop string) string {
+	best, bestDist := "", 3
+	for known := range v.properties {
+		best, bestDist = closer(prop, known, best, bestDist)
+	}
+	for _, known := range shorthandNames {
+		best, bestDist = closer(prop, known, best, bestDist)
+	}
+	return best
+}
+
+// closer returns known and its edit distance from prop if it is closer
+// than best.
+func closer(prop, known, best string, bestDist int) (string, int) {
+	if d := levenshtein(prop, known); d < bestDist || d == bestDist && best != "" && known < best {
+		return known, d
+	}
+	return best, bestDist
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Stylesheet validates the Declarations of every rule in ss including
+// nested rules and those in conditional AtRules. The descriptors of
+// AtRules like @font-face aren't properties so they're skipped.
+func (v *Validator) Stylesheet(ss *css.Stylesheet) []Problem {
+	var problems []Problem
+	for _, st := range ss.Statements {
+		switch {
+		case st.Ruleset != nil:
+			problems = v.ruleset(st.Ruleset, problems)
+		case st.AtRule != nil:
+			problems = v.atRule(st.AtRule, problems)
+		}
+	}
+	return problems
+}
+
+func (v *Validator) declarations(dl css.DeclarationList, problems []Problem) []Problem {
+	for _, d := range dl {
+		if p := v.Declaration(d); p != nil {
+			problems = append(problems, *p)
+		}
+	}
+	return problems
+}
+
+func (v *Validator) ruleset(rs *css.Ruleset, problems []Problem) []Problem {
+	problems = v.declarations(rs.DeclarationList, problems)
+	return v.items(rs.Nested, problems)
+}
+
+func (v *Validator) atRule(ar *css.AtRule, problems []Problem) []Problem {
+	switch strings.ToLower(ar.AtKeyword) {
+	case "font-face", "counter-style", "property", "font-palette-values", "page":
+		return problems
+	}
+	if ar.SimpleBlock == nil {
+		return problems
+	}
+	return v.items(ar.SimpleBlock.Content, problems)
+}
+
+func (v *Validator) items(items []css.BlockItem, problems []Problem) []Problem {
+	for _, bi := range items {
+		switch {
+		case bi.Ruleset != nil:
+			problems = v.ruleset(bi.Ruleset, problems)
+		case bi.AtRule != nil:
+			problems = v.atRule(bi.AtRule, problems)
+		default:
+			problems = v.declarations(bi.DeclarationList, problems)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,199 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+func TestGrammar(t *testing.T) {
+	g := &grammar{predicates: predicates, types: types, compiled: map[string]*node{}}
+	cases := []struct {
+		def, value string
+		ok         bool
+	}{
+		{"<length> | auto", "10px", true},
+		{"<length> | auto", "auto", true},
+		{"<length> | auto", "red", false},
+		{"<length> | auto", "10px auto", false},
+		{"a b", "a b", true},
+		{"a b", "b a", false},
+		{"a && b", "b a", true},
+		{"a && b", "a", false},
+		{"a || b", "b", true},
+		{"a || b", "b a", true},
+		{"a || b", "a a", false},
+		{"a?", "", true},
+		{"<number>{2,3}", "1 2 3", true},
+		{"<number>{2,3}", "1", false},
+		{"<number>{2,3}", "1 2 3 4", false},
+		{"<color>#", "red, blue", true},
+		{"<color>#", "red blue", false},
+		{"<color>#{1,2}", "red, blue, green", false},
+		{"[ a | b ]+ / <integer>", "a b a / 2", true},
+		{"[ a | b ]+ / <integer>", "a b a / 2.5", false},
+		{"<length>{1,2} && inset?", "inset 1px", true},
+	}
+	for _, c := range cases {
+		n, err := compile(c.def)
+		if err != nil {
+			t.Fatalf("compile(%q) failed: %s", c.def, err)
+		}
+		vl, _ := css.ParseValue(c.value)
+		if got := g.matches(n, vl); got != c.ok {
+			t.Errorf("%q matching %q expected %v got %v", c.def, c.value, c.ok, got)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, def := range []string{"[ a | b", "a |", "a ]", "<number>{x}"} {
+		if _, err := compile(def); err == nil {
+			t.Errorf("compile(%q) expected an error", def)
+		}
+	}
+}
+
+func TestDeclaration(t *testing.T) {
+	v := New()
+	cases := []struct {
+		prop, value string
+		ok          bool
+	}{
+		{"color", "red", true},
+		{"color", "currentColor", true},
+		{"color", "rgb(1 2 3 / 50%)", true},
+		{"color", "10px", false},
+		{"display", "flex", true},
+		{"display", "inline flex", true},
+		{"display", "flexbox", false},
+		{"width", "calc(100% - 10px)", true},
+		{"width", "10", false},
+		{"width", "0", true},
+		{"margin", "0 auto", true},
+		{"margin", "1px 2px 3px 4px 5px", false},
+		{"border", "1px solid red", true},
+		{"border", "1px solid 2px", false},
+		{"background", "url(a.png) no-repeat, #fff", true},
+		{"font", "bold 12px/1.5 Georgia, serif", true},
+		{"font-family", "\"Helvetica Neue\", Arial, sans-serif", true},
+		{"transition", "opacity 0.3s ease-in-out, transform 1s", true},
+		{"transition", "opacity 3", false},
+		{"animation", "spin 1s linear infinite", true},
+		{"box-shadow", "0 1px 2px rgba(0, 0, 0, 0.5), inset 0 0 1px red", true},
+		{"grid-template-columns", "[full] repeat(3, 1fr) 100px", true},
+		{"grid-column", "1 / span 2", true},
+		{"z-index", "1.5", false},
+		{"opacity", "inherit", true},
+		{"padding", "var(--gap)", true},
+		{"--anything", "{ whatever }", true},
+		{"fill", "currentColor", true},
+		{"fill", "url(#grad) none", true},
+		{"fill", "10px", false},
+		{"stroke-dasharray", "4 2, 1", true},
+		{"touch-action", "pan-x pinch-zoom", true},
+		{"touch-action", "manipulation", true},
+		{"touch-action", "pan-x manipulation", false},
+		{"contain", "layout paint", true},
+		{"contain", "size inline-size", false},
+		{"container-type", "inline-size", true},
+		{"container", "sidebar / inline-size", true},
+		{"inset-inline-start", "10px", true},
+		{"inset-inline-start", "red", false},
+		{"inset-block", "0 auto", true},
+		{"max-inline-size", "none", true},
+		{"border-inline-start", "1px solid red", true},
+		{"border-start-end-radius", "4px 2px", true},
+		{"place-self", "center start", true},
+	}
+	for _, c := range cases {
+		p := v.Declaration(css.Declaration{Property: c.prop, Value: c.value})
+		if ok := p == nil; ok != c.ok {
+			t.Errorf("%s: %s expected valid %v got %v", c.prop, c.value, c.ok, p)
+		} else if p != nil && p.Kind != InvalidValue {
+			t.Errorf("%s: %s expected %s got %s", c.prop, c.value, InvalidValue, p.Kind)
+		}
+	}
+}
+
+func TestUnknownProperty(t *testing.T) {
+	p := New().Declaration(css.Declaration{Property: "colour", Value: "red"})
+	if p == nil || p.Kind != UnknownProperty {
+		t.Fatalf("Expected an unknown property got %v", p)
+	}
+	if !strings.Contains(p.Message, "did you mean color?") {
+		t.Errorf("Expected a suggestion got %q", p.Message)
+	}
+	p = New().Declaration(css.Declaration{Property: "margn", Value: "0"})
+	if p == nil || !strings.Contains(p.Message, "did you mean margin?") {
+		t.Errorf("Expected a shorthand suggestion got %v", p)
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	v := New()
+	p := v.Declaration(css.Declaration{Property: "grid-gap", Value: "1em"})
+	if p == nil || p.Kind != DeprecatedProperty || !strings.Contains(p.Message, "use gap") {
+		t.Errorf("Expected grid-gap to be deprecated got %v", p)
+	}
+	v.Deprecate("float", "")
+	if p := v.Declaration(css.Declaration{Property: "float", Value: "left"}); p == nil || p.Kind != DeprecatedProperty {
+		t.Errorf("Expected float to be deprecated got %v", p)
+	}
+}
+
+func TestExtensions(t *testing.T) {
+	v := New()
+	webkit := css.Declaration{Property: "-webkit-line-clamp", Value: "3"}
+	if p := v.Declaration(webkit); p == nil || p.Kind != UnknownProperty {
+		t.Errorf("Expected an unknown property got %v", p)
+	}
+	v.AllowVendorPrefix("webkit")
+	if p := v.Declaration(webkit); p != nil {
+		t.Errorf("Expected the vendor prefix to be allowed got %v", p)
+	}
+	v.Allow("zoom")
+	if p := v.Declaration(css.Declaration{Property: "zoom", Value: "anything"}); p != nil {
+		t.Errorf("Expected zoom to be allowed got %v", p)
+	}
+	if err := v.Define("--size", "<length> | small | large"); err != nil {
+		t.Fatal(err)
+	}
+	if p := v.Declaration(css.Declaration{Property: "--size", Value: "huge"}); p == nil || p.Kind != InvalidValue {
+		t.Errorf("Expected an invalid --size got %v", p)
+	}
+	if p := v.Declaration(css.Declaration{Property: "--size", Value: "2em"}); p != nil {
+		t.Errorf("Expected a valid --size got %v", p)
+	}
+	if err := v.Define("tab-size", "<nope>"); err == nil {
+		t.Errorf("Expected an unknown type to fail")
+	}
+}
+
+func TestStylesheet(t *testing.T) {
+	ss, err := css.ParseWithPositions(strings.NewReader(`a {
+  colour: red;
+  display: flexbox;
+}
+@media print {
+  p { page-break-after: always; }
+}
+@font-face { font-family: x; src: url(x.woff); }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range New().Stylesheet(ss) {
+		got = append(got, p.Error())
+	}
+	expected := []string{
+		"2:3: Unknown property colour, did you mean color?",
+		"3:3: Invalid value \"flexbox\" for display",
+		"6:7: page-break-after is deprecated, use break-after",
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
@@ -0,0 +1,413 @@
+package css
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+)
+
+type valueType int
+
+const (
+	// An identifier like auto or sans-serif.
+	IdentValue valueType = iota
+	// A number without a unit.
+	NumberValue
+	// A number followed by a %.
+	PercentageValue
+	// A number followed by a unit like px or em.
+	DimensionValue
+	// A quoted string.
+	StringValue
+	// A url either as url(foo.png) or url("foo.png").
+	URLValue
+	// A hash like #fff.
+	HashValue
+	// A function like rgba(0, 0, 0, 0.5).
+	FunctionValue
+	// A parenthesized or bracketed block like the (1px + 2px) in
+	// calc((1px + 2px) * 2).
+	BlockValue
+	// A comma separator.
+	CommaValue
+	// A slash separator like the one in font: 12px/1.5 serif.
+	SlashValue
+	// Any other delimiter like the + in calc(1px + 2px).
+	DelimValue
+	// A unicode range like U+0025-00FF.
+	UnicodeRangeValue
+)
+
+func (t valueType) String() string {
+	switch t {
+	case IdentValue:
+		return "ident"
+	case NumberValue:
+		return "number"
+	case PercentageValue:
+		return "percentage"
+	case DimensionValue:
+		return "dimension"
+	case StringValue:
+		return "string"
+	case URLValue:
+		return "url"
+	case HashValue:
+		return "hash"
+	case FunctionValue:
+		return "function"
+	case BlockValue:
+		return "block"
+	case CommaValue:
+		return "comma"
+	case SlashValue:
+		return "slash"
+	case DelimValue:
+		return "delim"
+	case UnicodeRangeValue:
+		return "unicode-range"
+	}
+	panic("Unreachable")
+}
+
+// ComponentValue is one piece of a parsed Declaration value.
+// http://www.w3.org/TR/css-syntax-3/#component-value
+type ComponentValue struct {
+	// The type of the ComponentValue.
+	Type valueType
+	// The unescaped ident, string contents, url, hash name without the #,
+	// function name, delimiter or opening bracket of a block.
+	Value string
+	// The numeric value if Type is Number, Percentage or Dimension.
+	Number float64
+	// The lowercased unit if Type is Dimension.
+	Unit string
+	// The arguments of a Function or the contents of a Block including
+	// any separators.
+	Args ValueList
+}
+
+// ValueList is a list of ComponentValues forming a Declaration value.
+type ValueList []ComponentValue
+
+// ParseValue parses a Declaration value into a ValueList.
+func ParseValue(s string) (ValueList, error) {
+	return parseComponents(tokenizer.New(strings.NewReader(s)), "")
+}
+
+// Components parses the Value of a Declaration into a ValueList.
+func (d Declaration) Components() (ValueList, error) {
+	return ParseValue(d.Value)
+}
+
+// closes returns true if t closes a block opened by open.
+func closes(open string, t *tokenizer.Token) bool {
+	switch open {
+	case "(":
+		return t.Type == tokenizer.RParen
+	case "[":
+		return t.Type == tokenizer.RBracket
+	}
+	return false
+}
+
+// parseComponents consumes tokens until EOF or the closing bracket for
+// open.
+func parseComponents(tk *tokenizer.Tokenizer, open string) (ValueList, error) {
+	var vl ValueList
+	for {
+		t, err := tk.Next()
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			// EOF closes any open functions or blocks.
+			return vl, nil
+		}
+		if closes(open, t) {
+			return vl, nil
+		}
+		switch t.Type {
+		case tokenizer.WS, tokenizer.Comment:
+		case tokenizer.Ident:
+			vl = append(vl, ComponentValue{Type: IdentValue, Value: tokenizer.Unescape(t.String)})
+		case tokenizer.Number, tokenizer.Percentage, tokenizer.Dimension:
+			cv, err := numericValue(t)
+			if err != nil {
+				return nil, err
+			}
+			vl = append(vl, cv)
+		case tokenizer.String:
+			vl = append(vl, ComponentValue{Type: StringValue, Value: unquote(t.String)})
+		case tokenizer.Uri:
+			u := strings.TrimSpace(t.String[len("url(") : len(t.String)-1])
+			vl = append(vl, ComponentValue{Type: URLValue, Value: tokenizer.Unescape(u)})
+		case tokenizer.Hash:
+			vl = append(vl, ComponentValue{Type: HashValue, Value: tokenizer.Unescape(t.String[1:])})
+		case tokenizer.UnicodeRange:
+			vl = append(vl, ComponentValue{Type: UnicodeRangeValue, Value: t.String})
+		case tokenizer.Function:
+			args, err := parseComponents(tk, "(")
+			if err != nil {
+				return nil, err
+			}
+			name := tokenizer.Unescape(strings.TrimSuffix(t.String, "("))
+			if strings.EqualFold(name, "url") && len(args) == 1 && args[0].Type == StringValue {
+				vl = append(vl, ComponentValue{Type: URLValue, Value: args[0].Value})
+				continue
+			}
+			vl = append(vl, ComponentValue{Type: FunctionValue, Value: name, Args: args})
+		case tokenizer.LParen, tokenizer.LBracket:
+			bracket := "("
+			if t.Type == tokenizer.LBracket {
+				bracket = "["
+			}
+			args, err := parseComponents(tk, bracket)
+			if err != nil {
+				return nil, err
+			}
+			vl = append(vl, ComponentValue{Type: BlockValue, Value: bracket, Args: args})
+		case tokenizer.Comma:
+			vl = append(vl, ComponentValue{Type: CommaValue, Value: ","})
+		case tokenizer.Delim:
+			if t.String == "/" {
+				vl = append(vl, ComponentValue{Type: SlashValue, Value: "/"})
+			} else {
+				vl = append(vl, ComponentValue{Type: DelimValue, Value: t.String})
+			}
+		case tokenizer.Colon:
+			vl = append(vl, ComponentValue{Type: DelimValue, Value: ":"})
+		default:
+			return nil, fmt.Errorf("Unexpected %v token %q in value at line %d",
+				t.Type, t.String, t.Line)
+		}
+	}
+}
+
+func numericValue(t *tokenizer.Token) (ComponentValue, error) {
+	num, unit := tokenizer.SplitNumeric(t.String)
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return ComponentValue{}, err
+	}
+	switch t.Type {
+	case tokenizer.Percentage:
+		return ComponentValue{Type: PercentageValue, Number: f}, nil
+	case tokenizer.Dimension:
+		return ComponentValue{Type: DimensionValue, Number: f,
+			Unit: strings.ToLower(tokenizer.Unescape(unit))}, nil
+	}
+	return ComponentValue{Type: NumberValue, Number: f}, nil
+}
+
+// unquote strips the quotes from a string token and resolves any escapes
+// the tokenizer left in place.
+func unquote(s string) string {
+	q := s[0]
+	s = s[1:]
+	if len(s) > 0 && s[len(s)-1] == q {
+		s = s[:len(s)-1]
+	}
+	return tokenizer.Unescape(s)
+}
+
+// quote returns s as a double quoted css string.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\a `)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// escapeIdent escapes s so it parses back as an identifier or, if name is
+// set, as the name following the # of a hash.
+// http://www.w3.org/TR/cssom-1/#serialize-an-identifier
+func escapeIdent(s string, name bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == 0:
+			b.WriteRune(utf8.RuneError)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, "\\%x ", r)
+		case !name && '0' <= r && r <= '9' && (i == 0 || i == 1 && s[0] == '-'):
+			fmt.Fprintf(&b, "\\%x ", r)
+		case !name && r == '-' && s == "-":
+			b.WriteString(`\-`)
+		case r >= 0x80 || r == '-' || r == '_' || '0' <= r && r <= '9' ||
+			'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// IsIdent returns true if this ComponentValue is the identifier name
+// compared ASCII case-insensitively.
+func (cv ComponentValue) IsIdent(name string) bool {
+	return cv.Type == IdentValue && strings.EqualFold(cv.Value, name)
+}
+
+// IsFunction returns true if this ComponentValue is a call to the function
+// name compared ASCII case-insensitively.
+func (cv ComponentValue) IsFunction(name string) bool {
+	return cv.Type == FunctionValue && strings.EqualFold(cv.Value, name)
+}
+
+func (cv ComponentValue) String() string {
+	switch cv.Type {
+	case IdentValue:
+		return escapeIdent(cv.Value, false)
+	case NumberValue:
+		return formatNumber(cv.Number)
+	case PercentageValue:
+		return formatNumber(cv.Number) + "%"
+	case DimensionValue:
+		return formatNumber(cv.Number) + cv.Unit
+	case StringValue:
+		return quote(cv.Value)
+	case URLValue:
+		if strings.ContainsAny(cv.Value, "\"'() \t\n\\") {
+			return "url(" + quote(cv.Value) + ")"
+		}
+		return "url(" + cv.Value + ")"
+	case HashValue:
+		return "#" + escapeIdent(cv.Value, true)
+	case FunctionValue:
+		return escapeIdent(cv.Value, false) + "(" + cv.Args.String() + ")"
+	case BlockValue:
+		if cv.Value == "[" {
+			return "[" + cv.Args.String() + "]"
+		}
+		return "(" + cv.Args.String() + ")"
+	case CommaValue, SlashValue, DelimValue, UnicodeRangeValue:
+		return cv.Value
+	}
+	panic("Unreachable")
+}
+
+// String serializes a ValueList back into css.
+func (vl ValueList) String() string {
+	var b strings.Builder
+	for i, cv := range vl {
+		if i > 0 && needsSpace(vl[i-1], cv) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(cv.String())
+	}
+	return b.String()
+}
+
+func needsSpace(prev, cur ComponentValue) bool {
+	switch {
+	case cur.Type == CommaValue, cur.Type == SlashValue, prev.Type == SlashValue:
+		return false
+	case prev.Type == DelimValue && prev.Value == "!":
+		return false
+	}
+	return true
+}
+
+// Split splits a ValueList on its top level commas.
+func (vl ValueList) Split() []ValueList {
+	var lists []ValueList
+	start := 0
+	for i, cv := range vl {
+		if cv.Type == CommaValue {
+			lists = append(lists, vl[start:i])
+			start = i + 1
+		}
+	}
+	return append(lists, vl[start:])
+}
+
+// Length is a css length like 12px or 1.5em.
+type Length struct {
+	Value float64
+	// The lowercased unit of the Length. It is empty for a unitless 0.
+	Unit string
+}
+
+// pxPerUnit maps the absolute css length units to pixels.
+// http://www.w3.org/TR/css3-values/#absolute-lengths
+var pxPerUnit = map[string]float64{
+	"":   1,
+	"px": 1,
+	"in": 96,
+	"cm": 96 / 2.54,
+	"mm": 96 / 25.4,
+	"q":  96 / 101.6,
+	"pt": 96.0 / 72,
+	"pc": 16,
+}
+
+// relativeUnits are the css length units that depend on the font, the
+// viewport or the container.
+// http://www.w3.org/TR/css-values-4/#relative-lengths
+var relativeUnits = map[string]bool{
+	"em": true, "rem": true, "ex": true, "rex": true, "cap": true,
+	"rcap": true, "ch": true, "rch": true, "ic": true, "ric": true,
+	"lh": true, "rlh": true,
+	"vw": true, "vh": true, "vi": true, "vb": true, "vmin": true,
+	"vmax": true, "svw": true, "svh": true, "svi": true, "svb": true,
+	"svmin": true, "svmax": true, "lvw": true, "lvh": true, "lvi": true,
+	"lvb": true, "lvmin": true, "lvmax": true, "dvw": true, "dvh": true,
+	"dvi": true, "dvb": true, "dvmin": true, "dvmax": true,
+	"cqw": true, "cqh": true, "cqi": true, "cqb": true, "cqmin": true,
+	"cqmax": true,
+}
+
+// Length returns the Length of a Dimension ComponentValue with a length
+// unit or a unitless zero. It returns false if this ComponentValue is not
+// a length.
+func (cv ComponentValue) Length() (Length, bool) {
+	switch {
+	case cv.Type == DimensionValue && cv.Unit != "" &&
+		(pxPerUnit[cv.Unit] != 0 || relativeUnits[cv.Unit]):
+		return Length{Value: cv.Number, Unit: cv.Unit}, true
+	case cv.Type == NumberValue && cv.Number == 0:
+		return Length{}, true
+	}
+	return Length{}, false
+}
+
+// Px converts an absolute Length to pixels. It returns false for relative
+// units like em or vw.
+func (l Length) Px() (float64, bool) {
+	mul, ok := pxPerUnit[l.Unit]
+	if !ok {
+		return 0, false
+	}
+	return l.Value * mul, true
+}
+
+func (l Length) String() string {
+	return formatNumber(l.Value) + l.Unit
+}
+
+// Color returns the Color described by this ComponentValue. It returns
+// false if this ComponentValue is not a color.
+func (cv ComponentValue) Color() (Color, bool) {
+	return colorFromComponent(cv)
+}
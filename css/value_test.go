@@ -0,0 +1,281 @@
+package css
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestParseValue(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected ValueList
+	}{
+		{"12px", ValueList{{Type: DimensionValue, Number: 12, Unit: "px"}}},
+		{"50%", ValueList{{Type: PercentageValue, Number: 50}}},
+		{"-1.5", ValueList{{Type: NumberValue, Number: -1.5}}},
+		{"auto", ValueList{{Type: IdentValue, Value: "auto"}}},
+		{`"a b"`, ValueList{{Type: StringValue, Value: "a b"}}},
+		{"#fff", ValueList{{Type: HashValue, Value: "fff"}}},
+		{"url(foo.png)", ValueList{{Type: URLValue, Value: "foo.png"}}},
+		{`url("foo bar.png")`, ValueList{{Type: URLValue, Value: "foo bar.png"}}},
+		{"1px solid red", ValueList{
+			{Type: DimensionValue, Number: 1, Unit: "px"},
+			{Type: IdentValue, Value: "solid"},
+			{Type: IdentValue, Value: "red"},
+		}},
+		{"12px/1.5 serif", ValueList{
+			{Type: DimensionValue, Number: 12, Unit: "px"},
+			{Type: SlashValue, Value: "/"},
+			{Type: NumberValue, Number: 1.5},
+			{Type: IdentValue, Value: "serif"},
+		}},
+		{"rgba(0, 0, 0, .5)", ValueList{
+			{Type: FunctionValue, Value: "rgba", Args: ValueList{
+				{Type: NumberValue},
+				{Type: CommaValue, Value: ","},
+				{Type: NumberValue},
+				{Type: CommaValue, Value: ","},
+				{Type: NumberValue},
+				{Type: CommaValue, Value: ","},
+				{Type: NumberValue, Number: .5},
+			}},
+		}},
+		{"calc((1px + 2em) * 2)", ValueList{
+			{Type: FunctionValue, Value: "calc", Args: ValueList{
+				{Type: BlockValue, Value: "(", Args: ValueList{
+					{Type: DimensionValue, Number: 1, Unit: "px"},
+					{Type: DelimValue, Value: "+"},
+					{Type: DimensionValue, Number: 2, Unit: "em"},
+				}},
+				{Type: DelimValue, Value: "*"},
+				{Type: NumberValue, Number: 2},
+			}},
+		}},
+	}
+	for _, c := range cases {
+		vl, err := ParseValue(c.in)
+		if err != nil {
+			t.Errorf("Error parsing %q: %s", c.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(vl, c.expected) {
+			t.Errorf("Parsing %q expected %#v got %#v", c.in, c.expected, vl)
+		}
+	}
+}
+
+func TestValueListString(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"1px   solid  red", "1px solid red"},
+		{"12px/1.5 serif", "12px/1.5 serif"},
+		{"rgba( 0,0,0,.5 )", "rgba(0, 0, 0, 0.5)"},
+		{`"Helvetica Neue", Arial`, `"Helvetica Neue", Arial`},
+		{`url( foo.png )`, `url(foo.png)`},
+		{`url("a b.png")`, `url("a b.png")`},
+		{"red !important", "red !important"},
+		{"calc(100% - 2px)", "calc(100% - 2px)"},
+		{`a\,b`, `a\,b`},
+		{`\31 0px`, `\31 0px`},
+		{`-\32 x`, `-\32 x`},
+		{`#a\.b #\31 23`, `#a\.b #123`},
+		{`f\(x(1)`, `f\(x(1)`},
+		{`\-`, `\-`},
+	}
+	for _, c := range cases {
+		vl, err := ParseValue(c.in)
+		if err != nil {
+			t.Errorf("Error parsing %q: %s", c.in, err)
+			continue
+		}
+		if vl.String() != c.out {
+			t.Errorf("Expected %q got %q", c.out, vl.String())
+		}
+	}
+}
+
+func TestValueListSplit(t *testing.T) {
+	vl, _ := ParseValue("a b, c, d")
+	parts := vl.Split()
+	if len(parts) != 3 {
+		t.Fatalf("Expected 3 parts got %d", len(parts))
+	}
+	if parts[0].String() != "a b" || parts[2].String() != "d" {
+		t.Errorf("Unexpected split %v", parts)
+	}
+}
+
+func TestLength(t *testing.T) {
+	cases := []struct {
+		in string
+		px float64
+		ok bool
+	}{
+		{"12px", 12, true},
+		{"1in", 96, true},
+		{"12pt", 16, true},
+		{"0", 0, true},
+		{"2em", 0, false},
+	}
+	for _, c := range cases {
+		vl, _ := ParseValue(c.in)
+		l, ok := vl[0].Length()
+		if !ok {
+			t.Errorf("%q is not a length", c.in)
+			continue
+		}
+		px, ok := l.Px()
+		if ok != c.ok || px != c.px {
+			t.Errorf("%q expected %v px (%v) got %v (%v)", c.in, c.px, c.ok, px, ok)
+		}
+	}
+	for _, in := range []string{"auto", "5deg", "2s", "10hz", "2dpi", "3x", "1"} {
+		vl, _ := ParseValue(in)
+		if _, ok := vl[0].Length(); ok {
+			t.Errorf("%q should not be a length", in)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected Color
+	}{
+		{"#fff", Color{R: 255, G: 255, B: 255, A: 1}},
+		{"#00000080", Color{R: 0, G: 0, B: 0, A: 128.0 / 255}},
+		{"red", Color{R: 255, G: 0, B: 0, A: 1}},
+		{"Transparent", Color{R: 0, G: 0, B: 0, A: 0}},
+		{"rgb(1, 2, 3)", Color{R: 1, G: 2, B: 3, A: 1}},
+		{"rgba(1, 2, 3, 0.5)", Color{R: 1, G: 2, B: 3, A: 0.5}},
+		{"rgb(1 2 3 / 50%)", Color{R: 1, G: 2, B: 3, A: 0.5}},
+		{"rgb(100%, 0%, 0%)", Color{R: 255, G: 0, B: 0, A: 1}},
+		{"hsl(120, 100%, 50%)", Color{R: 0, G: 255, B: 0, A: 1}},
+		{"hsl(0.5turn 100% 50% / .25)", Color{R: 0, G: 255, B: 255, A: 0.25}},
+	}
+	for _, c := range cases {
+		col, err := ParseColor(c.in)
+		if err != nil {
+			t.Errorf("Error parsing color %q: %s", c.in, err)
+			continue
+		}
+		if col != c.expected {
+			t.Errorf("Color %q expected %v got %v", c.in, c.expected, col)
+		}
+	}
+	for _, in := range []string{"#ff", "rgb(1, 2)", "notacolor", "12px",
+		"rgb(1 2 3 4)", "rgb(255, 0 0)", "rgb(1 2, 3)", "rgb(1, 2, 3 / 0.5)", "rgb(1, 2, 3,)",
+		"rgb(1 2 3 / 0.5 1)", "rgb(1 2 / 3)", "hwb(0, 0%, 0%)", "lab(50%, 0, 0)"} {
+		if _, err := ParseColor(in); err == nil {
+			t.Errorf("Expected an error parsing %q", in)
+		}
+	}
+}
+
+func TestColorString(t *testing.T) {
+	if s := (Color{R: 255, G: 0, B: 16, A: 1}).String(); s != "#ff0010" {
+		t.Errorf("Expected #ff0010 got %s", s)
+	}
+	if s := (Color{R: 0, G: 0, B: 0, A: 0.5}).String(); s != "rgba(0, 0, 0, 0.5)" {
+		t.Errorf("Expected rgba(0, 0, 0, 0.5) got %s", s)
+	}
+}
+
+func TestColorSpaces(t *testing.T) {
+	cases := []struct {
+		in, expected string
+	}{
+		{"rebeccapurple", "#663399"},
+		{"#abcd", "rgba(170, 187, 204, 0.867)"},
+		{"rgba(255 0 0 / 0.5)", "rgba(255, 0, 0, 0.5)"},
+		{"hwb(0 0% 0%)", "#ff0000"},
+		{"hwb(120deg 20% 20%)", "#33cc33"},
+		{"hwb(0 60% 60%)", "#808080"},
+		{"lab(54.29% 80.81 69.89)", "#ff0000"},
+		{"lab(100 0 0)", "#ffffff"},
+		{"lch(54.29% 106.84 40.85)", "#ff0000"},
+		{"lab(60.17 93.55 -60.5)", "#ff00ff"},
+		{"oklab(62.8% 0.225 0.126)", "#ff0000"},
+		{"oklch(62.8% 0.2577 29.23)", "#ff0000"},
+		{"oklch(0% 0 none / 50%)", "rgba(0, 0, 0, 0.5)"},
+		{"rgb(none 0 0)", "#000000"},
+		{"rgb(255 none 0 / none)", "rgba(255, 0, 0, 0)"},
+		{"hsl(120 100 50)", "#00ff00"},
+		{"hsl(none 100% 50)", "#ff0000"},
+		{"hwb(0 0 0)", "#ff0000"},
+		{"hwb(120deg 20 none)", "#33ff33"},
+		{"currentColor", "currentcolor"},
+	}
+	for _, c := range cases {
+		col, err := ParseColor(c.in)
+		if err != nil {
+			t.Errorf("Error parsing color %q: %s", c.in, err)
+			continue
+		}
+		if col.String() != c.expected {
+			t.Errorf("Color %q expected %s got %s", c.in, c.expected, col)
+		}
+	}
+	for _, in := range []string{"hsl(120, 100, 50)", "rgb(none, 0, 0)", "hsl(0, 50%, 50%, none)", "lab(50% red 0)", "oklch(1 2)"} {
+		if _, err := ParseColor(in); err == nil {
+			t.Errorf("Expected an error parsing %q", in)
+		}
+	}
+	if c := (Color{Current: true}).Resolve(Color{R: 1, A: 1}); c != (Color{R: 1, A: 1}) {
+		t.Errorf("Expected currentColor to resolve got %v", c)
+	}
+}
+
+func TestColorShortest(t *testing.T) {
+	cases := []struct {
+		in, expected string
+	}{
+		{"#ff0000", "red"},
+		{"rgb(0, 255, 255)", "#0ff"},
+		{"#f5deb3", "wheat"},
+		{"#aabbcc", "#abc"},
+		{"#aabbcd", "#aabbcd"},
+		{"#d2b48c", "tan"},
+		{"#000000", "#000"},
+		{"transparent", "#0000"},
+		{"#11223344", "#1234"},
+		{"#11223345", "#11223345"},
+		{"rgba(255, 0, 0, 0.5)", "rgba(255,0,0,.5)"},
+		{"CurrentColor", "currentcolor"},
+	}
+	for _, c := range cases {
+		col, err := ParseColor(c.in)
+		if err != nil {
+			t.Errorf("Error parsing color %q: %s", c.in, err)
+			continue
+		}
+		if s := col.Shortest(); s != c.expected {
+			t.Errorf("Color %q expected %s got %s", c.in, c.expected, s)
+		}
+	}
+}
+
+func TestContrast(t *testing.T) {
+	black, white := Color{A: 1}, Color{R: 255, G: 255, B: 255, A: 1}
+	cases := []struct {
+		fg, bg   Color
+		expected float64
+	}{
+		{black, white, 21},
+		{white, black, 21},
+		{white, white, 1},
+		{Color{R: 118, G: 118, B: 118, A: 1}, white, 4.54},
+		{Color{A: 0.5}, white, 3.95},
+		{black, Color{A: 0}, 21},
+	}
+	for _, c := range cases {
+		if got := math.Round(Contrast(c.fg, c.bg)*100) / 100; got != c.expected {
+			t.Errorf("Contrast of %s on %s expected %v got %v", c.fg, c.bg, c.expected, got)
+		}
+	}
+	if r, g, b, a := (Color{R: 255, A: 0.5}).RGBA(); r != 0x8000 || g != 0 || b != 0 || a != 0x8000 {
+		t.Errorf("Unexpected RGBA %x %x %x %x", r, g, b, a)
+	}
+}
@@ -0,0 +1,416 @@
+package css
+
+import (
+	"sort"
+	"strings"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+)
+
+// IsCustomProperty returns true if prop is a custom property like
+// --brand-color.
+func IsCustomProperty(prop string) bool {
+	return strings.HasPrefix(prop, "--")
+}
+
+func hasVar(value string) bool {
+	return strings.Contains(strings.ToLower(value), "var(")
+}
+
+// SubstituteVars replaces the var() functions in value with the values
+// lookup returns for their custom property or their fallback if lookup
+// returns false. It returns false if value is invalid at computed-value
+// time because a var() has neither.
+// http://www.w3.org/TR/css-variables-1/#substitute-a-var
+func SubstituteVars(value string, lookup func(name string) (string, bool)) (string, bool) {
+	if !hasVar(value) {
+		return value, true
+	}
+	p, err := newParser(strings.NewReader(value))
+	if err != nil {
+		return "", false
+	}
+	v, ok := substituteVars(p.toks, lookup)
+	return strings.TrimSpace(v), ok
+}
+
+func isVar(t *tokenizer.Token) bool {
+	return t.Type == tokenizer.Function && strings.EqualFold(t.String, "var(")
+}
+
+// closeParen returns the index of the token closing the block opened
+// before toks[start] or len(toks) if it isn't closed.
+func closeParen(toks []*tokenizer.Token, start int) int {
+	depth := 0
+	for i := start; i < len(toks); i++ {
+		switch toks[i].Type {
+		case tokenizer.LParen, tokenizer.LBracket, tokenizer.LBrace, tokenizer.Function:
+			depth++
+		case tokenizer.RParen, tokenizer.RBracket, tokenizer.RBrace:
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return len(toks)
+}
+
+// varArgs splits the arguments of a var() into the custom property name
+// and the fallback tokens. hasFallback is false if there is no comma.
+func varArgs(args []*tokenizer.Token) (name string, fallback []*tokenizer.Token, hasFallback bool) {
+	i := 0
+	for i < len(args) && (args[i].Type == tokenizer.WS || args[i].Type == tokenizer.Comment) {
+		i++
+	}
+	if i == len(args) || args[i].Type != tokenizer.Ident || !IsCustomProperty(args[i].String) {
+		return "", nil, false
+	}
+	name = tokenizer.Unescape(args[i].String)
+	for i++; i < len(args); i++ {
+		switch args[i].Type {
+		case tokenizer.WS, tokenizer.Comment:
+		case tokenizer.Comma:
+			return name, args[i+1:], true
+		default:
+			return "", nil, false
+		}
+	}
+	return name, nil, false
+}
+
+func substituteVars(toks []*tokenizer.Token, lookup func(name string) (string, bool)) (string, bool) {
+	var b strings.Builder
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		if !isVar(t) {
+			if t.Type == tokenizer.WS {
+				b.WriteByte(' ')
+			} else if t.Type != tokenizer.Comment {
+				b.WriteString(t.Text())
+			}
+			continue
+		}
+		end := closeParen(toks, i+1)
+		name, fallback, hasFallback := varArgs(toks[i+1 : end])
+		if name == "" {
+			return "", false
+		}
+		v, ok := lookup(name)
+		if !ok {
+			if !hasFallback {
+				return "", false
+			}
+			if v, ok = substituteVars(fallback, lookup); !ok {
+				return "", false
+			}
+		}
+		b.WriteString(strings.TrimSpace(v))
+		i = end
+	}
+	return b.String(), true
+}
+
+// VarNames returns the names of the custom properties referenced by the
+// var() functions in value including those in fallbacks.
+func VarNames(value string) []string {
+	if !hasVar(value) {
+		return nil
+	}
+	p, err := newParser(strings.NewReader(value))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for i, t := range p.toks {
+		if isVar(t) {
+			if name, _, _ := varArgs(p.toks[i+1 : closeParen(p.toks, i+1)]); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// ResolveVars resolves the var() references between a set of custom
+// properties. The custom properties that are part of a dependency cycle or
+// reference a missing custom property without a fallback are invalid at
+// computed-value time and left out of the result.
+// http://www.w3.org/TR/css-variables-1/#cycles
+func ResolveVars(vars map[string]string) map[string]string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cyclic := varCycles(names, vars)
+	out := map[string]string{}
+	invalid := map[string]bool{}
+	var resolve func(name string) (string, bool)
+	resolve = func(name string) (string, bool) {
+		if v, ok := out[name]; ok {
+			return v, true
+		}
+		raw, ok := vars[name]
+		if !ok || cyclic[name] || invalid[name] {
+			return "", false
+		}
+		v, ok := SubstituteVars(raw, resolve)
+		if !ok {
+			invalid[name] = true
+			return "", false
+		}
+		out[name] = v
+		return v, true
+	}
+	for _, name := range names {
+		resolve(name)
+	}
+	return out
+}
+
+// varCycles returns the custom properties in a dependency cycle using
+// Tarjan's strongly connected components algorithm.
+func varCycles(names []string, vars map[string]string) map[string]bool {
+	cyclic := map[string]bool{}
+	index, low := map[string]int{}, map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var strongConnect func(v string)
+	strongConnect = func(v string) {
+		index[v] = len(index)
+		low[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+		selfLoop := false
+		for _, w := range VarNames(vars[v]) {
+			if _, ok := vars[w]; !ok {
+				continue
+			}
+			if w == v {
+				selfLoop = true
+			}
+			if _, seen := index[w]; !seen {
+				strongConnect(w)
+				if low[w] < low[v] {
+					low[v] = low[w]
+				}
+			} else if onStack[w] && index[w] < low[v] {
+				low[v] = index[w]
+			}
+		}
+		if low[v] != index[v] {
+			return
+		}
+		i := len(stack) - 1
+		for stack[i] != v {
+			i--
+		}
+		component := stack[i:]
+		stack = stack[:i]
+		for _, w := range component {
+			onStack[w] = false
+			if len(component) > 1 || selfLoop {
+				cyclic[w] = true
+			}
+		}
+	}
+	for _, name := range names {
+		if _, seen := index[name]; !seen {
+			strongConnect(name)
+		}
+	}
+	return cyclic
+}
+
+// isRoot returns true if g is exactly :root.
+func isRoot(g selector.Group) bool {
+	return len(g) == 1 && g[0].String() == ":root"
+}
+
+// ResolveRootVars returns a copy of the Stylesheet ss with the custom
+// properties that are only defined in top level :root rules substituted
+// into the declarations using them. Their definitions are removed and a
+// :root rule left empty is dropped. var() references to custom properties
+// defined anywhere else, which could have a different value per element,
+// are left alone as are the declarations using them. ss isn't modified.
+func ResolveRootVars(ss *Stylesheet) *Stylesheet {
+	root := map[string]Declaration{}
+	elsewhere := map[string]bool{}
+	walkDeclarations(ss, func(dl DeclarationList, sel selector.Group, top bool) {
+		for _, d := range dl {
+			if !IsCustomProperty(d.Property) {
+				continue
+			}
+			if !top || !isRoot(sel) {
+				elsewhere[d.Property] = true
+				continue
+			}
+			if prev, ok := root[d.Property]; !ok || !prev.Important || d.Important {
+				root[d.Property] = d
+			}
+		}
+	})
+	// A custom property is static if it and everything it references is
+	// only defined on :root or not at all.
+	static := map[string]bool{}
+	var isStatic func(name string, seen map[string]bool) bool
+	isStatic = func(name string, seen map[string]bool) bool {
+		if elsewhere[name] {
+			return false
+		}
+		d, ok := root[name]
+		if !ok || seen[name] {
+			return true
+		}
+		seen[name] = true
+		for _, ref := range VarNames(d.Value) {
+			if !isStatic(ref, seen) {
+				return false
+			}
+		}
+		return true
+	}
+	vars := map[string]string{}
+	dynamic := map[string]bool{}
+	for name := range elsewhere {
+		dynamic[name] = true
+	}
+	for name, d := range root {
+		if isStatic(name, map[string]bool{}) {
+			static[name] = true
+			vars[name] = d.Value
+		} else {
+			dynamic[name] = true
+		}
+	}
+	resolved := ResolveVars(vars)
+	lookup := func(name string) (string, bool) {
+		v, ok := resolved[name]
+		return v, ok
+	}
+	substitute := func(dl DeclarationList) DeclarationList {
+		out := make(DeclarationList, 0, len(dl))
+		for _, d := range dl {
+			if hasVar(d.Value) && !IsCustomProperty(d.Property) && !anyOf(VarNames(d.Value), dynamic) {
+				// Values that are invalid are left for the browser.
+				if v, ok := SubstituteVars(d.Value, lookup); ok {
+					d.Value = v
+				}
+			}
+			out = append(out, d)
+		}
+		return out
+	}
+	out := mapDeclarations(ss, func(dl DeclarationList, sel selector.Group, top bool) DeclarationList {
+		return substitute(dl)
+	})
+	// Keep the definitions something still refers to.
+	used := map[string]bool{}
+	walkDeclarations(out, func(dl DeclarationList, sel selector.Group, top bool) {
+		for _, d := range dl {
+			if top && isRoot(sel) && static[d.Property] {
+				continue
+			}
+			for _, name := range VarNames(d.Value) {
+				used[name] = true
+			}
+		}
+	})
+	return mapDeclarations(out, func(dl DeclarationList, sel selector.Group, top bool) DeclarationList {
+		if !top || !isRoot(sel) {
+			return dl
+		}
+		var kept DeclarationList
+		for _, d := range dl {
+			if !static[d.Property] || used[d.Property] {
+				kept = append(kept, d)
+			}
+		}
+		if kept == nil {
+			// Drop the empty :root rule.
+			return nil
+		}
+		return kept
+	})
+}
+
+func anyOf(names []string, set map[string]bool) bool {
+	for _, name := range names {
+		if set[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// walkDeclarations calls f with each DeclarationList in ss, the selector
+// of the Ruleset it belongs to and whether that Ruleset is at the top
+// level.
+func walkDeclarations(ss *Stylesheet, f func(dl DeclarationList, sel selector.Group, top bool)) {
+	mapDeclarations(ss, func(dl DeclarationList, sel selector.Group, top bool) DeclarationList {
+		f(dl, sel, top)
+		return dl
+	})
+}
+
+// mapDeclarations returns a copy of ss with each DeclarationList replaced
+// by the result of f. Rulesets f returns a nil DeclarationList for without
+// any nested rules are dropped.
+func mapDeclarations(ss *Stylesheet, f func(dl DeclarationList, sel selector.Group, top bool) DeclarationList) *Stylesheet {
+	out := &Stylesheet{}
+	for _, st := range ss.Statements {
+		switch {
+		case st.Ruleset != nil:
+			if rs := mapRuleset(st.Ruleset, true, f); rs != nil {
+				out.Statements = append(out.Statements, Statement{Ruleset: rs})
+			}
+		case st.AtRule != nil:
+			out.Statements = append(out.Statements, Statement{AtRule: mapAtRule(st.AtRule, nil, f)})
+		default:
+			out.Statements = append(out.Statements, st)
+		}
+	}
+	return out
+}
+
+func mapRuleset(rs *Ruleset, top bool, f func(DeclarationList, selector.Group, bool) DeclarationList) *Ruleset {
+	out := &Ruleset{Selector: rs.Selector, DeclarationList: f(rs.DeclarationList, rs.Selector, top)}
+	out.Nested = mapItems(rs.Nested, rs.Selector, f)
+	if out.DeclarationList == nil && rs.DeclarationList != nil && len(out.Nested) == 0 {
+		return nil
+	}
+	return out
+}
+
+func mapAtRule(ar *AtRule, parent selector.Group, f func(DeclarationList, selector.Group, bool) DeclarationList) *AtRule {
+	if ar.SimpleBlock == nil {
+		return ar
+	}
+	return &AtRule{AtKeyword: ar.AtKeyword, Param: ar.Param,
+		SimpleBlock: &SimpleBlock{Content: mapItems(ar.SimpleBlock.Content, parent, f)}}
+}
+
+// mapItems maps the contents of a block. parent is the selector of the
+// enclosing Ruleset or nil outside of one.
+func mapItems(items []BlockItem, parent selector.Group, f func(DeclarationList, selector.Group, bool) DeclarationList) []BlockItem {
+	var out []BlockItem
+	for _, bi := range items {
+		switch {
+		case bi.Ruleset != nil:
+			if rs := mapRuleset(bi.Ruleset, false, f); rs != nil {
+				out = append(out, BlockItem{Ruleset: rs})
+			}
+		case bi.AtRule != nil:
+			out = append(out, BlockItem{AtRule: mapAtRule(bi.AtRule, parent, f)})
+		case len(bi.DeclarationList) > 0:
+			if dl := f(bi.DeclarationList, parent, false); len(dl) > 0 {
+				out = append(out, BlockItem{DeclarationList: dl})
+			}
+		default:
+			out = append(out, bi)
+		}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package css
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSubstituteVars(t *testing.T) {
+	vars := map[string]string{"--a": "1px", "--b": "red"}
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	cases := []struct {
+		value, expected string
+		ok              bool
+	}{
+		{"0 auto", "0 auto", true},
+		{"var(--a)", "1px", true},
+		{"var(--a) solid var(--b)", "1px solid red", true},
+		{"calc(var(--a) * 2)", "calc(1px * 2)", true},
+		{"var(--c, blue)", "blue", true},
+		{"var(--c, var(--b))", "red", true},
+		{"var(--c)", "", false},
+	}
+	for _, c := range cases {
+		got, ok := SubstituteVars(c.value, lookup)
+		if ok != c.ok || ok && got != c.expected {
+			t.Errorf("SubstituteVars(%q) expected %q %v got %q %v", c.value, c.expected, c.ok, got, ok)
+		}
+	}
+}
+
+func TestVarNames(t *testing.T) {
+	got := VarNames("var(--a) calc(var(--b, var(--c)) + 1px)")
+	expected := []string{"--a", "--b", "--c"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %q got %q", expected, got)
+	}
+}
+
+func TestResolveVars(t *testing.T) {
+	got := ResolveVars(map[string]string{
+		"--a": "var(--b) 2px",
+		"--b": "1px",
+		"--c": "var(--d)",
+		"--d": "var(--c)",
+		"--e": "var(--missing)",
+	})
+	expected := map[string]string{"--a": "1px 2px", "--b": "1px"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v got %v", expected, got)
+	}
+}
+
+func TestResolveRootVars(t *testing.T) {
+	ss, err := ParseString(`
+:root { --pad: 4px; --brand: red; --x: var(--y); --y: var(--x) }
+.dark { --brand: white }
+p { padding: var(--pad); color: var(--brand); margin: var(--x, 0) }
+@media print { p { padding: calc(var(--pad) * 2) } }
+`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	expected, err := ParseString(`
+:root { --brand: red }
+.dark { --brand: white }
+p { padding: 4px; color: var(--brand); margin: 0 }
+@media print { p { padding: calc(4px * 2) } }
+`)
+	if err != nil {
+		t.Fatalf("Error parsing stylesheet: %s", err)
+	}
+	before := ss.String()
+	if got := ResolveRootVars(ss).String(); got != expected.String() {
+		t.Errorf("Expected\n%s\ngot\n%s", expected, got)
+	}
+	if ss.String() != before {
+		t.Errorf("ResolveRootVars modified its input")
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2011 Jeremy Wall (jeremy@marzhillstudios.com)
+// Use of this source code is governed by the Artistic License 2.0.
+// That License is included in the LICENSE file.
+
+package h5
+
+//go:generate go run gen_elements.go
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// AttrFunc sets an attribute on an element under construction.
+type AttrFunc func(n *html.Node)
+
+// Attr creates an AttrFunc that sets the attribute key to val.
+func Attr(key, val string) AttrFunc {
+	return func(n *html.Node) {
+		for i, a := range n.Attr {
+			if a.Key == key && a.Namespace == "" {
+				n.Attr[i].Val = val
+				return
+			}
+		}
+		n.Attr = append(n.Attr, html.Attribute{Key: key, Val: val})
+	}
+}
+
+// ID creates an AttrFunc that sets the id attribute.
+func ID(id string) AttrFunc {
+	return Attr("id", id)
+}
+
+// Class creates an AttrFunc that adds the class names to the class
+// attribute.
+func Class(names ...string) AttrFunc {
+	return func(n *html.Node) {
+		for i, a := range n.Attr {
+			if a.Key == "class" && a.Namespace == "" {
+				n.Attr[i].Val = strings.Join(append(strings.Fields(a.Val), names...), " ")
+				return
+			}
+		}
+		Attr("class", strings.Join(names, " "))(n)
+	}
+}
+
+// DataAttr creates an AttrFunc that sets the data-key attribute to val.
+func DataAttr(key, val string) AttrFunc {
+	return Attr("data-"+key, val)
+}
+
+// Bool creates an AttrFunc that sets the boolean attribute key, eg:
+// disabled.
+func Bool(key string) AttrFunc {
+	return Attr(key, "")
+}
+
+// Build constructs the element name. The element builders like P and Ul
+// call it. Its args can be:
+//
+//	AttrFunc      sets an attribute
+//	*html.Node    appended as a child
+//	[]*html.Node  appended as children
+//	string        appended as a TextNode
+//
+// Nil args are ignored and anything else panics as do children of a void
+// element like <br>. html element names are lower cased.
+func Build(name string, args ...interface{}) *html.Node {
+	name = strings.ToLower(name)
+	return build(atom.Lookup([]byte(name)), name, args)
+}
+
+func build(a atom.Atom, name string, args []interface{}) *html.Node {
+	return buildNS(a, "", name, args)
+}
+
+// buildNS constructs the element name in the namespace ns.
+func buildNS(a atom.Atom, ns, name string, args []interface{}) *html.Node {
+	n := &html.Node{Type: html.ElementNode, DataAtom: a, Data: name, Namespace: ns}
+	add := func(c *html.Node) {
+		if c == nil {
+			return
+		}
+		if voidElements[name] && n.Namespace == "" {
+			panic(fmt.Sprintf("Attempt to add children to void element <%s>", name))
+		}
+		n.AppendChild(c)
+	}
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case nil:
+		case AttrFunc:
+			arg(n)
+		case *html.Node:
+			add(arg)
+		case []*html.Node:
+			for _, c := range arg {
+				add(c)
+			}
+		case string:
+			add(Text(arg))
+		default:
+			panic(fmt.Sprintf("Unexpected %T argument for <%s>", arg, name))
+		}
+	}
+	return n
+}
+
+func void(a atom.Atom, name string, attrs []AttrFunc) *html.Node {
+	n := &html.Node{Type: html.ElementNode, DataAtom: a, Data: name}
+	for _, f := range attrs {
+		f(n)
+	}
+	return n
+}
+
+// Svg constructs an <svg> element. The elements among its args and their
+// descendants are put in the svg namespace, apart from the contents of a
+// <foreignObject>. See Build for the args it takes.
+func Svg(args ...interface{}) *html.Node {
+	return SvgElement("svg", args...)
+}
+
+// SvgElement constructs the svg element name, eg: "circle" or
+// "foreignObject", like Svg.
+func SvgElement(name string, args ...interface{}) *html.Node {
+	return foreign("svg", "foreignObject", name, args)
+}
+
+// Math constructs a MathML <math> element. The elements among its args and
+// their descendants are put in the math namespace, apart from the contents
+// of an <annotation-xml>. See Build for the args it takes.
+func Math(args ...interface{}) *html.Node {
+	return MathElement("math", args...)
+}
+
+// MathElement constructs the MathML element name, eg: "mi", like Math.
+func MathElement(name string, args ...interface{}) *html.Node {
+	return foreign("math", "annotation-xml", name, args)
+}
+
+// foreign constructs the element name in the namespace ns. The html
+// contents of an integration element are left alone. Foreign names keep
+// their case so the atom of eg: "foreignObject" is atom.ForeignObject.
+func foreign(ns, integration, name string, args []interface{}) *html.Node {
+	n := buildNS(atom.Lookup([]byte(name)), ns, name, args)
+	if name != integration {
+		setNamespace(n, ns, integration)
+	}
+	return n
+}
+
+func setNamespace(n *html.Node, ns, integration string) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Namespace != "" {
+			continue
+		}
+		c.Namespace = ns
+		if c.Data != integration {
+			setNamespace(c, ns, integration)
+		}
+	}
+}
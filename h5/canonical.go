@@ -0,0 +1,229 @@
+// Copyright 2011 Jeremy Wall (jeremy@marzhillstudios.com)
+// Use of this source code is governed by the Artistic License 2.0.
+// That License is included in the LICENSE file.
+
+package h5
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/h5/walk"
+)
+
+// CanonicalOptions configures Canonicalize.
+type CanonicalOptions struct {
+	// DropComments removes the comment nodes.
+	DropComments bool
+}
+
+// preformatted are the elements whose whitespace is kept as it is.
+var preformatted = map[string]bool{
+	"pre": true, "textarea": true, "script": true, "style": true,
+	"listing": true, "plaintext": true,
+}
+
+// blockElements are the elements whose whitespace at their start and end
+// and next to them doesn't render. They are the elements the default
+// stylesheet displays as blocks, list items or table parts. Elements that
+// aren't displayed like <script> or <datalist> and inline blocks like
+// <select> aren't included since the whitespace around them still
+// renders between the content on either side.
+// http://www.w3.org/TR/html5/rendering.html#the-css-user-agent-style-sheet-and-presentational-hints
+var blockElements = map[string]bool{
+	"html": true, "head": true, "body": true, "address": true,
+	"article": true, "aside": true, "blockquote": true, "center": true,
+	"details": true, "dialog": true, "dir": true, "summary": true,
+	"div": true, "dl": true, "dt": true, "dd": true, "fieldset": true,
+	"legend": true, "figure": true, "figcaption": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hgroup": true, "hr": true, "li": true,
+	"listing": true, "main": true, "menu": true, "nav": true, "ol": true,
+	"p": true, "pre": true, "search": true, "section": true, "table": true,
+	"caption": true, "colgroup": true, "col": true, "thead": true,
+	"tbody": true, "tfoot": true, "tr": true, "td": true, "th": true,
+	"ul": true, "xmp": true, "option": true, "optgroup": true,
+}
+
+// booleanAttrs are the html attributes that are true when present whatever
+// their value.
+var booleanAttrs = map[string]bool{
+	"allowfullscreen": true, "async": true, "autofocus": true,
+	"autoplay": true, "checked": true, "controls": true, "default": true,
+	"defer": true, "disabled": true, "formnovalidate": true, "inert": true,
+	"ismap": true, "itemscope": true, "loop": true, "multiple": true,
+	"muted": true, "nomodule": true, "novalidate": true, "open": true,
+	"playsinline": true, "readonly": true, "required": true,
+	"reversed": true, "selected": true,
+}
+
+// Canonicalize rewrites the tree rooted at n to a canonical form so
+// documents that are the same apart from their formatting render the
+// same. Attributes are sorted, the class names in class attributes are
+// sorted and deduplicated, style attributes are written like
+// "color: red; margin: 0", boolean attributes like disabled="disabled"
+// are emptied and empty class and style attributes are removed. Adjacent
+// text is merged, runs of whitespace are collapsed to a space and
+// whitespace that doesn't render in <head> or next to block elements is
+// removed, apart from in <pre>, <textarea>, <script> and <style> elements.
+// Comments are removed if opts.DropComments is set.
+//
+// The parser already makes optional tags explicit so rendering a
+// canonical tree always writes them.
+func Canonicalize(n *html.Node, opts CanonicalOptions) {
+	walk.Walker(func(c *html.Node) walk.Action {
+		switch c.Type {
+		case html.CommentNode:
+			if opts.DropComments && c.Parent != nil {
+				c.Parent.RemoveChild(c)
+			}
+		case html.ElementNode:
+			canonicalAttrs(c)
+		}
+		return walk.Continue
+	}).Walk(n)
+	collapseWhitespace(n)
+}
+
+// collapseWhitespace merges the adjacent text in the tree rooted at n and
+// collapses and trims its whitespace outside the preformatted elements.
+func collapseWhitespace(n *html.Node) {
+	NormalizeText(n)
+	walk.Walker(func(c *html.Node) walk.Action {
+		switch {
+		case c.Type == html.ElementNode && c.Namespace == "" && preformatted[c.Data]:
+			return walk.SkipChildren
+		case c.Type == html.TextNode:
+			canonicalText(c)
+		}
+		return walk.Continue
+	}).Walk(n)
+	NormalizeText(n)
+}
+
+func canonicalAttrs(n *html.Node) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if a.Namespace == "" {
+			switch a.Key {
+			case "class":
+				a.Val = canonicalClass(a.Val)
+			case "style":
+				a.Val = canonicalStyle(a.Val)
+			}
+			if a.Val == "" && (a.Key == "class" || a.Key == "style") {
+				continue
+			}
+			if booleanAttrs[a.Key] && strings.EqualFold(a.Val, a.Key) {
+				a.Val = ""
+			}
+		}
+		attrs = append(attrs, a)
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		if attrs[i].Namespace != attrs[j].Namespace {
+			return attrs[i].Namespace < attrs[j].Namespace
+		}
+		return attrs[i].Key < attrs[j].Key
+	})
+	n.Attr = attrs
+}
+
+func canonicalClass(s string) string {
+	names := strings.Fields(s)
+	sort.Strings(names)
+	out := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			out = append(out, name)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// canonicalStyle rewrites the valid declarations of a style attribute
+// with lower case property names and collapsed whitespace. Their order
+// matters so it is kept.
+func canonicalStyle(s string) string {
+	var decls []string
+	for _, d := range parseStyle(s) {
+		decl := d.Property + ":"
+		if d.Value != "" {
+			decl += " " + d.Value
+		}
+		if d.Important {
+			decl += " !important"
+		}
+		decls = append(decls, decl)
+	}
+	return strings.Join(decls, "; ")
+}
+
+// isBlock returns true if whitespace next to n doesn't render. Nothing in
+// <head> renders so its children count as blocks.
+func isBlock(n *html.Node) bool {
+	switch n.Type {
+	case html.DocumentNode, html.DoctypeNode:
+		return true
+	case html.ElementNode:
+		if n.Namespace != "" {
+			return false
+		}
+		return blockElements[n.Data] || n.Parent != nil && n.Parent.Type == html.ElementNode &&
+			n.Parent.Namespace == "" && n.Parent.Data == "head"
+	}
+	return false
+}
+
+// canonicalText collapses the whitespace of the TextNode n and trims it
+// next to block boundaries removing n if nothing is left.
+func canonicalText(n *html.Node) {
+	if n.Data == "" {
+		return
+	}
+	var b strings.Builder
+	space := false
+	for _, f := range strings.FieldsFunc(n.Data, func(r rune) bool {
+		return r < 0x80 && strings.IndexByte(htmlSpace, byte(r)) >= 0
+	}) {
+		if space {
+			b.WriteByte(' ')
+		}
+		b.WriteString(f)
+		space = true
+	}
+	text := b.String()
+	if text == "" {
+		text = " "
+	}
+	if strings.IndexByte(htmlSpace, n.Data[0]) >= 0 && text != " " {
+		text = " " + text
+	}
+	if strings.IndexByte(htmlSpace, n.Data[len(n.Data)-1]) >= 0 && text != " " {
+		text += " "
+	}
+	if n.PrevSibling == nil && n.Parent != nil && isBlock(n.Parent) || n.PrevSibling != nil && isBlock(n.PrevSibling) {
+		text = strings.TrimLeft(text, " ")
+	}
+	if n.NextSibling == nil && n.Parent != nil && isBlock(n.Parent) || n.NextSibling != nil && isBlock(n.NextSibling) {
+		text = strings.TrimRight(text, " ")
+	}
+	n.Data = text
+	if text == "" && n.Parent != nil {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+// Hash returns a hex encoded sha256 hash of the canonical form of the
+// tree rooted at n with the default CanonicalOptions. n isn't changed.
+func Hash(n *html.Node) string {
+	c := CloneNode(n)
+	Canonicalize(c, CanonicalOptions{})
+	h := sha256.New()
+	RenderNodes(h, []*html.Node{c})
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,588 @@
+// Copyright 2011 Jeremy Wall (jeremy@marzhillstudios.com)
+// Use of this source code is governed by the Artistic License 2.0.
+// That License is included in the LICENSE file.
+
+package h5
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+type changeKind int
+
+const (
+	// Inserted is a node only in the second tree.
+	Inserted changeKind = iota
+	// Removed is a node only in the first tree.
+	Removed
+	// Moved is a node that changed places among its siblings.
+	Moved
+	// TextChanged is a text, comment or doctype node with changed data.
+	TextChanged
+	// AttrAdded is an attribute only in the second tree.
+	AttrAdded
+	// AttrRemoved is an attribute only in the first tree.
+	AttrRemoved
+	// AttrChanged is an attribute with a changed value.
+	AttrChanged
+	// AttrsReordered is an element with its attributes in a different
+	// order.
+	AttrsReordered
+)
+
+func (k changeKind) String() string {
+	switch k {
+	case Inserted:
+		return "inserted"
+	case Removed:
+		return "removed"
+	case Moved:
+		return "moved"
+	case TextChanged:
+		return "text changed"
+	case AttrAdded:
+		return "attribute added"
+	case AttrRemoved:
+		return "attribute removed"
+	case AttrChanged:
+		return "attribute changed"
+	case AttrsReordered:
+		return "attributes reordered"
+	}
+	panic("Unreachable")
+}
+
+// Change is one edit turning the first tree of a Diff into the second.
+type Change struct {
+	Kind changeKind
+	// A and B are the node in the first tree and its counterpart in the
+	// second. A is nil for an Inserted node and B for a Removed one.
+	A, B *html.Node
+	// Path and NewPath are the paths of A and B from the roots of their
+	// trees, eg: /html/body/ul/li[2]/text().
+	Path, NewPath string
+	// Key is the name of the attribute for attribute changes.
+	Key string
+	// Old and New are the text or attribute values before and after or
+	// the attribute names in order when they are reordered.
+	Old, New string
+}
+
+// DiffOptions configures DiffWithOptions.
+type DiffOptions struct {
+	// IgnoreWhitespace skips text nodes that are only whitespace.
+	IgnoreWhitespace bool
+	// IgnoreAttrOrder doesn't report reordered attributes.
+	IgnoreAttrOrder bool
+	// IgnoreComments skips comment nodes.
+	IgnoreComments bool
+	// Keys are the attributes that identify an element among its siblings
+	// in order of preference. They default to id.
+	Keys []string
+}
+
+// Diff returns the changes that turn the tree rooted at a into the tree
+// rooted at b in document order.
+func Diff(a, b *html.Node) []Change {
+	return DiffWithOptions(a, b, DiffOptions{})
+}
+
+// DiffWithOptions returns the changes that turn the tree rooted at a into
+// the tree rooted at b.
+//
+// Matched nodes are compared recursively. Children are aligned first on
+// their keys and then on a longest common subsequence of identical
+// subtrees and of elements with the same name. Matched children out of
+// order are reported as Moved. Nodes moved to another parent are reported
+// as Removed and Inserted.
+func DiffWithOptions(a, b *html.Node, opts DiffOptions) []Change {
+	if opts.Keys == nil {
+		opts.Keys = []string{"id"}
+	}
+	d := &differ{opts: opts, ra: a, rb: b, hashes: map[*html.Node]uint64{}}
+	if !d.similar(a, b) {
+		d.removed(a)
+		d.inserted(b)
+	} else {
+		d.diff(a, b)
+	}
+	return d.changes
+}
+
+type differ struct {
+	opts    DiffOptions
+	ra, rb  *html.Node
+	hashes  map[*html.Node]uint64
+	changes []Change
+}
+
+func (d *differ) add(c Change) {
+	if c.A != nil {
+		c.Path = nodePath(d.ra, c.A)
+	}
+	if c.B != nil {
+		c.NewPath = nodePath(d.rb, c.B)
+	}
+	d.changes = append(d.changes, c)
+}
+
+func (d *differ) removed(n *html.Node) {
+	d.add(Change{Kind: Removed, A: n})
+}
+
+func (d *differ) inserted(n *html.Node) {
+	d.add(Change{Kind: Inserted, B: n})
+}
+
+// skip returns true if the options ignore n.
+func (d *differ) skip(n *html.Node) bool {
+	switch n.Type {
+	case html.TextNode:
+		return d.opts.IgnoreWhitespace && strings.Trim(n.Data, htmlSpace) == ""
+	case html.CommentNode:
+		return d.opts.IgnoreComments
+	}
+	return false
+}
+
+func (d *differ) children(n *html.Node) []*html.Node {
+	var cs []*html.Node
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if !d.skip(c) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// similar returns true if a and b are the same type of node with the same
+// name so they can be compared.
+func (d *differ) similar(a, b *html.Node) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	return a.Type != html.ElementNode || a.Data == b.Data && a.Namespace == b.Namespace
+}
+
+// hash returns a hash of the tree rooted at n that is equal for trees the
+// options consider identical.
+func (d *differ) hash(n *html.Node) uint64 {
+	if h, ok := d.hashes[n]; ok {
+		return h
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00", n.Type, n.Namespace, n.Data)
+	attrs := make([]string, len(n.Attr))
+	for i, a := range n.Attr {
+		attrs[i] = attrName(a) + "=" + a.Val
+	}
+	if d.opts.IgnoreAttrOrder {
+		sort.Strings(attrs)
+	}
+	for _, a := range attrs {
+		fmt.Fprintf(h, "%s\x00", a)
+	}
+	for _, c := range d.children(n) {
+		fmt.Fprintf(h, "%x\x01", d.hash(c))
+	}
+	d.hashes[n] = h.Sum64()
+	return d.hashes[n]
+}
+
+func attrName(a html.Attribute) string {
+	if a.Namespace != "" {
+		return a.Namespace + ":" + a.Key
+	}
+	return a.Key
+}
+
+// key returns the key identifying the element n among its siblings or ""
+// if it hasn't got one.
+func (d *differ) key(n *html.Node) string {
+	if n.Type != html.ElementNode {
+		return ""
+	}
+	for _, k := range d.opts.Keys {
+		for _, a := range n.Attr {
+			if a.Key == k && a.Namespace == "" {
+				return n.Data + "\x00" + k + "\x00" + a.Val
+			}
+		}
+	}
+	return ""
+}
+
+// diff records the changes between the similar nodes a and b.
+func (d *differ) diff(a, b *html.Node) {
+	switch a.Type {
+	case html.TextNode, html.CommentNode, html.DoctypeNode:
+		if a.Data != b.Data {
+			d.add(Change{Kind: TextChanged, A: a, B: b, Old: a.Data, New: b.Data})
+		}
+		return
+	case html.ElementNode:
+		d.diffAttrs(a, b)
+	}
+	if d.hash(a) == d.hash(b) {
+		return
+	}
+	d.diffChildren(a, b)
+}
+
+// firstAttrs returns the attributes of n keeping only the first
+// occurrence of each name like a parser does.
+func firstAttrs(n *html.Node) ([]html.Attribute, map[string]string) {
+	var attrs []html.Attribute
+	vals := map[string]string{}
+	for _, at := range n.Attr {
+		name := attrName(at)
+		if _, ok := vals[name]; ok {
+			continue
+		}
+		vals[name] = at.Val
+		attrs = append(attrs, at)
+	}
+	return attrs, vals
+}
+
+func (d *differ) diffAttrs(a, b *html.Node) {
+	aAttrs, av := firstAttrs(a)
+	bAttrs, bv := firstAttrs(b)
+	var aOrder, bOrder []string
+	for _, at := range aAttrs {
+		name := attrName(at)
+		v, ok := bv[name]
+		switch {
+		case !ok:
+			d.add(Change{Kind: AttrRemoved, A: a, B: b, Key: name, Old: at.Val})
+		case v != at.Val:
+			d.add(Change{Kind: AttrChanged, A: a, B: b, Key: name, Old: at.Val, New: v})
+			aOrder = append(aOrder, name)
+		default:
+			aOrder = append(aOrder, name)
+		}
+	}
+	for _, at := range bAttrs {
+		name := attrName(at)
+		if _, ok := av[name]; !ok {
+			d.add(Change{Kind: AttrAdded, A: a, B: b, Key: name, New: at.Val})
+		} else {
+			bOrder = append(bOrder, name)
+		}
+	}
+	if d.opts.IgnoreAttrOrder || len(aOrder) != len(bOrder) {
+		return
+	}
+	for i := range aOrder {
+		if aOrder[i] != bOrder[i] {
+			d.add(Change{Kind: AttrsReordered, A: a, B: b,
+				Old: strings.Join(aOrder, " "), New: strings.Join(bOrder, " ")})
+			return
+		}
+	}
+}
+
+// diffChildren aligns the children of a and b and records the changes
+// between them.
+func (d *differ) diffChildren(a, b *html.Node) {
+	as, bs := d.children(a), d.children(b)
+	match := make([]int, len(as))
+	matched := make([]bool, len(bs))
+	for i := range match {
+		match[i] = -1
+	}
+	// Align the children with keys unique among their siblings.
+	aKeys, bKeys := d.keys(as), d.keys(bs)
+	for k, i := range aKeys {
+		if j, ok := bKeys[k]; ok && i >= 0 && j >= 0 {
+			match[i], matched[j] = j, true
+		}
+	}
+	// Align the rest on identical subtrees and then within the gaps on
+	// elements with the same name.
+	var ua, ub []int
+	for i, c := range as {
+		if match[i] < 0 && d.key(c) == "" {
+			ua = append(ua, i)
+		}
+	}
+	for j, c := range bs {
+		if !matched[j] && d.key(c) == "" {
+			ub = append(ub, j)
+		}
+	}
+	same := lcs(ua, ub, func(i, j int) bool { return d.hash(as[i]) == d.hash(bs[j]) })
+	for _, p := range same {
+		match[p[0]], matched[p[1]] = p[1], true
+	}
+	// gap runs the shallow alignment between consecutive identical pairs.
+	gap := func(ia, ib []int) {
+		for _, p := range lcs(ia, ib, func(i, j int) bool { return d.similar(as[i], bs[j]) }) {
+			match[p[0]], matched[p[1]] = p[1], true
+		}
+	}
+	pa, pb := 0, 0
+	for _, p := range append(same, [2]int{len(as), len(bs)}) {
+		var ia, ib []int
+		for ; pa < len(ua) && ua[pa] < p[0]; pa++ {
+			ia = append(ia, ua[pa])
+		}
+		for ; pb < len(ub) && ub[pb] < p[1]; pb++ {
+			ib = append(ib, ub[pb])
+		}
+		gap(ia, ib)
+		pa++
+		pb++
+	}
+	// Identical subtrees the alignment left out have moved. Moving them
+	// out of the way can leave similar elements to align.
+	unmatched := map[uint64][]int{}
+	for _, j := range ub {
+		if !matched[j] {
+			unmatched[d.hash(bs[j])] = append(unmatched[d.hash(bs[j])], j)
+		}
+	}
+	var ia, ib []int
+	moved := false
+	for _, i := range ua {
+		if js := unmatched[d.hash(as[i])]; match[i] < 0 && len(js) > 0 {
+			match[i], matched[js[0]] = js[0], true
+			unmatched[d.hash(as[i])] = js[1:]
+			moved = true
+		}
+		if match[i] < 0 {
+			ia = append(ia, i)
+		}
+	}
+	for _, j := range ub {
+		if !matched[j] {
+			ib = append(ib, j)
+		}
+	}
+	if moved {
+		gap(ia, ib)
+	}
+	// Matched children outside the longest increasing run of positions in
+	// b have moved.
+	var order []int
+	for _, j := range match {
+		if j >= 0 {
+			order = append(order, j)
+		}
+	}
+	stays := lis(order)
+	next := 0
+	insertBefore := func(j int) {
+		for ; next < j; next++ {
+			if !matched[next] {
+				d.inserted(bs[next])
+			}
+		}
+	}
+	for i, j := range match {
+		switch {
+		case j < 0:
+			d.removed(as[i])
+			continue
+		case stays[j]:
+			insertBefore(j)
+		default:
+			d.add(Change{Kind: Moved, A: as[i], B: bs[j]})
+		}
+		d.diff(as[i], bs[j])
+	}
+	insertBefore(len(bs))
+}
+
+// keys indexes the children with keys by their keys. Keys that aren't
+// unique index -1.
+func (d *differ) keys(ns []*html.Node) map[string]int {
+	m := map[string]int{}
+	for i, n := range ns {
+		k := d.key(n)
+		if k == "" {
+			continue
+		}
+		if _, ok := m[k]; ok {
+			m[k] = -1
+		} else {
+			m[k] = i
+		}
+	}
+	return m
+}
+
+// lcs returns the pairs of a longest common subsequence of xs and ys.
+func lcs(xs, ys []int, eq func(x, y int) bool) [][2]int {
+	if len(xs) == 0 || len(ys) == 0 {
+		return nil
+	}
+	// l[i][j] is the length of the lcs of xs[i:] and ys[j:].
+	l := make([][]int, len(xs)+1)
+	for i := range l {
+		l[i] = make([]int, len(ys)+1)
+	}
+	for i := len(xs) - 1; i >= 0; i-- {
+		for j := len(ys) - 1; j >= 0; j-- {
+			switch {
+			case eq(xs[i], ys[j]):
+				l[i][j] = l[i+1][j+1] + 1
+			case l[i+1][j] >= l[i][j+1]:
+				l[i][j] = l[i+1][j]
+			default:
+				l[i][j] = l[i][j+1]
+			}
+		}
+	}
+	var pairs [][2]int
+	for i, j := 0, 0; i < len(xs) && j < len(ys); {
+		switch {
+		case eq(xs[i], ys[j]):
+			pairs = append(pairs, [2]int{xs[i], ys[j]})
+			i++
+			j++
+		case l[i+1][j] >= l[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	return pairs
+}
+
+// lis returns the set of values in a longest increasing subsequence of
+// the distinct values in vs.
+func lis(vs []int) map[int]bool {
+	// tails[k] is the index in vs of the smallest tail of an increasing
+	// subsequence of length k+1.
+	var tails []int
+	prev := make([]int, len(vs))
+	for i, v := range vs {
+		k := sort.Search(len(tails), func(k int) bool { return vs[tails[k]] >= v })
+		if k > 0 {
+			prev[i] = tails[k-1]
+		} else {
+			prev[i] = -1
+		}
+		if k == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[k] = i
+		}
+	}
+	set := map[int]bool{}
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			set[vs[i]] = true
+		}
+	}
+	return set
+}
+
+// nodePath returns the path of n from root, eg: /html/body/p[2]/text().
+// An index is added when siblings share the name.
+func nodePath(root, n *html.Node) string {
+	var parts []string
+	for ; n != nil; n = n.Parent {
+		if n.Type != html.DocumentNode {
+			parts = append(parts, pathStep(n))
+		}
+		if n == root {
+			break
+		}
+	}
+	if len(parts) == 0 {
+		return "/"
+	}
+	var b strings.Builder
+	for i := len(parts) - 1; i >= 0; i-- {
+		b.WriteString("/" + parts[i])
+	}
+	return b.String()
+}
+
+func pathStep(n *html.Node) string {
+	name := stepName(n)
+	index, count := 0, 0
+	if n.Parent == nil {
+		return name
+	}
+	for c := n.Parent.FirstChild; c != nil; c = c.NextSibling {
+		if stepName(c) == name {
+			count++
+			if c == n {
+				index = count
+			}
+		}
+	}
+	if count > 1 {
+		return fmt.Sprintf("%s[%d]", name, index)
+	}
+	return name
+}
+
+func stepName(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return "text()"
+	case html.CommentNode:
+		return "comment()"
+	case html.DoctypeNode:
+		return "doctype()"
+	case html.ElementNode:
+		if n.Namespace != "" {
+			return n.Namespace + ":" + n.Data
+		}
+	}
+	return n.Data
+}
+
+// RenderChanges writes the changes as a unified diff with a hunk for each
+// change headed by the path of the node.
+func RenderChanges(w io.Writer, cs []Change) error {
+	for _, c := range cs {
+		var err error
+		switch c.Kind {
+		case Removed:
+			_, err = fmt.Fprintf(w, "@@ %s %s @@\n%s", c.Path, c.Kind, prefixLines("-", RenderNodesToString([]*html.Node{c.A})))
+		case Inserted:
+			_, err = fmt.Fprintf(w, "@@ %s %s @@\n%s", c.NewPath, c.Kind, prefixLines("+", RenderNodesToString([]*html.Node{c.B})))
+		case Moved:
+			_, err = fmt.Fprintf(w, "@@ %s moved to %s @@\n", c.Path, c.NewPath)
+		case AttrAdded, AttrRemoved, AttrChanged:
+			_, err = fmt.Fprintf(w, "@@ %s @%s %s @@\n", c.Path, c.Key, c.Kind)
+			if err == nil && c.Kind != AttrAdded {
+				_, err = io.WriteString(w, prefixLines("-", c.Old))
+			}
+			if err == nil && c.Kind != AttrRemoved {
+				_, err = io.WriteString(w, prefixLines("+", c.New))
+			}
+		default:
+			_, err = fmt.Fprintf(w, "@@ %s %s @@\n%s%s", c.Path, c.Kind, prefixLines("-", c.Old), prefixLines("+", c.New))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderChangesToString renders the changes as a unified diff.
+func RenderChangesToString(cs []Change) string {
+	buf := bytes.NewBufferString("")
+	RenderChanges(buf, cs)
+	return buf.String()
+}
+
+// prefixLines puts prefix before each line of s.
+func prefixLines(prefix, s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	return prefix + strings.Join(lines, "\n"+prefix) + "\n"
+}
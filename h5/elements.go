@@ -0,0 +1,568 @@
+// generated by go run gen_elements.go; DO NOT EDIT
+
+package h5
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// A constructs an <a> element. See Build for the args it takes.
+func A(args ...interface{}) *html.Node {
+	return build(atom.A, "a", args)
+}
+
+// Abbr constructs an <abbr> element. See Build for the args it takes.
+func Abbr(args ...interface{}) *html.Node {
+	return build(atom.Abbr, "abbr", args)
+}
+
+// Address constructs an <address> element. See Build for the args it takes.
+func Address(args ...interface{}) *html.Node {
+	return build(atom.Address, "address", args)
+}
+
+// Area constructs a void <area> element.
+func Area(attrs ...AttrFunc) *html.Node {
+	return void(atom.Area, "area", attrs)
+}
+
+// Article constructs an <article> element. See Build for the args it takes.
+func Article(args ...interface{}) *html.Node {
+	return build(atom.Article, "article", args)
+}
+
+// Aside constructs an <aside> element. See Build for the args it takes.
+func Aside(args ...interface{}) *html.Node {
+	return build(atom.Aside, "aside", args)
+}
+
+// Audio constructs an <audio> element. See Build for the args it takes.
+func Audio(args ...interface{}) *html.Node {
+	return build(atom.Audio, "audio", args)
+}
+
+// B constructs a <b> element. See Build for the args it takes.
+func B(args ...interface{}) *html.Node {
+	return build(atom.B, "b", args)
+}
+
+// Base constructs a void <base> element.
+func Base(attrs ...AttrFunc) *html.Node {
+	return void(atom.Base, "base", attrs)
+}
+
+// Bdi constructs a <bdi> element. See Build for the args it takes.
+func Bdi(args ...interface{}) *html.Node {
+	return build(atom.Bdi, "bdi", args)
+}
+
+// Bdo constructs a <bdo> element. See Build for the args it takes.
+func Bdo(args ...interface{}) *html.Node {
+	return build(atom.Bdo, "bdo", args)
+}
+
+// Blockquote constructs a <blockquote> element. See Build for the args it takes.
+func Blockquote(args ...interface{}) *html.Node {
+	return build(atom.Blockquote, "blockquote", args)
+}
+
+// Body constructs a <body> element. See Build for the args it takes.
+func Body(args ...interface{}) *html.Node {
+	return build(atom.Body, "body", args)
+}
+
+// Br constructs a void <br> element.
+func Br(attrs ...AttrFunc) *html.Node {
+	return void(atom.Br, "br", attrs)
+}
+
+// Button constructs a <button> element. See Build for the args it takes.
+func Button(args ...interface{}) *html.Node {
+	return build(atom.Button, "button", args)
+}
+
+// Canvas constructs a <canvas> element. See Build for the args it takes.
+func Canvas(args ...interface{}) *html.Node {
+	return build(atom.Canvas, "canvas", args)
+}
+
+// Caption constructs a <caption> element. See Build for the args it takes.
+func Caption(args ...interface{}) *html.Node {
+	return build(atom.Caption, "caption", args)
+}
+
+// Cite constructs a <cite> element. See Build for the args it takes.
+func Cite(args ...interface{}) *html.Node {
+	return build(atom.Cite, "cite", args)
+}
+
+// Code constructs a <code> element. See Build for the args it takes.
+func Code(args ...interface{}) *html.Node {
+	return build(atom.Code, "code", args)
+}
+
+// Col constructs a void <col> element.
+func Col(attrs ...AttrFunc) *html.Node {
+	return void(atom.Col, "col", attrs)
+}
+
+// Colgroup constructs a <colgroup> element. See Build for the args it takes.
+func Colgroup(args ...interface{}) *html.Node {
+	return build(atom.Colgroup, "colgroup", args)
+}
+
+// DataEl constructs a <data> element. See Build for the args it takes.
+func DataEl(args ...interface{}) *html.Node {
+	return build(atom.Data, "data", args)
+}
+
+// Datalist constructs a <datalist> element. See Build for the args it takes.
+func Datalist(args ...interface{}) *html.Node {
+	return build(atom.Datalist, "datalist", args)
+}
+
+// Dd constructs a <dd> element. See Build for the args it takes.
+func Dd(args ...interface{}) *html.Node {
+	return build(atom.Dd, "dd", args)
+}
+
+// Del constructs a <del> element. See Build for the args it takes.
+func Del(args ...interface{}) *html.Node {
+	return build(atom.Del, "del", args)
+}
+
+// Details constructs a <details> element. See Build for the args it takes.
+func Details(args ...interface{}) *html.Node {
+	return build(atom.Details, "details", args)
+}
+
+// Dfn constructs a <dfn> element. See Build for the args it takes.
+func Dfn(args ...interface{}) *html.Node {
+	return build(atom.Dfn, "dfn", args)
+}
+
+// Dialog constructs a <dialog> element. See Build for the args it takes.
+func Dialog(args ...interface{}) *html.Node {
+	return build(atom.Dialog, "dialog", args)
+}
+
+// DivEl constructs a <div> element. See Build for the args it takes.
+func DivEl(args ...interface{}) *html.Node {
+	return build(atom.Div, "div", args)
+}
+
+// Dl constructs a <dl> element. See Build for the args it takes.
+func Dl(args ...interface{}) *html.Node {
+	return build(atom.Dl, "dl", args)
+}
+
+// Dt constructs a <dt> element. See Build for the args it takes.
+func Dt(args ...interface{}) *html.Node {
+	return build(atom.Dt, "dt", args)
+}
+
+// Em constructs an <em> element. See Build for the args it takes.
+func Em(args ...interface{}) *html.Node {
+	return build(atom.Em, "em", args)
+}
+
+// Embed constructs a void <embed> element.
+func Embed(attrs ...AttrFunc) *html.Node {
+	return void(atom.Embed, "embed", attrs)
+}
+
+// Fieldset constructs a <fieldset> element. See Build for the args it takes.
+func Fieldset(args ...interface{}) *html.Node {
+	return build(atom.Fieldset, "fieldset", args)
+}
+
+// Figcaption constructs a <figcaption> element. See Build for the args it takes.
+func Figcaption(args ...interface{}) *html.Node {
+	return build(atom.Figcaption, "figcaption", args)
+}
+
+// Figure constructs a <figure> element. See Build for the args it takes.
+func Figure(args ...interface{}) *html.Node {
+	return build(atom.Figure, "figure", args)
+}
+
+// Footer constructs a <footer> element. See Build for the args it takes.
+func Footer(args ...interface{}) *html.Node {
+	return build(atom.Footer, "footer", args)
+}
+
+// Form constructs a <form> element. See Build for the args it takes.
+func Form(args ...interface{}) *html.Node {
+	return build(atom.Form, "form", args)
+}
+
+// H1 constructs a <h1> element. See Build for the args it takes.
+func H1(args ...interface{}) *html.Node {
+	return build(atom.H1, "h1", args)
+}
+
+// H2 constructs a <h2> element. See Build for the args it takes.
+func H2(args ...interface{}) *html.Node {
+	return build(atom.H2, "h2", args)
+}
+
+// H3 constructs a <h3> element. See Build for the args it takes.
+func H3(args ...interface{}) *html.Node {
+	return build(atom.H3, "h3", args)
+}
+
+// H4 constructs a <h4> element. See Build for the args it takes.
+func H4(args ...interface{}) *html.Node {
+	return build(atom.H4, "h4", args)
+}
+
+// H5 constructs a <h5> element. See Build for the args it takes.
+func H5(args ...interface{}) *html.Node {
+	return build(atom.H5, "h5", args)
+}
+
+// H6 constructs a <h6> element. See Build for the args it takes.
+func H6(args ...interface{}) *html.Node {
+	return build(atom.H6, "h6", args)
+}
+
+// Head constructs a <head> element. See Build for the args it takes.
+func Head(args ...interface{}) *html.Node {
+	return build(atom.Head, "head", args)
+}
+
+// Header constructs a <header> element. See Build for the args it takes.
+func Header(args ...interface{}) *html.Node {
+	return build(atom.Header, "header", args)
+}
+
+// Hgroup constructs a <hgroup> element. See Build for the args it takes.
+func Hgroup(args ...interface{}) *html.Node {
+	return build(atom.Hgroup, "hgroup", args)
+}
+
+// Hr constructs a void <hr> element.
+func Hr(attrs ...AttrFunc) *html.Node {
+	return void(atom.Hr, "hr", attrs)
+}
+
+// Html constructs a <html> element. See Build for the args it takes.
+func Html(args ...interface{}) *html.Node {
+	return build(atom.Html, "html", args)
+}
+
+// I constructs an <i> element. See Build for the args it takes.
+func I(args ...interface{}) *html.Node {
+	return build(atom.I, "i", args)
+}
+
+// Iframe constructs an <iframe> element. See Build for the args it takes.
+func Iframe(args ...interface{}) *html.Node {
+	return build(atom.Iframe, "iframe", args)
+}
+
+// Img constructs a void <img> element.
+func Img(attrs ...AttrFunc) *html.Node {
+	return void(atom.Img, "img", attrs)
+}
+
+// Input constructs a void <input> element.
+func Input(attrs ...AttrFunc) *html.Node {
+	return void(atom.Input, "input", attrs)
+}
+
+// Ins constructs an <ins> element. See Build for the args it takes.
+func Ins(args ...interface{}) *html.Node {
+	return build(atom.Ins, "ins", args)
+}
+
+// Kbd constructs a <kbd> element. See Build for the args it takes.
+func Kbd(args ...interface{}) *html.Node {
+	return build(atom.Kbd, "kbd", args)
+}
+
+// Label constructs a <label> element. See Build for the args it takes.
+func Label(args ...interface{}) *html.Node {
+	return build(atom.Label, "label", args)
+}
+
+// Legend constructs a <legend> element. See Build for the args it takes.
+func Legend(args ...interface{}) *html.Node {
+	return build(atom.Legend, "legend", args)
+}
+
+// Li constructs a <li> element. See Build for the args it takes.
+func Li(args ...interface{}) *html.Node {
+	return build(atom.Li, "li", args)
+}
+
+// Link constructs a void <link> element.
+func Link(attrs ...AttrFunc) *html.Node {
+	return void(atom.Link, "link", attrs)
+}
+
+// Main constructs a <main> element. See Build for the args it takes.
+func Main(args ...interface{}) *html.Node {
+	return build(atom.Main, "main", args)
+}
+
+// Map constructs a <map> element. See Build for the args it takes.
+func Map(args ...interface{}) *html.Node {
+	return build(atom.Map, "map", args)
+}
+
+// Mark constructs a <mark> element. See Build for the args it takes.
+func Mark(args ...interface{}) *html.Node {
+	return build(atom.Mark, "mark", args)
+}
+
+// Menu constructs a <menu> element. See Build for the args it takes.
+func Menu(args ...interface{}) *html.Node {
+	return build(atom.Menu, "menu", args)
+}
+
+// Meta constructs a void <meta> element.
+func Meta(attrs ...AttrFunc) *html.Node {
+	return void(atom.Meta, "meta", attrs)
+}
+
+// Meter constructs a <meter> element. See Build for the args it takes.
+func Meter(args ...interface{}) *html.Node {
+	return build(atom.Meter, "meter", args)
+}
+
+// Nav constructs a <nav> element. See Build for the args it takes.
+func Nav(args ...interface{}) *html.Node {
+	return build(atom.Nav, "nav", args)
+}
+
+// Noscript constructs a <noscript> element. See Build for the args it takes.
+func Noscript(args ...interface{}) *html.Node {
+	return build(atom.Noscript, "noscript", args)
+}
+
+// Object constructs an <object> element. See Build for the args it takes.
+func Object(args ...interface{}) *html.Node {
+	return build(atom.Object, "object", args)
+}
+
+// Ol constructs an <ol> element. See Build for the args it takes.
+func Ol(args ...interface{}) *html.Node {
+	return build(atom.Ol, "ol", args)
+}
+
+// Optgroup constructs an <optgroup> element. See Build for the args it takes.
+func Optgroup(args ...interface{}) *html.Node {
+	return build(atom.Optgroup, "optgroup", args)
+}
+
+// Option constructs an <option> element. See Build for the args it takes.
+func Option(args ...interface{}) *html.Node {
+	return build(atom.Option, "option", args)
+}
+
+// Output constructs an <output> element. See Build for the args it takes.
+func Output(args ...interface{}) *html.Node {
+	return build(atom.Output, "output", args)
+}
+
+// P constructs a <p> element. See Build for the args it takes.
+func P(args ...interface{}) *html.Node {
+	return build(atom.P, "p", args)
+}
+
+// Picture constructs a <picture> element. See Build for the args it takes.
+func Picture(args ...interface{}) *html.Node {
+	return build(atom.Picture, "picture", args)
+}
+
+// Pre constructs a <pre> element. See Build for the args it takes.
+func Pre(args ...interface{}) *html.Node {
+	return build(atom.Pre, "pre", args)
+}
+
+// Progress constructs a <progress> element. See Build for the args it takes.
+func Progress(args ...interface{}) *html.Node {
+	return build(atom.Progress, "progress", args)
+}
+
+// Q constructs a <q> element. See Build for the args it takes.
+func Q(args ...interface{}) *html.Node {
+	return build(atom.Q, "q", args)
+}
+
+// Rp constructs a <rp> element. See Build for the args it takes.
+func Rp(args ...interface{}) *html.Node {
+	return build(atom.Rp, "rp", args)
+}
+
+// Rt constructs a <rt> element. See Build for the args it takes.
+func Rt(args ...interface{}) *html.Node {
+	return build(atom.Rt, "rt", args)
+}
+
+// Ruby constructs a <ruby> element. See Build for the args it takes.
+func Ruby(args ...interface{}) *html.Node {
+	return build(atom.Ruby, "ruby", args)
+}
+
+// S constructs a <s> element. See Build for the args it takes.
+func S(args ...interface{}) *html.Node {
+	return build(atom.S, "s", args)
+}
+
+// Samp constructs a <samp> element. See Build for the args it takes.
+func Samp(args ...interface{}) *html.Node {
+	return build(atom.Samp, "samp", args)
+}
+
+// Script constructs a <script> element. See Build for the args it takes.
+func Script(args ...interface{}) *html.Node {
+	return build(atom.Script, "script", args)
+}
+
+// Search constructs a <search> element. See Build for the args it takes.
+func Search(args ...interface{}) *html.Node {
+	return build(atom.Search, "search", args)
+}
+
+// Section constructs a <section> element. See Build for the args it takes.
+func Section(args ...interface{}) *html.Node {
+	return build(atom.Section, "section", args)
+}
+
+// Select constructs a <select> element. See Build for the args it takes.
+func Select(args ...interface{}) *html.Node {
+	return build(atom.Select, "select", args)
+}
+
+// Slot constructs a <slot> element. See Build for the args it takes.
+func Slot(args ...interface{}) *html.Node {
+	return build(atom.Slot, "slot", args)
+}
+
+// Small constructs a <small> element. See Build for the args it takes.
+func Small(args ...interface{}) *html.Node {
+	return build(atom.Small, "small", args)
+}
+
+// Source constructs a void <source> element.
+func Source(attrs ...AttrFunc) *html.Node {
+	return void(atom.Source, "source", attrs)
+}
+
+// SpanEl constructs a <span> element. See Build for the args it takes.
+func SpanEl(args ...interface{}) *html.Node {
+	return build(atom.Span, "span", args)
+}
+
+// Strong constructs a <strong> element. See Build for the args it takes.
+func Strong(args ...interface{}) *html.Node {
+	return build(atom.Strong, "strong", args)
+}
+
+// Style constructs a <style> element. See Build for the args it takes.
+func Style(args ...interface{}) *html.Node {
+	return build(atom.Style, "style", args)
+}
+
+// Sub constructs a <sub> element. See Build for the args it takes.
+func Sub(args ...interface{}) *html.Node {
+	return build(atom.Sub, "sub", args)
+}
+
+// Summary constructs a <summary> element. See Build for the args it takes.
+func Summary(args ...interface{}) *html.Node {
+	return build(atom.Summary, "summary", args)
+}
+
+// Sup constructs a <sup> element. See Build for the args it takes.
+func Sup(args ...interface{}) *html.Node {
+	return build(atom.Sup, "sup", args)
+}
+
+// Table constructs a <table> element. See Build for the args it takes.
+func Table(args ...interface{}) *html.Node {
+	return build(atom.Table, "table", args)
+}
+
+// Tbody constructs a <tbody> element. See Build for the args it takes.
+func Tbody(args ...interface{}) *html.Node {
+	return build(atom.Tbody, "tbody", args)
+}
+
+// Td constructs a <td> element. See Build for the args it takes.
+func Td(args ...interface{}) *html.Node {
+	return build(atom.Td, "td", args)
+}
+
+// Template constructs a <template> element. See Build for the args it takes.
+func Template(args ...interface{}) *html.Node {
+	return build(atom.Template, "template", args)
+}
+
+// Textarea constructs a <textarea> element. See Build for the args it takes.
+func Textarea(args ...interface{}) *html.Node {
+	return build(atom.Textarea, "textarea", args)
+}
+
+// Tfoot constructs a <tfoot> element. See Build for the args it takes.
+func Tfoot(args ...interface{}) *html.Node {
+	return build(atom.Tfoot, "tfoot", args)
+}
+
+// Th constructs a <th> element. See Build for the args it takes.
+func Th(args ...interface{}) *html.Node {
+	return build(atom.Th, "th", args)
+}
+
+// Thead constructs a <thead> element. See Build for the args it takes.
+func Thead(args ...interface{}) *html.Node {
+	return build(atom.Thead, "thead", args)
+}
+
+// Time constructs a <time> element. See Build for the args it takes.
+func Time(args ...interface{}) *html.Node {
+	return build(atom.Time, "time", args)
+}
+
+// Title constructs a <title> element. See Build for the args it takes.
+func Title(args ...interface{}) *html.Node {
+	return build(atom.Title, "title", args)
+}
+
+// Tr constructs a <tr> element. See Build for the args it takes.
+func Tr(args ...interface{}) *html.Node {
+	return build(atom.Tr, "tr", args)
+}
+
+// Track constructs a void <track> element.
+func Track(attrs ...AttrFunc) *html.Node {
+	return void(atom.Track, "track", attrs)
+}
+
+// U constructs an <u> element. See Build for the args it takes.
+func U(args ...interface{}) *html.Node {
+	return build(atom.U, "u", args)
+}
+
+// Ul constructs an <ul> element. See Build for the args it takes.
+func Ul(args ...interface{}) *html.Node {
+	return build(atom.Ul, "ul", args)
+}
+
+// Var constructs a <var> element. See Build for the args it takes.
+func Var(args ...interface{}) *html.Node {
+	return build(atom.Var, "var", args)
+}
+
+// Video constructs a <video> element. See Build for the args it takes.
+func Video(args ...interface{}) *html.Node {
+	return build(atom.Video, "video", args)
+}
+
+// Wbr constructs a void <wbr> element.
+func Wbr(attrs ...AttrFunc) *html.Node {
+	return void(atom.Wbr, "wbr", attrs)
+}
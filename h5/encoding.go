@@ -0,0 +1,243 @@
+// Copyright 2011 Jeremy Wall (jeremy@marzhillstudios.com)
+// Use of this source code is governed by the Artistic License 2.0.
+// That License is included in the LICENSE file.
+
+package h5
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/transform"
+)
+
+// Options configures how NewWithOptions decodes a document.
+type Options struct {
+	// ContentType is the Content-Type the document was served with, eg:
+	// "text/html; charset=shift_jis". Its charset is used unless the
+	// document starts with a byte order mark.
+	ContentType string
+	// DefaultEncoding is the label of the encoding used when nothing
+	// identifies the document's encoding. It defaults to windows-1252.
+	DefaultEncoding string
+	// Positions records the source Span of every Node and the parse
+	// errors in the document. See Tree.Span and Tree.ParseErrors.
+	Positions bool
+}
+
+const htmlSpace = " \t\n\f\r"
+
+// prescanLen is how many bytes are examined for a byte order mark, a
+// <meta charset> or valid utf-8.
+const prescanLen = 1024
+
+var boms = []struct {
+	bom   string
+	label string
+}{
+	{"\xef\xbb\xbf", "utf-8"},
+	{"\xfe\xff", "utf-16be"},
+	{"\xff\xfe", "utf-16le"},
+}
+
+// NewWithOptions constructs a new h5 parser from an io.Reader decoding
+// the document to utf-8 first. The encoding is determined following
+// http://www.w3.org/TR/html5/syntax.html#determining-the-character-encoding
+// from a byte order mark, then the charset of opts.ContentType, then a
+// prescan of the first 1024 bytes for a <meta charset> and finally
+// utf-8 if those bytes are valid utf-8 with non ascii characters or
+// opts.DefaultEncoding. The Tree reports the encoding it was decoded from.
+func NewWithOptions(r io.Reader, opts Options) (*Tree, error) {
+	br := bufio.NewReaderSize(r, prescanLen)
+	head, err := br.Peek(prescanLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	name, bomLen, err := determineEncoding(head, opts)
+	if err != nil {
+		return nil, err
+	}
+	br.Discard(bomLen)
+	var src io.Reader = br
+	if name != "utf-8" {
+		e, _ := charset.Lookup(name)
+		src = transform.NewReader(br, e.NewDecoder())
+	}
+	if !opts.Positions {
+		t, err := New(src)
+		if err != nil {
+			return nil, err
+		}
+		t.encoding = name
+		return t, nil
+	}
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	doc, spans, errors, err := locate(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{n: doc, encoding: name, spans: spans, errors: errors}, nil
+}
+
+// determineEncoding returns the canonical name of the encoding of a
+// document starting with head and the length of its byte order mark.
+func determineEncoding(head []byte, opts Options) (string, int, error) {
+	for _, b := range boms {
+		if bytes.HasPrefix(head, []byte(b.bom)) {
+			return b.label, len(b.bom), nil
+		}
+	}
+	if _, params, err := mime.ParseMediaType(opts.ContentType); err == nil {
+		if _, name := charset.Lookup(params["charset"]); name != "" {
+			return name, 0, nil
+		}
+	}
+	if name := prescan(head); name != "" {
+		return name, 0, nil
+	}
+	if hasUTF8(head) {
+		return "utf-8", 0, nil
+	}
+	if opts.DefaultEncoding == "" {
+		return "windows-1252", 0, nil
+	}
+	if _, name := charset.Lookup(opts.DefaultEncoding); name != "" {
+		return name, 0, nil
+	}
+	return "", 0, fmt.Errorf("Unknown encoding %q", opts.DefaultEncoding)
+}
+
+// prescan looks for the encoding declared by a <meta> element.
+// http://www.w3.org/TR/html5/syntax.html#prescan-a-byte-stream-to-determine-its-encoding
+func prescan(head []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(head))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			tag, hasAttr := z.TagName()
+			if atom.Lookup(tag) != atom.Meta {
+				continue
+			}
+			var label, content string
+			pragma := false
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "http-equiv":
+					pragma = pragma || strings.EqualFold(string(val), "content-type")
+				case "content":
+					if content == "" {
+						content = string(val)
+					}
+				case "charset":
+					if label == "" {
+						label = string(val)
+						pragma = true
+					}
+				}
+			}
+			if label == "" && pragma {
+				label = metaContentCharset(content)
+			}
+			if label == "" || !pragma {
+				continue
+			}
+			_, name := charset.Lookup(label)
+			switch name {
+			case "":
+				continue
+			case "utf-16be", "utf-16le":
+				return "utf-8"
+			case "x-user-defined":
+				return "windows-1252"
+			}
+			return name
+		}
+	}
+}
+
+// metaContentCharset extracts the charset from the content of a
+// <meta http-equiv=content-type>.
+// http://www.w3.org/TR/html5/infrastructure.html#algorithm-for-extracting-a-character-encoding-from-a-meta-element
+func metaContentCharset(s string) string {
+	lower := strings.ToLower(s)
+	i := 0
+	for {
+		j := strings.Index(lower[i:], "charset")
+		if j < 0 {
+			return ""
+		}
+		i += j + len("charset")
+		rest := strings.TrimLeft(s[i:], htmlSpace)
+		if !strings.HasPrefix(rest, "=") {
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], htmlSpace)
+		switch {
+		case rest == "":
+			return ""
+		case rest[0] == '"' || rest[0] == '\'':
+			if k := strings.IndexByte(rest[1:], rest[0]); k >= 0 {
+				return rest[1 : k+1]
+			}
+			return ""
+		}
+		if k := strings.IndexAny(rest, htmlSpace+";"); k >= 0 {
+			return rest[:k]
+		}
+		return rest
+	}
+}
+
+// hasUTF8 returns true if head has non ascii characters and is valid
+// utf-8 apart from a rune cut off at its end.
+func hasUTF8(head []byte) bool {
+	for i := len(head) - 1; i >= 0 && i >= len(head)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(head[i]) {
+			if !utf8.FullRune(head[i:]) {
+				head = head[:i]
+			}
+			break
+		}
+	}
+	return bytes.IndexFunc(head, func(r rune) bool { return r >= utf8.RuneSelf }) >= 0 &&
+		utf8.Valid(head)
+}
+
+// Encoding returns the canonical name of the encoding the Tree was decoded
+// from by NewWithOptions or "" if it wasn't decoded.
+func (t Tree) Encoding() string {
+	return t.encoding
+}
+
+// RenderEncoded renders the Tree encoded with the encoding label, eg:
+// shift_jis. Characters the encoding can't represent are written as
+// character references. The document's <meta charset> is left as it is.
+func (t Tree) RenderEncoded(w io.Writer, label string) error {
+	e, name := charset.Lookup(label)
+	if e == nil {
+		return fmt.Errorf("Unknown encoding %q", label)
+	}
+	if name == "utf-8" {
+		return t.Render(w)
+	}
+	tw := transform.NewWriter(w, e.NewEncoder())
+	if err := t.Render(tw); err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,87 @@
+//go:build ignore
+
+// gen_elements generates elements.go, the builders for the html elements.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html/atom"
+)
+
+// elements are the html elements that aren't obsolete.
+// http://html.spec.whatwg.org/multipage/indices.html#elements-3
+var elements = strings.Fields(`
+	a abbr address area article aside audio b base bdi bdo blockquote body
+	br button canvas caption cite code col colgroup data datalist dd del
+	details dfn dialog div dl dt em embed fieldset figcaption figure footer
+	form h1 h2 h3 h4 h5 h6 head header hgroup hr html i iframe img input ins
+	kbd label legend li link main map mark menu meta meter nav noscript
+	object ol optgroup option output p picture pre progress q rp rt ruby s
+	samp script search section select slot small source span strong style
+	sub summary sup table tbody td template textarea tfoot th thead time
+	title tr track u ul var video wbr
+`)
+
+var voids = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// renamed are the elements whose names are already taken in h5.
+var renamed = map[string]string{
+	"data": "DataEl",
+	"div":  "DivEl",
+	"span": "SpanEl",
+}
+
+func main() {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// generated by go run gen_elements.go; DO NOT EDIT")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package h5")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, `import (`)
+	fmt.Fprintln(&b, `	"golang.org/x/net/html"`)
+	fmt.Fprintln(&b, `	"golang.org/x/net/html/atom"`)
+	fmt.Fprintln(&b, `)`)
+	for _, name := range elements {
+		a := atom.Lookup([]byte(name))
+		if a == 0 {
+			log.Fatalf("No atom for <%s>", name)
+		}
+		fn := renamed[name]
+		if fn == "" {
+			fn = strings.ToUpper(name[:1]) + name[1:]
+		}
+		// The atom constants are named like the funcs.
+		at := "atom." + strings.ToUpper(name[:1]) + name[1:]
+		fmt.Fprintln(&b)
+		if voids[name] {
+			fmt.Fprintf(&b, "// %s constructs a void <%s> element.\n", fn, name)
+			fmt.Fprintf(&b, "func %s(attrs ...AttrFunc) *html.Node {\n", fn)
+			fmt.Fprintf(&b, "\treturn void(%s, %q, attrs)\n}\n", at, name)
+		} else {
+			article := "a"
+			if strings.ContainsAny(name[:1], "aeiou") {
+				article = "an"
+			}
+			fmt.Fprintf(&b, "// %s constructs %s <%s> element. See Build for the args it takes.\n", fn, article, name)
+			fmt.Fprintf(&b, "func %s(args ...interface{}) *html.Node {\n", fn)
+			fmt.Fprintf(&b, "\treturn build(%s, %q, args)\n}\n", at, name)
+		}
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("elements.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
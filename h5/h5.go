@@ -12,6 +12,17 @@ Package h5 implements a wrapper and DSL for golang.org/x/net/html.
     })
 
     tree2 := tree.Clone()
+
+Nodes can be built with a func for each html element.
+
+    menu := h5.Ul(h5.ID("menu"),
+        h5.Li(h5.Class("item", "active"), h5.A(h5.Attr("href", "/"), "Home")),
+        h5.Li(h5.Input(h5.Attr("type", "checkbox"), h5.Bool("checked"))),
+    )
+
+The <div>, <span> and <data> builders are DivEl, SpanEl and DataEl since
+Div, Span and Data were already taken and data-* attributes are set with
+DataAttr.
 */
 package h5
 
@@ -37,6 +48,41 @@ func PartialFromString(s string) ([]*html.Node, error) {
 	return Partial(strings.NewReader(s))
 }
 
+// PartialInContext parses an html fragment as if it were the contents of
+// the element ctx so eg: <tr> fragments keep their table structure in a
+// <tbody> context. Only ctx's name, namespace and attributes matter so it
+// needn't be part of a tree. An svg or math ctx without a namespace is
+// treated as the foreign element. A nil ctx parses in a <body> like
+// Partial.
+func PartialInContext(r io.Reader, ctx *html.Node) ([]*html.Node, error) {
+	if ctx == nil {
+		return Partial(r)
+	}
+	if ctx.Type != html.ElementNode {
+		return nil, fmt.Errorf("Fragment context must be an element")
+	}
+	c := &html.Node{Type: html.ElementNode, Data: ctx.Data, Namespace: ctx.Namespace, Attr: ctx.Attr}
+	if c.Namespace == "" && (c.Data == "svg" || c.Data == "math") {
+		c.Namespace = c.Data
+	}
+	c.DataAtom = atom.Lookup([]byte(c.Data))
+	// The parser looks for a <form> ancestor of the context.
+	for p := ctx.Parent; p != nil; p = p.Parent {
+		if p.Type == html.ElementNode && p.DataAtom == atom.Form {
+			form := &html.Node{Type: html.ElementNode, Data: p.Data, DataAtom: atom.Form}
+			form.AppendChild(c)
+			break
+		}
+	}
+	return html.ParseFragment(r, c)
+}
+
+// PartialInContextFromString parses an html fragment from a string as if
+// it were the contents of the element ctx.
+func PartialInContextFromString(s string, ctx *html.Node) ([]*html.Node, error) {
+	return PartialInContext(strings.NewReader(s), ctx)
+}
+
 func RenderNodes(w io.Writer, ns []*html.Node) error {
 	for _, n := range ns {
 		err := html.Render(w, n)
@@ -75,5 +121,5 @@ func New(r io.Reader) (*Tree, error) {
 	if n == nil {
 		return nil, fmt.Errorf("Error parsing html from reader")
 	}
-	return &Tree{n}, nil
+	return &Tree{n: n}, nil
 }
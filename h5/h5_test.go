@@ -1,8 +1,13 @@
 package h5
 
 import (
+	"bytes"
+	"fmt"
 	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"math/rand"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -63,3 +68,591 @@ func TestNodeWalk(t *testing.T) {
 //		t, p.Top != nil, "We didn't get a node tree back while parsing snippet")
 //	assertEqual(t, p.Tree()).String(), "<a></a><b>")
 //}
+
+func TestGetStyle(t *testing.T) {
+	n := Element("p", []html.Attribute{{Key: "style",
+		Val: `COLOR: red !important; color: blue; background: url("a;b.png") /* ; */; --Gap: 1px; margin: 0; margin: 2px; font: 12px/*;*/serif; FONT-FAMILY: "a;b" , serif`}})
+	cases := map[string]string{
+		"color":       "red",
+		"background":  `url("a;b.png")`,
+		"--Gap":       "1px",
+		"--gap":       "",
+		"margin":      "2px",
+		"padding":     "",
+		"font":        "12px serif",
+		"font-family": `"a;b" , serif`,
+	}
+	for prop, expected := range cases {
+		assertEqual(t, GetStyle(n, prop), expected)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	cases := []struct {
+		doc      string
+		opts     Options
+		encoding string
+		text     string
+	}{
+		{"<p>caf\xe9</p>", Options{}, "windows-1252", "café"},
+		{"<p>caf\xc3\xa9</p>", Options{}, "utf-8", "café"},
+		{"\xef\xbb\xbf<p>caf\xc3\xa9</p>", Options{ContentType: "text/html; charset=iso-8859-2"}, "utf-8", "café"},
+		{"<p>\x93\xfa\x96\x7b</p>", Options{ContentType: "text/html; charset=Shift_JIS"}, "shift_jis", "日本"},
+		{"<meta charset=shift_jis><p>\x93\xfa\x96\x7b</p>", Options{}, "shift_jis", "日本"},
+		{`<meta http-equiv="Content-Type" content="text/html; charset='sjis'"><p>` + "\x93\xfa\x96\x7b</p>",
+			Options{}, "shift_jis", "日本"},
+		{`<meta content="text/html; charset=sjis"><p>caf` + "\xe9</p>", Options{}, "windows-1252", "café"},
+		{"<meta charset=utf-16><p>caf\xc3\xa9</p>", Options{}, "utf-8", "café"},
+		{"<p>\xe9</p>", Options{DefaultEncoding: "iso-8859-7"}, "iso-8859-7", "ι"},
+	}
+	for _, c := range cases {
+		tree, err := NewWithOptions(strings.NewReader(c.doc), c.opts)
+		assertOrDie(t, err == nil, "error while parsing %q: %s", c.doc, err)
+		assertEqual(t, tree.Encoding(), c.encoding)
+		var text string
+		tree.Walk(func(n *html.Node) {
+			if n.Type == html.TextNode {
+				text += n.Data
+			}
+		})
+		assertEqual(t, text, c.text)
+	}
+	_, err := NewWithOptions(strings.NewReader("<p>"), Options{DefaultEncoding: "nope"})
+	assertTrue(t, err != nil, "Expected an unknown default encoding to fail")
+}
+
+func TestRenderEncoded(t *testing.T) {
+	tree, err := NewFromString("<p>日本 café</p>")
+	assertOrDie(t, err == nil, "error while parsing string: %s", err)
+	var buf bytes.Buffer
+	assertOrDie(t, tree.RenderEncoded(&buf, "shift_jis") == nil, "error rendering")
+	assertEqual(t, buf.String(),
+		"<html><head></head><body><p>\x93\xfa\x96\x7b caf&#233;</p></body></html>")
+	assertTrue(t, tree.RenderEncoded(&buf, "nope") != nil, "Expected an unknown encoding to fail")
+}
+
+func TestPartialInContext(t *testing.T) {
+	cases := []struct {
+		ctx      *html.Node
+		fragment string
+		expected string
+	}{
+		{nil, "<tr><td>x</td></tr>", "x"},
+		{Element("tbody", nil), "<tr><td>x</td></tr>", "<tr><td>x</td></tr>"},
+		{Element("table", nil), "<tr><td>x</td></tr>", "<tbody><tr><td>x</td></tr></tbody>"},
+		{Element("select", nil), "<option>a<option>b", "<option>a</option><option>b</option>"},
+		{Element("ul", nil), "<li>a<li>b", "<li>a</li><li>b</li>"},
+		{Element("svg", nil), "<circle r=1></circle>", `<circle r="1"></circle>`},
+	}
+	for _, c := range cases {
+		ns, err := PartialInContextFromString(c.fragment, c.ctx)
+		assertOrDie(t, err == nil, "error while parsing %q: %s", c.fragment, err)
+		assertEqual(t, RenderNodesToString(ns), c.expected)
+	}
+	ns, _ := PartialInContextFromString("<circle/>", Element("svg", nil))
+	assertEqual(t, ns[0].Namespace, "svg")
+	_, err := PartialInContextFromString("x", Text("y"))
+	assertTrue(t, err != nil, "Expected a text context to fail")
+}
+
+func TestPositions(t *testing.T) {
+	doc := "<!DOCTYPE html>\n<html><body>\n  <p class=a>hello <b>world</b></p>\n" +
+		"  <!-- note -->\n  <table><tr><td>x</td></tr></table>\n</body></html>"
+	tree, err := NewWithOptions(strings.NewReader(doc), Options{Positions: true})
+	assertOrDie(t, err == nil, "error while parsing: %s", err)
+	spans := map[string]string{}
+	tree.Walk(func(n *html.Node) {
+		s, ok := tree.Span(n)
+		key := Data(n)
+		if n.Type == html.CommentNode {
+			key = "comment"
+		}
+		if !ok {
+			spans[key] = "none"
+			return
+		}
+		spans[key] = fmt.Sprintf("%s-%s %d", s.Start, s.End, s.End.Offset-s.Start.Offset)
+	})
+	expected := map[string]string{
+		"html":    "2:1-6:15 116",
+		"head":    "none",
+		"p":       "3:3-3:36 33",
+		"hello ":  "3:14-3:20 6",
+		"b":       "3:20-3:32 12",
+		"world":   "3:23-3:28 5",
+		"comment": "4:3-4:16 13",
+		"table":   "5:3-5:37 34",
+		"tbody":   "none",
+		"tr":      "5:10-5:29 19",
+		"x":       "5:18-5:19 1",
+	}
+	for k, v := range expected {
+		assertEqual(t, spans[k], v)
+	}
+	assertEqual(t, len(tree.ParseErrors()), 0)
+	clone := tree.Clone()
+	s, ok := clone.Span(clone.Top().LastChild)
+	assertTrue(t, ok && s.Start.Line == 2, "Expected the clone to keep its spans got %v", s)
+}
+
+func TestParseErrors(t *testing.T) {
+	doc := "<div>\n<b><i>x</b></i>\n<p/></span>\n<a href=1 href=2>\n<ul><li>a<li>b</ul>"
+	tree, err := NewWithOptions(strings.NewReader(doc), Options{Positions: true})
+	assertOrDie(t, err == nil, "error while parsing: %s", err)
+	var got []string
+	for _, e := range tree.ParseErrors() {
+		got = append(got, e.Error())
+	}
+	assertEqual(t, got, []string{
+		"1:1: Missing <!DOCTYPE html>",
+		"1:1: Unclosed element <div>",
+		"2:4: Unclosed element <i> before </b>",
+		"2:12: Unexpected end tag </i>",
+		"3:1: Self-closing syntax on non-void element <p/>",
+		"3:5: Unexpected end tag </span>",
+		"4:1: Duplicate attribute href on <a>",
+		"4:1: Unclosed element <a>",
+	})
+}
+
+func TestMutations(t *testing.T) {
+	a, b, c := Element("a", nil), Element("b", nil), Element("c", nil)
+	div := Element("div", nil, a, b)
+	InsertAfter(a, c)
+	assertEqual(t, NewTree(div).String(), "<div><a></a><c></c><b></b></div>")
+	InsertAfter(b, a)
+	assertEqual(t, NewTree(div).String(), "<div><c></c><b></b><a></a></div>")
+	MoveTo(a, div, c)
+	assertEqual(t, NewTree(div).String(), "<div><a></a><c></c><b></b></div>")
+	span := Element("span", nil)
+	Wrap(c, span)
+	assertEqual(t, NewTree(div).String(), "<div><a></a><span><c></c></span><b></b></div>")
+	cs := Unwrap(span)
+	assertEqual(t, len(cs), 1)
+	assertTrue(t, span.Parent == nil && span.FirstChild == nil, "Expected span to be detached and empty")
+	assertEqual(t, NewTree(div).String(), "<div><a></a><c></c><b></b></div>")
+	ReplaceWith(c, Text("x"), c, b)
+	assertEqual(t, NewTree(div).String(), "<div><a></a>x<c></c><b></b></div>")
+	ReplaceWith(a, Text("y"))
+	assertTrue(t, a.Parent == nil, "Expected a to be detached")
+	assertEqual(t, Detach(b), b)
+	assertEqual(t, NewTree(div).String(), "<div>yx<c></c></div>")
+	assertEqual(t, len(Children(div)), 3)
+	NormalizeText(div)
+	assertEqual(t, len(Children(div)), 2)
+	assertEqual(t, div.FirstChild.Data, "yx")
+	Empty(div)
+	assertEqual(t, NewTree(div).String(), "<div></div>")
+	assertTrue(t, c.Parent == nil, "Expected c to be detached")
+}
+
+func TestMutationPanics(t *testing.T) {
+	parent := Element("div", nil)
+	child := Element("p", nil)
+	parent.AppendChild(child)
+	grandchild := Element("b", nil)
+	child.AppendChild(grandchild)
+	cases := map[string]func(){
+		"wrap in descendant": func() { Wrap(parent, child) },
+		"wrap in parent":     func() { Wrap(child, parent) },
+		"wrap in ancestor":   func() { Wrap(grandchild, parent) },
+		"move into itself":   func() { MoveTo(parent, child, nil) },
+		"insert after root":  func() { InsertAfter(parent, Element("a", nil)) },
+		"replace root":       func() { ReplaceWith(parent, Element("a", nil)) },
+		"before non child":   func() { MoveTo(Element("a", nil), parent, Element("b", nil)) },
+	}
+	for name, f := range cases {
+		func() {
+			defer func() {
+				assertTrue(t, recover() != nil, "Expected %s to panic", name)
+			}()
+			f()
+		}()
+	}
+	assertEqual(t, NewTree(parent).String(), "<div><p><b></b></p></div>")
+}
+
+func TestBuilder(t *testing.T) {
+	ul := Ul(ID("menu"), Class("nav"), Class("main"),
+		Li(Class("item"), A(Attr("href", "/"), "Home")),
+		Li(DataAttr("id", "2"), Img(Attr("src", "a.png"), Attr("alt", "a"))),
+		[]*html.Node{Li(), nil},
+		nil,
+	)
+	assertEqual(t, NewTree(ul).String(), `<ul id="menu" class="nav main"><li class="item"><a href="/">Home</a></li>`+
+		`<li data-id="2"><img src="a.png" alt="a"/></li><li></li></ul>`)
+	input := Input(Attr("type", "checkbox"), Bool("disabled"), Attr("type", "radio"))
+	assertEqual(t, NewTree(input).String(), `<input type="radio" disabled=""/>`)
+	WalkNodes(ul, func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			assertTrue(t, n.DataAtom != 0 && n.DataAtom.String() == n.Data, "Expected an atom for <%s>", n.Data)
+		}
+	})
+	assertEqual(t, DivEl("x").DataAtom.String(), "div")
+	assertEqual(t, SpanEl().DataAtom.String(), "span")
+	assertEqual(t, DataEl().DataAtom.String(), "data")
+	assertEqual(t, Element("table", nil).DataAtom.String(), "table")
+	assertEqual(t, Build("my-widget", Bool("open")).DataAtom, Build("my-widget").DataAtom)
+
+	svg := Svg(Attr("viewBox", "0 0 10 10"),
+		SvgElement("circle", Attr("r", "5")),
+		SvgElement("foreignObject", P("text")),
+	)
+	assertEqual(t, svg.Namespace, "svg")
+	assertEqual(t, svg.FirstChild.Namespace, "svg")
+	assertEqual(t, svg.LastChild.Namespace, "svg")
+	assertEqual(t, svg.LastChild.FirstChild.Namespace, "")
+	assertEqual(t, svg.LastChild.DataAtom, atom.ForeignObject)
+	assertEqual(t, Element("DIV", nil).Data, "div")
+	assertEqual(t, Build("SPAN").Data, "span")
+	for _, ctx := range []*html.Node{svg.LastChild, Element("DIV", nil), Build("P")} {
+		ns, err := html.ParseFragment(strings.NewReader("<b>x</b>"), ctx)
+		assertTrue(t, err == nil && len(ns) == 1, "Failed to parse a fragment in <%s>: %v", ctx.Data, err)
+	}
+	math := Math(Build("mi", "x"))
+	assertEqual(t, math.FirstChild.Namespace, "math")
+	tree, _ := NewFromString("<svg><circle/></svg>")
+	WalkNodes(tree.Top(), func(n *html.Node) {
+		if n.Data == "circle" {
+			assertEqual(t, n.Namespace, svg.FirstChild.Namespace)
+		}
+	})
+
+	for name, f := range map[string]func(){
+		"children of void": func() { Build("br", "x") },
+		"bad argument":     func() { P(42) },
+		"attached child":   func() { p := P(); DivEl(p); DivEl(p) },
+	} {
+		func() {
+			defer func() {
+				assertTrue(t, recover() != nil, "Expected %s to panic", name)
+			}()
+			f()
+		}()
+	}
+}
+
+func TestDiff(t *testing.T) {
+	parse := func(s string) *html.Node {
+		tree, err := NewFromString(s)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", s, err)
+		}
+		return tree.Top()
+	}
+	a := parse(`<ul><li id="a">A</li><li id="b">B</li><li id="c">C</li></ul>` +
+		`<p class="x" title="t">old</p><!-- note --><div><span>1</span><span>2</span></div>`)
+	b := parse(`<ul><li id="c">C</li><li id="a">A!</li><li id="b">B</li></ul>` +
+		`<p title="t" class="y" lang="en">new</p><div><span>1</span><em>3</em><span>2</span></div>`)
+	expected := `@@ /html/body/ul/li[1]/text() text changed @@
+-A
++A!
+@@ /html/body/ul/li[3] moved to /html/body/ul/li[1] @@
+@@ /html/body/p @class attribute changed @@
+-x
++y
+@@ /html/body/p @lang attribute added @@
++en
+@@ /html/body/p attributes reordered @@
+-class title
++title class
+@@ /html/body/p/text() text changed @@
+-old
++new
+@@ /html/body/comment() removed @@
+-<!-- note -->
+@@ /html/body/div/em inserted @@
++<em>3</em>
+`
+	cs := Diff(a, b)
+	if got := RenderChangesToString(cs); got != expected {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", expected, got)
+	}
+	assertEqual(t, cs[1].Kind, Moved)
+	assertEqual(t, cs[1].A.Attr, cs[1].B.Attr)
+	root := cs[1].A
+	for root.Parent != nil {
+		root = root.Parent
+	}
+	assertTrue(t, root == a, "Expected A in the first tree")
+	assertEqual(t, cs[len(cs)-1].NewPath, "/html/body/div/em")
+
+	cs = DiffWithOptions(a, b, DiffOptions{IgnoreAttrOrder: true, IgnoreComments: true})
+	for _, c := range cs {
+		assertTrue(t, c.Kind != AttrsReordered && c.Kind != Removed, "Unexpected change %s", c.Kind)
+	}
+	assertEqual(t, len(cs), 6)
+
+	a = parse("<div>\n  <p>x</p>\n  <p>y</p>\n</div>")
+	b = parse("<div><p>x</p><p>y</p><p>z</p></div>")
+	assertEqual(t, RenderChangesToString(DiffWithOptions(a, b, DiffOptions{IgnoreWhitespace: true})),
+		"@@ /html/body/div/p[3] inserted @@\n+<p>z</p>\n")
+	assertEqual(t, len(Diff(a, b)), 4)
+	assertEqual(t, len(Diff(a, parse("<div>\n  <p>x</p>\n  <p>y</p>\n</div>"))), 0)
+
+	a = parse(`<p><b>1</b><b>2</b></p>`)
+	b = parse(`<p><b>2</b></p>`)
+	assertEqual(t, RenderChangesToString(Diff(a, b)), "@@ /html/body/p/b[1] removed @@\n-<b>1</b>\n")
+	a = parse(`<p><b data-key="k">1</b></p>`)
+	b = parse(`<p><b data-key="j">1</b></p>`)
+	assertEqual(t, len(Diff(a, b)), 1)
+	assertEqual(t, len(DiffWithOptions(a, b, DiffOptions{Keys: []string{"data-key"}})), 2)
+	assertEqual(t, RenderChangesToString(Diff(P("x"), DivEl("x"))), "@@ /p removed @@\n-<p>x</p>\n@@ /div inserted @@\n+<div>x</div>\n")
+	a = parse(`<ul><li>a</li><li>b</li><li>c</li></ul>`)
+	b = parse(`<ul><li>c</li><li>a</li><li>b</li></ul>`)
+	assertEqual(t, RenderChangesToString(Diff(a, b)), "@@ /html/body/ul/li[3] moved to /html/body/ul/li[1] @@\n")
+	a = Element("div", []html.Attribute{{Key: "a", Val: "1"}, {Key: "a", Val: "2"}})
+	b = Element("div", []html.Attribute{{Key: "a", Val: "1"}})
+	assertEqual(t, len(Diff(a, b)), 0)
+	assertEqual(t, len(Diff(b, a)), 0)
+	b = Element("div", []html.Attribute{{Key: "b", Val: "1"}, {Key: "a", Val: "1"}})
+	assertEqual(t, RenderChangesToString(Diff(a, b)), "@@ /div @b attribute added @@\n+1\n")
+	a = parse(`<ul><li>a</li><li>b</li><li>c</li></ul>`)
+	b = parse(`<ul><li>c</li><li>a</li><li>b!</li></ul>`)
+	assertEqual(t, RenderChangesToString(Diff(a, b)),
+		"@@ /html/body/ul/li[2]/text() text changed @@\n-b\n+b!\n@@ /html/body/ul/li[3] moved to /html/body/ul/li[1] @@\n")
+}
+
+func TestCanonicalize(t *testing.T) {
+	a, _ := NewFromString(`<!DOCTYPE html>
+<html>
+  <head>
+    <title> Page </title>
+  </head>
+  <body>
+    <!-- nav -->
+    <p title="t"   class="b  a b" style="COLOR:red;margin : 0  auto;;--Gap: 1px">Some   <b>bold</b>
+      text</p>
+    <pre>  keep
+   this </pre>
+    <textarea>  and  this</textarea><div class="" style=" "> x </div>
+  </body>
+</html>`)
+	Canonicalize(a.Top(), CanonicalOptions{})
+	expected := `<!DOCTYPE html><html><head><title>Page</title></head><body><!-- nav --><p class="a b" ` +
+		`style="color: red; margin: 0 auto; --Gap: 1px" title="t">Some <b>bold</b> text</p>` +
+		"<pre>  keep\n   this </pre><textarea>  and  this</textarea><div>x</div></body></html>"
+	assertEqual(t, a.String(), expected)
+	Canonicalize(a.Top(), CanonicalOptions{})
+	assertEqual(t, a.String(), expected)
+	Canonicalize(a.Top(), CanonicalOptions{DropComments: true})
+	assertEqual(t, strings.Contains(a.String(), "nav"), false)
+
+	b, _ := NewFromString(`<!DOCTYPE html><p style="margin:0 auto;color :red" class="x  y">  <span>a</span>  <i>b</i></p>`)
+	c, _ := NewFromString("<!DOCTYPE html>\n<p class='y x' style='margin: 0 auto; color: red'><span>a</span> <i>b</i>\n</p>")
+	assertEqual(t, Hash(b.Top()), Hash(c.Top()))
+	assertTrue(t, strings.Contains(b.String(), "  <i>"), "Expected Hash not to change the tree")
+	d, _ := NewFromString(`<!DOCTYPE html><p class="x y"><span>a</span><i>b</i></p>`)
+	assertTrue(t, Hash(b.Top()) != Hash(d.Top()), "Expected significant whitespace to change the Hash")
+	assertEqual(t, len(Hash(d.Top())), 64)
+
+	// Whitespace next to inline blocks and elements that aren't displayed
+	// renders.
+	for _, pair := range [][2]string{
+		{"<p>x <select></select> y</p>", "<p>x<select></select>y</p>"},
+		{"<p>x <datalist></datalist> y</p>", "<p>x<datalist></datalist>y</p>"},
+		{"<p>x <script></script> y</p>", "<p>x<script></script>y</p>"},
+	} {
+		e, _ := NewFromString(pair[0])
+		f, _ := NewFromString(pair[1])
+		assertTrue(t, Hash(e.Top()) != Hash(f.Top()), "Expected %q and %q to Hash differently", pair[0], pair[1])
+	}
+
+	e, _ := NewFromString(`<p style="COLOR: /* c */ red ; ; content: 'a;b' ;--X:  1px  2px;margin:0!important;bad">` +
+		`<input disabled="DISABLED" checked="" value="value"></p>`)
+	Canonicalize(e.Top(), CanonicalOptions{})
+	assertEqual(t, e.String(), `<html><head></head><body><p style="color: red; content: &#39;a;b&#39;; --X: 1px 2px; `+
+		`margin: 0 !important"><input checked="" disabled="" value="value"/></p></body></html>`)
+}
+
+func TestRenderWithOptions(t *testing.T) {
+	tree, _ := NewFromString("<!DOCTYPE html><html><head><title>T</title></head><body>\n" +
+		"<!-- x --><!--[if IE]>old<![endif]--><div id=\"a b\"><ul><li>One <b>1</b></li><li>Two</li></ul>" +
+		"<p class=x>  some   text</p><pre>\n\n keep  </pre></div><form><input disabled=\"disabled\" value=\"\"></form>\n" +
+		"<table><tbody><tr><td>1</td><td>2</td></tr></tbody></table></body></html>")
+	var buf bytes.Buffer
+	assertTrue(t, tree.RenderWithOptions(&buf, RenderOptions{}) == nil, "Render failed")
+	assertEqual(t, buf.String(), tree.String())
+	buf.Reset()
+	tree.RenderWithOptions(&buf, RenderOptions{Pretty: true})
+	assertEqual(t, buf.String(), `<!DOCTYPE html>
+<html>
+  <head>
+    <title>T</title>
+  </head>
+  <body>
+    <!-- x --><!--[if IE]>old<![endif]-->
+    <div id="a b">
+      <ul>
+        <li>One <b>1</b></li>
+        <li>Two</li>
+      </ul>
+      <p class="x">  some   text</p>
+      <pre>
+
+ keep  </pre>
+    </div>
+    <form><input disabled="disabled" value=""/></form>
+    <table>
+      <tbody>
+        <tr>
+          <td>1</td>
+          <td>2</td>
+        </tr>
+      </tbody>
+    </table>
+  </body>
+</html>
+`)
+	buf.Reset()
+	tree.RenderWithOptions(&buf, RenderOptions{Minify: true})
+	assertEqual(t, buf.String(), `<!DOCTYPE html><html><head><title>T</title><body><!--[if IE]>old<![endif]-->`+
+		`<div id="a b"><ul><li>One <b>1</b><li>Two</ul><p class=x>some text<pre>`+"\n\n keep  </pre></div>"+
+		`<form><input disabled value></form><table><tbody><tr><td>1<td>2</table>`)
+	assertTrue(t, tree.RenderWithOptions(&buf, RenderOptions{Pretty: true, Minify: true}) != nil,
+		"Expected Pretty with Minify to fail")
+
+	// Whitespace next to inline blocks like <select> renders.
+	tree, _ = NewFromString("<div>Name  <select><option>a</option></select>\n units</div>" +
+		"<div><select></select><select></select></div>")
+	buf.Reset()
+	tree.RenderWithOptions(&buf, RenderOptions{Minify: true})
+	assertEqual(t, buf.String(), "<html><head><body><div>Name <select><option>a</select> units</div>"+
+		"<div><select></select><select></select></div>")
+	buf.Reset()
+	tree.RenderWithOptions(&buf, RenderOptions{Pretty: true})
+	assertEqual(t, buf.String(), `<html>
+  <head></head>
+  <body>
+    <div>Name  <select><option>a</option></select>
+ units</div>
+    <div><select></select><select></select></div>
+  </body>
+</html>
+`)
+}
+
+// randomFragment generates random html with awkward whitespace, nesting,
+// attributes and optional tags.
+func randomFragment(r *rand.Rand, depth int) string {
+	texts := []string{"a", " b ", "\n  c\n", "x &amp; y", "1 &lt; 2", "  ", "\u00a0", "'q\"", "d\r\ne"}
+	attrs := []string{"", ` id=x`, ` class=" a  b "`, ` title="a b"`, ` title='"'`, ` data-x=""`,
+		` disabled`, ` checked=checked`, ` href="/a?b=1&amp;c=2"`, ` title="x/"`, " title=`"}
+	blocks := []string{"div", "p", "section", "ul", "li", "ol", "dl", "dt", "dd", "table", "tr", "td",
+		"th", "tbody", "thead", "tfoot", "caption", "select", "option", "optgroup", "pre", "textarea",
+		"h1", "blockquote", "details", "summary", "button", "ruby", "rt", "rp", "form", "fieldset", "legend"}
+	inlines := []string{"span", "b", "a", "em", "code", "label", "q"}
+	var b strings.Builder
+	for i := r.Intn(4); i >= 0; i-- {
+		switch k := r.Intn(10); {
+		case k < 3 || depth == 0:
+			b.WriteString(texts[r.Intn(len(texts))])
+		case k == 3:
+			b.WriteString([]string{"<!-- c -->", "<!--[if IE]>x<![endif]-->", "<br>", "<img src=a.png>",
+				"<hr>", "<svg><circle r=1 /><text>t</text></svg>", "<script>if (a<b) {}</script>"}[r.Intn(7)])
+		default:
+			name := inlines[r.Intn(len(inlines))]
+			if k > 5 {
+				name = blocks[r.Intn(len(blocks))]
+			}
+			b.WriteString("<" + name + attrs[r.Intn(len(attrs))] + ">")
+			b.WriteString(randomFragment(r, depth-1))
+			if r.Intn(4) > 0 {
+				b.WriteString("</" + name + ">")
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestRenderWithOptionsReparses(t *testing.T) {
+	r := rand.New(rand.NewSource(50))
+	canonical := func(n *html.Node, dropComments bool) *html.Node {
+		c := CloneNode(n)
+		Canonicalize(c, CanonicalOptions{DropComments: dropComments})
+		return c
+	}
+	skipped := 0
+	for i := 0; i < 500; i++ {
+		src := "<!DOCTYPE html><html><head><title> t </title></head><body>" + randomFragment(r, 4) + "</body></html>"
+		tree, err := NewFromString(src)
+		if err != nil {
+			t.Fatalf("Failed to parse %q: %s", src, err)
+		}
+		// Misnested markup can parse to trees that even a plain Render
+		// doesn't reproduce, eg: an <a> in an <a>.
+		plain, _ := NewFromString(tree.String())
+		if Hash(canonical(plain.Top(), false)) != Hash(canonical(tree.Top(), false)) {
+			skipped++
+			continue
+		}
+		for _, opts := range []RenderOptions{{Pretty: true}, {Pretty: true, Indent: "\t"}, {Minify: true}} {
+			var buf bytes.Buffer
+			if err := tree.RenderWithOptions(&buf, opts); err != nil {
+				t.Fatalf("Failed to render %q: %s", src, err)
+			}
+			reparsed, err := NewFromString(buf.String())
+			if err != nil {
+				t.Fatalf("Failed to reparse %q: %s", buf.String(), err)
+			}
+			expected, got := canonical(tree.Top(), opts.Minify), canonical(reparsed.Top(), opts.Minify)
+			if Hash(expected) != Hash(got) {
+				t.Errorf("Render with %+v of\n%s\nas\n%s\nreparses differently:\n%s",
+					opts, tree.String(), buf.String(), RenderChangesToString(Diff(expected, got)))
+				return
+			}
+		}
+	}
+	if skipped > 250 {
+		t.Errorf("Only %d of 500 documents rendered the same", 500-skipped)
+	}
+}
+
+func TestPositionsParserCreated(t *testing.T) {
+	cases := []struct {
+		doc    string
+		spans  []string
+		errors []string
+	}{
+		{
+			"<b>1<p>2</b>3</p><b>4</b>",
+			[]string{"html none", "head none", "body none", "b 0-12", "1 3-4", "p 4-17", "b none", "2 7-8", "3 12-13", "b 17-25", "4 20-21"},
+			[]string{"1:1: Missing <!DOCTYPE html>", "1:9: Misnested end tag </b>"},
+		},
+		{
+			"<div></p><p>x</p></div>",
+			[]string{"html none", "head none", "body none", "div 0-23", "p none", "p 9-17", "x 12-13"},
+			[]string{"1:1: Missing <!DOCTYPE html>", "1:6: Unexpected end tag </p>"},
+		},
+		{
+			"<p>x</p><body class=a><html lang=en>",
+			[]string{"html none", "head none", "body none", "p 0-8", "x 3-4"},
+			[]string{"1:1: Missing <!DOCTYPE html>"},
+		},
+	}
+	for _, c := range cases {
+		tree, err := NewWithOptions(strings.NewReader(c.doc), Options{Positions: true})
+		assertOrDie(t, err == nil, "error while parsing: %s", err)
+		var spans []string
+		tree.Walk(func(n *html.Node) {
+			if n.Type == html.DocumentNode {
+				return
+			}
+			for _, a := range n.Attr {
+				assertTrue(t, a.Key != markerAttr, "Expected the markers to be removed from %s", c.doc)
+			}
+			s, ok := tree.Span(n)
+			if !ok {
+				spans = append(spans, Data(n)+" none")
+				return
+			}
+			spans = append(spans, fmt.Sprintf("%s %d-%d", Data(n), s.Start.Offset, s.End.Offset))
+		})
+		assertEqual(t, spans, c.spans)
+		var errors []string
+		for _, e := range tree.ParseErrors() {
+			errors = append(errors, e.Error())
+		}
+		assertEqual(t, errors, c.errors)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2011 Jeremy Wall (jeremy@marzhillstudios.com)
+// Use of this source code is governed by the Artistic License 2.0.
+// That License is included in the LICENSE file.
+
+package h5
+
+import (
+	"fmt"
+
+	exphtml "golang.org/x/net/html"
+)
+
+// Detach removes n from its parent if it has one and returns it.
+func Detach(n *exphtml.Node) *exphtml.Node {
+	if n.Parent != nil {
+		n.Parent.RemoveChild(n)
+	}
+	return n
+}
+
+// checkInsert panics if inserting n under parent would make n its own
+// ancestor.
+func checkInsert(parent, n *exphtml.Node) {
+	for p := parent; p != nil; p = p.Parent {
+		if p == n {
+			panic(fmt.Sprintf("Attempt to insert <%s> inside itself", Data(n)))
+		}
+	}
+}
+
+// MoveTo moves n into newParent before the child before or to the end if
+// before is nil. n is detached from its current parent first. It panics
+// if before isn't a child of newParent or n is newParent or one of its
+// ancestors.
+func MoveTo(n, newParent, before *exphtml.Node) {
+	if before == n {
+		return
+	}
+	if before != nil && before.Parent != newParent {
+		panic(fmt.Sprintf("Attempt to insert before <%s> which isn't a child of <%s>", Data(before), Data(newParent)))
+	}
+	checkInsert(newParent, n)
+	newParent.InsertBefore(Detach(n), before)
+}
+
+// InsertAfter inserts n after ref detaching it from its current parent
+// first. It panics if ref has no parent.
+func InsertAfter(ref, n *exphtml.Node) {
+	if ref.Parent == nil {
+		panic(fmt.Sprintf("Attempt to insert after <%s> which has no parent", Data(ref)))
+	}
+	if ref == n {
+		return
+	}
+	MoveTo(n, ref.Parent, ref.NextSibling)
+}
+
+// ReplaceWith replaces n with the nodes ns detaching them from their
+// current parents first. n is left detached unless it is among ns. It
+// panics if n has no parent.
+func ReplaceWith(n *exphtml.Node, ns ...*exphtml.Node) {
+	p := n.Parent
+	if p == nil {
+		panic(fmt.Sprintf("Attempt to replace Root node: %s", RenderNodesToString([]*exphtml.Node{n})))
+	}
+	for _, c := range ns {
+		checkInsert(p, c)
+	}
+	// A marker holds n's place so n itself can be among ns.
+	marker := &exphtml.Node{Type: exphtml.TextNode}
+	p.InsertBefore(marker, n)
+	p.RemoveChild(n)
+	for _, c := range ns {
+		MoveTo(c, p, marker)
+	}
+	p.RemoveChild(marker)
+}
+
+// Wrap puts wrapper where n is and moves n inside it as its last child.
+// wrapper is detached from its current parent first. It panics if
+// wrapper is n or one of n's descendants or ancestors.
+func Wrap(n, wrapper *exphtml.Node) {
+	checkInsert(wrapper, n)
+	if n.Parent != nil {
+		checkInsert(n.Parent, wrapper)
+	}
+	Detach(wrapper)
+	if n.Parent != nil {
+		n.Parent.InsertBefore(wrapper, n)
+	}
+	wrapper.AppendChild(Detach(n))
+}
+
+// Unwrap replaces n with its children and returns them. n is left
+// detached and empty. If n has no parent its children are only detached.
+func Unwrap(n *exphtml.Node) []*exphtml.Node {
+	cs := Children(n)
+	for _, c := range cs {
+		n.RemoveChild(c)
+		if n.Parent != nil {
+			n.Parent.InsertBefore(c, n)
+		}
+	}
+	Detach(n)
+	return cs
+}
+
+// Empty removes all the children of n.
+func Empty(n *exphtml.Node) {
+	for n.FirstChild != nil {
+		n.RemoveChild(n.FirstChild)
+	}
+}
+
+// NormalizeText merges the adjacent TextNodes in the tree rooted at n and
+// removes the empty ones.
+func NormalizeText(n *exphtml.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch {
+		case c.Type == exphtml.TextNode && c.Data == "":
+			n.RemoveChild(c)
+		case c.Type == exphtml.TextNode:
+			for next != nil && next.Type == exphtml.TextNode {
+				c.Data += next.Data
+				following := next.NextSibling
+				n.RemoveChild(next)
+				next = following
+			}
+		default:
+			NormalizeText(c)
+		}
+		c = next
+	}
+}
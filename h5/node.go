@@ -8,6 +8,8 @@ import (
 	exphtml "golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
 
+	"go.marzhillstudios.com/pkg/go-html-transform/h5/walk"
+
 	"io"
 	"strings"
 )
@@ -37,12 +39,15 @@ func CloneNode(n *exphtml.Node) *exphtml.Node {
 }
 
 func NewTree(n *exphtml.Node) Tree {
-	return Tree{n}
+	return Tree{n: n}
 }
 
 // Tree represents an html5 Node tree.
 type Tree struct {
-	n *exphtml.Node
+	n        *exphtml.Node
+	encoding string
+	spans    map[*exphtml.Node]Span
+	errors   []ParseError
 }
 
 func (t Tree) Top() *exphtml.Node {
@@ -63,19 +68,26 @@ func (t Tree) String() string {
 }
 
 // Walk walks a Node with all descendants applying a given function to each one.
+// It's safe for f to detach the node it's applied to; its descendants are
+// skipped. See the walk package for more control over the walk.
 func WalkNodes(n *exphtml.Node, f func(*exphtml.Node)) {
 	if n != nil {
-		f(n)
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			WalkNodes(c, f)
-		}
+		walk.Walker(func(n *exphtml.Node) walk.Action {
+			f(n)
+			return walk.Continue
+		}).Walk(n)
 	}
 }
 
 // Clone clones an html5 nodetree to get a detached copy
 // the parent of the node we are cloning will not be copied.
 func (t Tree) Clone() Tree {
-	return Tree{CloneNode(t.n)}
+	c := Tree{n: CloneNode(t.n), encoding: t.encoding, errors: t.errors}
+	if t.spans != nil {
+		c.spans = map[*exphtml.Node]Span{}
+		cloneSpans(t.spans, c.spans, t.n, c.n)
+	}
+	return c
 }
 
 // Text constructs a TextNode
@@ -117,10 +129,12 @@ func Div(id string, class []string, children ...*exphtml.Node) *exphtml.Node {
 }
 
 func Element(name string, attrs []exphtml.Attribute, children ...*exphtml.Node) *exphtml.Node {
+	name = strings.ToLower(name)
 	n := &exphtml.Node{
-		Data: name,
-		Type: exphtml.ElementNode,
-		Attr: attrs,
+		DataAtom: atom.Lookup([]byte(name)),
+		Data:     name,
+		Type:     exphtml.ElementNode,
+		Attr:     attrs,
 	}
 	for _, c := range children {
 		n.AppendChild(c)
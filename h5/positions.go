@@ -0,0 +1,467 @@
+// Copyright 2011 Jeremy Wall (jeremy@marzhillstudios.com)
+// Use of this source code is governed by the Artistic License 2.0.
+// That License is included in the LICENSE file.
+
+package h5
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Position is a location in the source of a document. Lines and columns
+// count from 1 and columns and offsets count bytes of the utf-8 source.
+type Position struct {
+	Line, Column, Offset int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Span is the source range of a Node. An element's Span runs from the
+// start of its start tag to the end of its end tag or, if that was
+// omitted, of its last descendant.
+type Span struct {
+	Start, End Position
+}
+
+// ParseError is a problem with the markup of a document.
+type ParseError struct {
+	Pos Position
+	Msg string
+}
+
+func (e ParseError) Error() string {
+	return e.Pos.String() + ": " + e.Msg
+}
+
+// Span returns the source Span of n if the Tree was parsed by
+// NewWithOptions with Options.Positions set. Nodes the parser implied,
+// like a <tbody> without a tag, and the copies of misnested formatting
+// elements it made have no Span.
+func (t Tree) Span(n *html.Node) (Span, bool) {
+	s, ok := t.spans[n]
+	return s, ok
+}
+
+// ParseErrors returns the parse errors found in the document if the Tree
+// was parsed by NewWithOptions with Options.Positions set.
+func (t Tree) ParseErrors() []ParseError {
+	return t.errors
+}
+
+// cloneSpans copies the Spans of the Nodes in the tree rooted at n to
+// their counterparts in the clone c.
+func cloneSpans(spans, out map[*html.Node]Span, n, c *html.Node) {
+	if s, ok := spans[n]; ok {
+		out[c] = s
+	}
+	for n, c = n.FirstChild, c.FirstChild; n != nil && c != nil; n, c = n.NextSibling, c.NextSibling {
+		cloneSpans(spans, out, n, c)
+	}
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"hr": true, "img": true, "input": true, "link": true, "meta": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+// optionalEndTags are the elements whose end tags may be omitted.
+// http://www.w3.org/TR/html5/syntax.html#optional-tags
+var optionalEndTags = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true,
+	"dt": true, "dd": true, "option": true, "optgroup": true, "tr": true,
+	"td": true, "th": true, "thead": true, "tbody": true, "tfoot": true,
+	"colgroup": true, "caption": true, "rb": true, "rt": true, "rtc": true,
+	"rp": true,
+}
+
+// formattingElements are the elements the adoption agency algorithm
+// reopens when they are misnested.
+// http://www.w3.org/TR/html5/syntax.html#formatting
+var formattingElements = map[string]bool{
+	"a": true, "b": true, "big": true, "code": true, "em": true, "font": true,
+	"i": true, "nobr": true, "s": true, "small": true, "strike": true,
+	"strong": true, "tt": true, "u": true,
+}
+
+// specialElements are the elements that stay open when a formatting
+// element opened before them is closed.
+// http://www.w3.org/TR/html5/syntax.html#special
+var specialElements = map[string]bool{
+	"address": true, "applet": true, "area": true, "article": true, "aside": true,
+	"base": true, "basefont": true, "bgsound": true, "blockquote": true, "body": true,
+	"br": true, "button": true, "caption": true, "center": true, "col": true,
+	"colgroup": true, "dd": true, "details": true, "dir": true, "div": true, "dl": true,
+	"dt": true, "embed": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "frame": true, "frameset": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "head": true,
+	"header": true, "hgroup": true, "hr": true, "html": true, "iframe": true,
+	"img": true, "input": true, "li": true, "link": true, "listing": true,
+	"main": true, "marquee": true, "menu": true, "meta": true, "nav": true,
+	"noembed": true, "noframes": true, "noscript": true, "object": true, "ol": true,
+	"p": true, "param": true, "plaintext": true, "pre": true, "script": true,
+	"search": true, "section": true, "select": true, "source": true, "style": true,
+	"summary": true, "table": true, "tbody": true, "td": true, "template": true,
+	"textarea": true, "tfoot": true, "th": true, "thead": true, "title": true,
+	"tr": true, "track": true, "ul": true, "wbr": true, "xmp": true,
+}
+
+// headElements are the elements that can be in <head>. Other elements
+// and text imply a <body>.
+var headElements = map[string]bool{
+	"base": true, "basefont": true, "bgsound": true, "link": true, "meta": true,
+	"noframes": true, "noscript": true, "script": true, "style": true,
+	"template": true, "title": true,
+}
+
+// markerAttr is the attribute locate adds to every start tag so the
+// Nodes the parser creates from a tag can be told apart from the ones it
+// implies.
+const markerAttr = "data-h5-position"
+
+// tagRecord is a start tag and, once it's seen, its matching end tag.
+type tagRecord struct {
+	start, tagEnd int
+	// end is the end of the end tag or -1 if it was omitted.
+	end     int
+	claimed bool
+}
+
+// openElement is an element on the stack of open elements scan keeps.
+type openElement struct {
+	name string
+	rec  *tagRecord
+}
+
+// textRecord is a text, comment or doctype token.
+type textRecord struct {
+	data       string
+	start, end int
+	claimed    bool
+}
+
+// locator finds the source positions of the Nodes of a parsed document by
+// tokenizing the source again and matching the tokens to the Nodes.
+type locator struct {
+	lines []int
+	// tags are the start tags in source order. Their index is the value
+	// of the markerAttr added to them.
+	tags  []*tagRecord
+	texts []*textRecord
+	// textsNext indexes the first unclaimed text record.
+	textsNext int
+	comments  []*textRecord
+	doctypes  []*textRecord
+	spans     map[*html.Node]Span
+	errors    []ParseError
+}
+
+// locate parses src returning the document, the Spans of its Nodes and
+// the parse errors in src. Every start tag is marked with a markerAttr
+// before parsing so each element is matched to the tag the parser made it
+// from rather than guessed from the tags with its name. Elements the
+// parser implied have no marker and the clones the adoption agency
+// algorithm makes of misnested formatting elements share their original's.
+func locate(src []byte) (*html.Node, map[*html.Node]Span, []ParseError, error) {
+	l := &locator{
+		lines: []int{0},
+		spans: map[*html.Node]Span{},
+	}
+	for i, c := range src {
+		if c == '\n' {
+			l.lines = append(l.lines, i+1)
+		}
+	}
+	doc, err := html.Parse(bytes.NewReader(l.scan(src)))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	l.assign(doc, 0, len(src))
+	sort.SliceStable(l.errors, func(i, j int) bool {
+		return l.errors[i].Pos.Offset < l.errors[j].Pos.Offset
+	})
+	return doc, l.spans, l.errors, nil
+}
+
+func (l *locator) pos(off int) Position {
+	i := sort.Search(len(l.lines), func(i int) bool { return l.lines[i] > off }) - 1
+	return Position{Line: i + 1, Column: off - l.lines[i] + 1, Offset: off}
+}
+
+func (l *locator) errorf(off int, msg string, args ...interface{}) {
+	l.errors = append(l.errors, ParseError{Pos: l.pos(off), Msg: fmt.Sprintf(msg, args...)})
+}
+
+// scan records the tokens of src pairing start and end tags like the
+// stack of open elements and reports the parse errors it finds. It
+// returns src with a markerAttr added to every start tag.
+func (l *locator) scan(src []byte) []byte {
+	marked := make([]byte, 0, len(src)+len(src)/4)
+	z := html.NewTokenizer(bytes.NewReader(src))
+	var stack []openElement
+	foreign := func() bool {
+		for _, o := range stack {
+			if o.name == "svg" || o.name == "math" {
+				return true
+			}
+		}
+		return false
+	}
+	off := 0
+	// opened records which of html, head and body exist. Their tags only
+	// add attributes to the existing element after that so they don't
+	// create anything.
+	opened := map[string]bool{}
+	imply := func(names ...string) {
+		for _, n := range names {
+			opened[n] = true
+		}
+	}
+	seenContent := false
+	content := func(start int, doctype bool) {
+		if !seenContent && !doctype {
+			l.errorf(start, "Missing <!DOCTYPE html>")
+		}
+		seenContent = true
+	}
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		start := off
+		raw := z.Raw()
+		off += len(raw)
+		if tt == html.StartTagToken || tt == html.SelfClosingTagToken {
+			// The marker goes right after the tag name. It is quoted so a
+			// trailing / still makes the tag self-closing.
+			k := 1
+			for k < len(raw) && !strings.ContainsRune(htmlSpace+"/>", rune(raw[k])) {
+				k++
+			}
+			marked = append(marked, raw[:k]...)
+			marked = append(marked, ' ')
+			marked = append(marked, markerAttr...)
+			marked = append(marked, '=', '"')
+			marked = strconv.AppendInt(marked, int64(len(l.tags)), 10)
+			marked = append(marked, '"')
+			marked = append(marked, raw[k:]...)
+		} else {
+			marked = append(marked, raw...)
+		}
+		switch tt {
+		case html.DoctypeToken:
+			l.doctypes = append(l.doctypes, &textRecord{data: string(z.Text()), start: start, end: off})
+			if seenContent {
+				l.errorf(start, "Unexpected <!DOCTYPE>")
+			}
+			content(start, true)
+		case html.CommentToken:
+			l.comments = append(l.comments, &textRecord{data: string(z.Text()), start: start, end: off})
+		case html.TextToken:
+			text := string(z.Text())
+			l.texts = append(l.texts, &textRecord{data: text, start: start, end: off})
+			if strings.TrimLeft(text, htmlSpace) != "" {
+				content(start, false)
+				if len(stack) == 0 || !headElements[stack[len(stack)-1].name] {
+					imply("html", "head", "body")
+				}
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			content(start, false)
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			seen := map[string]bool{}
+			for hasAttr {
+				var key []byte
+				key, _, hasAttr = z.TagAttr()
+				if seen[string(key)] {
+					l.errorf(start, "Duplicate attribute %s on <%s>", key, tag)
+				}
+				seen[string(key)] = true
+			}
+			rec := &tagRecord{start: start, tagEnd: off, end: -1}
+			l.tags = append(l.tags, rec)
+			switch tag {
+			case "html", "head", "body":
+				// The tags of elements that exist already create nothing.
+				rec.claimed = opened[tag]
+			}
+			switch {
+			case tag == "html":
+				imply("html")
+			case tag == "head" || headElements[tag]:
+				imply("html", "head")
+			default:
+				imply("html", "head", "body")
+			}
+			inForeign := foreign() || tag == "svg" || tag == "math"
+			switch {
+			case voidElements[tag] && !inForeign, tt == html.SelfClosingTagToken && inForeign:
+				rec.end = off
+			default:
+				if tt == html.SelfClosingTagToken {
+					l.errorf(start, "Self-closing syntax on non-void element <%s/>", tag)
+				}
+				stack = append(stack, openElement{tag, rec})
+			}
+			z.AllowCDATA(foreign())
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			i := len(stack) - 1
+			for i >= 0 && stack[i].name != tag {
+				i--
+			}
+			if i < 0 {
+				l.errorf(start, "Unexpected end tag </%s>", tag)
+				continue
+			}
+			if formattingElements[tag] && misnested(stack[i+1:]) {
+				// The adoption agency algorithm closes the formatting
+				// element and moves a clone of it into the elements
+				// opened after it which stay open.
+				l.errorf(start, "Misnested end tag </%s>", tag)
+				stack[i].rec.end = off
+				stack = append(stack[:i], stack[i+1:]...)
+				continue
+			}
+			for _, o := range stack[i+1:] {
+				if !optionalEndTags[o.name] {
+					l.errorf(o.rec.start, "Unclosed element <%s> before </%s>", o.name, tag)
+				}
+			}
+			stack[i].rec.end = off
+			stack = stack[:i]
+			z.AllowCDATA(foreign())
+		}
+	}
+	for _, o := range stack {
+		if !optionalEndTags[o.name] {
+			l.errorf(o.rec.start, "Unclosed element <%s>", o.name)
+		}
+	}
+	return append(marked, src[off:]...)
+}
+
+// misnested returns true if closing a formatting element leaves the
+// elements opened after it open because one of them is special.
+func misnested(open []openElement) bool {
+	for _, o := range open {
+		if specialElements[o.name] {
+			return true
+		}
+	}
+	return false
+}
+
+// assign matches the children of n to the tokens starting between lo and
+// hi recording their Spans.
+func (l *locator) assign(n *html.Node, lo, hi int) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			rec := l.claimTag(c)
+			if rec == nil {
+				l.assign(c, lo, hi)
+				continue
+			}
+			end := rec.end
+			if end < 0 {
+				l.assign(c, rec.tagEnd, hi)
+				end = rec.tagEnd
+				if last, ok := l.spans[c.LastChild]; ok && last.End.Offset > end {
+					end = last.End.Offset
+				}
+			} else {
+				l.assign(c, rec.tagEnd, end)
+			}
+			l.spans[c] = Span{l.pos(rec.start), l.pos(end)}
+		case html.TextNode:
+			l.claimText(c, lo, hi)
+		case html.CommentNode:
+			l.claimExact(l.comments, c, lo, hi)
+		case html.DoctypeNode:
+			l.claimExact(l.doctypes, c, lo, hi)
+		}
+	}
+}
+
+// claimTag removes the markerAttr from n and claims the start tag it
+// refers to. It returns nil if n has no marker or it was already claimed
+// by the element n is a clone of.
+func (l *locator) claimTag(n *html.Node) *tagRecord {
+	for i, a := range n.Attr {
+		if a.Namespace != "" || a.Key != markerAttr {
+			continue
+		}
+		n.Attr = append(n.Attr[:i], n.Attr[i+1:]...)
+		j, err := strconv.Atoi(a.Val)
+		if err != nil || j < 0 || j >= len(l.tags) || l.tags[j].claimed {
+			return nil
+		}
+		l.tags[j].claimed = true
+		return l.tags[j]
+	}
+	return nil
+}
+
+// claimExact claims the first unclaimed record between lo and hi with the
+// same data as n.
+func (l *locator) claimExact(recs []*textRecord, n *html.Node, lo, hi int) {
+	for _, rec := range recs {
+		if rec.start >= hi {
+			return
+		}
+		if !rec.claimed && rec.start >= lo && strings.EqualFold(rec.data, n.Data) {
+			rec.claimed = true
+			l.spans[n] = Span{l.pos(rec.start), l.pos(rec.end)}
+			return
+		}
+	}
+}
+
+// claimText claims the text tokens between lo and hi making up the text
+// of n. The parser merges adjacent text and drops the newline starting a
+// <pre> so n may span several tokens or only part of one.
+func (l *locator) claimText(n *html.Node, lo, hi int) {
+	for l.textsNext < len(l.texts) && l.texts[l.textsNext].claimed {
+		l.textsNext++
+	}
+	for i := l.textsNext; i < len(l.texts); i++ {
+		rec := l.texts[i]
+		if rec.start >= hi {
+			return
+		}
+		if rec.claimed || rec.start < lo {
+			continue
+		}
+		text := rec.data
+		if !strings.HasPrefix(n.Data, text) && strings.HasPrefix(text, "\n") {
+			text = text[1:]
+		}
+		if text == "" || !strings.HasPrefix(n.Data, text) {
+			continue
+		}
+		rec.claimed = true
+		end := rec.end
+		for _, next := range l.texts[i+1:] {
+			if text == n.Data || next.claimed || !strings.HasPrefix(n.Data, text+next.data) {
+				break
+			}
+			next.claimed = true
+			text += next.data
+			end = next.end
+		}
+		l.spans[n] = Span{l.pos(rec.start), l.pos(end)}
+		return
+	}
+}
@@ -0,0 +1,308 @@
+// Copyright 2011 Jeremy Wall (jeremy@marzhillstudios.com)
+// Use of this source code is governed by the Artistic License 2.0.
+// That License is included in the LICENSE file.
+
+package h5
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// RenderOptions configures RenderNodesWithOptions.
+type RenderOptions struct {
+	// Pretty puts the children of elements that only contain block
+	// elements and comments on their own lines indented by Indent.
+	// Inline content and the contents of <pre>, <textarea>, <script> and
+	// <style> are left alone.
+	Pretty bool
+	// Indent is the indentation for each level when Pretty is set. It
+	// defaults to two spaces.
+	Indent string
+	// Minify collapses whitespace, drops comments apart from conditional
+	// comments and leaves out optional end tags, attribute quotes and the
+	// values of boolean attributes.
+	Minify bool
+}
+
+// RenderNodesWithOptions renders the nodes formatted with opts. The output
+// parses to nodes that Canonicalize the same as ns.
+func RenderNodesWithOptions(w io.Writer, ns []*html.Node, opts RenderOptions) error {
+	if opts.Pretty && opts.Minify {
+		return fmt.Errorf("Pretty and Minify can't both be set")
+	}
+	if !opts.Pretty && !opts.Minify {
+		return RenderNodes(w, ns)
+	}
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	for _, n := range ns {
+		c := CloneNode(n)
+		if opts.Pretty {
+			indent(c, opts.Indent, 0)
+			if err := html.Render(w, c); err != nil {
+				return err
+			}
+			continue
+		}
+		dropComments(c)
+		collapseWhitespace(c)
+		bw := bufio.NewWriter(w)
+		minify(bw, c)
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderWithOptions renders the Tree formatted with opts.
+func (t Tree) RenderWithOptions(w io.Writer, opts RenderOptions) error {
+	return RenderNodesWithOptions(w, []*html.Node{t.n}, opts)
+}
+
+// indent lays out the children of n on their own lines if they are block
+// elements or comments and does the same for the descendants. Whitespace
+// is only added or removed next to block elements where it doesn't
+// render.
+func indent(n *html.Node, unit string, depth int) {
+	if n.Type == html.ElementNode && (n.Namespace != "" || preformatted[n.Data]) {
+		return
+	}
+	cs := Children(n)
+	layout := n.Type == html.DocumentNode || isBlock(n)
+	blocks := 0
+	for _, c := range cs {
+		switch {
+		case c.Type == html.TextNode && strings.Trim(c.Data, htmlSpace) == "":
+		case c.Type == html.CommentNode:
+		case isBlock(c):
+			blocks++
+		default:
+			layout = false
+		}
+	}
+	childDepth := depth + 1
+	if n.Type == html.DocumentNode {
+		childDepth = 0
+	}
+	for _, c := range cs {
+		if c.Type == html.ElementNode {
+			indent(c, unit, childDepth)
+		}
+	}
+	if !layout || blocks == 0 {
+		return
+	}
+	// Whitespace between comments renders so it stays.
+	var prev *html.Node
+	for _, c := range cs {
+		if c.Type == html.TextNode {
+			if prev == nil || isBlock(prev) || c.NextSibling == nil || isBlock(c.NextSibling) {
+				n.RemoveChild(c)
+			}
+			continue
+		}
+		if prev != nil && (isBlock(prev) || isBlock(c)) || prev == nil && n.Type != html.DocumentNode {
+			n.InsertBefore(Text("\n"+strings.Repeat(unit, childDepth)), c)
+		}
+		prev = c
+	}
+	if n.Type == html.DocumentNode {
+		n.AppendChild(Text("\n"))
+	} else {
+		n.AppendChild(Text("\n" + strings.Repeat(unit, depth)))
+	}
+}
+
+// dropComments removes the comments in the tree rooted at n apart from
+// conditional comments like <!--[if IE]>...<![endif]-->.
+func dropComments(n *html.Node) {
+	WalkNodes(n, func(c *html.Node) {
+		if c.Type == html.CommentNode && c.Parent != nil &&
+			!strings.HasPrefix(c.Data, "[if") && !strings.HasPrefix(c.Data, "<![endif]") {
+			c.Parent.RemoveChild(c)
+		}
+	})
+}
+
+// rawTextElements are the elements whose text isn't escaped.
+var rawTextElements = map[string]bool{
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true,
+	"plaintext": true, "script": true, "style": true, "xmp": true,
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", "\r", "&#13;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", `"`, "&quot;", "\r", "&#13;")
+)
+
+// minify writes the tree rooted at n without its optional end tags and
+// attribute quotes.
+func minify(w *bufio.Writer, n *html.Node) {
+	switch n.Type {
+	case html.DocumentNode:
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			minify(w, c)
+		}
+		return
+	case html.ElementNode:
+	case html.TextNode:
+		if p := n.Parent; p != nil && p.Type == html.ElementNode && p.Namespace == "" && rawTextElements[p.Data] {
+			w.WriteString(n.Data)
+		} else {
+			textEscaper.WriteString(w, n.Data)
+		}
+		return
+	default:
+		// Doctypes and comments have no optional parts.
+		html.Render(w, n)
+		return
+	}
+	w.WriteString("<" + n.Data)
+	for _, a := range n.Attr {
+		w.WriteByte(' ')
+		if a.Namespace != "" {
+			w.WriteString(a.Namespace + ":")
+		}
+		w.WriteString(a.Key)
+		val := a.Val
+		if n.Namespace == "" && booleanAttrs[a.Key] && strings.EqualFold(val, a.Key) {
+			val = ""
+		}
+		switch {
+		case val == "":
+		case strings.ContainsAny(val, htmlSpace+"\"'=<>`"):
+			w.WriteString(`="`)
+			attrEscaper.WriteString(w, val)
+			w.WriteByte('"')
+		default:
+			w.WriteByte('=')
+			attrEscaper.WriteString(w, val)
+		}
+	}
+	if n.Namespace != "" && n.FirstChild == nil {
+		// The space keeps an unquoted value from taking the /.
+		w.WriteString(" />")
+		return
+	}
+	w.WriteByte('>')
+	if n.Namespace == "" && voidElements[n.Data] {
+		return
+	}
+	if c := n.FirstChild; c != nil && c.Type == html.TextNode && strings.HasPrefix(c.Data, "\n") &&
+		n.Namespace == "" && (n.Data == "pre" || n.Data == "textarea" || n.Data == "listing") {
+		// The parser drops a newline starting these elements.
+		w.WriteByte('\n')
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		minify(w, c)
+	}
+	if n.Namespace == "" && n.Data == "plaintext" {
+		return
+	}
+	if !optionalEndTag(n) {
+		w.WriteString("</" + n.Data + ">")
+	}
+}
+
+// closesP are the elements whose start tags close an open <p>.
+var closesP = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true,
+	"details": true, "dialog": true, "div": true, "dl": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true,
+	"h6": true, "header": true, "hgroup": true, "hr": true, "main": true,
+	"menu": true, "nav": true, "ol": true, "p": true, "pre": true,
+	"search": true, "section": true, "table": true, "ul": true,
+}
+
+// pParents are the elements whose end tags close a <p> they contain.
+var pParents = map[string]bool{
+	"body": true, "li": true, "dd": true, "dt": true, "td": true, "th": true,
+	"caption": true, "summary": true, "template": true,
+}
+
+// optionalEndTag returns true if the end tag of n can be left out.
+// http://html.spec.whatwg.org/multipage/syntax.html#optional-tags
+func optionalEndTag(n *html.Node) bool {
+	if n.Namespace != "" {
+		return false
+	}
+	next := n.NextSibling
+	// is returns true if m is one of the html elements.
+	is := func(m *html.Node, names ...string) bool {
+		if m == nil || m.Type != html.ElementNode || m.Namespace != "" {
+			return false
+		}
+		for _, name := range names {
+			if m.Data == name {
+				return true
+			}
+		}
+		return false
+	}
+	followedBy := func(names ...string) bool {
+		return is(next, names...)
+	}
+	// last returns true if n ends one of the elements. The end tags of
+	// other parents wouldn't close n.
+	last := func(parents ...string) bool {
+		return next == nil && is(n.Parent, parents...)
+	}
+	spaceOrComment := next != nil && (next.Type == html.CommentNode ||
+		next.Type == html.TextNode && next.Data != "" && strings.IndexByte(htmlSpace, next.Data[0]) >= 0)
+	switch n.Data {
+	case "html", "body":
+		return next == nil || next.Type != html.CommentNode
+	// The spec allows any content that isn't whitespace or a comment to
+	// follow these but misplaced elements like a <script> would be
+	// parsed into them.
+	case "head":
+		return next == nil || followedBy("body", "frameset")
+	case "colgroup":
+		return !spaceOrComment && !followedBy("template")
+	case "caption":
+		return next == nil || followedBy("caption", "col", "colgroup", "tbody", "td", "tfoot", "th", "thead", "tr")
+	case "li":
+		return last("ul", "ol", "menu") || followedBy("li")
+	case "dt":
+		return followedBy("dt", "dd")
+	case "dd":
+		return last("dl", "div") || followedBy("dd", "dt")
+	case "rt", "rp":
+		return is(n.Parent, "ruby") && (next == nil || followedBy("rt", "rp"))
+	case "optgroup":
+		return is(n.Parent, "select") && (next == nil || followedBy("optgroup", "hr"))
+	case "option":
+		// Only a <select> closes an <option> before an <hr>.
+		if is(n.Parent, "select") || is(n.Parent, "optgroup") && is(n.Parent.Parent, "select") {
+			return next == nil || followedBy("option", "optgroup", "hr")
+		}
+		return next == nil || followedBy("option", "optgroup")
+	case "thead":
+		return followedBy("tbody", "tfoot")
+	case "tbody":
+		return next == nil || followedBy("tbody", "tfoot")
+	case "tfoot":
+		return next == nil
+	case "tr":
+		return next == nil || followedBy("tr")
+	case "td", "th":
+		return next == nil || followedBy("td", "th")
+	case "p":
+		// The spec allows more parents but the end tags of most inline
+		// elements are ignored while a <p> is open.
+		if next == nil {
+			p := n.Parent
+			return p != nil && p.Type == html.ElementNode && p.Namespace == "" && (closesP[p.Data] || pParents[p.Data])
+		}
+		return next.Type == html.ElementNode && next.Namespace == "" && closesP[next.Data]
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package h5
+
+import (
+	"strings"
+
+	exphtml "golang.org/x/net/html"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+)
+
+// GetStyle returns the value of prop in the style attribute of a Node
+// without any !important. If prop is declared more than once it returns
+// the value that applies: the last !important one or else the last one.
+// It returns "" if the Node doesn't declare prop.
+func GetStyle(n *exphtml.Node, prop string) string {
+	if !strings.HasPrefix(prop, "--") {
+		prop = strings.ToLower(prop)
+	}
+	var value string
+	important := false
+	for _, a := range n.Attr {
+		if a.Key != "style" {
+			continue
+		}
+		for _, d := range parseStyle(a.Val) {
+			if d.Property == prop && (d.Important || !important) {
+				value, important = d.Value, d.Important
+			}
+		}
+	}
+	return value
+}
+
+// parseStyle parses the declarations of a style attribute with
+// css.ParseDeclarations skipping the invalid ones.
+func parseStyle(s string) css.DeclarationList {
+	dl, err := css.ParseDeclarations(s)
+	if err != nil {
+		return nil
+	}
+	return dl
+}
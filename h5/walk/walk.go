@@ -0,0 +1,232 @@
+/*
+Package walk implements iterative traversals of html node trees.
+
+The traversals are iter.Seq iterators so they can be ranged over and
+stopped with break.
+
+	for n := range walk.Descendants(doc) {
+	    if n.Type == html.ElementNode && n.Data == "title" {
+	        break
+	    }
+	}
+
+A Walker visits a tree pre-order and controls the traversal with the
+Action it returns.
+
+	walk.Walker(func(n *html.Node) walk.Action {
+	    if n.Data == "script" {
+	        return walk.SkipChildren
+	    }
+	    return walk.Continue
+	}).Walk(doc)
+
+None of the traversals recurse so deep documents can't overflow the
+stack. It's safe to detach the current node while walking: its subtree
+is skipped and the traversal continues from the node that followed it.
+*/
+package walk
+
+import (
+	"iter"
+
+	"golang.org/x/net/html"
+)
+
+// Action is the result of a Walker's func for a node.
+type Action int
+
+const (
+	// Continue walks into the children of the node.
+	Continue Action = iota
+	// SkipChildren skips the children of the node.
+	SkipChildren
+	// Stop ends the walk.
+	Stop
+)
+
+func (a Action) String() string {
+	switch a {
+	case Continue:
+		return "Continue"
+	case SkipChildren:
+		return "SkipChildren"
+	case Stop:
+		return "Stop"
+	}
+	panic("Unreachable")
+}
+
+// Walker is a func called for each node of a walk.
+type Walker func(n *html.Node) Action
+
+// Walk calls w for n and its descendants in pre-order. It returns false
+// if w returned Stop.
+func (w Walker) Walk(n *html.Node) bool {
+	root := n
+	for n != nil {
+		parent, next := n.Parent, n.NextSibling
+		act := w(n)
+		if act == Stop {
+			return false
+		}
+		if n.Parent == parent {
+			if act == Continue && n.FirstChild != nil {
+				n = n.FirstChild
+				continue
+			}
+			next = n.NextSibling
+		} else if next != nil && next.Parent != parent {
+			// the following node was detached along with n.
+			next = nil
+		}
+		if n == root {
+			return true
+		}
+		n = nextUp(root, parent, next)
+	}
+	return true
+}
+
+// nextUp returns next or, if it's nil, the next sibling of the nearest
+// ancestor below root that has one starting from parent.
+func nextUp(root, parent, next *html.Node) *html.Node {
+	for next == nil {
+		if parent == nil || parent == root {
+			return nil
+		}
+		next, parent = parent.NextSibling, parent.Parent
+	}
+	return next
+}
+
+// Descendants yields the descendants of n in pre-order.
+func Descendants(n *html.Node) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if !preOrder(c, yield) {
+				return
+			}
+			if c.Parent == n {
+				next = c.NextSibling
+			}
+			c = next
+		}
+	}
+}
+
+// preOrder yields the tree rooted at n in pre-order and returns false if
+// yield did.
+func preOrder(n *html.Node, yield func(*html.Node) bool) bool {
+	return Walker(func(n *html.Node) Action {
+		if !yield(n) {
+			return Stop
+		}
+		return Continue
+	}).Walk(n)
+}
+
+// Ancestors yields the ancestors of n starting with its parent.
+func Ancestors(n *html.Node) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		for p := n.Parent; p != nil; p = p.Parent {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+}
+
+// Children yields the children of n.
+func Children(n *html.Node) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		for c := n.FirstChild; c != nil; {
+			next := c.NextSibling
+			if !yield(c) {
+				return
+			}
+			if c.Parent == n {
+				next = c.NextSibling
+			}
+			c = next
+		}
+	}
+}
+
+// Following yields the nodes after n in document order that aren't its
+// descendants.
+func Following(n *html.Node) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		for a := n; a != nil; a = a.Parent {
+			for s := a.NextSibling; s != nil; {
+				parent, next := s.Parent, s.NextSibling
+				if !preOrder(s, yield) {
+					return
+				}
+				if s.Parent == parent {
+					next = s.NextSibling
+				}
+				s = next
+			}
+		}
+	}
+}
+
+// Preceding yields the nodes before n in reverse document order that
+// aren't its ancestors.
+func Preceding(n *html.Node) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		for a := n; a != nil; a = a.Parent {
+			for s := a.PrevSibling; s != nil; {
+				prev := s.PrevSibling
+				if !postOrder(s, yield, lastChild, prevSibling) {
+					return
+				}
+				s = prev
+			}
+		}
+	}
+}
+
+// PostOrder yields the tree rooted at n in post-order so every node comes
+// after its descendants.
+func PostOrder(n *html.Node) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		postOrder(n, yield, firstChild, nextSibling)
+	}
+}
+
+func firstChild(n *html.Node) *html.Node  { return n.FirstChild }
+func lastChild(n *html.Node) *html.Node   { return n.LastChild }
+func nextSibling(n *html.Node) *html.Node { return n.NextSibling }
+func prevSibling(n *html.Node) *html.Node { return n.PrevSibling }
+
+// postOrder yields the tree rooted at n in post-order descending with
+// first and moving across with next. Going with lastChild and prevSibling
+// yields the tree in reverse document order. It returns false if yield
+// did.
+func postOrder(n *html.Node, yield func(*html.Node) bool, first, next func(*html.Node) *html.Node) bool {
+	root := n
+	for c := first(n); c != nil; c = first(n) {
+		n = c
+	}
+	for {
+		// the node is yielded after its descendants so only where to
+		// go next needs saving in case it's detached.
+		parent, sibling := n.Parent, next(n)
+		if !yield(n) {
+			return false
+		}
+		if n == root || parent == nil {
+			return true
+		}
+		if sibling == nil || sibling.Parent != parent {
+			n = parent
+			continue
+		}
+		n = sibling
+		for c := first(n); c != nil; c = first(n) {
+			n = c
+		}
+	}
+}
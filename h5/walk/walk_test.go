@@ -0,0 +1,165 @@
+package walk
+
+import (
+	"iter"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// tree builds <a><b><d/><e/></b><c><f/></c></a> and returns a.
+func tree() *html.Node {
+	el := func(name string, cs ...*html.Node) *html.Node {
+		n := &html.Node{Type: html.ElementNode, Data: name}
+		for _, c := range cs {
+			n.AppendChild(c)
+		}
+		return n
+	}
+	return el("a", el("b", el("d"), el("e")), el("c", el("f")))
+}
+
+func find(root *html.Node, name string) *html.Node {
+	for n := range Descendants(root) {
+		if n.Data == name {
+			return n
+		}
+	}
+	return nil
+}
+
+func names(seq iter.Seq[*html.Node]) string {
+	var ss []string
+	for n := range seq {
+		ss = append(ss, n.Data)
+	}
+	return strings.Join(ss, "")
+}
+
+func TestTraversals(t *testing.T) {
+	a := tree()
+	testCases := []struct {
+		name     string
+		seq      iter.Seq[*html.Node]
+		expected string
+	}{
+		{"Descendants", Descendants(a), "bdecf"},
+		{"Descendants of leaf", Descendants(find(a, "d")), ""},
+		{"Ancestors", Ancestors(find(a, "e")), "ba"},
+		{"Children", Children(a), "bc"},
+		{"Following", Following(find(a, "d")), "ecf"},
+		{"Following with descendants", Following(find(a, "b")), "cf"},
+		{"Preceding", Preceding(find(a, "f")), "edb"},
+		{"Preceding of first", Preceding(find(a, "d")), ""},
+		{"PostOrder", PostOrder(a), "debfca"},
+		{"PostOrder of leaf", PostOrder(find(a, "f")), "f"},
+	}
+	for _, tc := range testCases {
+		if got := names(tc.seq); got != tc.expected {
+			t.Errorf("%s expected %q got %q", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestBreak(t *testing.T) {
+	a := tree()
+	for _, seq := range []iter.Seq[*html.Node]{Descendants(a), Children(a), PostOrder(a),
+		Ancestors(find(a, "f")), Following(find(a, "d")), Preceding(find(a, "f"))} {
+		n := 0
+		for range seq {
+			n++
+			break
+		}
+		if n != 1 {
+			t.Errorf("Expected the iterator to stop after one node")
+		}
+	}
+}
+
+func TestWalker(t *testing.T) {
+	a := tree()
+	var seen string
+	Walker(func(n *html.Node) Action {
+		seen += n.Data
+		switch n.Data {
+		case "b":
+			return SkipChildren
+		case "c":
+			return Stop
+		}
+		return Continue
+	}).Walk(a)
+	if seen != "abc" {
+		t.Errorf("Walker visited %q", seen)
+	}
+	seen = ""
+	ok := Walker(func(n *html.Node) Action {
+		seen += n.Data
+		return Continue
+	}).Walk(find(a, "b"))
+	if seen != "bde" || !ok {
+		t.Errorf("Walker of subtree visited %q", seen)
+	}
+}
+
+func TestDetachWhileWalking(t *testing.T) {
+	a := tree()
+	var seen string
+	Walker(func(n *html.Node) Action {
+		seen += n.Data
+		if n.Data == "b" || n.Data == "f" {
+			n.Parent.RemoveChild(n)
+		}
+		return Continue
+	}).Walk(a)
+	if seen != "abcf" {
+		t.Errorf("Walker visited %q", seen)
+	}
+	if got := names(PostOrder(a)); got != "ca" {
+		t.Errorf("Expected the detached nodes gone got %q", got)
+	}
+	a = tree()
+	seen = ""
+	for n := range Descendants(a) {
+		seen += n.Data
+		if n.Data == "d" {
+			n.Parent.RemoveChild(n)
+		}
+	}
+	if seen != "bdecf" {
+		t.Errorf("Descendants visited %q", seen)
+	}
+	a = tree()
+	seen = ""
+	for n := range PostOrder(a) {
+		seen += n.Data
+		if n.Parent != nil {
+			n.Parent.RemoveChild(n)
+		}
+	}
+	if seen != "debfca" || a.FirstChild != nil {
+		t.Errorf("PostOrder visited %q", seen)
+	}
+}
+
+func TestDeepTree(t *testing.T) {
+	root := &html.Node{Type: html.ElementNode, Data: "div"}
+	n := root
+	for i := 0; i < 1000000; i++ {
+		c := &html.Node{Type: html.ElementNode, Data: "div"}
+		n.AppendChild(c)
+		n = c
+	}
+	count := 0
+	Walker(func(*html.Node) Action {
+		count++
+		return Continue
+	}).Walk(root)
+	for range PostOrder(root) {
+		count++
+	}
+	if count != 2000002 {
+		t.Errorf("Expected to visit 2000002 nodes got %d", count)
+	}
+}
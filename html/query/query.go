@@ -0,0 +1,438 @@
+/*
+Package query implements a fluent Selection api for scraping and mutating
+an h5.Tree with css selectors.
+
+	tree, _ := h5.New(rdr)
+	doc := query.New(tree)
+	third := doc.Find("ul.menu").Children("li").Eq(2).Text()
+	links := doc.Find("a[href^=http]").SetAttr("rel", "nofollow")
+	if err := links.Err(); err != nil {
+	    // a selector was invalid
+	}
+
+A Selection is an ordered list of distinct nodes. Methods that narrow or
+move a Selection return a new one and leave the receiver alone. Mutations
+change the tree in place and return the receiver so they can be chained.
+Invalid selectors don't panic; the Selection is empty and Err reports the
+first error in the chain.
+
+Selections interoperate with the transform package: FindWith takes a
+transform.Collector, Collector returns one and Apply runs a
+transform.TransformFunc on every node.
+*/
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+	"go.marzhillstudios.com/pkg/go-html-transform/html/transform"
+)
+
+// Selection is a list of nodes from an html document.
+type Selection struct {
+	nodes []*html.Node
+	err   error
+}
+
+// New constructs a Selection of the top of the Tree. The Tree isn't
+// copied so mutations change it.
+func New(t *h5.Tree) *Selection {
+	return Select(t.Top())
+}
+
+// Select constructs a Selection of the nodes passed in.
+func Select(ns ...*html.Node) *Selection {
+	return &Selection{nodes: unique(ns)}
+}
+
+// unique returns the nodes of ns without duplicates keeping the first of
+// each.
+func unique(ns []*html.Node) []*html.Node {
+	set := make(map[*html.Node]struct{}, len(ns))
+	var out []*html.Node
+	for _, n := range ns {
+		if _, ok := set[n]; !ok && n != nil {
+			out = append(out, n)
+			set[n] = struct{}{}
+		}
+	}
+	return out
+}
+
+// derive returns a Selection of ns carrying the error of s.
+func (s *Selection) derive(ns []*html.Node) *Selection {
+	return &Selection{nodes: unique(ns), err: s.err}
+}
+
+// group parses sel carrying the error into the returned Selection if it
+// fails or can't be matched against a document.
+func (s *Selection) group(sel string) (selector.Group, *Selection) {
+	g, err := selector.SelectorGroup(sel)
+	if err == nil && !g.Matchable() {
+		err = fmt.Errorf("Can't match selector %q against a document", sel)
+	}
+	if err != nil {
+		if s.err != nil {
+			err = s.err
+		}
+		return nil, &Selection{err: err}
+	}
+	return g, nil
+}
+
+// Err returns the first invalid selector error in the chain that produced
+// the Selection.
+func (s *Selection) Err() error {
+	return s.err
+}
+
+// Nodes returns the nodes of the Selection.
+func (s *Selection) Nodes() []*html.Node {
+	return s.nodes
+}
+
+// Len returns the number of nodes in the Selection.
+func (s *Selection) Len() int {
+	return len(s.nodes)
+}
+
+// Find returns the descendants of the nodes in the Selection that match
+// the selector group sel.
+func (s *Selection) Find(sel string) *Selection {
+	g, bad := s.group(sel)
+	if bad != nil {
+		return bad
+	}
+	return s.FindWith(g)
+}
+
+// FindWith returns the descendants of the nodes in the Selection found by
+// coll in document order.
+func (s *Selection) FindWith(coll transform.Collector) *Selection {
+	var found []*html.Node
+	for _, n := range s.nodes {
+		var matches []*html.Node
+		matched := map[*html.Node]bool{}
+		for _, f := range coll.Find(n) {
+			if f != n && !matched[f] {
+				matches = append(matches, f)
+				matched[f] = true
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		// A selector group finds the matches of each selector in turn so
+		// they are put back in document order.
+		h5.WalkNodes(n, func(d *html.Node) {
+			if matched[d] {
+				found = append(found, d)
+				delete(matched, d)
+			}
+		})
+		for _, f := range matches {
+			if matched[f] {
+				found = append(found, f)
+			}
+		}
+	}
+	return s.derive(found)
+}
+
+// filter returns the nodes of the Selection for which keep returns true.
+func (s *Selection) filter(keep func(n *html.Node) bool) *Selection {
+	var found []*html.Node
+	for _, n := range s.nodes {
+		if keep(n) {
+			found = append(found, n)
+		}
+	}
+	return s.derive(found)
+}
+
+// Filter returns the nodes of the Selection that match sel.
+func (s *Selection) Filter(sel string) *Selection {
+	g, bad := s.group(sel)
+	if bad != nil {
+		return bad
+	}
+	return s.filter(g.Match)
+}
+
+// FilterFunc returns the nodes of the Selection for which f returns true.
+func (s *Selection) FilterFunc(f func(i int, s *Selection) bool) *Selection {
+	i := -1
+	return s.filter(func(n *html.Node) bool {
+		i++
+		return f(i, Select(n))
+	})
+}
+
+// Not returns the nodes of the Selection that don't match sel.
+func (s *Selection) Not(sel string) *Selection {
+	g, bad := s.group(sel)
+	if bad != nil {
+		return bad
+	}
+	return s.filter(func(n *html.Node) bool { return !g.Match(n) })
+}
+
+// Is returns true if any node of the Selection matches sel.
+func (s *Selection) Is(sel string) bool {
+	return s.Filter(sel).Len() > 0
+}
+
+// Has returns the nodes of the Selection with a descendant that matches
+// sel.
+func (s *Selection) Has(sel string) *Selection {
+	g, bad := s.group(sel)
+	if bad != nil {
+		return bad
+	}
+	return s.filter(func(n *html.Node) bool {
+		found := false
+		for c := n.FirstChild; c != nil && !found; c = c.NextSibling {
+			h5.WalkNodes(c, func(d *html.Node) {
+				found = found || g.Match(d)
+			})
+		}
+		return found
+	})
+}
+
+// Parent returns the parent elements of the nodes in the Selection.
+func (s *Selection) Parent() *Selection {
+	var found []*html.Node
+	for _, n := range s.nodes {
+		if p := n.Parent; p != nil && p.Type == html.ElementNode {
+			found = append(found, p)
+		}
+	}
+	return s.derive(found)
+}
+
+// Closest returns the first node matching sel of each node in the
+// Selection and its ancestors.
+func (s *Selection) Closest(sel string) *Selection {
+	g, bad := s.group(sel)
+	if bad != nil {
+		return bad
+	}
+	var found []*html.Node
+	for _, n := range s.nodes {
+		for p := n; p != nil; p = p.Parent {
+			if g.Match(p) {
+				found = append(found, p)
+				break
+			}
+		}
+	}
+	return s.derive(found)
+}
+
+// Children returns the child elements of the nodes in the Selection that
+// match sel or all of them if sel is "".
+func (s *Selection) Children(sel string) *Selection {
+	var found []*html.Node
+	for _, n := range s.nodes {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode {
+				found = append(found, c)
+			}
+		}
+	}
+	cs := s.derive(found)
+	if sel == "" {
+		return cs
+	}
+	return cs.Filter(sel)
+}
+
+// Next returns the next sibling elements of the nodes in the Selection.
+func (s *Selection) Next() *Selection {
+	var found []*html.Node
+	for _, n := range s.nodes {
+		c := n.NextSibling
+		for c != nil && c.Type != html.ElementNode {
+			c = c.NextSibling
+		}
+		found = append(found, c)
+	}
+	return s.derive(found)
+}
+
+// Prev returns the previous sibling elements of the nodes in the
+// Selection.
+func (s *Selection) Prev() *Selection {
+	var found []*html.Node
+	for _, n := range s.nodes {
+		c := n.PrevSibling
+		for c != nil && c.Type != html.ElementNode {
+			c = c.PrevSibling
+		}
+		found = append(found, c)
+	}
+	return s.derive(found)
+}
+
+// Eq returns the i'th node of the Selection. A negative i counts from the
+// end. The Selection is empty if i is out of range.
+func (s *Selection) Eq(i int) *Selection {
+	if i < 0 {
+		i += len(s.nodes)
+	}
+	if i < 0 || i >= len(s.nodes) {
+		return s.derive(nil)
+	}
+	return s.derive(s.nodes[i : i+1])
+}
+
+// First returns the first node of the Selection.
+func (s *Selection) First() *Selection {
+	return s.Eq(0)
+}
+
+// Last returns the last node of the Selection.
+func (s *Selection) Last() *Selection {
+	return s.Eq(-1)
+}
+
+// Each calls f with each node of the Selection and its index.
+func (s *Selection) Each(f func(i int, s *Selection)) *Selection {
+	for i, n := range s.nodes {
+		f(i, Select(n))
+	}
+	return s
+}
+
+// Map returns the results of calling f with each node of the Selection
+// and its index.
+func (s *Selection) Map(f func(i int, s *Selection) string) []string {
+	out := make([]string, len(s.nodes))
+	for i, n := range s.nodes {
+		out[i] = f(i, Select(n))
+	}
+	return out
+}
+
+// Attr returns the value of the attribute key of the first node of the
+// Selection and true or false if it doesn't have one.
+func (s *Selection) Attr(key string) (string, bool) {
+	if len(s.nodes) == 0 {
+		return "", false
+	}
+	for _, a := range s.nodes[0].Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+// Text returns the combined text of the nodes in the Selection and their
+// descendants.
+func (s *Selection) Text() string {
+	var b strings.Builder
+	for _, n := range s.nodes {
+		h5.WalkNodes(n, func(c *html.Node) {
+			if c.Type == html.TextNode {
+				b.WriteString(c.Data)
+			}
+		})
+	}
+	return b.String()
+}
+
+// Html returns the rendered children of the first node of the Selection.
+func (s *Selection) Html() string {
+	if len(s.nodes) == 0 {
+		return ""
+	}
+	return h5.RenderNodesToString(h5.Children(s.nodes[0]))
+}
+
+// Collector returns a transform.Collector that finds the nodes of the
+// Selection whatever node it's asked to search.
+func (s *Selection) Collector() transform.Collector {
+	return transform.CollectorFunc(func(*html.Node) []*html.Node {
+		return s.nodes
+	})
+}
+
+// Apply runs f on each node of the Selection.
+func (s *Selection) Apply(f transform.TransformFunc) *Selection {
+	for _, n := range s.nodes {
+		f(n)
+	}
+	return s
+}
+
+// SetAttr sets the attribute key to val on the nodes of the Selection.
+func (s *Selection) SetAttr(key, val string) *Selection {
+	return s.Apply(transform.ModifyAttrib(key, val))
+}
+
+// RemoveAttr removes the attribute key from the nodes of the Selection.
+func (s *Selection) RemoveAttr(key string) *Selection {
+	return s.Apply(func(n *html.Node) {
+		attrs := n.Attr[:0]
+		for _, a := range n.Attr {
+			if a.Key != key {
+				attrs = append(attrs, a)
+			}
+		}
+		n.Attr = attrs
+	})
+}
+
+// SetText replaces the children of the nodes of the Selection with the
+// text t.
+func (s *Selection) SetText(t string) *Selection {
+	return s.Apply(transform.ReplaceChildren(h5.Text(t)))
+}
+
+// SetHtml replaces the children of the nodes of the Selection with the
+// html fragment frag parsed in their context.
+func (s *Selection) SetHtml(frag string) *Selection {
+	return s.Apply(transform.ReplaceChildrenFromString(frag))
+}
+
+// Append appends copies of ns to the nodes of the Selection.
+func (s *Selection) Append(ns ...*html.Node) *Selection {
+	return s.Apply(transform.AppendChildren(ns...))
+}
+
+// Prepend prepends copies of ns to the nodes of the Selection.
+func (s *Selection) Prepend(ns ...*html.Node) *Selection {
+	return s.Apply(transform.PrependChildren(ns...))
+}
+
+// Empty removes the children of the nodes of the Selection.
+func (s *Selection) Empty() *Selection {
+	return s.Apply(transform.RemoveChildren())
+}
+
+// Remove detaches the nodes of the Selection from the tree.
+func (s *Selection) Remove() *Selection {
+	return s.Apply(transform.Detach())
+}
+
+// ReplaceWith replaces the nodes of the Selection with copies of ns.
+func (s *Selection) ReplaceWith(ns ...*html.Node) *Selection {
+	return s.Apply(transform.Replace(ns...))
+}
+
+// Wrap wraps the nodes of the Selection in copies of wrapper.
+func (s *Selection) Wrap(wrapper *html.Node) *Selection {
+	return s.Apply(transform.Wrap(wrapper))
+}
+
+// Unwrap replaces the nodes of the Selection with their children.
+func (s *Selection) Unwrap() *Selection {
+	return s.Apply(transform.Unwrap())
+}
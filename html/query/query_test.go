@@ -0,0 +1,139 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+	"go.marzhillstudios.com/pkg/go-html-transform/html/transform"
+)
+
+const page = `<html><body>
+<ul class="menu"><li>Home</li><li class="x">About <b>us</b></li><li><a href="/c">Contact</a></li></ul>
+<div id="main"><p>one</p><p class="x">two</p><span>three</span></div>
+</body></html>`
+
+func doc(t *testing.T) (*h5.Tree, *Selection) {
+	tree, err := h5.NewFromString(page)
+	if err != nil {
+		t.Fatalf("Failed to parse page: %s", err)
+	}
+	return tree, New(tree)
+}
+
+func TestTraversal(t *testing.T) {
+	_, d := doc(t)
+	lis := d.Find("ul.menu").Children("li")
+	if lis.Len() != 3 {
+		t.Errorf("Expected 3 li got %d", lis.Len())
+	}
+	testCases := []struct {
+		s    *Selection
+		text string
+	}{
+		{lis.Eq(2), "Contact"},
+		{lis.Eq(-3), "Home"},
+		{lis.First().Next(), "About us"},
+		{lis.Last().Prev().Prev(), "Home"},
+		{lis.Eq(5), ""},
+		{d.Find(".x"), "About ustwo"},
+		{d.Find("p, span").Filter(".x"), "two"},
+		{d.Find("span, p").First(), "one"},
+		{d.Find("span, li, p").Eq(3), "one"},
+		{d.Find("b, li.x").First(), "About us"},
+		{d.Find("#main").Children("").Not("p"), "three"},
+		{d.Find("li").Has("a, b"), "About usContact"},
+		{d.Find("b").Closest("li"), "About us"},
+		{d.Find("b, a").Closest("ul").Children("li").Parent().Find("a"), "Contact"},
+		{d.Find("p").Parent().Children("span"), "three"},
+		{d.Find("p").FilterFunc(func(i int, s *Selection) bool { return i == 1 }), "two"},
+	}
+	for i, tc := range testCases {
+		if got := tc.s.Text(); got != tc.text {
+			t.Errorf("Case %d expected %q got %q", i, tc.text, got)
+		}
+	}
+	if !lis.Is(".x") || lis.Eq(0).Is(".x") {
+		t.Errorf("Is matched the wrong li")
+	}
+	if got := lis.Eq(1).Html(); got != "About <b>us</b>" {
+		t.Errorf("Html returned %q", got)
+	}
+	if href, ok := d.Find("a").Attr("href"); !ok || href != "/c" {
+		t.Errorf("Attr returned %q %v", href, ok)
+	}
+	if _, ok := d.Find("p").Attr("href"); ok {
+		t.Errorf("Attr found a missing attribute")
+	}
+	got := strings.Join(lis.Map(func(i int, s *Selection) string { return s.Text() }), "|")
+	if got != "Home|About us|Contact" {
+		t.Errorf("Map returned %q", got)
+	}
+	n := 0
+	lis.Each(func(i int, s *Selection) { n += i })
+	if n != 3 {
+		t.Errorf("Each passed the wrong indexes")
+	}
+}
+
+func TestInvalidSelector(t *testing.T) {
+	_, d := doc(t)
+	s := d.Find("li,").Children("b").Filter("b")
+	if s.Len() != 0 || s.Err() == nil {
+		t.Errorf("Expected an empty Selection with an error got %d %v", s.Len(), s.Err())
+	}
+	if d.Find("li").Err() != nil {
+		t.Errorf("Unexpected error %s", d.Find("li").Err())
+	}
+	for _, sel := range []string{"a:hover", "li::before", ":visited", ":focus-within", ":any-link", ":lang(en)", "li:has(a)"} {
+		for _, s := range []*Selection{d.Find(sel), d.Find("li").Filter(sel), d.Find("li").Not(sel),
+			d.Find("ul").Has(sel), d.Find("a").Closest(sel)} {
+			if s.Len() != 0 || s.Err() == nil {
+				t.Errorf("%q expected an empty Selection with an error got %d %v", sel, s.Len(), s.Err())
+			}
+		}
+	}
+}
+
+func TestMutation(t *testing.T) {
+	tree, d := doc(t)
+	d.Find("li.x").SetText("Team").SetAttr("id", "team").RemoveAttr("class")
+	d.Find("a").Unwrap()
+	d.Find("p").Wrap(h5.Element("section", nil)).Append(h5.Text("!"))
+	d.Find("span").ReplaceWith(h5.Element("hr", nil))
+	d.Find("ul").Prepend(h5.Element("li", nil, h5.Text("Start")))
+	d.Find("#main").Apply(transform.ModifyAttrib("class", "box"))
+	expected := `<html><head></head><body>
+<ul class="menu"><li>Start</li><li>Home</li><li id="team">Team</li><li>Contact</li></ul>
+<div id="main" class="box"><section><p>one!</p></section><section><p class="x">two!</p></section><hr/></div>
+</body></html>`
+	if got := tree.String(); got != expected {
+		t.Errorf("Expected %s got %s", expected, got)
+	}
+	d.Find("section").Remove()
+	d.Find("ul").SetHtml("<li>a<li>b")
+	d.Find("body").Children("").Last().Empty()
+	expected = `<html><head></head><body>
+<ul class="menu"><li>a</li><li>b</li></ul>
+<div id="main" class="box"></div>
+</body></html>`
+	if got := tree.String(); got != expected {
+		t.Errorf("Expected %s got %s", expected, got)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	tree, d := doc(t)
+	tf := transform.New(tree)
+	tf.ApplyWithCollector(transform.ModifyAttrib("lang", "en"), Select(tf.Doc()).Find("p").Collector())
+	s := Select(tf.Doc()).FindWith(Select(tf.Doc()).Find("p").Collector())
+	if s.Len() != 2 {
+		t.Errorf("Expected 2 p got %d", s.Len())
+	}
+	if v, _ := s.Attr("lang"); v != "en" {
+		t.Errorf("ApplyWithCollector didn't modify the Selection")
+	}
+	if _, ok := d.Find("p").Attr("lang"); ok {
+		t.Errorf("The transform modified the original tree")
+	}
+}
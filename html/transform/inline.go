@@ -0,0 +1,482 @@
+package transform
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/cascade"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/selector"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5/walk"
+)
+
+// InlineOptions configures the TransformFunc returned by InlineCSS.
+type InlineOptions struct {
+	// FS is used to load Sheets and the stylesheets referenced by
+	// <link rel="stylesheet"> elements with a relative href.
+	FS fs.FS
+	// Sheets are paths in FS of stylesheets that are applied before any
+	// stylesheets in the document.
+	Sheets []string
+	// RemoveClasses removes the classes that are no longer used by any
+	// remaining <style> rules once the css has been inlined.
+	RemoveClasses bool
+	// LegacyAttributes adds html attributes like bgcolor, width and align
+	// mirroring the inlined styles for mail clients that ignore css.
+	LegacyAttributes bool
+}
+
+// InlineCSS creates a TransformFunc that inlines css into the style
+// attributes of the elements it matches, in the manner of premailer for
+// html email. Run it against the root of the document:
+//
+//	f, err := InlineCSS(InlineOptions{})
+//	t.Apply(f, "html")
+//
+// The rules of the <style> elements, of the linked stylesheets found in
+// FS and of the Sheets option are applied in cascade order and merged with
+// any existing style attributes. Rules that can't be inlined, like @media
+// rules or rules using pseudo classes like :hover, and rules that don't
+// match any element outside the <head> are kept in a <style> element.
+// <style> elements with a media attribute, a non css type or a
+// data-inline="false" attribute are left alone. Custom properties are
+// resolved and their var() functions substituted since mail clients don't
+// support them.
+// It returns an error if one of the Sheets can't be loaded or parsed.
+func InlineCSS(opts InlineOptions) (TransformFunc, error) {
+	var sheets []*css.Stylesheet
+	for _, p := range opts.Sheets {
+		ss, err := loadSheet(opts.FS, p)
+		if err != nil {
+			return nil, err
+		}
+		sheets = append(sheets, ss)
+	}
+	return func(n *html.Node) {
+		inlineCSS(n, sheets, opts)
+	}, nil
+}
+
+// MustInlineCSS creates a TransformFunc that inlines css.
+// Panics if one of the Sheets can't be loaded or parsed.
+func MustInlineCSS(opts InlineOptions) TransformFunc {
+	f, err := InlineCSS(opts)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func loadSheet(fsys fs.FS, p string) (*css.Stylesheet, error) {
+	b, err := fs.ReadFile(fsys, p)
+	if err != nil {
+		return nil, err
+	}
+	return css.ParseString(string(b))
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
+
+func removeAttr(n *html.Node, key string) {
+	attrs := n.Attr[:0]
+	for _, a := range n.Attr {
+		if a.Key != key {
+			attrs = append(attrs, a)
+		}
+	}
+	n.Attr = attrs
+}
+
+// inlineStyleSource returns the stylesheet an element contributes to the
+// inliner or nil if it doesn't contribute one.
+func inlineStyleSource(n *html.Node, fsys fs.FS) *css.Stylesheet {
+	if n.Type != html.ElementNode {
+		return nil
+	}
+	if v, _ := attr(n, "data-inline"); v == "false" {
+		return nil
+	}
+	switch n.DataAtom {
+	case atom.Style:
+		if v, ok := attr(n, "media"); ok && v != "" && v != "all" {
+			return nil
+		}
+		if v, ok := attr(n, "type"); ok && v != "" && !strings.EqualFold(v, "text/css") {
+			return nil
+		}
+		var b strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			b.WriteString(c.Data)
+		}
+		ss, err := css.ParseString(b.String())
+		if err != nil {
+			// Leave stylesheets we can't parse alone.
+			return nil
+		}
+		return ss
+	case atom.Link:
+		rel, _ := attr(n, "rel")
+		href, _ := attr(n, "href")
+		if fsys == nil || !strings.EqualFold(rel, "stylesheet") || href == "" ||
+			strings.Contains(href, ":") || strings.HasPrefix(href, "//") {
+			return nil
+		}
+		if v, ok := attr(n, "media"); ok && v != "" && v != "all" {
+			return nil
+		}
+		ss, err := loadSheet(fsys, path.Clean(strings.TrimPrefix(href, "/")))
+		if err != nil {
+			return nil
+		}
+		return ss
+	}
+	return nil
+}
+
+// inlinableRules returns the rules of the flattened stylesheet ss with
+// the selectors that can be inlined.
+func inlinableRules(ss *css.Stylesheet) *css.Stylesheet {
+	inlinable := &css.Stylesheet{}
+	for _, st := range ss.Statements {
+		if st.Ruleset == nil {
+			continue
+		}
+		var in selector.Group
+		for _, chn := range st.Ruleset.Selector {
+			if chn.Matchable() {
+				in = append(in, chn)
+			}
+		}
+		if len(in) > 0 {
+			inlinable.Statements = append(inlinable.Statements, css.Statement{
+				Ruleset: &css.Ruleset{Selector: in, DeclarationList: st.Ruleset.DeclarationList}})
+		}
+	}
+	return inlinable
+}
+
+// remainingRules returns the statements of the flattened stylesheet ss
+// that have to stay in a <style> element. Those are the AtRules and the
+// rules with selectors that weren't applied to any element.
+func remainingRules(ss *css.Stylesheet, applied map[*selector.Chain]bool) *css.Stylesheet {
+	rest := &css.Stylesheet{}
+	for _, st := range ss.Statements {
+		switch {
+		case st.Ruleset != nil:
+			var out selector.Group
+			for _, chn := range st.Ruleset.Selector {
+				if !applied[chn] {
+					out = append(out, chn)
+				}
+			}
+			if len(out) > 0 {
+				rest.Statements = append(rest.Statements, css.Statement{
+					Ruleset: &css.Ruleset{Selector: out, DeclarationList: st.Ruleset.DeclarationList}})
+			}
+		case st.AtRule != nil:
+			rest.Statements = append(rest.Statements, st)
+		}
+	}
+	return rest
+}
+
+func inlineCSS(root *html.Node, external []*css.Stylesheet, opts InlineOptions) {
+	var sources []*html.Node
+	all := &css.Stylesheet{}
+	for _, ss := range external {
+		all.Statements = append(all.Statements, ss.Statements...)
+	}
+	h5.WalkNodes(root, func(n *html.Node) {
+		if ss := inlineStyleSource(n, opts.FS); ss != nil {
+			all.Statements = append(all.Statements, ss.Statements...)
+			sources = append(sources, n)
+		}
+	})
+	all = css.Flatten(all)
+	inlinable := inlinableRules(all)
+	c := cascade.New(inlinable)
+	applied := map[*selector.Chain]bool{}
+	vars := map[*html.Node]map[string]string{}
+	walk.Walker(func(n *html.Node) walk.Action {
+		if n.Type != html.ElementNode {
+			return walk.Continue
+		}
+		if n.DataAtom == atom.Head {
+			// Nothing in the <head> renders.
+			return walk.SkipChildren
+		}
+		for _, st := range inlinable.Statements {
+			for _, chn := range st.Ruleset.Selector {
+				if !applied[chn] && chn.Match(n) {
+					applied[chn] = true
+				}
+			}
+		}
+		dl, err := c.Declarations(n)
+		if err != nil {
+			return walk.Continue
+		}
+		if vars[n], err = c.Vars(n, vars[n.Parent]); err != nil {
+			return walk.Continue
+		}
+		dl = substituteVars(dl, vars[n])
+		if len(dl) == 0 {
+			removeAttr(n, "style")
+			return walk.Continue
+		}
+		ModifyAttrib("style", dl.String())(n)
+		if opts.LegacyAttributes {
+			addLegacyAttributes(n, dl)
+		}
+		return walk.Continue
+	}).Walk(root)
+	rest := resolveRestVars(inlinable, remainingRules(all, applied))
+	replaceStyleSources(root, sources, rest)
+	if opts.RemoveClasses {
+		removeUnusedClasses(root, usedClasses(rest))
+	}
+}
+
+// resolveRestVars substitutes the custom properties defined on :root into
+// the rules that can't be inlined.
+func resolveRestVars(inlinable, rest *css.Stylesheet) *css.Stylesheet {
+	all := &css.Stylesheet{}
+	for _, st := range inlinable.Statements {
+		if st.Ruleset != nil && st.Ruleset.Selector.String() == ":root" {
+			all.Statements = append(all.Statements, st)
+		}
+	}
+	all.Statements = append(all.Statements, rest.Statements...)
+	out := &css.Stylesheet{}
+	for _, st := range css.ResolveRootVars(all).Statements {
+		// The :root rules are inlined already.
+		if st.Ruleset == nil || st.Ruleset.Selector.String() != ":root" {
+			out.Statements = append(out.Statements, st)
+		}
+	}
+	return out
+}
+
+// substituteVars substitutes the var() functions in dl since mail clients
+// don't support custom properties. The custom properties and any
+// declarations with invalid var() functions are dropped.
+func substituteVars(dl css.DeclarationList, vars map[string]string) css.DeclarationList {
+	lookup := func(name string) (string, bool) {
+		v, ok := vars[name]
+		return v, ok
+	}
+	var out css.DeclarationList
+	for _, d := range dl {
+		if css.IsCustomProperty(d.Property) {
+			continue
+		}
+		v, ok := css.SubstituteVars(d.Value, lookup)
+		if !ok {
+			continue
+		}
+		d.Value = v
+		out = append(out, d)
+	}
+	return out
+}
+
+// replaceStyleSources removes the elements the inlined css came from and
+// puts the css that couldn't be inlined in a <style> element.
+func replaceStyleSources(root *html.Node, sources []*html.Node, rest *css.Stylesheet) {
+	if len(rest.Statements) > 0 {
+		style := h5.Element("style", nil, h5.Text("\n"+rest.String()+"\n"))
+		style.DataAtom = atom.Style
+		switch {
+		case len(sources) > 0:
+			sources[0].Parent.InsertBefore(style, sources[0])
+		case findHead(root) != nil:
+			findHead(root).AppendChild(style)
+		default:
+			root.InsertBefore(style, root.FirstChild)
+		}
+	}
+	for _, n := range sources {
+		n.Parent.RemoveChild(n)
+	}
+}
+
+func findHead(root *html.Node) *html.Node {
+	var head *html.Node
+	h5.WalkNodes(root, func(n *html.Node) {
+		if head == nil && n.Type == html.ElementNode && n.DataAtom == atom.Head {
+			head = n
+		}
+	})
+	return head
+}
+
+// usedClasses returns the class names referenced by the selectors in ss.
+func usedClasses(ss *css.Stylesheet) map[string]bool {
+	used := map[string]bool{}
+	var addRuleset func(rs *css.Ruleset)
+	addRuleset = func(rs *css.Ruleset) {
+		for _, chn := range rs.Selector {
+			for _, seq := range append([]selector.Sequence{chn.Head}, tailSequences(chn)...) {
+				for _, sel := range seq {
+					if sel.Type == selector.Class {
+						used[sel.Value] = true
+					} else if sel.Type == selector.PseudoClass {
+						// Classes can hide in functional pseudo classes
+						// like :not(.foo).
+						for _, m := range classRe.FindAllStringSubmatch(sel.Value, -1) {
+							used[m[1]] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	var addAtRule func(ar *css.AtRule)
+	addAtRule = func(ar *css.AtRule) {
+		if ar.SimpleBlock == nil {
+			return
+		}
+		for _, bi := range ar.SimpleBlock.Content {
+			if bi.Ruleset != nil {
+				addRuleset(bi.Ruleset)
+			}
+			if bi.AtRule != nil {
+				addAtRule(bi.AtRule)
+			}
+		}
+	}
+	for _, st := range ss.Statements {
+		if st.Ruleset != nil {
+			addRuleset(st.Ruleset)
+		}
+		if st.AtRule != nil {
+			addAtRule(st.AtRule)
+		}
+	}
+	return used
+}
+
+var classRe = regexp.MustCompile(`\.([-_a-zA-Z0-9]+)`)
+
+func tailSequences(chn *selector.Chain) []selector.Sequence {
+	seqs := make([]selector.Sequence, len(chn.Tail))
+	for i, l := range chn.Tail {
+		seqs[i] = l.Sequence
+	}
+	return seqs
+}
+
+func removeUnusedClasses(root *html.Node, used map[string]bool) {
+	h5.WalkNodes(root, func(n *html.Node) {
+		class, ok := attr(n, "class")
+		if !ok {
+			return
+		}
+		var keep []string
+		for _, c := range strings.Fields(class) {
+			if used[c] {
+				keep = append(keep, c)
+			}
+		}
+		if len(keep) == 0 {
+			removeAttr(n, "class")
+		} else {
+			ModifyAttrib("class", strings.Join(keep, " "))(n)
+		}
+	})
+}
+
+// legacyAttributes maps css properties to the html attributes that mirror
+// them and the elements those attributes apply to.
+var legacyAttributes = []struct {
+	prop, attr string
+	elements   []atom.Atom
+}{
+	{"background-color", "bgcolor", []atom.Atom{atom.Body, atom.Table, atom.Tr, atom.Td, atom.Th}},
+	{"width", "width", []atom.Atom{atom.Table, atom.Td, atom.Th, atom.Img}},
+	{"height", "height", []atom.Atom{atom.Table, atom.Td, atom.Th, atom.Img}},
+	{"text-align", "align", []atom.Atom{atom.Td, atom.Th, atom.Tr, atom.P, atom.Div,
+		atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6}},
+	{"vertical-align", "valign", []atom.Atom{atom.Td, atom.Th, atom.Tr}},
+	{"float", "align", []atom.Atom{atom.Img, atom.Table}},
+	{"background-image", "background", []atom.Atom{atom.Body, atom.Table, atom.Td, atom.Th}},
+}
+
+func addLegacyAttributes(n *html.Node, dl css.DeclarationList) {
+	for _, la := range legacyAttributes {
+		if !hasAtom(la.elements, n.DataAtom) {
+			continue
+		}
+		if _, ok := attr(n, la.attr); ok {
+			continue
+		}
+		for _, d := range dl {
+			if d.Property != la.prop {
+				continue
+			}
+			if v, ok := legacyValue(la.attr, d.Value); ok {
+				n.Attr = append(n.Attr, html.Attribute{Key: la.attr, Val: v})
+			}
+		}
+	}
+}
+
+func hasAtom(as []atom.Atom, a atom.Atom) bool {
+	for _, a1 := range as {
+		if a1 == a {
+			return true
+		}
+	}
+	return false
+}
+
+// legacyValue converts a css value into the value of the html attribute
+// attr. It returns false if the value can't be expressed as an attribute.
+func legacyValue(attr, value string) (string, bool) {
+	vl, err := css.ParseValue(value)
+	if err != nil || len(vl) != 1 {
+		return "", false
+	}
+	cv := vl[0]
+	switch attr {
+	case "bgcolor":
+		if c, ok := cv.Color(); ok && c.A == 1 {
+			return c.String(), true
+		}
+	case "width", "height":
+		if cv.Type == css.PercentageValue {
+			return cv.String(), true
+		}
+		if l, ok := cv.Length(); ok && (l.Unit == "px" || l.Unit == "") {
+			return css.ComponentValue{Type: css.NumberValue, Number: l.Value}.String(), true
+		}
+	case "align":
+		switch strings.ToLower(cv.Value) {
+		case "left", "right", "center", "justify":
+			return strings.ToLower(cv.Value), cv.Type == css.IdentValue
+		}
+	case "valign":
+		switch strings.ToLower(cv.Value) {
+		case "top", "middle", "bottom", "baseline":
+			return strings.ToLower(cv.Value), cv.Type == css.IdentValue
+		}
+	case "background":
+		if cv.Type == css.URLValue {
+			return cv.Value, true
+		}
+	}
+	return "", false
+}
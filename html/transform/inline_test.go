@@ -0,0 +1,108 @@
+package transform
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+func inlineString(t *testing.T, doc string, opts InlineOptions) string {
+	tree, err := h5.NewFromString(doc)
+	if err != nil {
+		t.Fatalf("Error parsing document: %s", err)
+	}
+	f, err := InlineCSS(opts)
+	if err != nil {
+		t.Fatalf("Error creating inliner: %s", err)
+	}
+	f(tree.Top())
+	return tree.String()
+}
+
+func TestInlineCSS(t *testing.T) {
+	out := inlineString(t, `<html><head><style>
+p { color: red; margin: 0 }
+.big { font-size: 20px }
+#x { color: blue }
+a:hover { color: green }
+@media (max-width: 600px) { p { color: black } }
+</style></head><body><p id="x" class="big" style="margin: 1px">a</p><p>b</p></body></html>`, InlineOptions{})
+	assertEqual(t, out, `<html><head><style>
+a:hover { color: green; }
+@media (max-width: 600px) {
+p { color: black; }
+}
+</style></head><body><p id="x" class="big" style="font-size: 20px; color: blue; margin: 1px">a</p><p style="color: red; margin: 0">b</p></body></html>`)
+}
+
+func TestInlineCSSSheets(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.css":  {Data: []byte("p { color: red }")},
+		"extra.css": {Data: []byte("p { color: blue !important }")},
+	}
+	out := inlineString(t, `<html><head><link rel="stylesheet" href="/extra.css"><link rel="stylesheet" href="http://example.com/a.css"></head><body><p>a</p></body></html>`,
+		InlineOptions{FS: fsys, Sheets: []string{"base.css"}})
+	assertEqual(t, out, `<html><head><link rel="stylesheet" href="http://example.com/a.css"/></head><body><p style="color: blue !important">a</p></body></html>`)
+	if _, err := InlineCSS(InlineOptions{FS: fsys, Sheets: []string{"missing.css"}}); err == nil {
+		t.Error("Expected an error for a missing sheet")
+	}
+}
+
+func TestInlineCSSRemoveClasses(t *testing.T) {
+	out := inlineString(t, `<html><head><style>.a { color: red } .b:hover { color: blue }</style></head><body><p class="a b">a</p><p class="a">b</p></body></html>`,
+		InlineOptions{RemoveClasses: true})
+	assertEqual(t, out, `<html><head><style>
+.b:hover { color: blue; }
+</style></head><body><p class="b" style="color: red">a</p><p style="color: red">b</p></body></html>`)
+}
+
+func TestInlineCSSLegacyAttributes(t *testing.T) {
+	out := inlineString(t, `<html><head><style>
+table { background-color: #ff0000; width: 600px }
+td { text-align: center; vertical-align: top; width: 50% }
+img { height: 10em }
+</style></head><body><table width="500"><tbody><tr><td>a</td></tr></tbody></table><img src="a.png"/></body></html>`,
+		InlineOptions{LegacyAttributes: true})
+	assertEqual(t, out, `<html><head></head><body><table width="500" style="background-color: #ff0000; width: 600px" bgcolor="#ff0000"><tbody><tr><td style="text-align: center; vertical-align: top; width: 50%" width="50%" align="center" valign="top">a</td></tr></tbody></table><img src="a.png" style="height: 10em"/></body></html>`)
+}
+
+func TestInlineCSSVars(t *testing.T) {
+	out := inlineString(t, `<html><head><style>
+:root { --brand: #ff0000; --pad: 4px; }
+.dark { --brand: black; }
+p { color: var(--brand); padding: var(--pad) calc(var(--pad) * 2); margin: var(--missing); }
+a { border-color: var(--none, var(--pad, blue)); }
+@media (max-width: 600px) { p { padding: var(--pad); } }
+</style></head><body><p>a</p><div class="dark"><p style="--pad: 1px">b</p></div><a>c</a></body></html>`, InlineOptions{})
+	assertEqual(t, out, `<html><head><style>
+@media (max-width: 600px) {
+p { padding: 4px; }
+}
+</style></head><body><p style="color: #ff0000; padding: 4px calc(4px * 2)">a</p><div class="dark"><p style="color: black; padding: 1px calc(1px * 2)">b</p></div><a style="border-color: 4px">c</a></body></html>`)
+}
+
+func TestInlineCSSUnappliedRules(t *testing.T) {
+	out := inlineString(t, `<html><head><style>
+* { margin: 0 }
+td:first-child { color: red }
+.unused { color: blue }
+</style></head><body>
+<table>
+  <tr>
+    <td>a</td>
+    <td>b</td>
+  </tr>
+</table>
+</body></html>`, InlineOptions{})
+	assertEqual(t, out, `<html style="margin: 0"><head><style>
+.unused { color: blue; }
+</style></head><body style="margin: 0">
+<table style="margin: 0">
+  <tbody style="margin: 0"><tr style="margin: 0">
+    <td style="margin: 0; color: red">a</td>
+    <td style="margin: 0">b</td>
+  </tr>
+</tbody></table>
+</body></html>`)
+}
@@ -0,0 +1,70 @@
+package transform
+
+import (
+	"golang.org/x/net/html"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+// Wrap creates a TransformFunc that wraps the node it operates on in a
+// copy of wrapper.
+func Wrap(wrapper *html.Node) TransformFunc {
+	return func(n *html.Node) {
+		h5.Wrap(n, h5.CloneNode(wrapper))
+	}
+}
+
+// Unwrap creates a TransformFunc that replaces the node it operates on
+// with its children.
+func Unwrap() TransformFunc {
+	return func(n *html.Node) {
+		h5.Unwrap(n)
+	}
+}
+
+// InsertBefore creates a TransformFunc that inserts copies of the nodes
+// passed in before the node it operates on.
+func InsertBefore(ns ...*html.Node) TransformFunc {
+	return func(n *html.Node) {
+		for _, c := range ns {
+			h5.MoveTo(h5.CloneNode(c), n.Parent, n)
+		}
+	}
+}
+
+// InsertAfter creates a TransformFunc that inserts copies of the nodes
+// passed in after the node it operates on.
+func InsertAfter(ns ...*html.Node) TransformFunc {
+	return func(n *html.Node) {
+		ref := n
+		for _, c := range ns {
+			nc := h5.CloneNode(c)
+			h5.InsertAfter(ref, nc)
+			ref = nc
+		}
+	}
+}
+
+// Detach creates a TransformFunc that removes the node it operates on
+// from the tree.
+func Detach() TransformFunc {
+	return func(n *html.Node) {
+		h5.Detach(n)
+	}
+}
+
+// MoveTo creates a TransformFunc that moves the node it operates on into
+// newParent before the child before or to the end if before is nil.
+func MoveTo(newParent, before *html.Node) TransformFunc {
+	return func(n *html.Node) {
+		h5.MoveTo(n, newParent, before)
+	}
+}
+
+// NormalizeText creates a TransformFunc that merges the adjacent text
+// nodes under the node it operates on.
+func NormalizeText() TransformFunc {
+	return func(n *html.Node) {
+		h5.NormalizeText(n)
+	}
+}
@@ -0,0 +1,25 @@
+package transform
+
+import (
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+func TestMutationTransforms(t *testing.T) {
+	tree, _ := h5.NewFromString(`<html><body><p>a</p><p>b</p><div id="x"><span>c</span></div><ul></ul></body></html>`)
+	tf := New(tree)
+	tf.Apply(Wrap(h5.Element("section", nil)), "p")
+	tf.Apply(InsertBefore(h5.Element("hr", nil)), "section")
+	tf.Apply(InsertAfter(h5.Text("1"), h5.Text("2")), "span")
+	tf.Apply(Unwrap(), "span")
+	tf.Apply(NormalizeText(), "div")
+	assertEqual(t, tf.String(), `<html><head></head><body><hr/><section><p>a</p></section>`+
+		`<hr/><section><p>b</p></section><div id="x">c12</div><ul></ul></body></html>`)
+	assertEqual(t, len(h5.Children(tf.Doc().LastChild.LastChild.LastChild.PrevSibling)), 1)
+	tf.Apply(Detach(), "hr")
+	ul := tf.Doc().LastChild.LastChild.LastChild
+	tf.Apply(MoveTo(ul, nil), "section")
+	assertEqual(t, tf.String(), `<html><head></head><body><div id="x">c12</div>`+
+		`<ul><section><p>a</p></section><section><p>b</p></section></ul></body></html>`)
+}
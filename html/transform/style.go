@@ -0,0 +1,172 @@
+package transform
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/sanitize"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+// TransformStyle creates a TransformFunc that transforms the declarations
+// in the style attribute of the node it operates on using the provided
+// func. A node without a style attribute is passed an empty
+// DeclarationList. The style attribute is removed if f returns no
+// declarations. Nodes whose style attribute can't be parsed are left
+// alone.
+func TransformStyle(f func(css.DeclarationList) css.DeclarationList) TransformFunc {
+	return func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		var dl css.DeclarationList
+		if s, ok := attr(n, "style"); ok {
+			var err error
+			if dl, err = css.ParseDeclarations(s); err != nil {
+				return
+			}
+		}
+		dl = f(dl)
+		if len(dl) == 0 {
+			removeAttr(n, "style")
+			return
+		}
+		ModifyAttrib("style", dl.String())(n)
+	}
+}
+
+// SetStyle creates a TransformFunc that sets a property in the style
+// attribute of the node it operates on. A value ending in !important
+// makes an important declaration. An existing declaration of prop is
+// replaced where it is, otherwise the declaration is appended.
+func SetStyle(prop, value string) TransformFunc {
+	return TransformStyle(func(dl css.DeclarationList) css.DeclarationList {
+		return setDeclaration(dl, newDeclaration(prop, value), true)
+	})
+}
+
+// RemoveStyle creates a TransformFunc that removes every declaration of
+// prop from the style attribute of the node it operates on.
+func RemoveStyle(prop string) TransformFunc {
+	prop = normalizeProperty(prop)
+	return TransformStyle(func(dl css.DeclarationList) css.DeclarationList {
+		out := dl[:0]
+		for _, d := range dl {
+			if d.Property != prop {
+				out = append(out, d)
+			}
+		}
+		return out
+	})
+}
+
+// MergeStyle creates a TransformFunc that merges decls into the style
+// attribute of the node it operates on as SetStyle would, except that
+// !important declarations already in the attribute aren't overridden by
+// declarations that aren't !important.
+func MergeStyle(decls css.DeclarationList) TransformFunc {
+	return TransformStyle(func(dl css.DeclarationList) css.DeclarationList {
+		for _, d := range decls {
+			d.Property = normalizeProperty(d.Property)
+			dl = setDeclaration(dl, d, false)
+		}
+		return dl
+	})
+}
+
+// newDeclaration makes a Declaration from a property and a value which may
+// end in !important.
+func newDeclaration(prop, value string) css.Declaration {
+	d := css.Declaration{Property: normalizeProperty(prop), Value: strings.TrimSpace(value)}
+	if i := strings.LastIndex(d.Value, "!"); i >= 0 &&
+		strings.EqualFold(strings.TrimSpace(d.Value[i+1:]), "important") {
+		d.Value = strings.TrimSpace(d.Value[:i])
+		d.Important = true
+	}
+	return d
+}
+
+// normalizeProperty lower cases property names except for custom
+// properties which are case sensitive.
+func normalizeProperty(prop string) string {
+	prop = strings.TrimSpace(prop)
+	if css.IsCustomProperty(prop) {
+		return prop
+	}
+	return strings.ToLower(prop)
+}
+
+// setDeclaration replaces the first declaration of d.Property in dl with d
+// and drops any later ones. d is appended if dl has no declaration of
+// d.Property. Unless force is true an important declaration isn't replaced
+// by one that isn't.
+func setDeclaration(dl css.DeclarationList, d css.Declaration, force bool) css.DeclarationList {
+	if !force && !d.Important {
+		for _, old := range dl {
+			if old.Property == d.Property && old.Important {
+				// The important declaration wins regardless of order.
+				d = old
+			}
+		}
+	}
+	out := make(css.DeclarationList, 0, len(dl)+1)
+	set := false
+	for _, old := range dl {
+		if old.Property != d.Property {
+			out = append(out, old)
+			continue
+		}
+		if !set {
+			out = append(out, d)
+			set = true
+		}
+	}
+	if !set {
+		out = append(out, d)
+	}
+	return out
+}
+
+// ScopeAttrib creates a TransformFunc that sets the attribute key on the
+// node it operates on and every element descending from it. It stamps
+// the elements of a component with the attribute its scoped css requires.
+func ScopeAttrib(key string) TransformFunc {
+	return func(n *html.Node) {
+		h5.WalkNodes(n, func(n *html.Node) {
+			if n.Type == html.ElementNode {
+				ModifyAttrib(key, "")(n)
+			}
+		})
+	}
+}
+
+// SanitizeCSS creates a TransformFunc that sanitizes the style attributes
+// and <style> elements of the node it operates on and its descendants
+// using s. Style attributes left empty are removed. It is meant to run as
+// part of sanitizing untrusted html.
+func SanitizeCSS(s *sanitize.Sanitizer) TransformFunc {
+	return func(n *html.Node) {
+		h5.WalkNodes(n, func(n *html.Node) {
+			if n.Type != html.ElementNode {
+				return
+			}
+			if n.DataAtom == atom.Style {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.TextNode {
+						c.Data = s.Stylesheet(c.Data)
+					}
+				}
+			}
+			if v, ok := attr(n, "style"); ok {
+				if v = s.Style(v); v == "" {
+					removeAttr(n, "style")
+				} else {
+					ModifyAttrib("style", v)(n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package transform
+
+import (
+	"strings"
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/sanitize"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+func styleString(f TransformFunc, style string) string {
+	n := h5.Element("p", nil)
+	if style != "" {
+		ModifyAttrib("style", style)(n)
+	}
+	f(n)
+	return h5.NewTree(n).String()
+}
+
+func TestSetStyle(t *testing.T) {
+	cases := []struct {
+		prop, value, style, expected string
+	}{
+		{"color", "red", "", `<p style="color: red"></p>`},
+		{"Color", "red", "margin: 0; color: blue; padding: 0", `<p style="margin: 0; color: red; padding: 0"></p>`},
+		{"color", "red", "color: blue !important; color: green", `<p style="color: red"></p>`},
+		{"color", "red !important", "margin: 0", `<p style="margin: 0; color: red !important"></p>`},
+	}
+	for _, c := range cases {
+		assertEqual(t, styleString(SetStyle(c.prop, c.value), c.style), c.expected)
+	}
+}
+
+func TestRemoveStyle(t *testing.T) {
+	assertEqual(t, styleString(RemoveStyle("color"), "color: red; margin: 0; COLOR: blue !important"),
+		`<p style="margin: 0"></p>`)
+	assertEqual(t, styleString(RemoveStyle("color"), "color: red"), `<p></p>`)
+	assertEqual(t, styleString(RemoveStyle("color"), ""), `<p></p>`)
+}
+
+func TestMergeStyle(t *testing.T) {
+	decls := css.DeclarationList{
+		{Property: "color", Value: "red"},
+		{Property: "margin", Value: "1px", Important: true},
+		{Property: "padding", Value: "2px"},
+	}
+	assertEqual(t, styleString(MergeStyle(decls), "color: blue !important; margin: 0; border: 0"),
+		`<p style="color: blue !important; margin: 1px !important; border: 0; padding: 2px"></p>`)
+}
+
+func TestTransformStyle(t *testing.T) {
+	upper := TransformStyle(func(dl css.DeclarationList) css.DeclarationList {
+		for i := range dl {
+			dl[i].Value = strings.ToUpper(dl[i].Value)
+		}
+		return dl
+	})
+	assertEqual(t, styleString(upper, "color: red; margin: 0 auto"), `<p style="color: RED; margin: 0 AUTO"></p>`)
+}
+
+func TestScopeAttrib(t *testing.T) {
+	n := h5.Div("", nil, h5.Element("p", nil, h5.Text("a")), h5.Anchor("/", "b"))
+	ScopeAttrib("data-s-x")(n)
+	assertEqual(t, h5.NewTree(n).String(),
+		`<div data-s-x=""><p data-s-x="">a</p><a href="/" data-s-x="">b</a></div>`)
+}
+
+func TestSanitizeCSS(t *testing.T) {
+	ns, err := h5.PartialFromString(`<div style="color: red; behavior: url(x.htc)">` +
+		`<style>p { color: blue; background: url(javascript:alert(1)) }</style>` +
+		`<p style="-moz-binding: url(x.xml#x)">a</p></div>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SanitizeCSS(sanitize.New())(ns[0])
+	assertEqual(t, h5.RenderNodesToString(ns),
+		`<div style="color: red"><style>p { color: blue; }</style><p>a</p></div>`)
+}
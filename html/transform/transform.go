@@ -184,9 +184,7 @@ func RemoveChildren() TransformFunc {
 }
 
 func removeChildren(n *html.Node) {
-	for c := n.FirstChild; c != nil; c = c.NextSibling {
-		defer n.RemoveChild(c)
-	}
+	h5.Empty(n)
 }
 
 // ReplaceChildren creates a TransformFunc that replaces the Children of the
@@ -208,17 +206,65 @@ func nodeToString(n *html.Node) string {
 // Replace constructs a TransformFunc that replaces a node with the nodes passed
 // in.
 func Replace(ns ...*html.Node) TransformFunc {
+	return func(n *html.Node) {
+		clones := make([]*html.Node, len(ns))
+		for i, nc := range ns {
+			clones[i] = h5.CloneNode(nc)
+		}
+		h5.ReplaceWith(n, clones...)
+	}
+}
+
+// mustParseIn parses the html fragment s in the context of the element n.
+func mustParseIn(s string, n *html.Node) []*html.Node {
+	ns, err := h5.PartialInContextFromString(s, n)
+	if err != nil {
+		panic(fmt.Sprintf("Unable to parse %q in %s: %s", s, nodeToString(n), err))
+	}
+	return ns
+}
+
+// AppendChildrenFromString creates a TransformFunc that appends the html
+// fragment s parsed in the context of the node it operates on. So eg:
+// <tr> fragments can be appended to a <tbody>.
+func AppendChildrenFromString(s string) TransformFunc {
+	return func(n *html.Node) {
+		for _, c := range mustParseIn(s, n) {
+			n.AppendChild(c)
+		}
+	}
+}
+
+// PrependChildrenFromString creates a TransformFunc that prepends the
+// html fragment s parsed in the context of the node it operates on.
+func PrependChildrenFromString(s string) TransformFunc {
+	return func(n *html.Node) {
+		first := n.FirstChild
+		for _, c := range mustParseIn(s, n) {
+			n.InsertBefore(c, first)
+		}
+	}
+}
+
+// ReplaceChildrenFromString creates a TransformFunc that replaces the
+// Children of the node it operates on with the html fragment s parsed in
+// its context.
+func ReplaceChildrenFromString(s string) TransformFunc {
+	return func(n *html.Node) {
+		removeChildren(n)
+		AppendChildrenFromString(s)(n)
+	}
+}
+
+// ReplaceFromString constructs a TransformFunc that replaces a node with
+// the html fragment s parsed in the context of its parent.
+func ReplaceFromString(s string) TransformFunc {
 	return func(n *html.Node) {
 		p := n.Parent
-		switch p {
-		case nil:
+		if p == nil || p.Type != html.ElementNode {
 			panic(fmt.Sprintf("Attempt to replace Root node: %s", h5.RenderNodesToString([]*html.Node{n})))
-		default:
-			for _, nc := range ns {
-				p.InsertBefore(h5.CloneNode(nc), n)
-			}
-			p.RemoveChild(n)
 		}
+		h5.ReplaceWith(n, mustParseIn(s, p)...)
 	}
 }
 
@@ -210,3 +210,15 @@ func BenchmarkTransformApply(b *testing.B) {
 		tf.Doc()
 	}
 }
+
+func TestFromStringInContext(t *testing.T) {
+	tree, _ := h5.NewFromString("<table><tbody><tr><td>a</td></tr></tbody></table><select></select><ul><li>x</li></ul>")
+	tf := New(tree)
+	tf.Apply(AppendChildrenFromString("<tr><td>b</td></tr>"), "tbody")
+	tf.Apply(PrependChildrenFromString("<tr><td>c</td></tr>"), "tbody")
+	tf.Apply(ReplaceChildrenFromString("<option>1</option><option>2</option>"), "select")
+	tf.Apply(ReplaceFromString("<li>y</li><li>z</li>"), "li")
+	assertEqual(t, tf.String(), "<html><head></head><body><table><tbody>"+
+		"<tr><td>c</td></tr><tr><td>a</td></tr><tr><td>b</td></tr></tbody></table>"+
+		"<select><option>1</option><option>2</option></select><ul><li>y</li><li>z</li></ul></body></html>")
+}
@@ -0,0 +1,389 @@
+package transform
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/css"
+	"go.marzhillstudios.com/pkg/go-html-transform/css/tokenizer"
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+type urlKind int
+
+const (
+	// LinkURL is a navigation like an <a href>, a <form action> or a
+	// <meta http-equiv=refresh>.
+	LinkURL urlKind = iota
+	// ImageURL is an image like an <img src> or a <video poster>.
+	ImageURL
+	// StylesheetURL is a <link rel=stylesheet href> or an @import.
+	StylesheetURL
+	// ScriptURL is a <script src>.
+	ScriptURL
+	// MediaURL is audio, video or an embedded object.
+	MediaURL
+	// CSSURL is any other url() in css like a background image or a font.
+	CSSURL
+)
+
+func (k urlKind) String() string {
+	switch k {
+	case LinkURL:
+		return "link"
+	case ImageURL:
+		return "image"
+	case StylesheetURL:
+		return "stylesheet"
+	case ScriptURL:
+		return "script"
+	case MediaURL:
+		return "media"
+	case CSSURL:
+		return "css-url"
+	}
+	panic("Unreachable")
+}
+
+// URLContext describes where a url being rewritten was found.
+type URLContext struct {
+	Kind urlKind
+	// Node is the element containing the url. It is nil for urls in
+	// external stylesheets.
+	Node *html.Node
+	// Attr is the attribute containing the url or "" for urls in the
+	// contents of a <style> element or an external stylesheet.
+	Attr string
+}
+
+// URLRewriter returns the url that should replace u or nil to leave it
+// alone.
+type URLRewriter func(u *url.URL, ctx URLContext) *url.URL
+
+// RewriteURLs creates a TransformFunc that rewrites the urls referenced
+// by the node it operates on and its descendants. It covers the href,
+// src, srcset, poster, action and data attributes, the url of
+// <meta http-equiv=refresh> elements and the url() and @import references
+// in <style> elements and style attributes. Urls that don't parse are
+// left alone.
+func RewriteURLs(f URLRewriter) TransformFunc {
+	return func(n *html.Node) {
+		h5.WalkNodes(n, func(n *html.Node) {
+			if n.Type == html.ElementNode {
+				rewriteElementURLs(n, f)
+			}
+		})
+	}
+}
+
+// RewriteCSSURLs rewrites the url() and @import references in the css
+// source src like RewriteURLs does for <style> elements. It is meant for
+// external stylesheets so the URLContext has no Node.
+func RewriteCSSURLs(src string, f URLRewriter) string {
+	return rewriteCSS(src, URLContext{}, f)
+}
+
+func rewriteElementURLs(n *html.Node, f URLRewriter) {
+	if n.DataAtom == atom.Style {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.TextNode {
+				c.Data = rewriteCSS(c.Data, URLContext{Node: n}, f)
+			}
+		}
+	}
+	for i, a := range n.Attr {
+		if a.Namespace != "" {
+			continue
+		}
+		ctx := URLContext{Node: n, Attr: a.Key}
+		switch a.Key {
+		case "href", "src", "poster", "action", "data":
+			kind, ok := attrURLKind(n, a.Key)
+			if !ok {
+				continue
+			}
+			ctx.Kind = kind
+			n.Attr[i].Val = rewriteURL(a.Val, ctx, f)
+		case "srcset":
+			ctx.Kind = ImageURL
+			n.Attr[i].Val = rewriteSrcset(a.Val, ctx, f)
+		case "style":
+			n.Attr[i].Val = rewriteCSS(a.Val, ctx, f)
+		case "content":
+			if n.DataAtom == atom.Meta {
+				if v, ok := attr(n, "http-equiv"); ok && strings.EqualFold(v, "refresh") {
+					ctx.Kind = LinkURL
+					n.Attr[i].Val = rewriteRefresh(a.Val, ctx, f)
+				}
+			}
+		}
+	}
+}
+
+// attrURLKind returns the kind of url in the attribute key of n. It
+// returns false if the attribute doesn't hold a url on n.
+func attrURLKind(n *html.Node, key string) (urlKind, bool) {
+	switch key {
+	case "href":
+		if n.DataAtom == atom.Link {
+			return linkURLKind(n), true
+		}
+		return LinkURL, true
+	case "src":
+		switch n.DataAtom {
+		case atom.Img, atom.Input:
+			return ImageURL, true
+		case atom.Script:
+			return ScriptURL, true
+		case atom.Source:
+			if n.Parent != nil && n.Parent.DataAtom == atom.Picture {
+				return ImageURL, true
+			}
+			return MediaURL, true
+		case atom.Video, atom.Audio, atom.Track, atom.Embed:
+			return MediaURL, true
+		}
+		return LinkURL, true
+	case "poster":
+		return ImageURL, n.DataAtom == atom.Video
+	case "action":
+		return LinkURL, n.DataAtom == atom.Form
+	case "data":
+		return MediaURL, n.DataAtom == atom.Object
+	}
+	return 0, false
+}
+
+func linkURLKind(n *html.Node) urlKind {
+	rel, _ := attr(n, "rel")
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet":
+			return StylesheetURL
+		case "icon", "apple-touch-icon":
+			return ImageURL
+		}
+	}
+	return LinkURL
+}
+
+// rewriteURL returns the rewritten form of the url s or s if f leaves it
+// alone.
+func rewriteURL(s string, ctx URLContext, f URLRewriter) string {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil {
+		return s
+	}
+	if nu := f(u, ctx); nu != nil {
+		return nu.String()
+	}
+	return s
+}
+
+// rewriteSrcset rewrites the urls of the image candidates in a srcset
+// attribute.
+// http://www.w3.org/TR/html5/embedded-content-0.html#parse-a-srcset-attribute
+func rewriteSrcset(s string, ctx URLContext, f URLRewriter) string {
+	var candidates []string
+	i := 0
+	for {
+		for i < len(s) && (isHTMLSpace(s[i]) || s[i] == ',') {
+			i++
+		}
+		if i == len(s) {
+			break
+		}
+		start := i
+		for i < len(s) && !isHTMLSpace(s[i]) {
+			i++
+		}
+		u := s[start:i]
+		var descriptors string
+		if strings.HasSuffix(u, ",") {
+			u = strings.TrimRight(u, ",")
+		} else {
+			start = i
+			depth := 0
+			for ; i < len(s) && (s[i] != ',' || depth > 0); i++ {
+				switch s[i] {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+			}
+			descriptors = strings.Join(strings.Fields(s[start:i]), " ")
+		}
+		c := rewriteURL(u, ctx, f)
+		if descriptors != "" {
+			c += " " + descriptors
+		}
+		candidates = append(candidates, c)
+	}
+	return strings.Join(candidates, ", ")
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\f' || c == '\r'
+}
+
+// rewriteRefresh rewrites the url in the content of a
+// <meta http-equiv=refresh> like "5; url=/next".
+func rewriteRefresh(s string, ctx URLContext, f URLRewriter) string {
+	i := strings.IndexAny(s, ";,")
+	if i < 0 {
+		return s
+	}
+	start := i + 1
+	for start < len(s) && isHTMLSpace(s[start]) {
+		start++
+	}
+	if rest := s[start:]; len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+		j := start + 3
+		for j < len(s) && isHTMLSpace(s[j]) {
+			j++
+		}
+		if j < len(s) && s[j] == '=' {
+			start = j + 1
+			for start < len(s) && isHTMLSpace(s[start]) {
+				start++
+			}
+		}
+	}
+	end := len(s)
+	if start < end && (s[start] == '"' || s[start] == '\'') {
+		if k := strings.IndexByte(s[start+1:], s[start]); k >= 0 {
+			end = start + 1 + k
+		}
+		start++
+	}
+	if start >= end {
+		return s
+	}
+	return s[:start] + rewriteURL(s[start:end], ctx, f) + s[end:]
+}
+
+// rewriteCSS rewrites the url() and @import references in css source.
+// Everything else is left as it is.
+func rewriteCSS(src string, ctx URLContext, f URLRewriter) string {
+	var toks []*tokenizer.Token
+	tk := tokenizer.New(strings.NewReader(src))
+	for {
+		t, err := tk.Next()
+		if err != nil {
+			return src
+		}
+		if t == nil {
+			break
+		}
+		toks = append(toks, t)
+	}
+	// The spans that aren't rewritten are copied from src byte for byte so
+	// that a rewrite never changes the rest of the css.
+	offs := make([]int, len(toks)+1)
+	o := cssOffsets{src: src, line: 1, col: 1}
+	for i, t := range toks {
+		offs[i] = o.offset(t.Position)
+	}
+	offs[len(toks)] = len(src)
+	var b strings.Builder
+	last := 0
+	splice := func(from, to int, v string) {
+		b.WriteString(src[last:offs[from]])
+		b.WriteString(v)
+		last = offs[to+1]
+	}
+	importing := false
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		ctx.Kind = CSSURL
+		if importing {
+			ctx.Kind = StylesheetURL
+		}
+		switch {
+		case t.Type == tokenizer.AtKeyword:
+			importing = strings.EqualFold(t.String, "@import")
+			continue
+		case t.Type == tokenizer.Uri:
+			if v, ok := rewriteCSSValue(t.Text(), ctx, f); ok {
+				splice(i, i, v)
+			}
+		case t.Type == tokenizer.Function && strings.EqualFold(t.String, "url("):
+			// url("...") is a function with a string argument.
+			j := i + 1
+			for j < len(toks) && toks[j].Type == tokenizer.WS {
+				j++
+			}
+			k := j + 1
+			for k < len(toks) && toks[k].Type == tokenizer.WS {
+				k++
+			}
+			if k >= len(toks) || toks[j].Type != tokenizer.String || toks[k].Type != tokenizer.RParen {
+				break
+			}
+			if v, ok := rewriteCSSValue("url("+toks[j].Text()+")", ctx, f); ok {
+				splice(i, k, v)
+			}
+			i = k
+		case t.Type == tokenizer.String && importing:
+			if v, ok := rewriteCSSValue(t.Text(), ctx, f); ok {
+				splice(i, i, v)
+			}
+		case t.Type == tokenizer.WS || t.Type == tokenizer.Comment:
+			continue
+		}
+		importing = false
+	}
+	b.WriteString(src[last:])
+	return b.String()
+}
+
+// cssOffsets maps token Positions back to byte offsets in src. The
+// tokenizer counts positions after turning \r\n, \r and \f into \n and NUL
+// into U+FFFD so the walk does the same. Positions must be asked for in
+// order.
+type cssOffsets struct {
+	src          string
+	i, line, col int
+}
+
+func (o *cssOffsets) offset(p tokenizer.Position) int {
+	for o.i < len(o.src) && (o.line < p.Line || o.line == p.Line && o.col < p.Column) {
+		switch o.src[o.i] {
+		case '\r':
+			if o.i+1 < len(o.src) && o.src[o.i+1] == '\n' {
+				o.i++
+			}
+			fallthrough
+		case '\n', '\f':
+			o.line++
+			o.col = 1
+		case 0:
+			o.col += len("\uFFFD")
+		default:
+			o.col++
+		}
+		o.i++
+	}
+	return o.i
+}
+
+func rewriteCSSValue(s string, ctx URLContext, f URLRewriter) (string, bool) {
+	vl, err := css.ParseValue(s)
+	if err != nil || len(vl) != 1 || (vl[0].Type != css.URLValue && vl[0].Type != css.StringValue) {
+		return s, false
+	}
+	u, err := url.Parse(vl[0].Value)
+	if err != nil {
+		return s, false
+	}
+	nu := f(u, ctx)
+	if nu == nil || nu.String() == vl[0].Value {
+		return s, false
+	}
+	vl[0].Value = nu.String()
+	return vl[0].String(), true
+}
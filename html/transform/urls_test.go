@@ -0,0 +1,115 @@
+package transform
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"go.marzhillstudios.com/pkg/go-html-transform/h5"
+)
+
+// cdn moves relative urls to a cdn recording the contexts it sees.
+func cdn(seen *[]string) URLRewriter {
+	return func(u *url.URL, ctx URLContext) *url.URL {
+		if u.IsAbs() || strings.HasPrefix(u.Path, "#") || u.Path == "" {
+			return nil
+		}
+		tag := ""
+		if ctx.Node != nil {
+			tag = ctx.Node.Data
+		}
+		*seen = append(*seen, tag+" "+ctx.Attr+" "+ctx.Kind.String())
+		return &url.URL{Scheme: "https", Host: "cdn.example.com", Path: "/" + strings.TrimPrefix(u.Path, "/")}
+	}
+}
+
+func TestRewriteURLs(t *testing.T) {
+	tree, _ := h5.NewFromString(`<html><head>` +
+		`<meta http-equiv="refresh" content="5; URL='next.html'">` +
+		`<link rel="stylesheet" href="a.css"><link rel="icon" href="/i.png">` +
+		`<style>@import "b.css" screen; @import url(c.css); p { background: url("d.png") , url( e.png ) }</style>` +
+		`<script src="s.js"></script></head><body>` +
+		`<a href="page.html#top">a</a><a href="#top">b</a><a href="http://other.com/">c</a>` +
+		`<img src="f.png" srcset="f.png 1x,g.png 2x, h,i.png 3x">` +
+		`<picture><source srcset="j.webp"></picture><video poster="k.png"><source src="l.mp4"></video>` +
+		`<form action="/post"></form><object data="m.svg"></object>` +
+		`<div style="background: url(n.png)"></div></body></html>`)
+	var seen []string
+	RewriteURLs(cdn(&seen))(tree.Top())
+	expected := `<html><head>` +
+		`<meta http-equiv="refresh" content="5; URL=&#39;https://cdn.example.com/next.html&#39;"/>` +
+		`<link rel="stylesheet" href="https://cdn.example.com/a.css"/><link rel="icon" href="https://cdn.example.com/i.png"/>` +
+		`<style>@import "https://cdn.example.com/b.css" screen; @import url(https://cdn.example.com/c.css); p { background: url(https://cdn.example.com/d.png) , url(https://cdn.example.com/e.png) }</style>` +
+		`<script src="https://cdn.example.com/s.js"></script></head><body>` +
+		`<a href="https://cdn.example.com/page.html">a</a><a href="#top">b</a><a href="http://other.com/">c</a>` +
+		`<img src="https://cdn.example.com/f.png" srcset="https://cdn.example.com/f.png 1x, https://cdn.example.com/g.png 2x, https://cdn.example.com/h,i.png 3x"/>` +
+		`<picture><source srcset="https://cdn.example.com/j.webp"/></picture><video poster="https://cdn.example.com/k.png"><source src="https://cdn.example.com/l.mp4"/></video>` +
+		`<form action="https://cdn.example.com/post"></form><object data="https://cdn.example.com/m.svg"></object>` +
+		`<div style="background: url(https://cdn.example.com/n.png)"></div></body></html>`
+	assertEqual(t, tree.String(), expected)
+	expectedSeen := []string{
+		"meta content link",
+		"link href stylesheet",
+		"link href image",
+		"style  stylesheet",
+		"style  stylesheet",
+		"style  css-url",
+		"style  css-url",
+		"script src script",
+		"a href link",
+		"img src image",
+		"img srcset image",
+		"img srcset image",
+		"img srcset image",
+		"source srcset image",
+		"video poster image",
+		"source src media",
+		"form action link",
+		"object data media",
+		"div style css-url",
+	}
+	assertEqual(t, strings.Join(seen, "\n"), strings.Join(expectedSeen, "\n"))
+}
+
+func TestRewriteSrcset(t *testing.T) {
+	var seen []string
+	cases := map[string]string{
+		"a.png":                      "https://cdn.example.com/a.png",
+		" a.png  100w ,\n b.png 2x ": "https://cdn.example.com/a.png 100w, https://cdn.example.com/b.png 2x",
+		"a.png,b.png":                "https://cdn.example.com/a.png,b.png",
+		"a.png, b.png":               "https://cdn.example.com/a.png, https://cdn.example.com/b.png",
+		"a.png, b.png,":              "https://cdn.example.com/a.png, https://cdn.example.com/b.png",
+	}
+	for srcset, expected := range cases {
+		assertEqual(t, rewriteSrcset(srcset, URLContext{}, cdn(&seen)), expected)
+	}
+}
+
+func TestRewriteCSSURLs(t *testing.T) {
+	var seen []string
+	src := `@charset "utf-8"; @import 'x.css'; /* url(a.png) */ a { src: url('b c.woff') format("woff"); background: image-set("d.png" 1x) }`
+	expected := `@charset "utf-8"; @import "https://cdn.example.com/x.css"; /* url(a.png) */ a { src: url(https://cdn.example.com/b%20c.woff) format("woff"); background: image-set("d.png" 1x) }`
+	assertEqual(t, RewriteCSSURLs(src, cdn(&seen)), expected)
+	assertEqual(t, strings.Join(seen, ","), "  stylesheet,  css-url")
+}
+
+func TestRewriteCSSURLsUnchanged(t *testing.T) {
+	src := `@import 'x.css'; a { background: url( "b.png" ) , url( 'c.png' ), url( d.png ) }`
+	assertEqual(t, RewriteCSSURLs(src, func(u *url.URL, ctx URLContext) *url.URL { return nil }), src)
+}
+
+func TestRewriteCSSURLsNoop(t *testing.T) {
+	same := func(u *url.URL, ctx URLContext) *url.URL { return u }
+	src := "@import 'x.css';\r\np { content: 'it\\'s'; background: url( \"b.png\" ) }\f/* \x00 */ a { background: url(c.png) }"
+	assertEqual(t, RewriteCSSURLs(src, same), src)
+	tree, _ := h5.NewFromString(`<div style="content: 'it\'s'; background: url(a.png)"></div>`)
+	RewriteURLs(same)(tree.Top())
+	assertEqual(t, tree.String(), `<html><head></head><body><div style="content: &#39;it\&#39;s&#39;; background: url(a.png)"></div></body></html>`)
+}
+
+func TestRewriteCSSURLsSplice(t *testing.T) {
+	var seen []string
+	src := "p { content: 'it\\'s';\r\n\x00background: url(a.png) }"
+	expected := "p { content: 'it\\'s';\r\n\x00background: url(https://cdn.example.com/a.png) }"
+	assertEqual(t, RewriteCSSURLs(src, cdn(&seen)), expected)
+}